@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The access log keeps the most recent requests in memory for the
+// /debug/requests page (ENABLE_DEBUG_PAGE), e.g. to find a client hammering
+// the dashboard. Every request is also written to the log by logRequests.
+
+const maxAccessLogEntries = 500
+
+// AccessLogEntry is a served request.
+type AccessLogEntry struct {
+	ID       string
+	Time     time.Time
+	Client   string // see clientIP
+	Method   string
+	Path     string
+	Status   int
+	Bytes    int64
+	Duration time.Duration
+}
+
+// accessLog is a ring buffer of the last maxAccessLogEntries requests.
+type accessLog struct {
+	mu      sync.Mutex
+	entries []AccessLogEntry
+	next    int
+}
+
+func (l *accessLog) add(e AccessLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) < maxAccessLogEntries {
+		l.entries = append(l.entries, e)
+		return
+	}
+	l.entries[l.next] = e
+	l.next = (l.next + 1) % maxAccessLogEntries
+}
+
+// list returns the entries, newest first.
+func (l *accessLog) list() []AccessLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]AccessLogEntry, 0, len(l.entries))
+	for i := range l.entries {
+		j := (l.next - 1 - i + 2*len(l.entries)) % len(l.entries)
+		out = append(out, l.entries[j])
+	}
+	return out
+}
+
+// AccessLogCount is the number of requests for a client or path.
+type AccessLogCount struct {
+	Key   string
+	Count int
+}
+
+// topCounts returns the most frequent keys of the entries, at most n.
+func topCounts(entries []AccessLogEntry, key func(AccessLogEntry) string, n int) []AccessLogCount {
+	counts := map[string]int{}
+	for _, e := range entries {
+		counts[key(e)]++
+	}
+	out := make([]AccessLogCount, 0, len(counts))
+	for k, c := range counts {
+		out = append(out, AccessLogCount{k, c})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out[:min(n, len(out))]
+}
+
+func (s *Server) handleDebugRequests(w http.ResponseWriter, r *http.Request) {
+	if !s.enableDebugPage {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	entries := s.accessLog.list()
+	s.render(w, r, "requests.html", map[string]any{
+		"Title":      "Recent Requests",
+		"Entries":    entries,
+		"TopClients": topCounts(entries, func(e AccessLogEntry) string { return e.Client }, 10),
+		"TopPaths":   topCounts(entries, func(e AccessLogEntry) string { return e.Method + " " + e.Path }, 10),
+		"Max":        maxAccessLogEntries,
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogRing(t *testing.T) {
+	var l accessLog
+	for i := range maxAccessLogEntries + 3 {
+		l.add(AccessLogEntry{Status: i})
+	}
+	got := l.list()
+	if len(got) != maxAccessLogEntries {
+		t.Fatalf("len = %d, want %d", len(got), maxAccessLogEntries)
+	}
+	if got[0].Status != maxAccessLogEntries+2 || got[len(got)-1].Status != 3 {
+		t.Errorf("newest = %d, oldest = %d", got[0].Status, got[len(got)-1].Status)
+	}
+}
+
+func TestTopCounts(t *testing.T) {
+	entries := []AccessLogEntry{{Client: "b"}, {Client: "a"}, {Client: "b"}, {Client: "c"}, {Client: "a"}, {Client: "b"}}
+	got := topCounts(entries, func(e AccessLogEntry) string { return e.Client }, 2)
+	if len(got) != 2 || got[0] != (AccessLogCount{"b", 3}) || got[1] != (AccessLogCount{"a", 2}) {
+		t.Errorf("topCounts = %+v", got)
+	}
+}
+
+func TestDebugRequestsPage(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.trustedProxies, _ = parseTrustedProxies("127.0.0.1,::1")
+	app := httptest.NewServer(s.logRequests(s.newMux("podman")))
+	defer app.Close()
+
+	get := func(path string) (int, string) {
+		req, _ := http.NewRequest("GET", app.URL+path, nil)
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, _ := get("/debug/requests"); code != http.StatusNotFound {
+		t.Errorf("disabled: status = %d, want 404", code)
+	}
+	s.enableDebugPage = true
+	get("/containers")
+	code, body := get("/debug/requests")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	for _, want := range []string{"203.0.113.7", "GET /containers", "GET /debug/requests"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+	if e := s.accessLog.list()[1]; e.Path != "/containers" || e.Status != http.StatusOK || e.Bytes == 0 {
+		t.Errorf("entry = %+v", e)
+	}
+}
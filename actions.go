@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// The action log records changes made through the UI and webhooks. For
+// reversible actions it keeps an inverse that can be run with one click
+// within undoWindow. It is a guard rail against misclicks, not a
+// transaction system: the inverse is applied to whatever state the target
+// is in by then.
+
+const (
+	undoWindow = 10 * time.Minute
+	maxActions = 100
+)
+
+// action is an entry in the action log.
+type action struct {
+	ID     string
+	Time   time.Time
+	Name   string // e.g. "stop"
+	Target string // e.g. container name
+	Client string
+	Err    string
+	Undone bool
+
+	undoLabel string
+	undo      func(ctx context.Context) error
+}
+
+// CanUndo reports whether the action can still be undone.
+func (a action) CanUndo() bool {
+	return a.undo != nil && !a.Undone && a.Err == "" && time.Since(a.Time) < undoWindow
+}
+
+// UndoLabel describes the inverse action, e.g. "start".
+func (a action) UndoLabel() string { return a.undoLabel }
+
+// actionLog keeps the most recent actions in memory.
+type actionLog struct {
+	mu      sync.Mutex
+	entries []*action
+}
+
+// record adds an action made by the client of r to the log and writes an
+// audit log line. undo may be nil for irreversible actions.
+func (l *actionLog) record(r *http.Request, name, target string, err error, undoLabel string, undo func(context.Context) error) {
+	client := clientIP(r)
+	if user := currentUser(r.Context()); user != "" {
+		client = user + " (" + client + ")"
+	}
+	l.add(reqID(r.Context()), client, name, target, err, undoLabel, undo)
+}
+
+// add is like record for actions not made in a request, such as scheduled
+// ones; client names the originator.
+func (l *actionLog) add(logID, client, name, target string, err error, undoLabel string, undo func(context.Context) error) {
+	var b [8]byte
+	rand.Read(b[:])
+	a := &action{
+		ID:        fmt.Sprintf("%x", b),
+		Time:      time.Now(),
+		Name:      name,
+		Target:    target,
+		Client:    client,
+		undoLabel: undoLabel,
+		undo:      undo,
+	}
+	outcome := "ok"
+	if err != nil {
+		a.Err = err.Error()
+		outcome = "failed: " + a.Err
+	}
+	log.Printf("[%s] audit: %s %s from %s: %s", logID, name, target, client, outcome)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, a)
+	if len(l.entries) > maxActions {
+		l.entries = l.entries[len(l.entries)-maxActions:]
+	}
+}
+
+// list returns copies of all entries, newest first.
+func (l *actionLog) list() []action {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]action, len(l.entries))
+	for i, a := range l.entries {
+		out[len(out)-1-i] = *a
+	}
+	return out
+}
+
+var errCannotUndo = errors.New("action cannot be undone")
+
+// takeUndo marks the action as undone and returns it. It fails if the
+// action is unknown, irreversible, expired or already undone.
+func (l *actionLog) takeUndo(id string) (action, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, a := range l.entries {
+		if a.ID == id {
+			if !a.CanUndo() {
+				return action{}, errCannotUndo
+			}
+			a.Undone = true
+			return *a, nil
+		}
+	}
+	return action{}, errCannotUndo
+}
+
+func (s *Server) handleActions(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	s.render(w, r, "actions.html", map[string]any{
+		"Title":      "Recent Actions",
+		"Actions":    s.actions.list(),
+		"UndoWindow": undoWindow,
+	})
+}
+
+func (s *Server) handleActionUndo(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	a, err := s.actions.takeUndo(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Action cannot be undone", http.StatusConflict)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+	err = a.undo(ctx)
+	s.actions.record(r, a.undoLabel+" (undo "+a.Name+")", a.Target, err, "", nil)
+	http.Redirect(w, r, s.basePath+"/actions", http.StatusSeeOther)
+}
+
+// containerActions are the simple lifecycle actions, with their inverse.
+var containerActions = map[string]string{
+	"start":   "stop",
+	"stop":    "start",
+	"pause":   "unpause",
+	"unpause": "pause",
+	// Restarting cannot be undone.
+	"restart": "",
+}
+
+// handleContainerAction runs a start, stop, restart, pause or unpause and
+// redirects back to the container page.
+func (s *Server) handleContainerAction(name string) http.HandlerFunc {
+	inverse := containerActions[name]
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.enableActions {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		id := r.PathValue("id")
+		if !validID.MatchString(id) {
+			http.Error(w, "Invalid container ID", http.StatusBadRequest)
+			return
+		}
+		c, err := s.inspectContainer(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, errNotFound) {
+				http.Error(w, "Container Not Found", http.StatusNotFound)
+				return
+			}
+			log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		err = s.podmanPost(r.Context(), "/containers/"+c.ID+"/"+name)
+		var undo func(context.Context) error
+		if inverse != "" {
+			undo = func(ctx context.Context) error {
+				return s.podmanPost(ctx, "/containers/"+c.ID+"/"+inverse)
+			}
+		}
+		s.actions.record(r, name, c.Name, err, inverse, undo)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, s.basePath+"/container/"+c.ID, http.StatusSeeOther)
+	}
+}
+
+// killSignals are the signals the container page offers to send.
+var killSignals = []string{"SIGTERM", "SIGKILL", "SIGHUP", "SIGUSR1"}
+
+// handleContainerKill sends a signal from killSignals to the container and
+// redirects back to the container page.
+func (s *Server) handleContainerKill(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	signal := r.PostFormValue("signal")
+	if !slices.Contains(killSignals, signal) {
+		http.Error(w, "Invalid signal", http.StatusBadRequest)
+		return
+	}
+	c, ok := s.inspectForAction(w, r)
+	if !ok {
+		return
+	}
+	err := s.podmanPost(r.Context(), "/containers/"+c.ID+"/kill?"+url.Values{"signal": {signal}}.Encode())
+	s.actions.record(r, "kill ("+signal+")", c.Name, err, "", nil)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, s.basePath+"/container/"+c.ID, http.StatusSeeOther)
+}
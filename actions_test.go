@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestActionLog(t *testing.T) {
+	var l actionLog
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	undone := 0
+	undo := func(context.Context) error { undone++; return nil }
+
+	l.record(r, "stop", "web", nil, "start", undo)
+	l.record(r, "stop", "db", errors.New("boom"), "start", undo)
+	l.record(r, "restart", "web", nil, "", nil)
+
+	list := l.list()
+	if len(list) != 3 || list[0].Name != "restart" || list[2].Target != "web" {
+		t.Fatalf("list() = %+v, want newest first", list)
+	}
+	if list[0].CanUndo() || list[1].CanUndo() || !list[2].CanUndo() {
+		t.Errorf("CanUndo = %v %v %v, want false false true", list[0].CanUndo(), list[1].CanUndo(), list[2].CanUndo())
+	}
+
+	a, err := l.takeUndo(list[2].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.undo(t.Context())
+	if undone != 1 {
+		t.Errorf("undone = %d, want 1", undone)
+	}
+	if _, err := l.takeUndo(list[2].ID); err == nil {
+		t.Error("second takeUndo succeeded")
+	}
+	if _, err := l.takeUndo(list[1].ID); err == nil {
+		t.Error("takeUndo of failed action succeeded")
+	}
+
+	l.entries[0].Time = time.Now().Add(-undoWindow - time.Second)
+	l.entries[0].Undone = false
+	if _, err := l.takeUndo(l.entries[0].ID); err == nil {
+		t.Error("takeUndo of expired action succeeded")
+	}
+
+	for range maxActions {
+		l.record(r, "start", "web", nil, "stop", undo)
+	}
+	if n := len(l.list()); n != maxActions {
+		t.Errorf("len = %d, want %d", n, maxActions)
+	}
+}
+
+func TestContainerActionUndo(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Post(app.URL+"/container/jellyfin/stop", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stop: status = %d, want 200 after redirect", resp.StatusCode)
+	}
+
+	resp, err = http.Get(app.URL + "/actions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	m := regexp.MustCompile(`/actions/([0-9a-f]+)/undo`).FindStringSubmatch(string(body))
+	if m == nil {
+		t.Fatal("actions page has no undo button")
+	}
+
+	resp, err = http.Post(app.URL+"/actions/"+m[1]+"/undo", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "start (undo stop)") {
+		t.Errorf("undo: status = %d, body does not mention the undo", resp.StatusCode)
+	}
+
+	resp, err = http.Post(app.URL+"/actions/"+m[1]+"/undo", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("second undo: status = %d, want 409", resp.StatusCode)
+	}
+
+	s.enableActions = false
+	resp, err = http.Get(app.URL + "/actions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("disabled: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestContainerKill(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var posts []string
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			mu.Lock()
+			posts = append(posts, strings.TrimPrefix(r.URL.RequestURI(), "/v4.0.0/libpod"))
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(ContainerInspect{ID: "abc123", Name: "nginx"})
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	for signal, want := range map[string]int{"SIGHUP": http.StatusSeeOther, "SIGSEGV": http.StatusBadRequest, "": http.StatusBadRequest} {
+		resp, err := noRedirect.PostForm(app.URL+"/container/abc123/kill", url.Values{"signal": {signal}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("kill %q: status = %d, want %d", signal, resp.StatusCode, want)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(posts) != 1 || posts[0] != "/containers/abc123/kill?signal=SIGHUP" {
+		t.Errorf("posts = %v", posts)
+	}
+	if got := s.actions.list(); len(got) != 1 || got[0].Name != "kill (SIGHUP)" || got[0].Target != "nginx" {
+		t.Errorf("actions = %+v", got)
+	}
+}
+
+func TestContainerPause(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/container/jellyfin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "/pause") {
+		t.Error("running container has no pause button")
+	}
+
+	resp, err = http.Post(app.URL+"/container/jellyfin/pause", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("pause: status = %d, want 200 after redirect", resp.StatusCode)
+	}
+	if got := s.actions.list(); len(got) != 1 || got[0].Name != "pause" || got[0].UndoLabel() != "unpause" || !got[0].CanUndo() {
+		t.Errorf("actions = %+v, want an undoable pause", got)
+	}
+}
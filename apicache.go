@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// With API_CACHE_TTL, podmanGet keeps successful responses for that long,
+// keyed by path, so bursts of page loads and short refresh intervals are
+// served from memory instead of the Podman socket. Any change made through
+// podfather drops the whole cache (dropCaches): write calls to the API, POST
+// requests and the container events the live mode sees.
+
+// maxCacheEntries is the number of entries above which expired ones are
+// swept on insert.
+const maxCacheEntries = 256
+
+type cacheEntry struct {
+	body    []byte
+	expires time.Time
+}
+
+// apiCache caches Podman API responses. It is safe to use a nil *apiCache,
+// which caches nothing.
+type apiCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func newAPICache(ttl time.Duration) *apiCache {
+	return &apiCache{ttl: ttl, entries: map[string]cacheEntry{}}
+}
+
+// get returns the cached response for path, if it has not expired.
+func (c *apiCache) get(path string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e.body, true
+}
+
+func (c *apiCache) put(path string, body []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= maxCacheEntries {
+		for p, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, p)
+			}
+		}
+	}
+	c.entries[path] = cacheEntry{body: body, expires: now.Add(c.ttl)}
+}
+
+// clear drops all entries.
+func (c *apiCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	clear(c.entries)
+	c.mu.Unlock()
+}
+
+// dropCaches drops the API cache and the poller snapshot after a change.
+func (s *Server) dropCaches() {
+	s.apiCache.clear()
+	s.poller.invalidate()
+}
+
+// dropCachesOnPost drops the caches after each POST request, so the page
+// shown after an action reflects it, also if it ran podman or systemctl
+// instead of the API.
+func (s *Server) dropCachesOnPost(next http.Handler) http.Handler {
+	if s.apiCache == nil && s.poller == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if r.Method == http.MethodPost {
+			s.dropCaches()
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAPICache(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4.0.0/libpod/containers/json":
+			calls.Add(1)
+			w.Write([]byte(`[{"Id":"abc123","Names":["web"]}]`))
+		case "/v4.0.0/libpod/containers/abc123/restart":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.apiCache = newAPICache(time.Minute)
+	ctx := context.Background()
+
+	list := func() {
+		t.Helper()
+		var cs []Container
+		if err := s.podmanGet(ctx, "/containers/json", &cs); err != nil || len(cs) != 1 || cs[0].ID != "abc123" {
+			t.Fatalf("podmanGet = %+v, %v", cs, err)
+		}
+	}
+	list()
+	list()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("%d API calls, want 1 while cached", n)
+	}
+	if err := s.podmanPost(ctx, "/containers/abc123/restart"); err != nil {
+		t.Fatal(err)
+	}
+	list()
+	if n := calls.Load(); n != 2 {
+		t.Errorf("%d API calls, want 2 after a POST to the API", n)
+	}
+
+	h := s.dropCachesOnPost(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/containers", nil))
+	list()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/container/abc123/restart", nil))
+	list()
+	if n := calls.Load(); n != 3 {
+		t.Errorf("%d API calls, want 3 after a POST request", n)
+	}
+
+	if err := s.podmanGet(ctx, "/containers/missing/json", &Container{}); err != errNotFound {
+		t.Errorf("missing container: %v", err)
+	}
+	if _, ok := s.apiCache.get("/containers/missing/json"); ok {
+		t.Error("error response cached")
+	}
+}
+
+func TestAPICacheExpiry(t *testing.T) {
+	t.Parallel()
+	c := newAPICache(time.Millisecond)
+	c.put("/a", []byte("1"))
+	if _, ok := c.get("/a"); !ok {
+		t.Error("fresh entry missing")
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := c.get("/a"); ok {
+		t.Error("expired entry returned")
+	}
+	for i := range maxCacheEntries {
+		c.put(string(rune('a'+i)), nil)
+	}
+	time.Sleep(2 * time.Millisecond)
+	c.put("/b", nil)
+	if len(c.entries) != 1 {
+		t.Errorf("%d entries after sweep, want 1", len(c.entries))
+	}
+
+	var nilCache *apiCache
+	nilCache.put("/a", []byte("1"))
+	nilCache.clear()
+	if _, ok := nilCache.get("/a"); ok {
+		t.Error("nil cache returned an entry")
+	}
+}
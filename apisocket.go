@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// When podfather talks to the rootless Podman socket, the API is provided by
+// the podman.socket and podman.service units of the systemd user instance.
+// If the service hangs or the socket unit failed, every page fails too, so
+// the diagnostics page shows their state (from systemctl, which talks to
+// systemd over D-Bus) and, with ENABLE_ACTIONS, offers to restart them.
+
+// podmanAPIUnits are the user units that provide the Podman API.
+var podmanAPIUnits = []string{"podman.socket", "podman.service"}
+
+const (
+	apiRestartTimeout  = 30 * time.Second
+	apiRestartInterval = time.Second
+)
+
+// APIUnit is the state of a unit that provides the Podman API.
+type APIUnit struct {
+	ID            string
+	ActiveState   string
+	SubState      string
+	UnitFileState string
+}
+
+// supervisesAPI reports whether the API socket is the rootless Podman
+// socket, which the systemd user instance of podfather's user manages.
+func (s *Server) supervisesAPI() bool {
+	return s.backend != backendDocker && s.socketPath == userPodmanSocket()
+}
+
+// apiUnits returns the state of podmanAPIUnits.
+func (s *Server) apiUnits(ctx context.Context) ([]APIUnit, error) {
+	args := append([]string{"--user", "show", "--property=Id,ActiveState,SubState,UnitFileState", "--"}, podmanAPIUnits...)
+	out, err := exec.CommandContext(ctx, s.systemctlBin, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("systemctl show: %w", err)
+	}
+	return parseAPIUnits(out), nil
+}
+
+// parseAPIUnits parses the output of systemctl show for several units
+// (blocks separated by empty lines).
+func parseAPIUnits(out []byte) []APIUnit {
+	var units []APIUnit
+	var u APIUnit
+	flush := func() {
+		if u.ID != "" {
+			units = append(units, u)
+		}
+		u = APIUnit{}
+	}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		key, value, _ := strings.Cut(sc.Text(), "=")
+		switch key {
+		case "":
+			flush()
+		case "Id":
+			u.ID = value
+		case "ActiveState":
+			u.ActiveState = value
+		case "SubState":
+			u.SubState = value
+		case "UnitFileState":
+			u.UnitFileState = value
+		}
+	}
+	flush()
+	return units
+}
+
+// restartAPI clears failed states, stops the service and restarts the
+// socket, then waits until the API answers again. The next request on the
+// socket starts the service.
+func (s *Server) restartAPI(ctx context.Context, j *job) error {
+	steps := [][]string{
+		append([]string{"reset-failed"}, podmanAPIUnits...),
+		{"stop", "podman.service"},
+		{"restart", "podman.socket"},
+	}
+	for _, step := range steps {
+		j.logf("==> systemctl --user %s", strings.Join(step, " "))
+		if err := runCommand(ctx, j, s.systemctlBin, append([]string{"--user"}, step...)...); err != nil {
+			return err
+		}
+	}
+	j.logf("==> Waiting for the Podman API")
+	deadline := time.Now().Add(apiRestartTimeout)
+	for {
+		var version struct {
+			Version string `json:"Version"`
+		}
+		err := s.podmanGet(ctx, "/version", &version)
+		if err == nil {
+			j.logf("==> Podman %s is answering", version.Version)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the Podman API is still unreachable: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(apiRestartInterval):
+		}
+	}
+}
+
+func (s *Server) handleRestartAPI(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions || !s.supervisesAPI() {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	j := s.jobs.start("Restart Podman API")
+	s.actions.record(r, "restart Podman API", strings.Join(podmanAPIUnits, ", "), nil, "", nil)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		j.finish(s.restartAPI(ctx, j))
+	}()
+	http.Redirect(w, r, s.basePath+"/job/"+j.ID, http.StatusSeeOther)
+}
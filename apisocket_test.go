@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testAPIUnits = `Id=podman.socket
+ActiveState=failed
+SubState=failed
+UnitFileState=enabled
+
+Id=podman.service
+ActiveState=inactive
+SubState=dead
+UnitFileState=static
+`
+
+func TestParseAPIUnits(t *testing.T) {
+	t.Parallel()
+	units := parseAPIUnits([]byte(testAPIUnits))
+	want := []APIUnit{
+		{ID: "podman.socket", ActiveState: "failed", SubState: "failed", UnitFileState: "enabled"},
+		{ID: "podman.service", ActiveState: "inactive", SubState: "dead", UnitFileState: "static"},
+	}
+	if len(units) != len(want) || units[0] != want[0] || units[1] != want[1] {
+		t.Errorf("parseAPIUnits = %+v", units)
+	}
+}
+
+// fakeSystemctl writes a systemctl that prints testAPIUnits for show and
+// appends all other invocations to the returned log file.
+func fakeSystemctl(t *testing.T) (bin, calls string) {
+	t.Helper()
+	dir := t.TempDir()
+	bin = filepath.Join(dir, "systemctl")
+	units := filepath.Join(dir, "units")
+	calls = filepath.Join(dir, "calls")
+	if err := os.WriteFile(units, []byte(testAPIUnits), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(bin, []byte("#!/bin/sh\ncase \"$2\" in\n"+
+		"show) cat "+units+" ;;\n*) echo \"$@\" >>"+calls+" ;;\nesac\n"), 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bin, calls
+}
+
+func TestDiagnosticsAPIUnits(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	mock.Close() // the API is down
+	s := newTestServer(t, mock)
+	s.backend = backendPodman
+	s.socketPath = userPodmanSocket()
+	s.enableActions = true
+	s.systemctlBin, _ = fakeSystemctl(t)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/diagnostics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{"podman.socket", "dead", "/diagnostics/restart-api"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("diagnostics page lacks %q", want)
+		}
+	}
+
+	// Other sockets are not managed by the user's systemd instance.
+	s.socketPath = "/run/podman/podman.sock"
+	resp, err = http.Get(app.URL + "/diagnostics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(body), "Socket Activation") {
+		t.Error("socket units shown for a system socket")
+	}
+}
+
+func TestRestartAPI(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	bin, calls := fakeSystemctl(t)
+	s.systemctlBin = bin
+
+	j := s.jobs.start("Restart Podman API")
+	go func() { j.finish(s.restartAPI(t.Context(), j)) }()
+	waitJob(t, j)
+	out, _, errMsg := j.read(0)
+	if j.Status() != "done" {
+		t.Fatalf("status = %s (err %q), output:\n%s", j.Status(), errMsg, out)
+	}
+	got, _ := os.ReadFile(calls)
+	want := "--user reset-failed podman.socket podman.service\n--user stop podman.service\n--user restart podman.socket\n"
+	if string(got) != want {
+		t.Errorf("systemctl calls:\n%s\nwant:\n%s", got, want)
+	}
+}
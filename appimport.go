@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The apps importer converts the services of another dashboard into
+// PODFATHER_APP_* variables, to paste into the environment or the config
+// repository. It reads Homepage's services.yaml and Homarr's board config
+// (the JSON of Homarr before 1.0). Nothing is stored; the result is only
+// shown.
+
+// maxAppImport bounds the pasted config.
+const maxAppImport = 1 << 20
+
+var nonEnvKeyChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// importApps detects the format of data and returns its apps in file order,
+// with SortIndex set to the position in their category.
+func importApps(data []byte) (format string, apps []App, err error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		format = "Homarr"
+		apps, err = importHomarr(trimmed)
+	} else {
+		format = "Homepage"
+		apps, err = importHomepage(data)
+	}
+	if err == nil && len(apps) == 0 {
+		err = errors.New("no services found")
+	}
+	positions := map[string]int{}
+	for i := range apps {
+		apps[i].SortIndex = positions[apps[i].Category]
+		positions[apps[i].Category]++
+	}
+	return format, apps, err
+}
+
+// importHomepage reads Homepage's services.yaml, a list of groups, each a
+// list of services, each a mapping with href, description, icon, widget and
+// so on. Only the subset of YAML such files use is understood. Services are
+// the list items with a href, description or icon; their category is the
+// group they are in. Other keys are ignored.
+func importHomepage(data []byte) ([]App, error) {
+	type node struct {
+		indent int
+		name   string
+		item   bool // a "- name:" list item, a group or a service
+		app    int  // index in apps + 1, 0 if not a service
+	}
+	var apps []App
+	var stack []*node
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), " \r")
+		content := strings.TrimLeft(line, " ")
+		if content == "" || content[0] == '#' || content == "---" {
+			continue
+		}
+		if content[0] == '\t' {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", n)
+		}
+		indent := len(line) - len(content)
+		item := false
+		if rest, ok := strings.CutPrefix(content, "- "); ok {
+			item, content = true, strings.TrimLeft(rest, " ")
+		}
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		key, value, ok := cutYAMLKey(content)
+		if !ok {
+			continue // a scalar list item, e.g. in a widget's fields
+		}
+		if item && value == "" {
+			stack = append(stack, &node{indent: indent, name: key, item: true})
+			continue
+		}
+		if item || value == "" {
+			// A list of mappings or a nested mapping, neither is a service.
+			stack = append(stack, &node{indent: indent, name: key})
+			continue
+		}
+		if len(stack) == 0 || !stack[len(stack)-1].item {
+			continue
+		}
+		parent := stack[len(stack)-1]
+		if parent.app == 0 {
+			var category string
+			for i := len(stack) - 2; i >= 0; i-- {
+				if stack[i].item {
+					category = stack[i].name
+					break
+				}
+			}
+			apps = append(apps, App{Name: parent.name, Category: category})
+			parent.app = len(apps)
+		}
+		app := &apps[parent.app-1]
+		switch key {
+		case "href":
+			app.URL = value
+		case "description":
+			app.Description = value
+		case "icon":
+			app.Icon = value
+		}
+	}
+	return apps, sc.Err()
+}
+
+// cutYAMLKey splits a "key: value" line, unquoting the value and removing a
+// trailing comment. It reports false if content is not a mapping entry.
+func cutYAMLKey(content string) (key, value string, ok bool) {
+	if k, ok := strings.CutSuffix(content, ":"); ok && !strings.Contains(k, ": ") {
+		return unquoteYAML(k), "", true
+	}
+	key, value, ok = strings.Cut(content, ": ")
+	if !ok {
+		return "", "", false
+	}
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(value, `"`):
+		if end := strings.LastIndex(value, `"`); end > 0 {
+			if uq, err := strconv.Unquote(value[:end+1]); err == nil {
+				return unquoteYAML(key), uq, true
+			}
+			value = value[1:end]
+		}
+	case strings.HasPrefix(value, "'"):
+		if end := strings.LastIndex(value, "'"); end > 0 {
+			value = strings.ReplaceAll(value[1:end], "''", "'")
+		}
+	default:
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+	}
+	return unquoteYAML(key), value, true
+}
+
+// unquoteYAML removes the quotes around a key.
+func unquoteYAML(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// importHomarr reads a Homarr board config. The external URL of an app is
+// preferred over its URL, which Homarr uses from its own server.
+func importHomarr(data []byte) ([]App, error) {
+	var cfg struct {
+		Apps []struct {
+			Name      string `json:"name"`
+			URL       string `json:"url"`
+			Behaviour struct {
+				ExternalURL string `json:"externalUrl"`
+			} `json:"behaviour"`
+			Appearance struct {
+				IconURL string `json:"iconUrl"`
+			} `json:"appearance"`
+			Area struct {
+				Type       string `json:"type"`
+				Properties struct {
+					ID string `json:"id"`
+				} `json:"properties"`
+			} `json:"area"`
+		} `json:"apps"`
+		Categories []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"categories"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	categories := map[string]string{}
+	for _, c := range cfg.Categories {
+		categories[c.ID] = c.Name
+	}
+	var apps []App
+	for _, a := range cfg.Apps {
+		app := App{Name: a.Name, URL: a.Behaviour.ExternalURL, Icon: a.Appearance.IconURL}
+		if app.URL == "" {
+			app.URL = a.URL
+		}
+		if a.Area.Type == "category" {
+			app.Category = categories[a.Area.Properties.ID]
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+// cleanImportedApps drops what PODFATHER_APP_* variables cannot hold: apps
+// without a name or with the name of an earlier one, URLs other than
+// http(s) and icons that are neither emoji nor images, e.g. Homepage's
+// Material Design (mdi-) and Simple Icons (si-) names. It returns a note for
+// each.
+func cleanImportedApps(apps []App) ([]App, []string) {
+	var out []App
+	var notes []string
+	names := map[string]bool{}
+	for _, app := range apps {
+		switch {
+		case app.Name == "":
+			notes = append(notes, fmt.Sprintf("Skipped a service without a name (URL %q)", app.URL))
+			continue
+		case names[app.Name]:
+			notes = append(notes, fmt.Sprintf("%s: skipped, an app with this name comes earlier", app.Name))
+			continue
+		}
+		names[app.Name] = true
+		if u, err := url.Parse(app.URL); app.URL != "" && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+			notes = append(notes, fmt.Sprintf("%s: dropped URL %q, want an http(s) URL", app.Name, app.URL))
+			app.URL = ""
+		}
+		otherSet := strings.HasPrefix(app.Icon, "mdi-") || strings.HasPrefix(app.Icon, "si-")
+		if iconKey(app.Icon) == "" && strings.ContainsFunc(app.Icon, func(r rune) bool { return r < utf8.RuneSelf }) || otherSet {
+			notes = append(notes, fmt.Sprintf("%s: dropped icon %q, want an emoji, image URL or dashboard-icons name", app.Name, app.Icon))
+			app.Icon = ""
+		}
+		out = append(out, app)
+	}
+	return out, notes
+}
+
+// appsEnvFile returns the apps as PODFATHER_APP_* variables in env-file
+// syntax. The key of an app is its name in upper case.
+func appsEnvFile(apps []App) string {
+	var b strings.Builder
+	keys := map[string]bool{}
+	for i, app := range apps {
+		key := strings.Trim(nonEnvKeyChars.ReplaceAllString(strings.ToUpper(app.Name), "_"), "_")
+		if key == "" {
+			key = "APP"
+		}
+		for n := 2; keys[key]; n++ {
+			key = strings.TrimSuffix(key, "_"+strconv.Itoa(n-1)) + "_" + strconv.Itoa(n)
+		}
+		keys[key] = true
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		prefix := "PODFATHER_APP_" + key
+		fmt.Fprintf(&b, "%s_NAME=%s\n", prefix, envFileValue(app.Name))
+		for _, f := range []struct{ suffix, value string }{
+			{"_URL", app.URL},
+			{"_ICON", app.Icon},
+			{"_CATEGORY", app.Category},
+			{"_DESCRIPTION", app.Description},
+		} {
+			if f.value != "" {
+				fmt.Fprintf(&b, "%s%s=%s\n", prefix, f.suffix, envFileValue(f.value))
+			}
+		}
+		fmt.Fprintf(&b, "%s_SORT_INDEX=%d\n", prefix, app.SortIndex)
+	}
+	return b.String()
+}
+
+// envFileValue quotes v if parseEnvFile would not read it back unchanged.
+func envFileValue(v string) string {
+	if strings.ContainsAny(v, " \"'#\\") || strings.ContainsFunc(v, func(r rune) bool { return !strconv.IsPrint(r) }) {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+func (s *Server) handleAppsImport(w http.ResponseWriter, r *http.Request) {
+	data := map[string]any{"Title": "Import Apps", "MaxSize": maxAppImport}
+	if r.Method != http.MethodPost {
+		s.render(w, r, "apps-import.html", data)
+		return
+	}
+	input := r.PostFormValue("config")
+	data["Config"] = input
+	if len(input) > maxAppImport {
+		data["Error"] = "The config is too large."
+		s.render(w, r, "apps-import.html", data)
+		return
+	}
+	format, apps, err := importApps([]byte(input))
+	data["Format"] = format
+	if err != nil {
+		data["Error"] = err.Error()
+		s.render(w, r, "apps-import.html", data)
+		return
+	}
+	apps, notes := cleanImportedApps(apps)
+	data["Apps"] = apps
+	data["Notes"] = notes
+	data["EnvFile"] = appsEnvFile(apps)
+	s.render(w, r, "apps-import.html", data)
+}
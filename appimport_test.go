@@ -0,0 +1,136 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testHomepageServices = `---
+# Homepage services
+- Media:
+    - Jellyfin:
+        href: https://jellyfin.example.com/ # the public URL
+        description: "Movies and shows"
+        icon: jellyfin.png
+        widget:
+          type: jellyfin
+          url: http://jellyfin:8096
+          fields: ["movies", "series"]
+    - 'Audio Books':
+        href: http://audio.lan
+        icon: 🎧
+- Infra:
+    - Router:
+        href: ftp://192.168.1.1
+        description: It's the router: don't touch
+    - NAS:
+        href: https://nas.lan
+- Empty group:
+`
+
+func TestImportHomepage(t *testing.T) {
+	t.Parallel()
+	format, apps, err := importApps([]byte(testHomepageServices))
+	if err != nil || format != "Homepage" {
+		t.Fatalf("importApps = %s, %v", format, err)
+	}
+	want := []App{
+		{Name: "Jellyfin", Category: "Media", URL: "https://jellyfin.example.com/", Description: "Movies and shows", Icon: "jellyfin.png"},
+		{Name: "Audio Books", Category: "Media", URL: "http://audio.lan", Icon: "🎧", SortIndex: 1},
+		{Name: "Router", Category: "Infra", URL: "ftp://192.168.1.1", Description: "It's the router: don't touch"},
+		{Name: "NAS", Category: "Infra", URL: "https://nas.lan", SortIndex: 1},
+	}
+	if !reflect.DeepEqual(apps, want) {
+		t.Errorf("apps = %+v\nwant %+v", apps, want)
+	}
+	if _, _, err := importApps([]byte("- Media:\n\t- Jellyfin:\n")); err == nil {
+		t.Error("tab indentation accepted")
+	}
+}
+
+func TestImportHomarr(t *testing.T) {
+	t.Parallel()
+	config := `{"schemaVersion": 2, "categories": [{"id": "c1", "name": "Tools"}], "apps": [
+		{"name": "Gitea", "url": "http://gitea:3000", "behaviour": {"externalUrl": "https://git.example.com"},
+		 "appearance": {"iconUrl": "https://cdn.example.com/gitea.png"}, "area": {"type": "category", "properties": {"id": "c1"}}},
+		{"name": "Grafana", "url": "https://grafana.lan", "area": {"type": "wrapper", "properties": {"id": "w1"}}}]}`
+	format, apps, err := importApps([]byte(config))
+	if err != nil || format != "Homarr" {
+		t.Fatalf("importApps = %s, %v", format, err)
+	}
+	want := []App{
+		{Name: "Gitea", Category: "Tools", URL: "https://git.example.com", Icon: "https://cdn.example.com/gitea.png"},
+		{Name: "Grafana", URL: "https://grafana.lan"},
+	}
+	if !reflect.DeepEqual(apps, want) {
+		t.Errorf("apps = %+v\nwant %+v", apps, want)
+	}
+	if _, _, err := importApps([]byte(`{"apps": [`)); err == nil {
+		t.Error("invalid JSON accepted")
+	}
+}
+
+func TestAppsEnvFile(t *testing.T) {
+	t.Parallel()
+	apps, notes := cleanImportedApps([]App{
+		{Name: "Home Assistant", URL: "https://ha.lan", Icon: "🏠", Description: `Lights & "scenes" #1`},
+		{Name: "home-assistant", URL: "file:///etc", Icon: "mdi-home", SortIndex: 3},
+		{Name: "Home Assistant"},
+		{URL: "https://nameless.lan"},
+	})
+	if len(apps) != 2 || len(notes) != 4 {
+		t.Fatalf("cleanImportedApps = %+v, %q", apps, notes)
+	}
+	env := appsEnvFile(apps)
+	for _, want := range []string{
+		"PODFATHER_APP_HOME_ASSISTANT_NAME=\"Home Assistant\"\n",
+		`PODFATHER_APP_HOME_ASSISTANT_DESCRIPTION="Lights & \"scenes\" #1"`,
+		"PODFATHER_APP_HOME_ASSISTANT_2_NAME=home-assistant\n",
+		"PODFATHER_APP_HOME_ASSISTANT_2_SORT_INDEX=3\n",
+	} {
+		if !strings.Contains(env, want) {
+			t.Errorf("env file lacks %q:\n%s", want, env)
+		}
+	}
+	environ, err := parseEnvFile([]byte(env))
+	if err == nil {
+		err = validateExternalApps(environ)
+	}
+	if err != nil {
+		t.Fatalf("env file does not load: %v", err)
+	}
+	got := parseExternalApps(environ)
+	if len(got) != 2 {
+		t.Fatalf("parseExternalApps = %+v", got)
+	}
+	for _, a := range got {
+		if a.Name == "Home Assistant" && (a.Description != apps[0].Description || a.Icon != "🏠") {
+			t.Errorf("round trip = %+v, want %+v", a, apps[0])
+		}
+	}
+}
+
+func TestAppsImportPage(t *testing.T) {
+	t.Parallel()
+	mock := httptest.NewServer(http.NotFoundHandler())
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+	resp, err := http.PostForm(app.URL+"/apps/import", url.Values{"config": {testHomepageServices}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{"4 app(s) from Homepage", "PODFATHER_APP_NAS_URL=https://nas.lan", "Router: dropped URL", "PODFATHER_APP_JELLYFIN_ICON=jellyfin.png"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("import page lacks %q", want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// /auto-update/labels groups the containers by their auto-update policy
+// (the io.containers.autoupdate label) and onboards selected Quadlet
+// containers to `podman auto-update`: it sets AutoUpdate=registry in their
+// .container files (below QUADLET_DIR, like digest pinning), reloads
+// systemd and restarts the units so the containers get the label. Labels
+// of other containers cannot be changed without recreating them, so the
+// page only shows the command line option they need.
+
+const autoUpdateLabel = "io.containers.autoupdate"
+
+// AutoUpdateTarget is a container on the auto-update labels page.
+type AutoUpdateTarget struct {
+	ID      string
+	Name    string
+	Image   string
+	Policy  string // value of the label, empty if unset
+	Unit    string
+	File    string // Quadlet file below QUADLET_DIR, empty if there is none
+	Warning string
+}
+
+// AutoUpdateGroup is the containers with one auto-update policy.
+type AutoUpdateGroup struct {
+	Policy  string
+	Targets []AutoUpdateTarget
+}
+
+// AutoUpdateChange is the change of one Quadlet file in the preview.
+type AutoUpdateChange struct {
+	Target  AutoUpdateTarget
+	Old     string // replaced line, empty if a line is added
+	New     string
+	content []byte
+}
+
+// fullyQualified reports whether image names its registry, which
+// `podman auto-update` needs for the registry policy.
+func fullyQualified(image string) bool {
+	first, _, ok := strings.Cut(image, "/")
+	return ok && (strings.ContainsAny(first, ".:") || first == "localhost")
+}
+
+// setQuadletAutoUpdate sets AutoUpdate=policy in the [Container] section.
+// It replaces an AutoUpdate= line or a Label= line setting the label, or
+// adds the key after Image=. old is the replaced line.
+func setQuadletAutoUpdate(content []byte, policy string) (out []byte, old string, err error) {
+	lines := bytes.Split(content, []byte("\n"))
+	section, image, replace := "", -1, -1
+	for i, l := range lines {
+		l := strings.TrimSpace(string(l))
+		if strings.HasPrefix(l, "[") && strings.HasSuffix(l, "]") {
+			section = l
+			continue
+		}
+		key, value, found := strings.Cut(l, "=")
+		if !found || section != "[Container]" {
+			continue
+		}
+		switch key = strings.TrimSpace(key); {
+		case key == "Image":
+			image = i
+		case key == "AutoUpdate",
+			key == "Label" && strings.HasPrefix(strings.Trim(strings.TrimSpace(value), `"`), autoUpdateLabel+"="):
+			replace = i
+		}
+	}
+	line := []byte("AutoUpdate=" + policy)
+	switch {
+	case replace >= 0:
+		old = string(lines[replace])
+		lines[replace] = line
+	case image >= 0:
+		lines = slices.Insert(lines, image+1, line)
+	default:
+		return nil, "", errors.New("no Image= in [Container]")
+	}
+	return bytes.Join(lines, []byte("\n")), old, nil
+}
+
+// autoUpdateTargets returns all containers with their policy and Quadlet
+// file.
+func (s *Server) autoUpdateTargets(ctx context.Context) ([]AutoUpdateTarget, error) {
+	list, err := s.listContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []AutoUpdateTarget
+	for _, c := range list {
+		t := AutoUpdateTarget{
+			ID:     c.ID,
+			Name:   firstName(c.Names),
+			Image:  c.Image,
+			Policy: c.Labels[autoUpdateLabel],
+			Unit:   c.Labels[systemdUnitLabel],
+		}
+		if s.backend == backendPodman && s.quadletDir != "" && t.Unit != "" {
+			if f, err := quadletFile(s.quadletDir, t.Unit); err == nil {
+				t.File = f
+			} else if !errors.Is(err, errPinUnsupported) {
+				return nil, err
+			}
+		}
+		if !fullyQualified(t.Image) {
+			t.Warning = "image name without registry, auto-update needs e.g. docker.io/library/" + t.Image
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// groupAutoUpdateTargets groups the targets by policy: registry, local,
+// other values, then containers without the label.
+func groupAutoUpdateTargets(targets []AutoUpdateTarget) []AutoUpdateGroup {
+	rank := func(p string) int {
+		switch p {
+		case "registry":
+			return 0
+		case "local":
+			return 1
+		case "":
+			return 3
+		}
+		return 2
+	}
+	var groups []AutoUpdateGroup
+	for _, t := range targets {
+		i := slices.IndexFunc(groups, func(g AutoUpdateGroup) bool { return g.Policy == t.Policy })
+		if i < 0 {
+			groups = append(groups, AutoUpdateGroup{Policy: t.Policy})
+			i = len(groups) - 1
+		}
+		groups[i].Targets = append(groups[i].Targets, t)
+	}
+	slices.SortFunc(groups, func(a, b AutoUpdateGroup) int {
+		return cmp.Or(rank(a.Policy)-rank(b.Policy), strings.Compare(a.Policy, b.Policy))
+	})
+	for _, g := range groups {
+		slices.SortFunc(g.Targets, func(a, b AutoUpdateTarget) int { return strings.Compare(a.Name, b.Name) })
+	}
+	return groups
+}
+
+// autoUpdateChanges returns the file changes to set the registry policy on
+// the selected containers, which must have a Quadlet file.
+func (s *Server) autoUpdateChanges(ctx context.Context, ids []string) ([]AutoUpdateChange, error) {
+	targets, err := s.autoUpdateTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var changes []AutoUpdateChange
+	for _, id := range ids {
+		i := slices.IndexFunc(targets, func(t AutoUpdateTarget) bool { return t.ID == id })
+		if i < 0 {
+			return nil, fmt.Errorf("container %s: %w", shortID(id), errNotFound)
+		}
+		t := targets[i]
+		if t.File == "" {
+			return nil, fmt.Errorf("container %s: %w", t.Name, errPinUnsupported)
+		}
+		if t.Policy == "registry" {
+			continue
+		}
+		old, err := os.ReadFile(t.File)
+		if err != nil {
+			return nil, err
+		}
+		content, oldLine, err := setQuadletAutoUpdate(old, "registry")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.File, err)
+		}
+		changes = append(changes, AutoUpdateChange{Target: t, Old: oldLine, New: "AutoUpdate=registry", content: content})
+	}
+	return changes, nil
+}
+
+// applyAutoUpdateChanges writes the files, reloads systemd once and
+// restarts the units. It keeps going after a failed restart, the files are
+// already changed.
+func (s *Server) applyAutoUpdateChanges(ctx context.Context, j *job, changes []AutoUpdateChange) error {
+	for _, c := range changes {
+		j.logf("==> Setting AutoUpdate=registry in %s", c.Target.File)
+		if err := writeFileKeepMode(c.Target.File, c.content); err != nil {
+			return err
+		}
+	}
+	j.logf("==> systemctl --user daemon-reload")
+	if err := runCommand(ctx, j, s.systemctlBin, "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	var errs []error
+	for _, c := range changes {
+		if err := s.restartUnit(ctx, j, c.Target.Unit); err != nil {
+			j.logf("==> Failed: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Server) handleAutoUpdateLabels(w http.ResponseWriter, r *http.Request) {
+	targets, err := s.autoUpdateTargets(r.Context())
+	if err != nil {
+		log.Printf("[%s] auto-update labels: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.render(w, r, "autolabels.html", map[string]any{
+		"Title":     "Auto-Update Labels",
+		"Groups":    groupAutoUpdateTargets(targets),
+		"CanChange": s.enableActions && s.backend == backendPodman && s.quadletDir != "",
+	})
+}
+
+// autoUpdateChangesFor validates the form of the preview and apply
+// requests and returns the changes, writing the error response if there are
+// none.
+func (s *Server) autoUpdateChangesFor(w http.ResponseWriter, r *http.Request) ([]AutoUpdateChange, bool) {
+	if !s.enableActions || s.backend != backendPodman || s.quadletDir == "" {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return nil, false
+	}
+	r.ParseForm()
+	ids := r.PostForm["id"]
+	if len(ids) == 0 || slices.ContainsFunc(ids, func(id string) bool { return !validID.MatchString(id) }) {
+		http.Error(w, "Select at least one container", http.StatusBadRequest)
+		return nil, false
+	}
+	changes, err := s.autoUpdateChanges(r.Context(), ids)
+	switch {
+	case errors.Is(err, errNotFound):
+		http.Error(w, "Container Not Found", http.StatusNotFound)
+		return nil, false
+	case errors.Is(err, errPinUnsupported):
+		http.Error(w, "Only Quadlet-managed containers with their .container file in QUADLET_DIR can be changed", http.StatusBadRequest)
+		return nil, false
+	case err != nil:
+		log.Printf("[%s] auto-update labels: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return changes, true
+}
+
+func (s *Server) handleAutoUpdateLabelsPreview(w http.ResponseWriter, r *http.Request) {
+	changes, ok := s.autoUpdateChangesFor(w, r)
+	if !ok {
+		return
+	}
+	s.render(w, r, "autolabels.html", map[string]any{
+		"Title":   "Auto-Update Labels",
+		"Preview": true,
+		"Changes": changes,
+	})
+}
+
+func (s *Server) handleAutoUpdateLabelsApply(w http.ResponseWriter, r *http.Request) {
+	changes, ok := s.autoUpdateChangesFor(w, r)
+	if !ok {
+		return
+	}
+	if len(changes) == 0 {
+		http.Redirect(w, r, s.basePath+"/auto-update/labels", http.StatusSeeOther)
+		return
+	}
+	names := make([]string, len(changes))
+	for i, c := range changes {
+		names[i] = c.Target.Name
+	}
+	j := s.jobs.start("Enable auto-update for " + strings.Join(names, ", "))
+	// Reverting would need another restart, so there is no undo.
+	s.actions.record(r, "enable auto-update", strings.Join(names, ", "), nil, "", nil)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+		defer cancel()
+		j.finish(s.applyAutoUpdateChanges(ctx, j, changes))
+	}()
+	http.Redirect(w, r, s.basePath+"/job/"+j.ID, http.StatusSeeOther)
+}
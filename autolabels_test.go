@@ -0,0 +1,160 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetQuadletAutoUpdate(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		in, want, old string
+	}{
+		{
+			in:   "[Container]\nImage=docker.io/library/nginx\nLabel=a=b\n",
+			want: "[Container]\nImage=docker.io/library/nginx\nAutoUpdate=registry\nLabel=a=b\n",
+		},
+		{
+			in:   "[Container]\nAutoUpdate=local\nImage=docker.io/library/nginx\n",
+			want: "[Container]\nAutoUpdate=registry\nImage=docker.io/library/nginx\n",
+			old:  "AutoUpdate=local",
+		},
+		{
+			in:   "[Container]\nImage=docker.io/library/nginx\nLabel=\"io.containers.autoupdate=disabled\"\n",
+			want: "[Container]\nImage=docker.io/library/nginx\nAutoUpdate=registry\n",
+			old:  "Label=\"io.containers.autoupdate=disabled\"",
+		},
+		{
+			in:   "[Unit]\nAutoUpdate=local\n[Container]\nImage=docker.io/library/nginx\n",
+			want: "[Unit]\nAutoUpdate=local\n[Container]\nImage=docker.io/library/nginx\nAutoUpdate=registry\n",
+		},
+	} {
+		got, old, err := setQuadletAutoUpdate([]byte(tc.in), "registry")
+		if err != nil || string(got) != tc.want || old != tc.old {
+			t.Errorf("setQuadletAutoUpdate(%q) = %q, %q, %v; want %q, %q", tc.in, got, old, err, tc.want, tc.old)
+		}
+	}
+	if _, _, err := setQuadletAutoUpdate([]byte("[Container]\nExec=true\n"), "registry"); err == nil {
+		t.Error("no error for a file without Image=")
+	}
+}
+
+func TestGroupAutoUpdateTargets(t *testing.T) {
+	t.Parallel()
+	groups := groupAutoUpdateTargets([]AutoUpdateTarget{
+		{Name: "b"}, {Name: "c", Policy: "local"}, {Name: "a"}, {Name: "d", Policy: "registry"}, {Name: "e", Policy: "disabled"},
+	})
+	var got []string
+	for _, g := range groups {
+		names := make([]string, len(g.Targets))
+		for i, t := range g.Targets {
+			names[i] = t.Name
+		}
+		got = append(got, g.Policy+":"+strings.Join(names, ","))
+	}
+	if s := strings.Join(got, " "); s != "registry:d local:c disabled:e :a,b" {
+		t.Errorf("groups = %s", s)
+	}
+}
+
+func TestFullyQualified(t *testing.T) {
+	t.Parallel()
+	for image, want := range map[string]bool{
+		"docker.io/library/nginx:1": true,
+		"localhost/app":             true,
+		"registry:5000/app":         true,
+		"nginx:1":                   false,
+		"library/nginx":             false,
+	} {
+		if got := fullyQualified(image); got != want {
+			t.Errorf("fullyQualified(%q) = %v, want %v", image, got, want)
+		}
+	}
+}
+
+func TestAutoUpdateLabels(t *testing.T) {
+	t.Parallel()
+	dir, file := writeQuadlet(t)
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v4.0.0/libpod/containers/json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`[{"Id":"c1","Names":["web"],"Image":"ghcr.io/org/web:1.2","Labels":{"PODMAN_SYSTEMD_UNIT":"web.service"}},
+			{"Id":"c2","Names":["db"],"Image":"postgres:16","Labels":{"io.containers.autoupdate":"local"}}]`))
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.backend = backendPodman
+	s.enableActions = true
+	s.quadletDir = dir
+	bin, calls := fakeSystemctl(t)
+	s.systemctlBin = bin
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/auto-update/labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{"No auto-update label", `name="id" value="c1"`, file, "docker.io/library/postgres:16"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("labels page lacks %q", want)
+		}
+	}
+	if strings.Contains(string(body), `value="c2"`) {
+		t.Error("container without Quadlet file is selectable")
+	}
+
+	resp, err = http.PostForm(app.URL+"/auto-update/labels/preview", url.Values{"id": {"c1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "AutoUpdate=registry") || !strings.Contains(string(body), "Apply and restart 1 unit(s)") {
+		t.Errorf("preview lacks the change:\n%s", body)
+	}
+	if data, _ := os.ReadFile(file); string(data) != testQuadlet {
+		t.Error("preview changed the file")
+	}
+
+	resp, err = http.PostForm(app.URL+"/auto-update/labels/preview", url.Values{"id": {"c2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("preview of a non-Quadlet container: status %d, want 400", resp.StatusCode)
+	}
+
+	resp, err = http.PostForm(app.URL+"/auto-update/labels", url.Values{"id": {"c1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	id, ok := strings.CutPrefix(resp.Request.URL.Path, "/job/")
+	if !ok {
+		t.Fatalf("redirected to %s, want a job", resp.Request.URL.Path)
+	}
+	j := s.jobs.get(id)
+	waitJob(t, j)
+	if out, _, errMsg := j.read(0); j.Status() != "done" {
+		t.Fatalf("status = %s (err %q), output:\n%s", j.Status(), errMsg, out)
+	}
+	data, _ := os.ReadFile(file)
+	if !strings.Contains(string(data), "\nImage=ghcr.io/org/web:1.2@sha256:aaa\nAutoUpdate=registry\n") {
+		t.Errorf("file not changed:\n%s", data)
+	}
+	got, _ := os.ReadFile(calls)
+	if want := "--user daemon-reload\n--user restart -- web.service\n"; string(got) != want {
+		t.Errorf("systemctl calls:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// With ENABLE_FILE_DOWNLOAD, /container/{id}/files browses the container's
+// filesystem read-only: directories are listed, small text files shown and
+// everything else offered for download. The API has no listing endpoint, so
+// a directory is read as the tar stream of the archive endpoint, of which
+// only the headers are kept. Viewed files are recorded in the action log
+// like downloads. Listing /run shows the secrets directory, but not its entries.
+
+// maxListArchive bounds how much of a directory archive is read for its
+// listing. Larger directories (e.g. /) are listed partially.
+const maxListArchive = 256 << 20
+
+// maxListEntries bounds the entries of a listing.
+const maxListEntries = 1000
+
+// maxViewSize bounds the files shown inline.
+const maxViewSize = 256 << 10
+
+// FileEntry is an entry of a container directory.
+type FileEntry struct {
+	Name    string
+	Path    string
+	Dir     bool
+	Size    int64
+	Mode    string
+	ModTime time.Time
+	Link    string // target of a symlink
+}
+
+// FileCrumb is a step of the breadcrumb to the browsed path.
+type FileCrumb struct {
+	Name string
+	Path string
+}
+
+// browsePath is a browsed file or directory.
+type browsePath struct {
+	dir       bool
+	entries   []FileEntry
+	truncated bool
+	file      FileEntry
+	content   []byte // of files up to maxViewSize
+}
+
+// validBrowsePath reports whether p can be browsed: the root directory or
+// a path accepted by validContainerPath.
+func validBrowsePath(p string) bool {
+	return p == "/" || validContainerPath(p)
+}
+
+// fileCrumbs returns the breadcrumb to p, starting at the root directory.
+func fileCrumbs(p string) []FileCrumb {
+	out := []FileCrumb{{Name: "/", Path: "/"}}
+	cur := ""
+	for part := range strings.SplitSeq(strings.Trim(p, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		cur += "/" + part
+		out = append(out, FileCrumb{Name: part, Path: cur})
+	}
+	return out
+}
+
+// limitedReader returns errTooLarge once more than n bytes are read.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, errTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// fileEntry returns the entry for hdr at path p.
+func fileEntry(hdr *tar.Header, p string) FileEntry {
+	return FileEntry{
+		Name:    path.Base(p),
+		Path:    p,
+		Dir:     hdr.Typeflag == tar.TypeDir,
+		Size:    hdr.Size,
+		Mode:    hdr.FileInfo().Mode().String(),
+		ModTime: hdr.ModTime,
+		Link:    hdr.Linkname,
+	}
+}
+
+// readBrowse reads the archive of the container path p. Directories are
+// listed with their direct entries, directories first; the contents of
+// regular files up to maxViewSize are read.
+func readBrowse(archive io.Reader, p string) (browsePath, error) {
+	tr := tar.NewReader(&limitedReader{r: archive, n: maxListArchive})
+	hdr, err := tr.Next()
+	if err != nil {
+		return browsePath{}, fmt.Errorf("reading archive: %w", err)
+	}
+	if hdr.Typeflag != tar.TypeDir {
+		b := browsePath{file: fileEntry(hdr, p)}
+		if hdr.Typeflag == tar.TypeReg && hdr.Size <= maxViewSize {
+			if b.content, err = io.ReadAll(tr); err != nil {
+				return browsePath{}, fmt.Errorf("reading archive: %w", err)
+			}
+		}
+		return b, nil
+	}
+
+	// Entries are named relative to the parent of p, e.g. "etc/hosts" for
+	// /etc; the root directory is archived as "." or "/".
+	prefix := strings.Trim(path.Clean(hdr.Name), "/")
+	if prefix == "" || prefix == "." {
+		prefix = ""
+	} else {
+		prefix += "/"
+	}
+	b := browsePath{dir: true, file: fileEntry(hdr, p)}
+	seen := map[string]bool{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, errTooLarge) || errors.Is(err, io.ErrUnexpectedEOF) && len(b.entries) > 0 {
+			b.truncated = true
+			break
+		}
+		if err != nil {
+			return browsePath{}, fmt.Errorf("reading archive: %w", err)
+		}
+		rel, ok := strings.CutPrefix(strings.TrimPrefix(path.Clean(hdr.Name), "/"), prefix)
+		if !ok || rel == "" || rel == "." {
+			continue
+		}
+		name, _, nested := strings.Cut(rel, "/")
+		if seen[name] {
+			continue
+		}
+		if len(b.entries) == maxListEntries {
+			b.truncated = true
+			break
+		}
+		seen[name] = true
+		e := fileEntry(hdr, path.Join(p, name))
+		if nested {
+			// The directory's own entry was not in the archive.
+			e = FileEntry{Name: name, Path: path.Join(p, name), Dir: true}
+		}
+		b.entries = append(b.entries, e)
+	}
+	slices.SortFunc(b.entries, func(a, b FileEntry) int {
+		if a.Dir != b.Dir {
+			if a.Dir {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return b, nil
+}
+
+// textContent reports whether b looks like text that can be shown.
+func textContent(b []byte) bool {
+	return utf8.Valid(b) && !bytes.ContainsRune(b, 0)
+}
+
+// linkTarget returns the absolute path a symlink at p points to.
+func linkTarget(p, link string) string {
+	if path.IsAbs(link) {
+		return path.Clean(link)
+	}
+	return path.Join(path.Dir(p), link)
+}
+
+func (s *Server) handleContainerFiles(w http.ResponseWriter, r *http.Request) {
+	if !s.enableFileDownload {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid container ID", http.StatusBadRequest)
+		return
+	}
+	p := cmp.Or(r.URL.Query().Get("path"), "/")
+	if !validBrowsePath(p) {
+		http.Error(w, "Invalid path, want a clean absolute path like /etc/app", http.StatusBadRequest)
+		return
+	}
+	if secretPath(p, false) {
+		http.Error(w, "Secrets cannot be browsed", http.StatusForbidden)
+		return
+	}
+	c, err := s.inspectContainer(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Container Not Found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	target := c.Name + ":" + p
+	resp, err := s.podmanDo(r.Context(), http.MethodGet, "/containers/"+c.ID+"/archive?path="+url.QueryEscape(p), nil)
+	if errors.Is(err, errNotFound) {
+		http.Error(w, "File Not Found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[%s] browse %s: %v", reqID(r.Context()), target, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if secretPath(archiveLinkTarget(p, resp.Header), false) {
+		resp.Body.Close()
+		http.Error(w, "Secrets cannot be browsed", http.StatusForbidden)
+		return
+	}
+	// Directory archives may not be read to the end.
+	b, err := readBrowse(resp.Body, p)
+	resp.Body.Close()
+	if err != nil {
+		log.Printf("[%s] browse %s: %v", reqID(r.Context()), target, err)
+		http.Error(w, "Cannot read this path", http.StatusBadRequest)
+		return
+	}
+	data := map[string]any{
+		"Title":     "Files: " + c.Name,
+		"Container": c,
+		"Path":      p,
+		"Crumbs":    fileCrumbs(p),
+		"Dir":       b.dir,
+		"Entries":   b.entries,
+		"Truncated": b.truncated,
+		"File":      b.file,
+	}
+	if b.file.Link != "" {
+		data["LinkTarget"] = linkTarget(p, b.file.Link)
+	}
+	if p != "/" {
+		data["Parent"] = path.Dir(p)
+	}
+	if b.content != nil {
+		s.actions.record(r, "view file", target, nil, "", nil)
+		if textContent(b.content) {
+			data["Text"] = true
+			data["Content"] = string(b.content)
+		} else {
+			data["Binary"] = true
+		}
+	}
+	s.render(w, r, "files.html", data)
+}
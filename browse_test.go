@@ -0,0 +1,168 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFileCrumbs(t *testing.T) {
+	t.Parallel()
+	got := fileCrumbs("/etc/nginx/conf.d")
+	want := []FileCrumb{{"/", "/"}, {"etc", "/etc"}, {"nginx", "/etc/nginx"}, {"conf.d", "/etc/nginx/conf.d"}}
+	if len(got) != len(want) {
+		t.Fatalf("fileCrumbs = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("crumb %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if got := fileCrumbs("/"); len(got) != 1 {
+		t.Errorf("fileCrumbs(/) = %+v", got)
+	}
+}
+
+func TestReadBrowse(t *testing.T) {
+	t.Parallel()
+	archive := testArchive(t,
+		tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0o755},
+		tar.Header{Name: "etc/nginx/", Typeflag: tar.TypeDir, Mode: 0o755},
+		tar.Header{Name: "etc/nginx/nginx.conf", Typeflag: tar.TypeReg, Size: 10, Mode: 0o644},
+		tar.Header{Name: "etc/hosts", Typeflag: tar.TypeReg, Size: 3, Mode: 0o644},
+		tar.Header{Name: "etc/mtab", Typeflag: tar.TypeSymlink, Linkname: "../proc/mounts"},
+		tar.Header{Name: "etc/ssl/certs/ca.pem", Typeflag: tar.TypeReg, Size: 1},
+	)
+	b, err := readBrowse(bytes.NewReader(archive), "/etc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range b.entries {
+		names = append(names, e.Name)
+	}
+	if !b.dir || b.truncated || strings.Join(names, ",") != "nginx,ssl,hosts,mtab" {
+		t.Errorf("listing of /etc = %v (dir %v, truncated %v)", names, b.dir, b.truncated)
+	}
+	if e := b.entries[2]; e.Path != "/etc/hosts" || e.Size != 3 || e.Mode != "-rw-r--r--" {
+		t.Errorf("hosts = %+v", e)
+	}
+	if e := b.entries[3]; e.Link != "../proc/mounts" || linkTarget(e.Path, e.Link) != "/proc/mounts" {
+		t.Errorf("mtab = %+v", e)
+	}
+	if e := b.entries[1]; !e.Dir || e.Path != "/etc/ssl" {
+		t.Errorf("ssl, implied by a nested entry = %+v", e)
+	}
+
+	b, err = readBrowse(bytes.NewReader(testArchive(t,
+		tar.Header{Name: ".", Typeflag: tar.TypeDir},
+		tar.Header{Name: "./bin", Typeflag: tar.TypeDir},
+		tar.Header{Name: "./bin/sh", Typeflag: tar.TypeReg},
+	)), "/")
+	if err != nil || len(b.entries) != 1 || b.entries[0].Path != "/bin" {
+		t.Errorf("listing of / = %+v, %v", b.entries, err)
+	}
+
+	b, err = readBrowse(bytes.NewReader(testArchive(t, tar.Header{Name: "hosts", Typeflag: tar.TypeReg, Size: 3})), "/etc/hosts")
+	if err != nil || b.dir || string(b.content) != "xxx" || b.file.Path != "/etc/hosts" {
+		t.Errorf("file = %+v, %v", b, err)
+	}
+	b, err = readBrowse(bytes.NewReader(testArchive(t, tar.Header{Name: "big", Typeflag: tar.TypeReg, Size: maxViewSize + 1})), "/big")
+	if err != nil || b.content != nil || b.file.Size != maxViewSize+1 {
+		t.Errorf("large file = %+v, %v", b.file, err)
+	}
+	if textContent([]byte("a\x00b")) || !textContent([]byte("key: value\n")) {
+		t.Error("textContent is wrong")
+	}
+}
+
+func TestReadBrowseTruncated(t *testing.T) {
+	t.Parallel()
+	hdrs := []tar.Header{{Name: "d/", Typeflag: tar.TypeDir}}
+	for i := range maxListEntries + 1 {
+		hdrs = append(hdrs, tar.Header{Name: "d/" + strings.Repeat("f", i+1), Typeflag: tar.TypeReg})
+	}
+	b, err := readBrowse(bytes.NewReader(testArchive(t, hdrs...)), "/d")
+	if err != nil || !b.truncated || len(b.entries) != maxListEntries {
+		t.Errorf("listing: %d entries, truncated %v, %v", len(b.entries), b.truncated, err)
+	}
+}
+
+func TestContainerFiles(t *testing.T) {
+	t.Parallel()
+	archives := map[string][]byte{
+		"/etc": testArchive(t,
+			tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0o755},
+			tar.Header{Name: "etc/app.conf", Typeflag: tar.TypeReg, Size: 4, Mode: 0o644},
+		),
+		"/etc/app.conf": testArchive(t, tar.Header{Name: "app.conf", Typeflag: tar.TypeReg, Size: 4, Mode: 0o644}),
+	}
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4.0.0/libpod/containers/web/json":
+			w.Write([]byte(`{"Id":"c1","Name":"web","State":{"Status":"running"}}`))
+		case "/v4.0.0/libpod/containers/c1/archive":
+			if r.URL.Query().Get("path") == "/etc/key" {
+				// A symlink to a secret, followed by the API.
+				w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(`{"linkTarget":"/run/secrets/key"}`)))
+				w.Write(archives["/etc/app.conf"])
+				return
+			}
+			archive, ok := archives[r.URL.Query().Get("path")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(archive)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode, string(body)
+	}
+	if code, _ := get("/container/web/files?path=/etc"); code != http.StatusNotFound {
+		t.Errorf("browsing disabled: status %d, want 404", code)
+	}
+
+	s.enableFileDownload = true
+	code, body := get("/container/web/files?path=/etc")
+	if code != http.StatusOK || !strings.Contains(body, "files?path=%2fetc%2fapp.conf") || !strings.Contains(body, "download?path=%2fetc%2fapp.conf") {
+		t.Errorf("listing: status %d\n%s", code, body)
+	}
+	code, body = get("/container/web/files?path=/etc/app.conf")
+	if code != http.StatusOK || !strings.Contains(body, "<pre>xxxx</pre>") {
+		t.Errorf("file: status %d\n%s", code, body)
+	}
+	if code, _ := get("/container/web/files?path=/etc/../root"); code != http.StatusBadRequest {
+		t.Errorf("unclean path: status %d, want 400", code)
+	}
+	if code, _ := get("/container/web/files?path=/missing"); code != http.StatusNotFound {
+		t.Errorf("missing path: status %d, want 404", code)
+	}
+	for _, p := range []string{"/run/secrets", "/run/secrets/db_password", "/var/run/secrets", "/etc/key"} {
+		if code, _ := get("/container/web/files?path=" + p); code != http.StatusForbidden {
+			t.Errorf("secret %s: status %d, want 403", p, code)
+		}
+	}
+	if entries := s.actions.list(); len(entries) != 1 || entries[0].Name != "view file" || entries[0].Target != "web:/etc/app.conf" {
+		t.Errorf("action log = %+v, want the viewed file", entries)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// bulkActions are the actions the containers list can apply to the selected
+// containers at once.
+var bulkActions = []string{"start", "stop", "restart", "remove"}
+
+// maxBulkContainers bounds the containers of one bulk action.
+const maxBulkContainers = 100
+
+// BulkResult is the outcome of a bulk action for one container.
+type BulkResult struct {
+	ID      string
+	Name    string
+	Skipped string // why nothing was done, e.g. "already running"
+	Err     string
+}
+
+// bulkSkip returns why action is not applied to c, or "" if it is.
+func bulkSkip(action string, c Container) string {
+	running := c.State == "running"
+	switch {
+	case action == "start" && running:
+		return "already running"
+	case action == "stop" && !running:
+		return "not running"
+	case action == "remove" && running:
+		return "running, stop it first"
+	}
+	return ""
+}
+
+// handleContainersBulk applies an action to the containers selected on the
+// containers list and shows the result for each of them. Every container is
+// recorded in the action log like a single action.
+func (s *Server) handleContainersBulk(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	action := r.PostFormValue("action")
+	if !slices.Contains(bulkActions, action) {
+		http.Error(w, "Invalid action", http.StatusBadRequest)
+		return
+	}
+	ids := r.PostForm["id"]
+	if len(ids) == 0 {
+		http.Redirect(w, r, s.basePath+"/containers", http.StatusSeeOther)
+		return
+	}
+	if len(ids) > maxBulkContainers {
+		http.Error(w, "Too many containers", http.StatusBadRequest)
+		return
+	}
+	for _, id := range ids {
+		if !validID.MatchString(id) {
+			http.Error(w, "Invalid container ID", http.StatusBadRequest)
+			return
+		}
+	}
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	byID := make(map[string]Container, len(list))
+	for _, c := range list {
+		byID[c.ID] = c
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+	results := make([]BulkResult, len(ids))
+	// Errors are reported per container, so fn never fails.
+	parallel(ctx, len(ids), func(ctx context.Context, i int) error {
+		res := &results[i]
+		res.ID, res.Name = ids[i], shortID(ids[i])
+		c, ok := byID[ids[i]]
+		if !ok {
+			res.Err = errNotFound.Error()
+			return nil
+		}
+		res.Name = firstName(c.Names)
+		if res.Skipped = bulkSkip(action, c); res.Skipped != "" {
+			return nil
+		}
+		err := s.bulkAction(ctx, action, c.ID)
+		inverse := containerActions[action]
+		var undo func(context.Context) error
+		if inverse != "" {
+			undo = func(ctx context.Context) error {
+				return s.podmanPost(ctx, "/containers/"+c.ID+"/"+inverse)
+			}
+		}
+		s.actions.record(r, action, res.Name, err, inverse, undo)
+		if err != nil {
+			log.Printf("[%s] bulk %s %s: %v", reqID(r.Context()), action, res.Name, err)
+			res.Err = "failed"
+			if errors.Is(err, errNotFound) {
+				res.Err = errNotFound.Error()
+			}
+		}
+		return nil
+	})
+	s.render(w, r, "bulk.html", map[string]any{
+		"Title":   "Bulk " + action,
+		"Action":  action,
+		"Results": results,
+	})
+}
+
+// bulkAction runs a start, stop, restart or remove on the container id.
+// Removing fails for running containers and keeps volumes.
+func (s *Server) bulkAction(ctx context.Context, action, id string) error {
+	if action == "remove" {
+		return s.removeContainer(ctx, id, false, false)
+	}
+	return s.podmanPost(ctx, "/containers/"+id+"/"+action)
+}
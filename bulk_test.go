@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBulkSkip(t *testing.T) {
+	t.Parallel()
+	running, exited := Container{State: "running"}, Container{State: "exited"}
+	tests := []struct {
+		action string
+		c      Container
+		want   string
+	}{
+		{"start", running, "already running"},
+		{"start", exited, ""},
+		{"stop", exited, "not running"},
+		{"stop", running, ""},
+		{"restart", exited, ""},
+		{"remove", running, "running, stop it first"},
+		{"remove", exited, ""},
+	}
+	for _, tt := range tests {
+		if got := bulkSkip(tt.action, tt.c); got != tt.want {
+			t.Errorf("bulkSkip(%s, %s) = %q, want %q", tt.action, tt.c.State, got, tt.want)
+		}
+	}
+}
+
+func TestContainersBulk(t *testing.T) {
+	t.Parallel()
+	containers := []Container{
+		{ID: "aaa111", Names: []string{"web"}, State: "running"},
+		{ID: "bbb222", Names: []string{"db"}, State: "exited"},
+		{ID: "ccc333", Names: []string{"old"}, State: "exited"},
+	}
+	var mu sync.Mutex
+	var calls []string
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod")
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(containers)
+			return
+		}
+		mu.Lock()
+		calls = append(calls, r.Method+" "+p)
+		mu.Unlock()
+		if p == "/containers/ccc333" {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"container is in use"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	post := func(form url.Values) (int, string) {
+		t.Helper()
+		resp, err := http.PostForm(app.URL+"/containers/bulk", form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := post(url.Values{"action": {"stop"}, "id": {"aaa111", "bbb222", "fff999"}})
+	if status != http.StatusOK {
+		t.Fatalf("stop: %d", status)
+	}
+	for _, want := range []string{">web<", "ok", ">db<", "not running", ">fff999<", "not found"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stop: page lacks %q", want)
+		}
+	}
+	status, body = post(url.Values{"action": {"remove"}, "id": {"bbb222", "ccc333"}})
+	if status != http.StatusOK || !strings.Contains(body, "failed") {
+		t.Errorf("remove: %d, no failure reported", status)
+	}
+	mu.Lock()
+	slices.Sort(calls)
+	if want := []string{"DELETE /containers/bbb222", "DELETE /containers/ccc333", "POST /containers/aaa111/stop"}; !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	mu.Unlock()
+	if got := s.actions.list(); len(got) != 3 || !got[len(got)-1].CanUndo() {
+		t.Errorf("actions = %+v, want 3 with an undoable stop", got)
+	}
+
+	for name, form := range map[string]url.Values{
+		"unknown action": {"action": {"kill"}, "id": {"aaa111"}},
+		"invalid id":     {"action": {"stop"}, "id": {"!!!"}},
+	} {
+		if status, _ := post(form); status != http.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", name, status)
+		}
+	}
+}
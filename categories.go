@@ -0,0 +1,140 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Categories of the apps page are sorted by name with "Uncategorized" last.
+// PODFATHER_CATEGORY_<KEY>_<FIELD> variables, in the environment or the
+// config repository, give a category (matched by _NAME) an icon, a
+// description and a sort index. Categories are sorted by sort index (default
+// 0, as for apps), then by name; "Uncategorized" stays last unless it has a
+// sort index itself. Metadata for categories no app uses is ignored.
+
+const categoryPrefix = "PODFATHER_CATEGORY_"
+
+// uncategorized is the category of apps without a category label.
+const uncategorized = "Uncategorized"
+
+// categoryFields are the known PODFATHER_CATEGORY_<KEY>_<FIELD> suffixes.
+var categoryFields = []string{"_DESCRIPTION", "_SORT_INDEX", "_NAME", "_ICON"}
+
+// Category is the metadata of an app category.
+type Category struct {
+	Name        string
+	Icon        string
+	Description string
+	SortIndex   int
+	Sorted      bool // SortIndex is set
+}
+
+// categoryVars returns the fields of the PODFATHER_CATEGORY_* variables in
+// environ by key, and the variables that match no field.
+func categoryVars(environ []string) (fields map[string]map[string]string, unknown []string) {
+	fields = map[string]map[string]string{}
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		rest, ok := strings.CutPrefix(name, categoryPrefix)
+		if !ok {
+			continue
+		}
+		i := slices.IndexFunc(categoryFields, func(f string) bool { return strings.HasSuffix(rest, f) })
+		if i < 0 || rest == categoryFields[i] {
+			unknown = append(unknown, name)
+			continue
+		}
+		key := strings.TrimSuffix(rest, categoryFields[i])
+		if fields[key] == nil {
+			fields[key] = map[string]string{}
+		}
+		fields[key][categoryFields[i]] = value
+	}
+	return fields, unknown
+}
+
+// parseCategories returns the categories defined in environ by name.
+// Invalid entries are skipped; validateCategories reports them.
+func parseCategories(environ []string) map[string]Category {
+	fields, _ := categoryVars(environ)
+	cats := map[string]Category{}
+	for _, f := range fields {
+		name := f["_NAME"]
+		if name == "" {
+			continue
+		}
+		cat := Category{Name: name, Icon: f["_ICON"], Description: f["_DESCRIPTION"]}
+		if v, err := strconv.Atoi(f["_SORT_INDEX"]); err == nil {
+			cat.SortIndex, cat.Sorted = v, true
+		}
+		cats[name] = cat
+	}
+	return cats
+}
+
+// validateCategories checks PODFATHER_CATEGORY_* variables for unknown
+// fields, categories without a name or defined twice and malformed sort
+// indexes.
+func validateCategories(environ []string) error {
+	fields, unknown := categoryVars(environ)
+	var errs []error
+	for _, name := range unknown {
+		errs = append(errs, fmt.Errorf("unknown variable %s (want %s<KEY> followed by one of %s)", name, categoryPrefix, strings.Join(categoryFields, ", ")))
+	}
+	names := map[string]string{} // name -> key
+	for key, f := range fields {
+		name := f["_NAME"]
+		if name == "" {
+			errs = append(errs, fmt.Errorf("category %s has no non-empty %s%s_NAME", key, categoryPrefix, key))
+		} else if other, ok := names[name]; ok {
+			errs = append(errs, fmt.Errorf("categories %s and %s are both named %q", min(key, other), max(key, other), name))
+		}
+		names[name] = key
+		if v, ok := f["_SORT_INDEX"]; ok {
+			if _, err := strconv.Atoi(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s%s_SORT_INDEX=%q: want an integer", categoryPrefix, key, v))
+			}
+		}
+	}
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errors.Join(errs...)
+}
+
+// currentCategories returns the categories from the environment merged with
+// those from the git config, which take priority.
+func (s *Server) currentCategories() map[string]Category {
+	if s.gitConfig == nil {
+		return s.categories
+	}
+	cats := map[string]Category{}
+	maps.Copy(cats, s.categories)
+	maps.Copy(cats, s.gitConfig.externalCategories())
+	return cats
+}
+
+// sortCategories decorates categories with their metadata from cats and
+// sorts them.
+func sortCategories(categories []AppCategory, cats map[string]Category) {
+	for i := range categories {
+		c := cats[categories[i].Name]
+		categories[i].Icon, categories[i].Description = c.Icon, c.Description
+	}
+	// rank puts Uncategorized without a sort index after all others.
+	rank := func(name string) (int, int) {
+		c := cats[name]
+		if name == uncategorized && !c.Sorted {
+			return 1, 0
+		}
+		return 0, c.SortIndex
+	}
+	slices.SortFunc(categories, func(a, b AppCategory) int {
+		ga, ia := rank(a.Name)
+		gb, ib := rank(b.Name)
+		return cmp.Or(cmp.Compare(ga, gb), cmp.Compare(ia, ib), strings.Compare(a.Name, b.Name))
+	})
+}
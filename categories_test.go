@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseCategories(t *testing.T) {
+	t.Parallel()
+	environ := []string{
+		"PODFATHER_CATEGORY_MEDIA_NAME=Media",
+		"PODFATHER_CATEGORY_MEDIA_ICON=🎬",
+		"PODFATHER_CATEGORY_MEDIA_SORT_INDEX=-5",
+		"PODFATHER_CATEGORY_HOME_LAB_NAME=Home Lab",
+		"PODFATHER_CATEGORY_HOME_LAB_DESCRIPTION=Tinkering",
+		"PODFATHER_CATEGORY_BAD_SORT_INDEX=1",
+		"PODFATHER_APP_X_NAME=X",
+	}
+	got := parseCategories(environ)
+	if len(got) != 2 || got["Media"] != (Category{Name: "Media", Icon: "🎬", SortIndex: -5, Sorted: true}) ||
+		got["Home Lab"] != (Category{Name: "Home Lab", Description: "Tinkering"}) {
+		t.Errorf("parseCategories = %+v", got)
+	}
+
+	err := validateCategories(append(environ, "PODFATHER_CATEGORY_FILMS_NAME=Media", "PODFATHER_CATEGORY__ICON=x"))
+	if err == nil {
+		t.Fatal("validateCategories: no error")
+	}
+	for _, want := range []string{
+		"unknown variable PODFATHER_CATEGORY__ICON",
+		"category BAD has no non-empty PODFATHER_CATEGORY_BAD_NAME",
+		`categories FILMS and MEDIA are both named "Media"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not contain %q:\n%v", want, err)
+		}
+	}
+	if err := validateCategories(environ[:5]); err != nil {
+		t.Errorf("valid categories: %v", err)
+	}
+}
+
+func TestSortCategories(t *testing.T) {
+	t.Parallel()
+	names := func(cats []AppCategory) []string {
+		var out []string
+		for _, c := range cats {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+	newCategories := func() []AppCategory {
+		return []AppCategory{{Name: "Uncategorized"}, {Name: "Media"}, {Name: "Infra"}, {Name: "Dev"}}
+	}
+
+	cats := newCategories()
+	sortCategories(cats, nil)
+	if got := names(cats); !slices.Equal(got, []string{"Dev", "Infra", "Media", "Uncategorized"}) {
+		t.Errorf("without metadata: %q", got)
+	}
+
+	cats = newCategories()
+	sortCategories(cats, map[string]Category{
+		"Media":  {Name: "Media", Icon: "🎬", SortIndex: -1, Sorted: true},
+		"Dev":    {Name: "Dev", SortIndex: 10, Sorted: true},
+		"Unused": {Name: "Unused", SortIndex: -10, Sorted: true},
+	})
+	if got := names(cats); !slices.Equal(got, []string{"Media", "Infra", "Dev", "Uncategorized"}) {
+		t.Errorf("with sort indexes: %q", got)
+	}
+	if cats[0].Icon != "🎬" {
+		t.Errorf("Media icon = %q", cats[0].Icon)
+	}
+
+	cats = newCategories()
+	sortCategories(cats, map[string]Category{"Uncategorized": {Name: "Uncategorized", Sorted: true}})
+	if got := names(cats); !slices.Equal(got, []string{"Dev", "Infra", "Media", "Uncategorized"}) {
+		t.Errorf("Uncategorized with sort index 0: %q", got)
+	}
+	cats = newCategories()
+	sortCategories(cats, map[string]Category{"Uncategorized": {Name: "Uncategorized", SortIndex: -1, Sorted: true}})
+	if got := names(cats); got[0] != "Uncategorized" {
+		t.Errorf("Uncategorized with sort index -1: %q", got)
+	}
+}
+
+func TestCategoriesOnAppsPage(t *testing.T) {
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.categories = parseCategories([]string{
+		"PODFATHER_CATEGORY_X_NAME=Uncategorized",
+		"PODFATHER_CATEGORY_X_SORT_INDEX=-1",
+		"PODFATHER_CATEGORY_X_ICON=📦",
+		"PODFATHER_CATEGORY_X_DESCRIPTION=Needs **labels**",
+	})
+	w := httptest.NewRecorder()
+	s.handleApps(w, httptest.NewRequest(http.MethodGet, "/apps", nil))
+	body := w.Body.String()
+	if sections := categorySections(body); len(sections) == 0 || categoryHeading(sections[0]) != "📦 Uncategorized" {
+		t.Errorf("first category is not the decorated Uncategorized")
+	}
+	if !strings.Contains(body, `<p class="app-desc category-desc">Needs <strong>labels</strong></p>`) {
+		t.Error("category description missing")
+	}
+}
+
+// categorySections splits the apps page body into the HTML of its
+// categories, each starting with the heading.
+func categorySections(body string) []string {
+	sections := strings.Split(body, `<h2 class="category-title">`)
+	return sections[1:]
+}
+
+// categoryHeading returns the text of the heading of a category section,
+// without the collapse toggle and app count.
+func categoryHeading(section string) string {
+	heading, _, _ := strings.Cut(section, "</h2>")
+	if _, after, ok := strings.Cut(heading, "</form>"); ok {
+		heading = after
+	}
+	heading, _, _ = strings.Cut(heading, "<span")
+	return strings.TrimSpace(heading)
+}
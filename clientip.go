@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Behind a reverse proxy, the remote address of every request is the
+// proxy's. With TRUSTED_PROXIES, logRequests takes the client from the
+// X-Forwarded-For entries appended by trusted proxies: walking the header
+// from the right, the first address that is not a trusted proxy. Entries
+// further left are set by the client and can be faked. clientIP returns
+// that address to the access log, the audit log and the rate limiters.
+
+const clientIPKey ctxKey = 3
+
+// parseTrustedProxies parses a comma-separated list of IP addresses and
+// CIDR prefixes.
+func parseTrustedProxies(v string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for s := range strings.SplitSeq(v, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(s); err == nil {
+			out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR prefix", s)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
+
+// trustedProxy reports whether the address ip is in one of the prefixes.
+func trustedProxy(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the remote IP of the request, without port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedClient returns the client address of r: the remote IP, or, if
+// that is a trusted proxy, the rightmost X-Forwarded-For address that is
+// not.
+func forwardedClient(r *http.Request, trusted []netip.Prefix) string {
+	ip := remoteIP(r)
+	if !trustedProxy(ip, trusted) {
+		return ip
+	}
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		for hop := range strings.SplitSeq(h, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if hops[i] == "" {
+			continue
+		}
+		ip = hops[i]
+		if !trustedProxy(ip, trusted) {
+			break
+		}
+	}
+	return ip
+}
+
+// withClientIP returns r with its client address, as returned by clientIP.
+func (s *Server) withClientIP(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), clientIPKey, forwardedClient(r, s.trustedProxies)))
+}
+
+// clientIP returns the client address of the request, see forwardedClient.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
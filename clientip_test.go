@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	t.Parallel()
+	got, err := parseTrustedProxies("10.0.0.1, 172.16.0.0/12,::1,")
+	if err != nil || len(got) != 3 || got[0].String() != "10.0.0.1/32" || got[1].String() != "172.16.0.0/12" || got[2].String() != "::1/128" {
+		t.Errorf("parseTrustedProxies = %v, %v", got, err)
+	}
+	if _, err := parseTrustedProxies("proxy.local"); err == nil {
+		t.Error("parseTrustedProxies accepted a host name")
+	}
+}
+
+func TestForwardedClient(t *testing.T) {
+	t.Parallel()
+	trusted, _ := parseTrustedProxies("10.0.0.0/8")
+	for _, tt := range []struct {
+		remote, xff string
+		trusted     bool
+		want        string
+	}{
+		{"192.0.2.1:5555", "", true, "192.0.2.1"},
+		// Only a trusted proxy's header is used.
+		{"192.0.2.1:5555", "203.0.113.7", true, "192.0.2.1"},
+		{"10.0.0.1:5555", "203.0.113.7", false, "10.0.0.1"},
+		{"10.0.0.1:5555", "", true, "10.0.0.1"},
+		// The client can prepend fake entries; the proxy appends the real one.
+		{"10.0.0.1:5555", "198.51.100.9, 203.0.113.7", true, "203.0.113.7"},
+		// Chained trusted proxies are skipped.
+		{"10.0.0.1:5555", "203.0.113.7, 10.0.0.2", true, "203.0.113.7"},
+		{"[::ffff:10.0.0.1]:5555", "203.0.113.7", true, "203.0.113.7"},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			r.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		proxies := trusted
+		if !tt.trusted {
+			proxies = nil
+		}
+		if got := forwardedClient(r, proxies); got != tt.want {
+			t.Errorf("forwardedClient(%s, %q) = %q, want %q", tt.remote, tt.xff, got, tt.want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	t.Parallel()
+	s := &Server{}
+	s.trustedProxies, _ = parseTrustedProxies("127.0.0.1")
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "127.0.0.1:5555"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	if got := clientIP(r); got != "127.0.0.1" {
+		t.Errorf("clientIP without withClientIP = %q", got)
+	}
+	if got := clientIP(s.withClientIP(r)); got != "203.0.113.7" {
+		t.Errorf("clientIP = %q", got)
+	}
+}
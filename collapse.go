@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Categories of the apps page can be folded to their heading, e.g. rarely
+// used ones on a large dashboard. The folded categories are a name list
+// cookie (see favorites.go), so this works per browser and without
+// JavaScript.
+
+const collapsedCookieName = "collapsed"
+
+const maxCollapsed = 30
+
+// readCollapsed returns the names of the folded categories.
+func readCollapsed(r *http.Request) []string {
+	return readNameList(r, collapsedCookieName, maxCollapsed)
+}
+
+// handleCollapse folds (collapse=on) or unfolds the category named in the
+// form.
+func (s *Server) handleCollapse(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("category")
+	if !validListName(name) {
+		http.Error(w, "Invalid category", http.StatusBadRequest)
+		return
+	}
+	names := slices.DeleteFunc(readCollapsed(r), func(n string) bool { return n == name })
+	if r.FormValue("collapse") == "on" {
+		if len(names) >= maxCollapsed {
+			http.Error(w, "Too many collapsed categories", http.StatusBadRequest)
+			return
+		}
+		names = append(names, name)
+	}
+	s.writeNameList(w, r, collapsedCookieName, names)
+	http.Redirect(w, r, s.basePath+"/apps", http.StatusSeeOther)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCollapseCategories(t *testing.T) {
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	collapse := func(category string, on bool, cookies []*http.Cookie) []*http.Cookie {
+		t.Helper()
+		form := url.Values{"category": {category}}
+		if on {
+			form.Set("collapse", "on")
+		}
+		r := httptest.NewRequest(http.MethodPost, "/apps/collapse", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		s.handleCollapse(w, r)
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/apps" {
+			t.Fatalf("collapse %s: status %d location %q", category, w.Code, w.Header().Get("Location"))
+		}
+		return w.Result().Cookies()
+	}
+	sections := func(cookies []*http.Cookie) map[string]string {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/apps", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		s.handleApps(w, r)
+		out := map[string]string{}
+		for _, sec := range categorySections(w.Body.String()) {
+			out[categoryHeading(sec)] = sec
+		}
+		return out
+	}
+
+	cookies := collapse("Media", true, nil)
+	cookies = collapse("Monitoring", true, cookies)
+	got := sections(cookies)
+	for _, name := range []string{"Media", "Monitoring"} {
+		if sec := got[name]; strings.Contains(sec, `class="app-card"`) || !strings.Contains(sec, "2 apps</span>") || !strings.Contains(sec, `title="Expand"`) {
+			t.Errorf("%s is not collapsed:\n%s", name, sec)
+		}
+	}
+	if sec := got["Infrastructure"]; !strings.Contains(sec, `class="app-card"`) || !strings.Contains(sec, `name="collapse" value="on"`) {
+		t.Errorf("Infrastructure is collapsed:\n%s", sec)
+	}
+
+	cookies = collapse("Media", false, cookies)
+	got = sections(cookies)
+	if !strings.Contains(got["Media"], `class="app-card"`) || strings.Contains(got["Monitoring"], `class="app-card"`) {
+		t.Error("expanding Media changed the wrong categories")
+	}
+}
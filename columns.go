@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Custom columns show label or annotation values on the containers list,
+// for metadata that has no dedicated feature, e.g.
+//
+//	CONTAINER_COLUMNS=Backup=backup.schedule,Owner=annotation:team.owner
+//
+// Each entry is [Header=][annotation:]key; the header defaults to the key.
+
+const maxListColumns = 10
+
+// listColumn is a custom column of the containers list.
+type listColumn struct {
+	Header     string
+	Key        string
+	Annotation bool // read Config.Annotations instead of the labels
+}
+
+// parseColumns parses a CONTAINER_COLUMNS value.
+func parseColumns(s string) ([]listColumn, error) {
+	var cols []listColumn
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		var col listColumn
+		header, key, ok := strings.Cut(entry, "=")
+		if !ok {
+			key = header
+		}
+		if k, ok := strings.CutPrefix(key, "annotation:"); ok {
+			col.Annotation = true
+			key = k
+		}
+		col.Key = strings.TrimSpace(key)
+		col.Header = strings.TrimSpace(header)
+		if !ok {
+			col.Header = col.Key
+		}
+		if col.Key == "" || col.Header == "" || strings.ContainsAny(col.Key, " \t") {
+			return nil, fmt.Errorf("invalid column %q, want [Header=][annotation:]key", entry)
+		}
+		cols = append(cols, col)
+	}
+	if len(cols) > maxListColumns {
+		return nil, fmt.Errorf("at most %d columns", maxListColumns)
+	}
+	return cols, nil
+}
+
+// ContainerRow is a row of the containers list: the container and the
+// values of the custom columns.
+type ContainerRow struct {
+	Container
+	Columns []string
+}
+
+// containerRows adds the custom column values to the containers. Annotations
+// are not part of the list response, so containers are only inspected (in
+// parallel) if a column needs them.
+func (s *Server) containerRows(ctx context.Context, list []Container) ([]ContainerRow, error) {
+	needsInspect := false
+	for _, col := range s.containerColumns {
+		needsInspect = needsInspect || col.Annotation
+	}
+	annotations := make([]map[string]string, len(list))
+	if needsInspect {
+		err := parallel(ctx, len(list), func(ctx context.Context, i int) error {
+			ci, err := s.inspectContainer(ctx, list[i].ID)
+			if err != nil && !errors.Is(err, errNotFound) {
+				return err
+			}
+			annotations[i] = ci.Config.Annotations
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	rows := make([]ContainerRow, len(list))
+	for i, c := range list {
+		rows[i] = ContainerRow{Container: c}
+		if len(s.containerColumns) == 0 {
+			continue
+		}
+		rows[i].Columns = make([]string, len(s.containerColumns))
+		for j, col := range s.containerColumns {
+			if col.Annotation {
+				rows[i].Columns[j] = annotations[i][col.Key]
+			} else {
+				rows[i].Columns[j] = c.Labels[col.Key]
+			}
+		}
+	}
+	return rows, nil
+}
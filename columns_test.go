@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseColumns(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []listColumn
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"backup.schedule", []listColumn{{"backup.schedule", "backup.schedule", false}}, false},
+		{"Backup=backup.schedule, Owner=annotation:team.owner", []listColumn{
+			{"Backup", "backup.schedule", false},
+			{"Owner", "team.owner", true},
+		}, false},
+		{"annotation:team.owner,", []listColumn{{"team.owner", "team.owner", true}}, false},
+		{"Backup=", nil, true},
+		{"=backup.schedule", nil, true},
+		{"Owner=team owner", nil, true},
+		{strings.Repeat("a,", maxListColumns+1), nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseColumns(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseColumns(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseColumns(%q) = %+v, want %+v", tt.in, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseColumns(%q)[%d] = %+v, want %+v", tt.in, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestContainersCustomColumns(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.containerColumns, _ = parseColumns("Compose project=com.docker.compose.project,Manager=annotation:io.container.manager")
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/containers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	page := string(body)
+	for _, want := range []string{"<th>Compose project</th>", "<th>Manager</th>", "<td>podfather</td>", "<td>libpod</td>"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("containers page does not contain %q", want)
+		}
+	}
+}
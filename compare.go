@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DiffRow is one compared value of two objects.
+type DiffRow struct {
+	Key string
+	A   string
+	B   string
+}
+
+func (d DiffRow) Changed() bool { return d.A != d.B }
+
+// DiffSection is a group of compared values, e.g. all labels.
+type DiffSection struct {
+	Name string
+	Rows []DiffRow
+}
+
+// Changes returns the number of rows that differ.
+func (s DiffSection) Changes() int {
+	n := 0
+	for _, r := range s.Rows {
+		if r.Changed() {
+			n++
+		}
+	}
+	return n
+}
+
+// onlyChanged returns the sections with unchanged rows removed.
+func onlyChanged(sections []DiffSection) []DiffSection {
+	out := make([]DiffSection, len(sections))
+	for i, s := range sections {
+		out[i].Name = s.Name
+		for _, r := range s.Rows {
+			if r.Changed() {
+				out[i].Rows = append(out[i].Rows, r)
+			}
+		}
+	}
+	return out
+}
+
+// diffMaps compares two maps by key, sorted by key.
+func diffMaps(a, b map[string]string) []DiffRow {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	rows := make([]DiffRow, 0, len(keys))
+	for k := range keys {
+		rows = append(rows, DiffRow{Key: k, A: a[k], B: b[k]})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Key < rows[j].Key })
+	return rows
+}
+
+// diffLists compares two lists position by position, keyed by index, so
+// shared prefixes (e.g. base image layers) line up.
+func diffLists(a, b []string) []DiffRow {
+	rows := make([]DiffRow, max(len(a), len(b)))
+	for i := range rows {
+		rows[i].Key = fmt.Sprint(i + 1)
+		if i < len(a) {
+			rows[i].A = a[i]
+		}
+		if i < len(b) {
+			rows[i].B = b[i]
+		}
+	}
+	return rows
+}
+
+// envMap splits KEY=VALUE entries into a map.
+func envMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, e := range env {
+		m[envName(e)] = envValue(e)
+	}
+	return m
+}
+
+// setMap turns a set into a map with "yes" values for diffMaps.
+func setMap[V any](set map[string]V) map[string]string {
+	m := make(map[string]string, len(set))
+	for k := range set {
+		m[k] = "yes"
+	}
+	return m
+}
+
+// compareImages builds a structured diff of two images.
+func compareImages(a, b ImageInspect) []DiffSection {
+	general := func(img ImageInspect) map[string]string {
+		return map[string]string{
+			"Created":      img.Created.Format("2006-01-02 15:04:05 MST"),
+			"Size":         humanSize(img.Size),
+			"Architecture": img.Architecture,
+			"OS":           img.Os,
+			"User":         img.User,
+			"Entrypoint":   strings.Join(img.Config.Entrypoint, " "),
+			"Command":      strings.Join(img.Config.Cmd, " "),
+			"Working Dir":  img.Config.WorkingDir,
+			"Stop Signal":  string(img.Config.StopSignal),
+			"Layers":       fmt.Sprint(len(img.RootFS.Layers)),
+		}
+	}
+	history := func(img ImageInspect) []string {
+		out := make([]string, len(img.History))
+		for i, h := range img.History {
+			out[i] = h.CreatedBy
+			if h.Size > 0 {
+				out[i] += " (" + humanSize(h.Size) + ")"
+			}
+		}
+		return out
+	}
+	layers := func(img ImageInspect) []string {
+		out := make([]string, len(img.RootFS.Layers))
+		for i, l := range img.RootFS.Layers {
+			out[i] = shortID(l)
+		}
+		return out
+	}
+	return []DiffSection{
+		{Name: "General", Rows: diffMaps(general(a), general(b))},
+		{Name: "Environment Variables", Rows: diffMaps(envMap(a.Config.Env), envMap(b.Config.Env))},
+		{Name: "Exposed Ports", Rows: diffMaps(setMap(a.Config.ExposedPorts), setMap(b.Config.ExposedPorts))},
+		{Name: "Labels", Rows: diffMaps(a.Labels, b.Labels)},
+		{Name: "Layers", Rows: diffLists(layers(a), layers(b))},
+		{Name: "History", Rows: diffLists(history(a), history(b))},
+	}
+}
+
+func (s *Server) handleImageCompare(w http.ResponseWriter, r *http.Request) {
+	list, err := s.listImages(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	sort.Slice(list, func(i, j int) bool { return imageLabel(list[i]) < imageLabel(list[j]) })
+
+	q := r.URL.Query()
+	idA, idB := q.Get("a"), q.Get("b")
+	data := map[string]any{
+		"Title":   "Compare Images",
+		"Images":  list,
+		"A":       idA,
+		"B":       idB,
+		"Changed": q.Get("changed") != "",
+	}
+	if idA == "" || idB == "" {
+		s.render(w, r, "compare.html", data)
+		return
+	}
+	if !validID.MatchString(idA) || !validID.MatchString(idB) {
+		http.Error(w, "Invalid image ID", http.StatusBadRequest)
+		return
+	}
+	var a, b ImageInspect
+	for _, x := range []struct {
+		id  string
+		img *ImageInspect
+	}{{idA, &a}, {idB, &b}} {
+		var err error
+		if *x.img, err = s.inspectImage(r.Context(), x.id); err != nil {
+			if errors.Is(err, errNotFound) {
+				http.Error(w, "Image Not Found", http.StatusNotFound)
+				return
+			}
+			log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+	sections := compareImages(a, b)
+	if data["Changed"].(bool) {
+		sections = onlyChanged(sections)
+	}
+	data["ImageA"] = a
+	data["ImageB"] = b
+	data["Sections"] = sections
+	s.render(w, r, "compare.html", data)
+}
+
+// imageLabel returns the first tag of an image, or its short ID.
+func imageLabel(img ImageSummary) string {
+	if len(img.RepoTags) > 0 {
+		return img.RepoTags[0]
+	}
+	return shortID(img.ID)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffMaps(t *testing.T) {
+	got := diffMaps(map[string]string{"a": "1", "b": "2"}, map[string]string{"b": "3", "c": "4"})
+	want := []DiffRow{{"a", "1", ""}, {"b", "2", "3"}, {"c", "", "4"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffMaps() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffLists(t *testing.T) {
+	got := diffLists([]string{"base", "old"}, []string{"base", "new", "extra"})
+	want := []DiffRow{{"1", "base", "base"}, {"2", "old", "new"}, {"3", "", "extra"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffLists() = %v, want %v", got, want)
+	}
+}
+
+func TestCompareImages(t *testing.T) {
+	a := ImageInspect{
+		Config: ImageConfig{Env: []string{"PATH=/bin", "VERSION=1.0"}, ExposedPorts: map[string]struct{}{"80/tcp": {}}},
+		Labels: map[string]string{"org.opencontainers.image.version": "1.0"},
+		RootFS: RootFS{Layers: []string{"sha256:aaaaaaaaaaaaaaaa", "sha256:bbbbbbbbbbbbbbbb"}},
+	}
+	b := ImageInspect{
+		Config: ImageConfig{Env: []string{"PATH=/bin", "VERSION=1.1"}, ExposedPorts: map[string]struct{}{"80/tcp": {}, "443/tcp": {}}},
+		Labels: map[string]string{"org.opencontainers.image.version": "1.1"},
+		RootFS: RootFS{Layers: []string{"sha256:aaaaaaaaaaaaaaaa", "sha256:cccccccccccccccc"}},
+	}
+	changes := map[string]int{}
+	for _, s := range compareImages(a, b) {
+		changes[s.Name] = s.Changes()
+	}
+	want := map[string]int{
+		"General":               0,
+		"Environment Variables": 1,
+		"Exposed Ports":         1,
+		"Labels":                1,
+		"Layers":                1,
+		"History":               0,
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+
+	for _, s := range onlyChanged(compareImages(a, b)) {
+		for _, r := range s.Rows {
+			if !r.Changed() {
+				t.Errorf("onlyChanged kept unchanged row %v in %s", r, s.Name)
+			}
+		}
+	}
+}
+
+func TestImageComparePage(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	app := httptest.NewServer(newTestServer(t, mock).newMux("podman"))
+	defer app.Close()
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/images/compare", http.StatusOK, "<select"},
+		{"/images/compare?a=b76de378d572&b=b76de378d572", http.StatusOK, "Layers"},
+		{"/images/compare?a=b76de378d572&b=nonexistent", http.StatusNotFound, ""},
+		{"/images/compare?a=b76de378d572&b=!!!", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(app.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("GET %s status = %d, want %d", tt.path, resp.StatusCode, tt.wantStatus)
+		}
+		if !strings.Contains(string(body), tt.wantBody) {
+			t.Errorf("GET %s body does not contain %q", tt.path, tt.wantBody)
+		}
+	}
+}
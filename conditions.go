@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// App conditions let an app define when it counts as degraded or down,
+// beyond "some container is running", via container labels such as
+//
+//	ch.jo-m.go.podfather.app.degraded-when=restarts>3/h || failing>=2
+//	ch.jo-m.go.podfather.app.down-when=unhealthy || !running
+//
+// A condition is a small boolean expression over per-container variables
+// (condVars). `&&` binds tighter than `||`, `!` negates a single term. A
+// comparison with a rate suffix (`/m`, `/h`, `/d`) counts container exits
+// within that window, from the events API.
+
+const (
+	degradedWhenLabel = appLabelPrefix + "degraded-when"
+	downWhenLabel     = appLabelPrefix + "down-when"
+)
+
+// condVars are the variables available in conditions.
+var condVars = map[string]bool{
+	"running":   true, // 1 if running
+	"exited":    true, // 1 if exited or stopped
+	"paused":    true,
+	"unhealthy": true, // 1 if the healthcheck reports unhealthy
+	"oom":       true, // 1 if the last exit was an OOM kill
+	"failing":   true, // healthcheck failing streak
+	"exit-code": true,
+	"restarts":  true, // restart count; with a rate suffix, exits per window
+}
+
+var rateWindows = map[string]time.Duration{
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+}
+
+// condTerm is a single comparison, e.g. `restarts>3/h` or `!running`.
+type condTerm struct {
+	name   string
+	op     string // "" for a bare variable (true if non-zero)
+	value  float64
+	window time.Duration // for rates
+	negate bool
+}
+
+// condition is a parsed expression in disjunctive normal form: it is true
+// if all terms of any group are true.
+type condition [][]condTerm
+
+var condOps = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// parseCondition parses a condition expression.
+func parseCondition(expr string) (condition, error) {
+	var cond condition
+	for or := range strings.SplitSeq(expr, "||") {
+		var group []condTerm
+		for and := range strings.SplitSeq(or, "&&") {
+			t, err := parseCondTerm(strings.TrimSpace(and))
+			if err != nil {
+				return nil, err
+			}
+			group = append(group, t)
+		}
+		cond = append(cond, group)
+	}
+	return cond, nil
+}
+
+func parseCondTerm(s string) (condTerm, error) {
+	var t condTerm
+	if rest, ok := strings.CutPrefix(s, "!"); ok {
+		t.negate = true
+		s = strings.TrimSpace(rest)
+	}
+	name := s
+	for _, op := range condOps {
+		if i := strings.Index(s, op); i >= 0 {
+			name, t.op = strings.TrimSpace(s[:i]), op
+			value := strings.TrimSpace(s[i+len(op):])
+			if num, unit, ok := strings.Cut(value, "/"); ok {
+				w, ok := rateWindows[strings.TrimSpace(unit)]
+				if !ok {
+					return t, fmt.Errorf("invalid rate %q, want /m, /h or /d", value)
+				}
+				t.window = w
+				value = strings.TrimSpace(num)
+			}
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return t, fmt.Errorf("invalid number %q", value)
+			}
+			t.value = v
+			break
+		}
+	}
+	if name == "" || strings.ContainsFunc(name, unicode.IsSpace) {
+		return t, fmt.Errorf("invalid term %q", s)
+	}
+	if !condVars[name] {
+		return t, fmt.Errorf("unknown variable %q", name)
+	}
+	if t.window > 0 && name != "restarts" {
+		return t, fmt.Errorf("rates are only supported for restarts")
+	}
+	t.name = name
+	return t, nil
+}
+
+// maxWindow returns the longest rate window used, or 0.
+func (c condition) maxWindow() time.Duration {
+	var w time.Duration
+	for _, group := range c {
+		for _, t := range group {
+			w = max(w, t.window)
+		}
+	}
+	return w
+}
+
+// condInput is what a condition is evaluated against.
+type condInput struct {
+	Container ContainerInspect
+	Exits     []time.Time // container exits, for rates
+	Now       time.Time
+}
+
+func (in condInput) value(t condTerm) float64 {
+	b := func(v bool) float64 {
+		if v {
+			return 1
+		}
+		return 0
+	}
+	st := in.Container.State
+	switch t.name {
+	case "running":
+		return b(st.Running)
+	case "exited":
+		return b(st.Status == "exited" || st.Status == "stopped")
+	case "paused":
+		return b(st.Paused)
+	case "unhealthy":
+		return b(st.Health != nil && st.Health.Status == "unhealthy")
+	case "oom":
+		return b(st.OOMKilled)
+	case "failing":
+		if st.Health == nil {
+			return 0
+		}
+		return float64(st.Health.FailingStreak)
+	case "exit-code":
+		return float64(st.ExitCode)
+	case "restarts":
+		if t.window == 0 {
+			return float64(in.Container.RestartCount)
+		}
+		n := 0
+		for _, e := range in.Exits {
+			if in.Now.Sub(e) <= t.window {
+				n++
+			}
+		}
+		return float64(n)
+	}
+	return 0
+}
+
+func (t condTerm) eval(in condInput) bool {
+	v := in.value(t)
+	var res bool
+	switch t.op {
+	case "":
+		res = v != 0
+	case ">":
+		res = v > t.value
+	case ">=":
+		res = v >= t.value
+	case "<":
+		res = v < t.value
+	case "<=":
+		res = v <= t.value
+	case "==":
+		res = v == t.value
+	case "!=":
+		res = v != t.value
+	}
+	return res != t.negate
+}
+
+func (c condition) eval(in condInput) bool {
+	for _, group := range c {
+		all := true
+		for _, t := range group {
+			if !t.eval(in) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// containerExits returns the exit times of all containers since the given
+// time, by container ID.
+func (s *Server) containerExits(since time.Time) (map[string][]time.Time, error) {
+	filters, _ := json.Marshal(map[string][]string{"type": {"container"}, "event": {"die", "died"}})
+	q := url.Values{
+		"stream":  {"false"},
+		"since":   {strconv.FormatInt(since.Unix(), 10)},
+		"until":   {strconv.FormatInt(time.Now().Unix(), 10)},
+		"filters": {string(filters)},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := s.podmanDo(ctx, http.MethodGet, "/events?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	exits := map[string][]time.Time{}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev struct {
+			Action   string `json:"Action"`
+			TimeNano int64  `json:"timeNano"`
+			Actor    struct {
+				ID string `json:"ID"`
+			} `json:"Actor"`
+		}
+		if err := dec.Decode(&ev); errors.Is(err, io.EOF) {
+			return exits, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if ev.Action == "die" || ev.Action == "died" {
+			exits[ev.Actor.ID] = append(exits[ev.Actor.ID], time.Unix(0, ev.TimeNano))
+		}
+	}
+}
+
+// applyAppConditions evaluates the degraded-when and down-when labels of
+// the containers of each app and sets App.Condition. The first matching
+// container determines the reason. Apps without such labels are untouched.
+// The containers are inspected in parallel.
+func (s *Server) applyAppConditions(ctx context.Context, categories []AppCategory) error {
+	now := time.Now()
+	var window time.Duration
+	parsed := map[string]condition{}
+	parseErrs := map[string]error{}
+	var labeled []string // IDs of the containers to inspect
+	for _, cat := range categories {
+		for _, app := range cat.Apps {
+			for _, c := range app.Containers {
+				_, hasDown := c.Labels[downWhenLabel]
+				_, hasDegraded := c.Labels[degradedWhenLabel]
+				if hasDown || hasDegraded {
+					labeled = append(labeled, c.ID)
+				}
+				for _, label := range []string{downWhenLabel, degradedWhenLabel} {
+					expr, ok := c.Labels[label]
+					if !ok {
+						continue
+					}
+					if _, done := parsed[expr]; done || parseErrs[expr] != nil {
+						continue
+					}
+					cond, err := parseCondition(expr)
+					if err != nil {
+						parseErrs[expr] = err
+						continue
+					}
+					parsed[expr] = cond
+					window = max(window, cond.maxWindow())
+				}
+			}
+		}
+	}
+	if len(parsed) == 0 && len(parseErrs) == 0 {
+		return nil
+	}
+
+	var exits map[string][]time.Time
+	inspected := make([]*ContainerInspect, len(labeled))
+	err := parallel(ctx, len(labeled)+1, func(ctx context.Context, i int) (err error) {
+		if i == len(labeled) {
+			if window > 0 {
+				exits, err = s.containerExits(now.Add(-window))
+			}
+			return err
+		}
+		insp, err := s.inspectContainer(ctx, labeled[i])
+		if errors.Is(err, errNotFound) {
+			return nil
+		}
+		inspected[i] = &insp
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	byID := map[string]*ContainerInspect{}
+	for i, id := range labeled {
+		byID[id] = inspected[i]
+	}
+
+	for ci := range categories {
+		for ai := range categories[ci].Apps {
+			app := &categories[ci].Apps[ai]
+			for _, c := range app.Containers {
+				down, hasDown := c.Labels[downWhenLabel]
+				degraded, hasDegraded := c.Labels[degradedWhenLabel]
+				if !hasDown && !hasDegraded {
+					continue
+				}
+				for _, expr := range []string{down, degraded} {
+					if err := parseErrs[expr]; err != nil {
+						app.Condition = "invalid"
+						app.ConditionReason = firstName(c.Names) + ": " + err.Error()
+					}
+				}
+				if app.Condition == "invalid" {
+					break
+				}
+				insp := byID[c.ID]
+				if insp == nil { // removed meanwhile
+					continue
+				}
+				in := condInput{Container: *insp, Exits: exits[c.ID], Now: now}
+				if hasDown && parsed[down].eval(in) {
+					app.Condition = "down"
+					app.ConditionReason = firstName(c.Names) + ": " + down
+					break
+				}
+				if hasDegraded && app.Condition == "" && parsed[degraded].eval(in) {
+					app.Condition = "degraded"
+					app.ConditionReason = firstName(c.Names) + ": " + degraded
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCondition(t *testing.T) {
+	valid := []string{
+		"unhealthy",
+		"!running",
+		"restarts>3/h",
+		"restarts >= 1 / d || failing>=2 && !paused",
+		"exit-code!=0",
+	}
+	for _, expr := range valid {
+		if _, err := parseCondition(expr); err != nil {
+			t.Errorf("parseCondition(%q) error: %v", expr, err)
+		}
+	}
+	invalid := []string{
+		"",
+		"cpu>90",
+		"restarts>3/w",
+		"failing>2/h",
+		"running ||",
+		"restarts>many",
+		"not running",
+	}
+	for _, expr := range invalid {
+		if _, err := parseCondition(expr); err == nil {
+			t.Errorf("parseCondition(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestConditionEval(t *testing.T) {
+	now := time.Now()
+	in := condInput{
+		Container: ContainerInspect{
+			RestartCount: 5,
+			State: ContainerState{
+				Status:  "running",
+				Running: true,
+				Health:  &Health{Status: "unhealthy", FailingStreak: 3},
+			},
+		},
+		Exits: []time.Time{now.Add(-10 * time.Minute), now.Add(-50 * time.Minute), now.Add(-2 * time.Hour)},
+		Now:   now,
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"running", true},
+		{"!running", false},
+		{"unhealthy", true},
+		{"exited || paused", false},
+		{"failing>=3 && running", true},
+		{"failing>=3 && !running", false},
+		{"restarts>4", true},
+		{"restarts>2/h", false},
+		{"restarts>=2/h", true},
+		{"restarts>2/d", true},
+		{"restarts>0/m", false},
+		{"oom || exit-code!=0", false},
+	}
+	for _, tt := range tests {
+		cond, err := parseCondition(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCondition(%q): %v", tt.expr, err)
+		}
+		if got := cond.eval(in); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestApplyAppConditions(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+
+	app := func(name string, labels map[string]string) App {
+		return App{Name: name, Containers: []Container{{ID: name, Names: []string{name}, Labels: labels}}}
+	}
+	categories := []AppCategory{{Name: "Test", Apps: []App{
+		app("plain", nil),
+		app("crashy", map[string]string{degradedWhenLabel: "restarts>3/h", downWhenLabel: "!running"}),
+		app("flaky", map[string]string{degradedWhenLabel: "restarts>3/h"}),
+		app("strict", map[string]string{downWhenLabel: "running && failing==0"}),
+		app("typo", map[string]string{downWhenLabel: "unhealty"}),
+	}}}
+	if err := s.applyAppConditions(t.Context(), categories); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"plain":  "",
+		"crashy": "degraded",
+		"flaky":  "",
+		"strict": "down",
+		"typo":   "invalid",
+	}
+	for _, a := range categories[0].Apps {
+		if a.Condition != want[a.Name] {
+			t.Errorf("%s: Condition = %q (%s), want %q", a.Name, a.Condition, a.ConditionReason, want[a.Name])
+		}
+	}
+}
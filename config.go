@@ -0,0 +1,662 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configVars are the environment variables podfather reads, in the order
+// they are shown on /config. PODFATHER_APP_* variables are validated by
+// validateExternalApps, PODFATHER_CATEGORY_* ones by validateCategories.
+var configVars = []string{
+	"LISTEN_ADDR",
+	"PODFATHER_TLS_CERT",
+	"PODFATHER_TLS_KEY",
+	"PODMAN_SOCKET",
+	"API_BACKEND",
+	"BASE_PATH",
+	"TRUSTED_PROXIES",
+	"ENABLE_AUTOUPDATE_BUTTON",
+	"ENABLE_ACTIONS",
+	"ENABLE_DEBUG_PAGE",
+	"ENABLE_FILE_DOWNLOAD",
+	"ENABLE_FILE_UPLOAD",
+	"ENABLE_LIVE_MODE",
+	"QUADLET_DIR",
+	"ACTION_RATE_LIMIT",
+	"ACTION_RATE_BURST",
+	"REQUEST_TIMEOUT",
+	"ROUTE_TIMEOUTS",
+	"SLOW_API_THRESHOLD",
+	"API_CACHE_TTL",
+	"POLL_INTERVAL",
+	"WEBHOOK_TOKEN",
+	"OIDC_ISSUER",
+	"OIDC_CLIENT_ID",
+	"OIDC_CLIENT_SECRET",
+	"OIDC_REDIRECT_URL",
+	"DATA_DIR",
+	"CONFIG_GIT_URL",
+	"CONFIG_GIT_BRANCH",
+	"CONFIG_GIT_FILE",
+	"CONFIG_GIT_INTERVAL",
+	"UPDATE_CHECK_INTERVAL",
+	"APP_PROBE_INTERVAL",
+	"AUTO_UPDATE_SCHEDULE",
+	"PRUNE_SCHEDULE",
+	"SCHEDULE_TIMEZONE",
+	"SCHEDULE_SKIP_DATES",
+	"SCHEDULE_JITTER",
+	"ENERGY_WATTS_PER_CORE",
+	"ENERGY_CO2_PER_KWH",
+	"THEME",
+	"PODFATHER_CUSTOM_CSS",
+	"CONTAINER_COLUMNS",
+	"IMAGE_POLICY_LABELS",
+	"IMAGE_POLICY_REPOS",
+	"NTFY_URL",
+	"NTFY_TOKEN",
+	"GOTIFY_URL",
+	"GOTIFY_TOKEN",
+}
+
+// configPrefixes mark variables as likely meant for podfather, so unknown
+// ones are logged instead of silently ignored. Near misses of known
+// variables are rejected regardless of the prefix.
+var configPrefixes = []string{"PODFATHER_", "ENABLE_", "CONFIG_GIT_", "UPDATE_CHECK_", "APP_PROBE_", "ENERGY_", "NTFY_", "GOTIFY_", "IMAGE_POLICY_", "OIDC_", "SCHEDULE_"}
+
+// config is the validated configuration from the environment.
+type config struct {
+	ListenAddr          string
+	TLSCert             string // empty to serve plain HTTP
+	TLSKey              string
+	Socket              string // empty to use the default for the backend
+	Backend             string // empty to auto-detect
+	BasePath            string
+	TrustedProxies      []netip.Prefix // reverse proxies whose X-Forwarded-For is used
+	EnableAutoUpdate    bool
+	EnableActions       bool
+	EnableDebugPage     bool
+	EnableFileDownload  bool
+	EnableFileUpload    bool
+	EnableLiveMode      bool
+	QuadletDir          string  // empty to disable digest pinning
+	ActionRateLimit     float64 // POST requests per minute and client, 0 if unlimited
+	ActionRateBurst     int
+	RequestTimeout      time.Duration // 0 if disabled
+	RouteTimeouts       []routeTimeout
+	SlowAPIThreshold    time.Duration // 0 if disabled
+	APICacheTTL         time.Duration // 0 if disabled
+	PollInterval        time.Duration // 0 if disabled
+	WebhookToken        string
+	OIDCIssuer          string // empty to disable login
+	OIDCClientID        string
+	OIDCClientSecret    string
+	OIDCRedirectURL     string
+	DataDir             string
+	GitURL              string
+	GitBranch           string
+	GitFile             string
+	GitInterval         time.Duration
+	UpdateCheckInterval time.Duration // 0 if disabled
+	AppProbeInterval    time.Duration // 0 if disabled
+	AutoUpdateSchedule  cronSpec      // zero if disabled
+	PruneSchedule       cronSpec      // zero if disabled
+	ScheduleTimezone    *time.Location
+	ScheduleSkipDates   []skipDate
+	ScheduleJitter      time.Duration
+	EnergyWattsPerCore  float64 // 0 if disabled
+	EnergyCO2PerKWh     float64
+	Theme               string
+	CustomCSS           string // empty if there is no user stylesheet
+	ContainerColumns    []listColumn
+	ImagePolicy         imagePolicy
+	NtfyURL             string
+	NtfyToken           string
+	GotifyURL           string
+	GotifyToken         string
+	ExternalApps        []App
+	Categories          map[string]Category
+
+	env map[string]string // the podfather variables that were set
+}
+
+// loadConfig reads and validates the configuration from environ. All
+// problems are reported at once, one per line.
+func loadConfig(environ []string) (config, error) {
+	c := config{env: map[string]string{}}
+	var errs []error
+	bad := func(name, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s=%q: %s", name, c.env[name], fmt.Sprintf(format, args...)))
+	}
+
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if slices.Contains(configVars, name) {
+			c.env[name] = value
+			continue
+		}
+		if strings.HasPrefix(name, "PODFATHER_APP_") || strings.HasPrefix(name, categoryPrefix) {
+			continue
+		}
+		if suggestion := suggestConfigVar(name); suggestion != "" {
+			errs = append(errs, fmt.Errorf("unknown variable %s (did you mean %s?)", name, suggestion))
+		} else if slices.ContainsFunc(configPrefixes, func(p string) bool { return strings.HasPrefix(name, p) }) {
+			log.Printf("config: ignoring unknown variable %s", name)
+		}
+	}
+
+	c.ListenAddr = cmp.Or(c.env["LISTEN_ADDR"], "127.0.0.1:8080")
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		bad("LISTEN_ADDR", "want host:port")
+	}
+	c.TLSCert = c.env["PODFATHER_TLS_CERT"]
+	c.TLSKey = c.env["PODFATHER_TLS_KEY"]
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		errs = append(errs, errors.New("PODFATHER_TLS_CERT and PODFATHER_TLS_KEY must be set together"))
+	}
+	c.Socket = c.env["PODMAN_SOCKET"]
+	switch v := c.env["API_BACKEND"]; v {
+	case "", "auto":
+	case backendPodman, backendDocker:
+		c.Backend = v
+	default:
+		bad("API_BACKEND", "want auto, podman or docker")
+	}
+	if v := c.env["BASE_PATH"]; v != "" {
+		c.BasePath = strings.TrimRight(v, "/")
+		if !strings.HasPrefix(v, "/") || strings.ContainsAny(v, "?#") {
+			bad("BASE_PATH", "want a path like /podfather")
+		}
+	}
+	if v := c.env["TRUSTED_PROXIES"]; v != "" {
+		proxies, err := parseTrustedProxies(v)
+		if err != nil {
+			bad("TRUSTED_PROXIES", "%v", err)
+		}
+		c.TrustedProxies = proxies
+	}
+	boolVar := func(name string) bool {
+		switch c.env[name] {
+		case "", "false":
+			return false
+		case "true":
+			return true
+		}
+		bad(name, "want true or false")
+		return false
+	}
+	c.EnableAutoUpdate = boolVar("ENABLE_AUTOUPDATE_BUTTON")
+	c.EnableActions = boolVar("ENABLE_ACTIONS")
+	c.EnableDebugPage = boolVar("ENABLE_DEBUG_PAGE")
+	if c.EnableFileDownload = boolVar("ENABLE_FILE_DOWNLOAD"); c.EnableFileDownload && !c.EnableActions {
+		bad("ENABLE_FILE_DOWNLOAD", "has no effect without ENABLE_ACTIONS")
+	}
+	if c.EnableFileUpload = boolVar("ENABLE_FILE_UPLOAD"); c.EnableFileUpload && !c.EnableActions {
+		bad("ENABLE_FILE_UPLOAD", "has no effect without ENABLE_ACTIONS")
+	}
+	c.EnableLiveMode = boolVar("ENABLE_LIVE_MODE")
+	if c.QuadletDir = c.env["QUADLET_DIR"]; c.QuadletDir != "" {
+		if !filepath.IsAbs(c.QuadletDir) {
+			bad("QUADLET_DIR", "want an absolute path")
+		}
+		if !c.EnableActions {
+			bad("QUADLET_DIR", "has no effect without ENABLE_ACTIONS")
+		}
+	}
+	c.ActionRateLimit = 30
+	if v := c.env["ACTION_RATE_LIMIT"]; v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 {
+			bad("ACTION_RATE_LIMIT", "want requests per minute, or 0 for no limit")
+		}
+		c.ActionRateLimit = n
+	}
+	c.ActionRateBurst = 10
+	if v := c.env["ACTION_RATE_BURST"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			bad("ACTION_RATE_BURST", "want a positive integer")
+		}
+		c.ActionRateBurst = n
+	}
+	c.RequestTimeout = apiTimeout
+	if v := c.env["REQUEST_TIMEOUT"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			bad("REQUEST_TIMEOUT", "want a duration like 30s, or 0 for none")
+		}
+		c.RequestTimeout = d
+	}
+	if v := c.env["ROUTE_TIMEOUTS"]; v != "" {
+		rts, err := parseRouteTimeouts(v)
+		if err != nil {
+			bad("ROUTE_TIMEOUTS", "%v", err)
+		}
+		c.RouteTimeouts = rts
+	}
+	c.SlowAPIThreshold = time.Second
+	if v := c.env["SLOW_API_THRESHOLD"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			bad("SLOW_API_THRESHOLD", "want a duration like 1s, or 0 to disable")
+		}
+		c.SlowAPIThreshold = d
+	}
+	if v := c.env["API_CACHE_TTL"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			bad("API_CACHE_TTL", "want a duration like 2s, or 0 to disable")
+		}
+		c.APICacheTTL = d
+	}
+	if v := c.env["POLL_INTERVAL"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 || (d > 0 && d < time.Second) {
+			bad("POLL_INTERVAL", "want a duration of at least 1s, or 0 to disable")
+		}
+		c.PollInterval = d
+	}
+	c.WebhookToken = c.env["WEBHOOK_TOKEN"]
+	c.DataDir = cmp.Or(c.env["DATA_DIR"], defaultDataDir())
+
+	c.GitURL = c.env["CONFIG_GIT_URL"]
+	c.GitBranch = c.env["CONFIG_GIT_BRANCH"]
+	c.GitFile = cmp.Or(c.env["CONFIG_GIT_FILE"], "podfather.env")
+	if !filepath.IsLocal(c.GitFile) {
+		bad("CONFIG_GIT_FILE", "must be a relative path inside the repository")
+	}
+	c.GitInterval = 5 * time.Minute
+	if v := c.env["CONFIG_GIT_INTERVAL"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			bad("CONFIG_GIT_INTERVAL", "want a duration like 5m, or 0")
+		}
+		c.GitInterval = d
+	}
+	if c.GitURL == "" {
+		for _, name := range []string{"CONFIG_GIT_BRANCH", "CONFIG_GIT_FILE", "CONFIG_GIT_INTERVAL"} {
+			if _, ok := c.env[name]; ok {
+				bad(name, "has no effect without CONFIG_GIT_URL")
+			}
+		}
+	}
+
+	if v := c.env["UPDATE_CHECK_INTERVAL"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute {
+			bad("UPDATE_CHECK_INTERVAL", "want a duration of at least 1m")
+		}
+		c.UpdateCheckInterval = d
+	}
+
+	if v := c.env["APP_PROBE_INTERVAL"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 10*time.Second {
+			bad("APP_PROBE_INTERVAL", "want a duration of at least 10s")
+		}
+		c.AppProbeInterval = d
+	}
+
+	cronVar := func(name string) cronSpec {
+		v := c.env[name]
+		if v == "" {
+			return cronSpec{}
+		}
+		spec, err := parseCron(v)
+		if err != nil {
+			bad(name, "%v", err)
+		} else if spec.next(time.Now()).IsZero() {
+			bad(name, "never runs")
+		}
+		return spec
+	}
+	if c.AutoUpdateSchedule = cronVar("AUTO_UPDATE_SCHEDULE"); c.AutoUpdateSchedule.text != "" && !c.EnableAutoUpdate {
+		bad("AUTO_UPDATE_SCHEDULE", "has no effect without ENABLE_AUTOUPDATE_BUTTON")
+	}
+	if c.PruneSchedule = cronVar("PRUNE_SCHEDULE"); c.PruneSchedule.text != "" && !c.EnableActions {
+		bad("PRUNE_SCHEDULE", "has no effect without ENABLE_ACTIONS")
+	}
+	c.ScheduleTimezone = time.Local
+	if v := c.env["SCHEDULE_TIMEZONE"]; v != "" {
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			bad("SCHEDULE_TIMEZONE", "want a time zone like Europe/Zurich")
+		} else {
+			c.ScheduleTimezone = loc
+		}
+	}
+	if v := c.env["SCHEDULE_SKIP_DATES"]; v != "" {
+		dates, err := parseSkipDates(v)
+		if err != nil {
+			bad("SCHEDULE_SKIP_DATES", "%v", err)
+		}
+		c.ScheduleSkipDates = dates
+	}
+	if v := c.env["SCHEDULE_JITTER"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			bad("SCHEDULE_JITTER", "want a duration like 10m, or 0")
+		}
+		c.ScheduleJitter = d
+	}
+
+	if v := c.env["ENERGY_WATTS_PER_CORE"]; v != "" {
+		watts, err := strconv.ParseFloat(v, 64)
+		if err != nil || watts <= 0 {
+			bad("ENERGY_WATTS_PER_CORE", "want a positive number")
+		}
+		c.EnergyWattsPerCore = watts
+	}
+	c.EnergyCO2PerKWh = 400
+	if v := c.env["ENERGY_CO2_PER_KWH"]; v != "" {
+		co2, err := strconv.ParseFloat(v, 64)
+		if err != nil || co2 < 0 {
+			bad("ENERGY_CO2_PER_KWH", "want a number of grams")
+		}
+		c.EnergyCO2PerKWh = co2
+	}
+
+	c.Theme = cmp.Or(c.env["THEME"], defaultTheme)
+	if !validTheme(c.Theme) {
+		bad("THEME", "want one of %s", themeNames())
+	}
+	if c.CustomCSS = c.env["PODFATHER_CUSTOM_CSS"]; c.CustomCSS != "" {
+		if st, err := os.Stat(c.CustomCSS); !filepath.IsAbs(c.CustomCSS) || err != nil || st.IsDir() {
+			bad("PODFATHER_CUSTOM_CSS", "want the absolute path of a CSS file")
+		}
+	}
+
+	if v := c.env["CONTAINER_COLUMNS"]; v != "" {
+		cols, err := parseColumns(v)
+		if err != nil {
+			bad("CONTAINER_COLUMNS", "%v", err)
+		}
+		c.ContainerColumns = cols
+	}
+
+	c.ImagePolicy = parseImagePolicy(c.env["IMAGE_POLICY_LABELS"], c.env["IMAGE_POLICY_REPOS"])
+	if !c.ImagePolicy.enabled() && len(c.ImagePolicy.Repos) > 0 {
+		bad("IMAGE_POLICY_REPOS", "has no effect without IMAGE_POLICY_LABELS")
+	}
+
+	httpURL := func(name string) string {
+		v := c.env[name]
+		if v == "" {
+			return ""
+		}
+		if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			bad(name, "want an http(s) URL")
+		}
+		return v
+	}
+	c.NtfyURL = httpURL("NTFY_URL")
+	c.NtfyToken = c.env["NTFY_TOKEN"]
+	if c.NtfyToken != "" && c.NtfyURL == "" {
+		errs = append(errs, errors.New("NTFY_TOKEN has no effect without NTFY_URL"))
+	}
+	c.GotifyURL = httpURL("GOTIFY_URL")
+	c.GotifyToken = c.env["GOTIFY_TOKEN"]
+	if (c.GotifyURL == "") != (c.GotifyToken == "") {
+		errs = append(errs, errors.New("GOTIFY_URL and GOTIFY_TOKEN must be set together"))
+	}
+
+	c.OIDCIssuer = httpURL("OIDC_ISSUER")
+	c.OIDCClientID = c.env["OIDC_CLIENT_ID"]
+	c.OIDCClientSecret = c.env["OIDC_CLIENT_SECRET"]
+	c.OIDCRedirectURL = httpURL("OIDC_REDIRECT_URL")
+	if c.OIDCIssuer != "" || c.OIDCClientID != "" || c.OIDCClientSecret != "" || c.OIDCRedirectURL != "" {
+		if c.OIDCIssuer == "" || c.OIDCClientID == "" || c.OIDCClientSecret == "" || c.OIDCRedirectURL == "" {
+			errs = append(errs, errors.New("OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET and OIDC_REDIRECT_URL must be set together"))
+		}
+		if u, err := url.Parse(c.OIDCRedirectURL); c.OIDCRedirectURL != "" && (err != nil || !strings.HasSuffix(u.Path, c.BasePath+"/auth/callback")) {
+			bad("OIDC_REDIRECT_URL", "want the external URL of %s/auth/callback", c.BasePath)
+		}
+	}
+
+	if err := validateExternalApps(environ); err != nil {
+		errs = append(errs, err)
+	}
+	c.ExternalApps = parseExternalApps(environ)
+	if err := validateCategories(environ); err != nil {
+		errs = append(errs, err)
+	}
+	c.Categories = parseCategories(environ)
+
+	return c, errors.Join(errs...)
+}
+
+// defaultDataDir returns $XDG_STATE_HOME/podfather, or
+// ~/.local/state/podfather. It is created lazily by the features that need
+// it.
+func defaultDataDir() string {
+	if d := os.Getenv("XDG_STATE_HOME"); d != "" {
+		return filepath.Join(d, "podfather")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "state", "podfather")
+}
+
+// suggestConfigVar returns the known variable that name is likely a typo
+// of, or "".
+func suggestConfigVar(name string) string {
+	if len(name) < 8 {
+		return ""
+	}
+	for _, v := range configVars {
+		if editDistance(name, v) <= 2 {
+			return v
+		}
+	}
+	return ""
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// externalAppFields are the known PODFATHER_APP_<KEY>_<FIELD> suffixes.
+var externalAppFields = []string{"_DESCRIPTION", "_SORT_INDEX", "_CATEGORY", "_NAME", "_ICON", "_URL"}
+
+// validateExternalApps checks PODFATHER_APP_* variables for unknown fields,
+// apps without a name and malformed values. parseExternalApps skips such
+// entries silently.
+func validateExternalApps(environ []string) error {
+	const prefix = "PODFATHER_APP_"
+	var errs []error
+	named := map[string]bool{}
+	keys := map[string]string{} // key -> first variable, for messages
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		i := slices.IndexFunc(externalAppFields, func(f string) bool { return strings.HasSuffix(rest, f) })
+		if i < 0 {
+			errs = append(errs, fmt.Errorf("unknown variable %s (want %s<KEY> followed by one of %s)", name, prefix, strings.Join(externalAppFields, ", ")))
+			continue
+		}
+		field := externalAppFields[i]
+		key := strings.TrimSuffix(rest, field)
+		if key == "" {
+			errs = append(errs, fmt.Errorf("%s: missing app key", name))
+			continue
+		}
+		if _, ok := keys[key]; !ok {
+			keys[key] = name
+		}
+		switch field {
+		case "_NAME":
+			if value == "" {
+				errs = append(errs, fmt.Errorf("%s: empty name", name))
+			}
+			named[key] = true
+		case "_SORT_INDEX":
+			if _, err := strconv.Atoi(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s=%q: want an integer", name, value))
+			}
+		case "_URL":
+			if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("%s=%q: want an http(s) URL", name, value))
+			}
+		}
+	}
+	for key, first := range keys {
+		if !named[key] {
+			errs = append(errs, fmt.Errorf("%s: app %s has no %s%s_NAME", first, key, prefix, key))
+		}
+	}
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errors.Join(errs...)
+}
+
+// ConfigSetting is a row on the /config page.
+type ConfigSetting struct {
+	Name   string
+	Value  string
+	Source string // "env", "default" or "detected"
+}
+
+// settings returns the effective configuration for display, with secrets
+// redacted. backend and socket are the values in use after detection.
+func (c config) settings(backend, socket string) []ConfigSetting {
+	effective := map[string]string{
+		"LISTEN_ADDR":              c.ListenAddr,
+		"PODFATHER_TLS_CERT":       c.TLSCert,
+		"PODFATHER_TLS_KEY":        c.TLSKey,
+		"PODMAN_SOCKET":            socket,
+		"API_BACKEND":              backend,
+		"BASE_PATH":                c.BasePath,
+		"TRUSTED_PROXIES":          c.env["TRUSTED_PROXIES"],
+		"ENABLE_AUTOUPDATE_BUTTON": strconv.FormatBool(c.EnableAutoUpdate),
+		"ENABLE_ACTIONS":           strconv.FormatBool(c.EnableActions),
+		"ENABLE_DEBUG_PAGE":        strconv.FormatBool(c.EnableDebugPage),
+		"ENABLE_FILE_DOWNLOAD":     strconv.FormatBool(c.EnableFileDownload),
+		"ENABLE_FILE_UPLOAD":       strconv.FormatBool(c.EnableFileUpload),
+		"ENABLE_LIVE_MODE":         strconv.FormatBool(c.EnableLiveMode),
+		"QUADLET_DIR":              c.QuadletDir,
+		"ACTION_RATE_LIMIT":        "unlimited",
+		"ACTION_RATE_BURST":        strconv.Itoa(c.ActionRateBurst),
+		"REQUEST_TIMEOUT":          "none",
+		"ROUTE_TIMEOUTS":           c.env["ROUTE_TIMEOUTS"],
+		"SLOW_API_THRESHOLD":       "disabled",
+		"API_CACHE_TTL":            "disabled",
+		"POLL_INTERVAL":            "disabled",
+		"DATA_DIR":                 c.DataDir,
+		"CONFIG_GIT_URL":           redactURL(c.GitURL),
+		"CONFIG_GIT_BRANCH":        c.GitBranch,
+		"CONFIG_GIT_FILE":          c.GitFile,
+		"CONFIG_GIT_INTERVAL":      c.GitInterval.String(),
+		"UPDATE_CHECK_INTERVAL":    "disabled",
+		"APP_PROBE_INTERVAL":       "disabled",
+		"AUTO_UPDATE_SCHEDULE":     cmp.Or(c.AutoUpdateSchedule.text, "disabled"),
+		"PRUNE_SCHEDULE":           cmp.Or(c.PruneSchedule.text, "disabled"),
+		"SCHEDULE_TIMEZONE":        c.ScheduleTimezone.String(),
+		"SCHEDULE_SKIP_DATES":      c.env["SCHEDULE_SKIP_DATES"],
+		"SCHEDULE_JITTER":          "none",
+		"ENERGY_WATTS_PER_CORE":    "disabled",
+		"ENERGY_CO2_PER_KWH":       strconv.FormatFloat(c.EnergyCO2PerKWh, 'g', -1, 64),
+		"THEME":                    c.Theme,
+		"PODFATHER_CUSTOM_CSS":     c.CustomCSS,
+		"CONTAINER_COLUMNS":        c.env["CONTAINER_COLUMNS"],
+		"IMAGE_POLICY_LABELS":      strings.Join(c.ImagePolicy.Labels, ","),
+		"IMAGE_POLICY_REPOS":       strings.Join(c.ImagePolicy.Repos, ","),
+		"NTFY_URL":                 redactURL(c.NtfyURL),
+		"GOTIFY_URL":               redactURL(c.GotifyURL),
+		"OIDC_ISSUER":              c.OIDCIssuer,
+		"OIDC_CLIENT_ID":           c.OIDCClientID,
+		"OIDC_REDIRECT_URL":        c.OIDCRedirectURL,
+	}
+	if c.UpdateCheckInterval > 0 {
+		effective["UPDATE_CHECK_INTERVAL"] = c.UpdateCheckInterval.String()
+	}
+	if c.AppProbeInterval > 0 {
+		effective["APP_PROBE_INTERVAL"] = c.AppProbeInterval.String()
+	}
+	if c.ScheduleJitter > 0 {
+		effective["SCHEDULE_JITTER"] = c.ScheduleJitter.String()
+	}
+	if c.RequestTimeout > 0 {
+		effective["REQUEST_TIMEOUT"] = c.RequestTimeout.String()
+	}
+	if c.SlowAPIThreshold > 0 {
+		effective["SLOW_API_THRESHOLD"] = c.SlowAPIThreshold.String()
+	}
+	if c.APICacheTTL > 0 {
+		effective["API_CACHE_TTL"] = c.APICacheTTL.String()
+	}
+	if c.PollInterval > 0 {
+		effective["POLL_INTERVAL"] = c.PollInterval.String()
+	}
+	if c.ActionRateLimit > 0 {
+		effective["ACTION_RATE_LIMIT"] = strconv.FormatFloat(c.ActionRateLimit, 'g', -1, 64) + "/min"
+	}
+	if c.EnergyWattsPerCore > 0 {
+		effective["ENERGY_WATTS_PER_CORE"] = strconv.FormatFloat(c.EnergyWattsPerCore, 'g', -1, 64)
+	}
+	for name, secret := range map[string]string{
+		"WEBHOOK_TOKEN":      c.WebhookToken,
+		"OIDC_CLIENT_SECRET": c.OIDCClientSecret,
+		"NTFY_TOKEN":         c.NtfyToken,
+		"GOTIFY_TOKEN":       c.GotifyToken,
+	} {
+		if secret != "" {
+			effective[name] = "(set, redacted)"
+		}
+	}
+	out := make([]ConfigSetting, 0, len(configVars))
+	for _, name := range configVars {
+		st := ConfigSetting{Name: name, Value: effective[name], Source: "default"}
+		if _, ok := c.env[name]; ok {
+			st.Source = "env"
+		} else if name == "API_BACKEND" || name == "PODMAN_SOCKET" {
+			st.Source = "detected"
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	categories := slices.SortedFunc(maps.Values(s.currentCategories()), func(a, b Category) int {
+		return cmp.Or(cmp.Compare(a.SortIndex, b.SortIndex), strings.Compare(a.Name, b.Name))
+	})
+	s.render(w, r, "config.html", map[string]any{
+		"Title":      "Configuration",
+		"Settings":   s.configSettings,
+		"Apps":       s.currentExternalApps(),
+		"Categories": categories,
+		"GitSync":    s.gitConfig != nil,
+	})
+}
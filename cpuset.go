@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxCPU bounds parsed CPU numbers so a bogus cpuset cannot allocate a huge
+// map.
+const maxCPU = 4096
+
+// parseCPUList parses a Linux CPU list such as "0-3,8,10-11" (the format of
+// --cpuset-cpus and --cpuset-mems) into sorted, de-duplicated numbers.
+func parseCPUList(s string) ([]int, error) {
+	seen := make(map[int]bool)
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil || first < 0 || first >= maxCPU {
+			return nil, fmt.Errorf("invalid CPU list %q", s)
+		}
+		last := first
+		if isRange {
+			last, err = strconv.Atoi(hi)
+			if err != nil || last < first || last >= maxCPU {
+				return nil, fmt.Errorf("invalid CPU list %q", s)
+			}
+		}
+		for n := first; n <= last; n++ {
+			seen[n] = true
+		}
+	}
+	out := make([]int, 0, len(seen))
+	for n := range seen {
+		out = append(out, n)
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+// formatCPUList is the inverse of parseCPUList for sorted input.
+func formatCPUList(cpus []int) string {
+	var b strings.Builder
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		if j > i {
+			fmt.Fprintf(&b, "%d-%d", cpus[i], cpus[j])
+		} else {
+			fmt.Fprintf(&b, "%d", cpus[i])
+		}
+		i = j + 1
+	}
+	return b.String()
+}
+
+// pinnedCPUs returns the CPUs a container is pinned to, or nil if it may run
+// on any CPU.
+func pinnedCPUs(c ContainerInspect) []int {
+	if c.HostConfig == nil || c.HostConfig.CpusetCpus == "" {
+		return nil
+	}
+	cpus, err := parseCPUList(c.HostConfig.CpusetCpus)
+	if err != nil {
+		return nil
+	}
+	return cpus
+}
+
+// cpuOverlaps returns the running containers other than c that are pinned to
+// at least one of the CPUs c is pinned to.
+func cpuOverlaps(c ContainerInspect, all []ContainerInspect) []CPUOverlap {
+	mine := pinnedCPUs(c)
+	if len(mine) == 0 {
+		return nil
+	}
+	set := make(map[int]bool, len(mine))
+	for _, n := range mine {
+		set[n] = true
+	}
+	var out []CPUOverlap
+	for _, o := range all {
+		if o.ID == c.ID || !o.State.Running {
+			continue
+		}
+		var shared []int
+		for _, n := range pinnedCPUs(o) {
+			if set[n] {
+				shared = append(shared, n)
+			}
+		}
+		if len(shared) > 0 {
+			out = append(out, CPUOverlap{Container: o, CPUs: formatCPUList(shared)})
+		}
+	}
+	return out
+}
+
+// buildCPUMap assigns pinned containers to the host CPUs 0..numCPU-1 (grown
+// to fit any pinned CPU beyond that) and returns the containers without a
+// cpuset separately.
+func buildCPUMap(containers []ContainerInspect, numCPU int) (cores []CPUCore, unpinned []ContainerInspect) {
+	byCPU := make(map[int][]ContainerInspect)
+	for _, c := range containers {
+		cpus := pinnedCPUs(c)
+		if len(cpus) == 0 {
+			unpinned = append(unpinned, c)
+			continue
+		}
+		for _, n := range cpus {
+			byCPU[n] = append(byCPU[n], c)
+			numCPU = max(numCPU, n+1)
+		}
+	}
+	cores = make([]CPUCore, numCPU)
+	for n := range cores {
+		cores[n] = CPUCore{ID: n, Containers: byCPU[n]}
+	}
+	return cores, unpinned
+}
+
+// hostCPUs returns the number of CPUs reported by the API.
+func (s *Server) hostCPUs(ctx context.Context) (int, error) {
+	// libpod reports host.cpus, the Docker compat API NCPU.
+	var info struct {
+		Host struct {
+			CPUs int `json:"cpus"`
+		} `json:"host"`
+		NCPU int `json:"NCPU"`
+	}
+	if err := s.podmanGet(ctx, "/info", &info); err != nil {
+		return 0, err
+	}
+	return max(info.Host.CPUs, info.NCPU), nil
+}
+
+func (s *Server) handleCPUs(w http.ResponseWriter, r *http.Request) {
+	containers, err := s.inspectAllContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	// Without the host CPU count, only pinned CPUs are shown.
+	numCPU, err := s.hostCPUs(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+	}
+	cores, unpinned := buildCPUMap(containers, numCPU)
+	contended := 0
+	for _, c := range cores {
+		if c.Contended() {
+			contended++
+		}
+	}
+	thermal, err := readThermalZones(s.thermalDir)
+	if err != nil {
+		log.Printf("[%s] thermal: %v", reqID(r.Context()), err)
+	}
+	s.render(w, r, "cpus.html", map[string]any{
+		"Title":     "CPU Allocation",
+		"Thermal":   thermal,
+		"Cores":     cores,
+		"Unpinned":  unpinned,
+		"Contended": contended,
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{"", []int{}, false},
+		{"3", []int{3}, false},
+		{"0-3", []int{0, 1, 2, 3}, false},
+		{"8,0-1, 1-2", []int{0, 1, 2, 8}, false},
+		{"3-1", nil, true},
+		{"a", nil, true},
+		{"-1", nil, true},
+		{"0-100000", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseCPUList(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCPUList(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCPUList(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCPUList(t *testing.T) {
+	tests := []struct {
+		in   []int
+		want string
+	}{
+		{nil, ""},
+		{[]int{2}, "2"},
+		{[]int{0, 1, 2, 3, 8, 10, 11}, "0-3,8,10-11"},
+	}
+	for _, tt := range tests {
+		if got := formatCPUList(tt.in); got != tt.want {
+			t.Errorf("formatCPUList(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func pinnedContainer(id, cpus string, running bool) ContainerInspect {
+	return ContainerInspect{
+		ID:         id,
+		Name:       id,
+		State:      ContainerState{Running: running},
+		HostConfig: &HostConfig{CpusetCpus: cpus},
+	}
+}
+
+func TestCPUOverlaps(t *testing.T) {
+	transcode := pinnedContainer("transcode", "0-3", true)
+	all := []ContainerInspect{
+		transcode,
+		pinnedContainer("game", "2-5", true),
+		pinnedContainer("stopped", "0", false),
+		pinnedContainer("other", "6-7", true),
+		pinnedContainer("any", "", true),
+	}
+	got := cpuOverlaps(transcode, all)
+	if len(got) != 1 || got[0].Container.ID != "game" || got[0].CPUs != "2-3" {
+		t.Errorf("cpuOverlaps() = %+v, want game on 2-3", got)
+	}
+	if got := cpuOverlaps(all[4], all); got != nil {
+		t.Errorf("cpuOverlaps(unpinned) = %+v, want nil", got)
+	}
+}
+
+func TestBuildCPUMap(t *testing.T) {
+	all := []ContainerInspect{
+		pinnedContainer("a", "0-1", true),
+		pinnedContainer("b", "1", true),
+		pinnedContainer("c", "5", false),
+		pinnedContainer("d", "", true),
+	}
+	cores, unpinned := buildCPUMap(all, 4)
+	if len(cores) != 6 {
+		t.Fatalf("len(cores) = %d, want 6 (grown to fit CPU 5)", len(cores))
+	}
+	if len(unpinned) != 1 || unpinned[0].ID != "d" {
+		t.Errorf("unpinned = %+v, want [d]", unpinned)
+	}
+	for _, c := range cores {
+		if want := c.ID == 1; c.Contended() != want {
+			t.Errorf("core %d contended = %v, want %v", c.ID, c.Contended(), want)
+		}
+	}
+	if len(cores[5].Containers) != 1 {
+		t.Errorf("core 5 containers = %d, want 1", len(cores[5].Containers))
+	}
+}
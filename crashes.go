@@ -0,0 +1,231 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The crash tracker counts the crashes (exits with a code other than 0 or
+// 143) and OOM kills of each container from the events stream, by name so
+// the counts survive recreating the container. They are kept in
+// $DATA_DIR/crashes.json. Container pages show the totals, app cards warn
+// about containers that crashed within crashWindow.
+//
+// Exits after a stop or kill (podman stop and kill, by podfather's actions
+// or schedules too) are no crashes, even with 137 (SIGKILL) after the stop
+// timeout. The stopLog remembers these events for the crash tracker,
+// notifications and the watchdog. Podman sends the stop event before the
+// exit, Docker after it; kill events come first.
+
+const (
+	crashWindow = time.Hour
+	// crashRetention drops containers that have not crashed for this long.
+	crashRetention = 30 * 24 * time.Hour
+	// stopExitWindow is how long before an exit a stop or kill event is taken
+	// to have caused it, at least the stop timeout.
+	stopExitWindow = time.Minute
+)
+
+// crashRecord is what the tracker keeps per container name.
+type crashRecord struct {
+	Crashes  int         `json:"crashes"`
+	OOMKills int         `json:"oom_kills"`
+	Last     time.Time   `json:"last"`
+	LastCode string      `json:"last_code"`
+	LastOOM  time.Time   `json:"last_oom"`
+	Recent   []time.Time `json:"recent"` // crashes within crashWindow
+}
+
+// CrashStats are the crashes of a container.
+type CrashStats struct {
+	Crashes  int
+	OOMKills int
+	Last     time.Time
+	LastCode string
+	LastOOM  time.Time
+	InWindow int // crashes within crashWindow
+}
+
+// crashExitCode returns the exit code of a die event if it was a crash.
+// Exits with code 0 or 143 (SIGTERM) are regular stops.
+func crashExitCode(ev containerEvent) (string, bool) {
+	attrs := ev.Actor.Attributes
+	code := cmp.Or(attrs["containerExitCode"], attrs["exitCode"])
+	return code, code != "" && code != "0" && code != "143"
+}
+
+// stopLog is the last stop or kill event of each container.
+type stopLog struct {
+	mu    sync.Mutex
+	stops map[string]time.Time // by container ID
+}
+
+func newStopLog() *stopLog {
+	return &stopLog{stops: map[string]time.Time{}}
+}
+
+// record remembers ev if it is a stop or kill. It is safe to call on a nil
+// log.
+func (l *stopLog) record(ev containerEvent, now time.Time) {
+	if l == nil || ev.Action != "stop" && ev.Action != "kill" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for id, at := range l.stops {
+		if now.Sub(at) > stopExitWindow {
+			delete(l.stops, id)
+		}
+	}
+	l.stops[ev.Actor.ID] = now
+}
+
+// stopped reports whether the container id was stopped or killed since
+// stopExitWindow before t. It is safe to call on a nil log.
+func (l *stopLog) stopped(id string, t time.Time) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	at, ok := l.stops[id]
+	return ok && at.After(t.Add(-stopExitWindow))
+}
+
+// crashed returns the exit code of a die event at now if it was a crash,
+// see crashExitCode, and did not follow a stop or kill.
+func (l *stopLog) crashed(ev containerEvent, now time.Time) (string, bool) {
+	code, crashed := crashExitCode(ev)
+	return code, crashed && !l.stopped(ev.Actor.ID, now)
+}
+
+type crashTracker struct {
+	path  string
+	stops *stopLog
+
+	mu         sync.Mutex
+	containers map[string]*crashRecord // by name
+}
+
+func newCrashTracker(dataDir string, stops *stopLog) *crashTracker {
+	t := &crashTracker{
+		path:       filepath.Join(dataDir, "crashes.json"),
+		stops:      stops,
+		containers: map[string]*crashRecord{},
+	}
+	data, err := os.ReadFile(t.path)
+	if err == nil {
+		err = json.Unmarshal(data, &t.containers)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("crashes: loading %s: %v", t.path, err)
+	}
+	return t
+}
+
+// record counts ev if it is a crash or an OOM kill. An OOM kill is followed
+// by a die event, which counts as the crash. It is safe to call on a nil
+// tracker.
+func (t *crashTracker) record(ev containerEvent, now time.Time) {
+	if t == nil {
+		return
+	}
+	name := ev.Actor.Attributes["name"]
+	if name == "" {
+		return
+	}
+	at := now
+	if ev.TimeNano != 0 {
+		at = time.Unix(0, ev.TimeNano)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rec := t.containers[name]
+	if rec == nil {
+		rec = &crashRecord{}
+	}
+	switch ev.Action {
+	case "die", "died":
+		code, ok := t.stops.crashed(ev, now)
+		if !ok {
+			return
+		}
+		rec.Crashes++
+		rec.Last, rec.LastCode = at, code
+		rec.Recent = append(rec.Recent, at)
+	case "oom":
+		rec.OOMKills++
+		rec.LastOOM = at
+	default:
+		return
+	}
+	t.containers[name] = rec
+	for n, r := range t.containers {
+		r.Recent = recentCrashes(r.Recent, now)
+		if now.Sub(r.Last) > crashRetention && now.Sub(r.LastOOM) > crashRetention {
+			delete(t.containers, n)
+		}
+	}
+	if err := t.save(); err != nil {
+		log.Printf("crashes: %v", err)
+	}
+}
+
+// recentCrashes returns the times within crashWindow before now.
+func recentCrashes(times []time.Time, now time.Time) []time.Time {
+	out := times[:0:0]
+	for _, at := range times {
+		if now.Sub(at) < crashWindow {
+			out = append(out, at)
+		}
+	}
+	return out
+}
+
+func (t *crashTracker) save() error {
+	data, err := json.Marshal(t.containers)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(t.path, data, 0o600)
+}
+
+// stats returns the crashes of the container name, or false if it never
+// crashed. It is safe to call on a nil tracker.
+func (t *crashTracker) stats(name string, now time.Time) (CrashStats, bool) {
+	if t == nil {
+		return CrashStats{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rec := t.containers[name]
+	if rec == nil {
+		return CrashStats{}, false
+	}
+	return CrashStats{
+		Crashes:  rec.Crashes,
+		OOMKills: rec.OOMKills,
+		Last:     rec.Last,
+		LastCode: rec.LastCode,
+		LastOOM:  rec.LastOOM,
+		InWindow: len(recentCrashes(rec.Recent, now)),
+	}, true
+}
+
+// recentCrashStats returns the crashes of the containers in list that
+// crashed within crashWindow, by container ID.
+func (t *crashTracker) recentCrashStats(list []Container, now time.Time) map[string]CrashStats {
+	out := map[string]CrashStats{}
+	for _, c := range list {
+		if st, ok := t.stats(firstName(c.Names), now); ok && st.InWindow > 0 {
+			out[c.ID] = st
+		}
+	}
+	return out
+}
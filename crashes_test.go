@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrashTracker(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	ct := newCrashTracker(dir, nil)
+	now := time.Now()
+	for _, ev := range []containerEvent{
+		testEvent("died", map[string]string{"name": "jellyfin", "containerExitCode": "1"}),
+		testEvent("died", map[string]string{"name": "jellyfin", "containerExitCode": "0"}),
+		testEvent("die", map[string]string{"name": "jellyfin", "exitCode": "143"}),
+		testEvent("oom", map[string]string{"name": "jellyfin"}),
+		testEvent("died", map[string]string{"name": "jellyfin", "containerExitCode": "137"}),
+		testEvent("health_status", map[string]string{"name": "jellyfin", "health_status": "unhealthy"}),
+	} {
+		ct.record(ev, now)
+	}
+	st, ok := ct.stats("jellyfin", now)
+	if !ok || st.Crashes != 2 || st.OOMKills != 1 || st.LastCode != "137" || st.InWindow != 2 {
+		t.Errorf("stats = %+v, %v; want 2 crashes, 1 OOM kill, last code 137", st, ok)
+	}
+	if _, ok := ct.stats("db", now); ok {
+		t.Error("stats for a container that never crashed")
+	}
+
+	// The counts survive a restart; crashes leave the window after an hour.
+	later := now.Add(crashWindow + time.Minute)
+	st, ok = newCrashTracker(dir, nil).stats("jellyfin", later)
+	if !ok || st.Crashes != 2 || st.InWindow != 0 {
+		t.Errorf("reloaded stats = %+v, %v", st, ok)
+	}
+
+	// Exits after a stop or kill are no crashes, but a crash a while later is.
+	stops := newStopLog()
+	ct = newCrashTracker(t.TempDir(), stops)
+	for _, ev := range []struct {
+		action, code string
+		at           time.Time
+	}{
+		{"stop", "", now},
+		{"died", "137", now.Add(10 * time.Second)},
+		{"kill", "", now.Add(time.Minute)},
+		{"died", "137", now.Add(time.Minute)},
+		{"died", "1", now.Add(time.Hour)},
+	} {
+		e := testEvent(ev.action, map[string]string{"name": "jellyfin", "containerExitCode": ev.code})
+		stops.record(e, ev.at)
+		ct.record(e, ev.at)
+	}
+	if st, _ := ct.stats("jellyfin", now.Add(time.Hour)); st.Crashes != 1 || st.LastCode != "1" {
+		t.Errorf("stats after stop and kill = %+v, want only the later crash", st)
+	}
+
+	var nilTracker *crashTracker
+	nilTracker.record(testEvent("oom", map[string]string{"name": "jellyfin"}), now)
+	if _, ok := nilTracker.stats("jellyfin", now); ok {
+		t.Error("nil tracker has stats")
+	}
+}
+
+func TestCrashBadge(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.crashes = newCrashTracker(t.TempDir(), nil)
+	s.crashes.record(testEvent("died", map[string]string{"name": "jellyfin", "containerExitCode": "139"}), time.Now())
+
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+	for path, want := range map[string]string{
+		"/apps":               "crashed 1&times; in the last hour",
+		"/container/jellyfin": "exit code 139",
+	} {
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("%s: %d, missing %q", path, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scheduled jobs (AUTO_UPDATE_SCHEDULE, PRUNE_SCHEDULE) take cron
+// expressions, evaluated in SCHEDULE_TIMEZONE like the stop windows of
+// containers. Runs falling on a SCHEDULE_SKIP_DATES date are skipped, and
+// each run, like each registry update check, is delayed by a random
+// SCHEDULE_JITTER so that hosts sharing a config do not all hit the
+// registry at once. /schedules lists what runs next.
+
+// cronMacros are the shorthands for common schedules.
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// cronSpec is a parsed cron expression: minute, hour, day of month, month
+// and day of week, each as a bit set of matching values.
+type cronSpec struct {
+	text                          string
+	minute, hour, dom, month, dow uint64
+	// With a restricted day of month and day of week, either may match,
+	// like in cron.
+	domAny, dowAny bool
+}
+
+// parseCron parses a five-field cron expression or a macro like @daily.
+// Fields take *, values, ranges (1-5), steps (*/15, 0-30/10) and lists of
+// these. Day of week 0 and 7 are Sunday.
+func parseCron(s string) (cronSpec, error) {
+	spec := cronSpec{text: s}
+	if m, ok := cronMacros[s]; ok {
+		s = m
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return spec, fmt.Errorf("want 5 fields (minute hour day month weekday) or one of @hourly, @daily, @weekly, @monthly, got %d", len(fields))
+	}
+	for i, f := range []struct {
+		bits     *uint64
+		name     string
+		min, max int
+	}{
+		{&spec.minute, "minute", 0, 59},
+		{&spec.hour, "hour", 0, 23},
+		{&spec.dom, "day", 1, 31},
+		{&spec.month, "month", 1, 12},
+		{&spec.dow, "weekday", 0, 7},
+	} {
+		bits, err := parseCronField(fields[i], f.min, f.max)
+		if err != nil {
+			return spec, fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.bits = bits
+	}
+	if spec.dow&(1<<7) != 0 {
+		spec.dow |= 1
+	}
+	spec.domAny, spec.dowAny = fields[2] == "*", fields[4] == "*"
+	return spec, nil
+}
+
+func parseCronField(f string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(f, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err1, err2 error
+			from, err1 = strconv.Atoi(a)
+			to = from
+			if isRange {
+				to, err2 = strconv.Atoi(b)
+			} else if hasStep {
+				to = hi
+			}
+			if err1 != nil || err2 != nil || from < lo || to > hi || from > to {
+				return 0, fmt.Errorf("invalid value %q, want %d-%d", part, lo, hi)
+			}
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func (c cronSpec) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// matches reports whether c runs in the minute of t.
+func (c cronSpec) matches(t time.Time) bool {
+	return c.month&(1<<t.Month()) != 0 && c.dayMatches(t) &&
+		c.hour&(1<<t.Hour()) != 0 && c.minute&(1<<t.Minute()) != 0
+}
+
+// next returns the first time after after matching c, in the location of
+// after, or the zero time if there is none within five years (e.g. for
+// February 30). Times skipped by a daylight saving time switch do not run
+// that day.
+func (c cronSpec) next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		y, m, d := t.Date()
+		switch {
+		case c.month&(1<<m) == 0:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// skipDate is a date on which scheduled jobs do not run. A zero year
+// matches every year.
+type skipDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func (d skipDate) String() string {
+	if d.year == 0 {
+		return fmt.Sprintf("%02d-%02d (every year)", d.month, d.day)
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", d.year, d.month, d.day)
+}
+
+// parseSkipDates parses a comma-separated list of dates like 2026-12-24,
+// or 12-25 for every year.
+func parseSkipDates(s string) ([]skipDate, error) {
+	var dates []skipDate
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		if t, err := time.Parse("2006-01-02", part); err == nil {
+			dates = append(dates, skipDate{t.Year(), t.Month(), t.Day()})
+		} else if t, err := time.Parse("01-02", part); err == nil {
+			dates = append(dates, skipDate{0, t.Month(), t.Day()})
+		} else {
+			return nil, fmt.Errorf("invalid date %q, want YYYY-MM-DD or MM-DD", part)
+		}
+	}
+	return dates, nil
+}
+
+// calendar holds the time zone, skipped dates and jitter of all schedules.
+// It is safe to use a nil *calendar, which uses local time without skipped
+// dates or jitter.
+type calendar struct {
+	loc    *time.Location
+	skip   []skipDate
+	jitter time.Duration
+}
+
+func (cal *calendar) location() *time.Location {
+	if cal == nil || cal.loc == nil {
+		return time.Local
+	}
+	return cal.loc
+}
+
+// now returns the current time in the schedule time zone.
+func (cal *calendar) now() time.Time {
+	return time.Now().In(cal.location())
+}
+
+// skipped reports whether t falls on a skipped date.
+func (cal *calendar) skipped(t time.Time) bool {
+	if cal == nil {
+		return false
+	}
+	y, m, d := t.In(cal.location()).Date()
+	for _, sd := range cal.skip {
+		if (sd.year == 0 || sd.year == y) && sd.month == m && sd.day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the next run of spec after after that is not on a skipped
+// date, or the zero time if there is none within five years.
+func (cal *calendar) next(spec cronSpec, after time.Time) time.Time {
+	after = after.In(cal.location())
+	t := spec.next(after)
+	for limit := after.AddDate(5, 0, 0); !t.IsZero() && cal.skipped(t); {
+		y, m, d := t.Date()
+		if t = spec.next(time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()).Add(-time.Minute)); t.After(limit) {
+			return time.Time{}
+		}
+	}
+	return t
+}
+
+// jitterDelay returns a random delay up to the jitter.
+func (cal *calendar) jitterDelay() time.Duration {
+	if cal == nil || cal.jitter <= 0 {
+		return 0
+	}
+	return rand.N(cal.jitter)
+}
+
+// cronJob is a task run on a cron schedule.
+type cronJob struct {
+	name string
+	spec cronSpec
+	run  func(ctx context.Context) error
+
+	mu      sync.Mutex
+	next    time.Time // including jitter
+	lastRun time.Time
+	lastErr error
+}
+
+// CronJobStatus is a scheduled job on /schedules.
+type CronJobStatus struct {
+	Name    string
+	Spec    string
+	Next    time.Time
+	LastRun time.Time
+	LastErr string
+}
+
+func (j *cronJob) status() CronJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	st := CronJobStatus{Name: j.name, Spec: j.spec.text, Next: j.next, LastRun: j.lastRun}
+	if j.lastErr != nil {
+		st.LastErr = j.lastErr.Error()
+	}
+	return st
+}
+
+// runCronJob runs j at every time of its schedule until ctx is done.
+func (s *Server) runCronJob(ctx context.Context, j *cronJob) {
+	for {
+		next := s.calendar.next(j.spec, time.Now())
+		if next.IsZero() {
+			log.Printf("%s: schedule %q has no next run", j.name, j.spec.text)
+			return
+		}
+		next = next.Add(s.calendar.jitterDelay())
+		j.mu.Lock()
+		j.next = next
+		j.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		err := j.run(ctx)
+		if err != nil {
+			log.Printf("%s: %v", j.name, err)
+		}
+		j.mu.Lock()
+		j.lastRun, j.lastErr = time.Now(), err
+		j.mu.Unlock()
+	}
+}
+
+// newCronJobs returns the scheduled jobs for the given schedules; zero
+// specs are disabled.
+func (s *Server) newCronJobs(podmanBin string, autoUpdate, prune cronSpec) []*cronJob {
+	var jobs []*cronJob
+	if autoUpdate.text != "" && s.enableAutoUpdate {
+		jobs = append(jobs, &cronJob{name: "Auto update", spec: autoUpdate, run: func(context.Context) error {
+			if !s.startAutoUpdate(podmanBin) {
+				return errors.New("a run is already in progress")
+			}
+			return nil
+		}})
+	}
+	if prune.text != "" && s.enableActions {
+		jobs = append(jobs, &cronJob{name: "Image prune", spec: prune, run: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+			defer cancel()
+			deleted, reclaimed, err := s.pruneImages(ctx, false)
+			s.actions.add("-", "scheduler", "image prune", fmt.Sprintf("%d image(s), %s", deleted, humanSize(reclaimed)), err, "", nil)
+			return err
+		}})
+	}
+	return jobs
+}
+
+// StopWindowStatus is a container with a stop schedule on /schedules.
+type StopWindowStatus struct {
+	Container Container
+	Status    *ScheduleStatus
+}
+
+// ScheduledRestart is a container with a restart schedule on /schedules.
+type ScheduledRestart struct {
+	Container Container
+	Status    *RestartScheduleStatus
+}
+
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	now := s.calendar.now()
+	var windows []StopWindowStatus
+	var restarts []ScheduledRestart
+	for _, c := range list {
+		if st := scheduleStatus(c, now); st != nil {
+			windows = append(windows, StopWindowStatus{Container: c, Status: st})
+		}
+		if st := s.calendar.restartScheduleStatus(c, now); st != nil {
+			restarts = append(restarts, ScheduledRestart{Container: c, Status: st})
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		return firstName(windows[i].Container.Names) < firstName(windows[j].Container.Names)
+	})
+	sort.Slice(restarts, func(i, j int) bool {
+		return firstName(restarts[i].Container.Names) < firstName(restarts[j].Container.Names)
+	})
+	jobs := make([]CronJobStatus, len(s.cronJobs))
+	for i, j := range s.cronJobs {
+		jobs[i] = j.status()
+		jobs[i].Next = jobs[i].Next.In(now.Location())
+	}
+	var skip []string
+	if s.calendar != nil {
+		for _, d := range s.calendar.skip {
+			skip = append(skip, d.String())
+		}
+	}
+	data := map[string]any{
+		"Title":         "Schedules",
+		"TimeZone":      now.Location().String(),
+		"Now":           now,
+		"SkipDates":     skip,
+		"Jobs":          jobs,
+		"StopWindows":   windows,
+		"Restarts":      restarts,
+		"WindowsActive": s.enableActions,
+	}
+	if s.calendar != nil && s.calendar.jitter > 0 {
+		data["Jitter"] = s.calendar.jitter
+	}
+	if s.updates != nil {
+		data["UpdateInterval"] = s.updates.interval
+		data["UpdateNext"] = s.updates.nextCheck().In(now.Location())
+	}
+	s.render(w, r, "schedules.html", data)
+}
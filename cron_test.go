@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	t.Parallel()
+	for _, spec := range []string{"0 4 * * *", "*/15 * * * *", "0 2 1,15 * 1-5", "30 3 * * 7", "0 0-12/3 * 1-6 *", "@daily"} {
+		if _, err := parseCron(spec); err != nil {
+			t.Errorf("parseCron(%q): %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"", "0 4 * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@yearly"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("parseCron(%q): no error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	t.Parallel()
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := func(s string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", s, zurich)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	for _, tc := range []struct {
+		spec, after, want string
+	}{
+		{"0 4 * * *", "2026-03-01 03:59", "2026-03-01 04:00"},
+		{"0 4 * * *", "2026-03-01 04:00", "2026-03-02 04:00"},
+		{"*/15 * * * *", "2026-03-01 10:07", "2026-03-01 10:15"},
+		{"30 3 * * 0", "2026-03-02 12:00", "2026-03-08 03:30"}, // next Sunday
+		{"0 0 31 * *", "2026-04-01 00:00", "2026-05-31 00:00"}, // April has 30 days
+		{"0 2 13 * 5", "2026-03-01 00:00", "2026-03-06 02:00"}, // Friday or the 13th
+		{"0 2 * * *", "2026-03-28 12:00", "2026-03-30 02:00"},  // 02:00 does not exist on the DST switch
+		{"@monthly", "2026-12-15 00:00", "2027-01-01 00:00"},
+	} {
+		spec, err := parseCron(tc.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := spec.next(at(tc.after)); !got.Equal(at(tc.want)) {
+			t.Errorf("%q after %s = %s, want %s", tc.spec, tc.after, got.Format("2006-01-02 15:04 MST"), tc.want)
+		}
+	}
+	spec, _ := parseCron("0 0 30 2 *")
+	if got := spec.next(at("2026-01-01 00:00")); !got.IsZero() {
+		t.Errorf("February 30 = %s, want zero", got)
+	}
+}
+
+func TestParseSkipDates(t *testing.T) {
+	t.Parallel()
+	dates, err := parseSkipDates("2026-12-24, 12-25")
+	if err != nil || len(dates) != 2 || dates[0] != (skipDate{2026, time.December, 24}) || dates[1].String() != "12-25 (every year)" {
+		t.Errorf("parseSkipDates = %v, %v", dates, err)
+	}
+	for _, s := range []string{"2026-02-30", "24.12.", "12-25,"} {
+		if _, err := parseSkipDates(s); err == nil {
+			t.Errorf("parseSkipDates(%q): no error", s)
+		}
+	}
+}
+
+func TestCalendarNext(t *testing.T) {
+	t.Parallel()
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	dates, _ := parseSkipDates("2026-12-24,12-25")
+	cal := &calendar{loc: tokyo, skip: dates}
+	spec, _ := parseCron("0 4 * * *")
+	got := cal.next(spec, time.Date(2026, 12, 23, 20, 0, 0, 0, time.UTC)) // 05:00 on the 24th in Tokyo
+	if want := time.Date(2026, 12, 26, 4, 0, 0, 0, tokyo); !got.Equal(want) {
+		t.Errorf("next = %s, want %s", got, want)
+	}
+	if got.Location() != tokyo {
+		t.Errorf("next is in %s, want Asia/Tokyo", got.Location())
+	}
+	if !cal.skipped(time.Date(2027, 12, 25, 12, 0, 0, 0, tokyo)) || cal.skipped(time.Date(2027, 12, 24, 12, 0, 0, 0, tokyo)) {
+		t.Error("only 12-25 recurs every year")
+	}
+
+	var nilCal *calendar
+	if nilCal.jitterDelay() != 0 || nilCal.now().Location() != time.Local {
+		t.Error("nil calendar has jitter or is not local")
+	}
+	cal.jitter = time.Minute
+	for range 100 {
+		if d := cal.jitterDelay(); d < 0 || d >= time.Minute {
+			t.Fatalf("jitterDelay = %s, want [0, 1m)", d)
+		}
+	}
+}
+
+func TestSchedulesPage(t *testing.T) {
+	t.Parallel()
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v4.0.0/libpod/containers/json" {
+			w.Write([]byte(`[{"Id":"g1","Names":["game"],"State":"running","Labels":{"` + scheduleLabel + `":"01:00-07:00"}},{"Id":"w1","Names":["web"],"State":"running"},{"Id":"d1","Names":["db"],"State":"running","Labels":{"` + restartScheduleLabel + `":"30 4 * * *"}}]`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	dates, _ := parseSkipDates("12-25")
+	s.calendar = &calendar{loc: time.UTC, skip: dates, jitter: 5 * time.Minute}
+	prune, _ := parseCron("0 3 * * 0")
+	s.cronJobs = s.newCronJobs("podman", cronSpec{}, prune)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.runCronJob(ctx, s.cronJobs[0])
+	for s.cronJobs[0].status().Next.IsZero() {
+		time.Sleep(time.Millisecond)
+	}
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/schedules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	page := string(body)
+	next := s.calendar.next(prune, time.Now())
+	for _, want := range []string{"UTC", "12-25 (every year)", "up to 5m0s", "Image prune", "0 3 * * 0", next.Format("Mon 2006-01-02") + " 03:", ">game<", "01:00-07:00", ">db<", "30 4 * * *"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+	if strings.Contains(page, ">web<") {
+		t.Error("container without schedule listed")
+	}
+}
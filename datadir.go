@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// State files (under DATA_DIR, and Quadlet units when pinning digests) are
+// replaced atomically, so a crash while saving leaves the previous version
+// instead of a truncated file.
+
+// writeFileAtomic replaces path with data, creating its directory. It
+// writes path.tmp and renames it over path; callers writing the same path
+// concurrently must serialize.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every request gets a deadline (REQUEST_TIMEOUT, or the ROUTE_TIMEOUTS
+// entry with the longest matching path prefix) that bounds the Podman API
+// calls made for it, so a hanging API fails pages instead of piling up
+// requests. API calls slower than SLOW_API_THRESHOLD are logged and counted
+// per endpoint; /diagnostics lists the endpoints that took the most time, to
+// see where caching would pay off.
+
+// apiTimeout bounds Podman API calls made without a deadline, e.g. by the
+// background workers.
+const apiTimeout = 30 * time.Second
+
+// maxSlowEndpoints bounds the number of endpoints counted.
+const maxSlowEndpoints = 200
+
+// routeTimeout is a ROUTE_TIMEOUTS entry. A zero timeout disables the
+// deadline.
+type routeTimeout struct {
+	Prefix  string
+	Timeout time.Duration
+}
+
+// parseRouteTimeouts parses a ROUTE_TIMEOUTS value: comma-separated
+// /path/prefix=duration entries. They are returned longest prefix first.
+func parseRouteTimeouts(s string) ([]routeTimeout, error) {
+	var rts []routeTimeout
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, value, ok := strings.Cut(entry, "=")
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || !strings.HasPrefix(prefix, "/") || err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid entry %q, want /path/prefix=duration", entry)
+		}
+		rts = append(rts, routeTimeout{Prefix: prefix, Timeout: d})
+	}
+	slices.SortStableFunc(rts, func(a, b routeTimeout) int { return len(b.Prefix) - len(a.Prefix) })
+	return rts, nil
+}
+
+// timeoutFor returns the deadline for requests to path (without BASE_PATH).
+// Event streams stay open until the client leaves and image exports can take
+// long, so they get none unless configured explicitly.
+func (s *Server) timeoutFor(path string) time.Duration {
+	for _, rt := range s.routeTimeouts {
+		if strings.HasPrefix(path, rt.Prefix) {
+			return rt.Timeout
+		}
+	}
+	if strings.HasSuffix(path, "/events") || isImageExport(path) {
+		return 0
+	}
+	return s.requestTimeout
+}
+
+// withDeadline sets the deadline of the request context.
+func (s *Server) withDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d := s.timeoutFor(strings.TrimPrefix(r.URL.Path, s.basePath)); d > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SlowEndpoint counts the slow calls to one API endpoint.
+type SlowEndpoint struct {
+	Endpoint string
+	Count    int
+	Total    time.Duration
+	Max      time.Duration
+	Bytes    int64 // response size of the last slow call
+}
+
+// Mean returns the average duration of the slow calls.
+func (e SlowEndpoint) Mean() time.Duration {
+	return e.Total / time.Duration(max(e.Count, 1))
+}
+
+// slowCalls counts the API calls that took at least threshold. It is safe
+// to use a nil *slowCalls, which counts nothing.
+type slowCalls struct {
+	threshold time.Duration
+
+	mu        sync.Mutex
+	endpoints map[string]*SlowEndpoint
+}
+
+func newSlowCalls(threshold time.Duration) *slowCalls {
+	return &slowCalls{threshold: threshold, endpoints: map[string]*SlowEndpoint{}}
+}
+
+// apiEndpoint returns path without the query and with the object name or ID
+// replaced, so calls for different containers count as one endpoint.
+// Image names can contain slashes, so everything between the first and the
+// last segment is the object.
+func apiEndpoint(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segs := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(segs) < 3 {
+		return path
+	}
+	return "/" + segs[0] + "/{id}/" + segs[len(segs)-1]
+}
+
+// observe logs and counts the call if it was slow.
+func (sc *slowCalls) observe(ctx context.Context, path string, d time.Duration, size int64) {
+	if sc == nil || d < sc.threshold {
+		return
+	}
+	log.Printf("[%s] slow podman API call %s: %v, %d bytes", reqID(ctx), path, d.Round(time.Millisecond), size)
+	endpoint := apiEndpoint(path)
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	e := sc.endpoints[endpoint]
+	if e == nil {
+		if len(sc.endpoints) >= maxSlowEndpoints {
+			return
+		}
+		e = &SlowEndpoint{Endpoint: endpoint}
+		sc.endpoints[endpoint] = e
+	}
+	e.Count++
+	e.Total += d
+	e.Max = max(e.Max, d)
+	e.Bytes = size
+}
+
+// top returns the n endpoints with the most time spent in slow calls.
+func (sc *slowCalls) top(n int) []SlowEndpoint {
+	if sc == nil {
+		return nil
+	}
+	sc.mu.Lock()
+	out := make([]SlowEndpoint, 0, len(sc.endpoints))
+	for _, e := range sc.endpoints {
+		out = append(out, *e)
+	}
+	sc.mu.Unlock()
+	slices.SortFunc(out, func(a, b SlowEndpoint) int {
+		return cmp.Or(cmp.Compare(b.Total, a.Total), strings.Compare(a.Endpoint, b.Endpoint))
+	})
+	return out[:min(n, len(out))]
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
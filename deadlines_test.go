@@ -0,0 +1,122 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRouteTimeouts(t *testing.T) {
+	t.Parallel()
+	rts, err := parseRouteTimeouts("/system=10s, /system/df=2m,/job/=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []routeTimeout{{"/system/df", 2 * time.Minute}, {"/system", 10 * time.Second}, {"/job/", 0}}
+	if len(rts) != len(want) || rts[0] != want[0] || rts[1] != want[1] || rts[2] != want[2] {
+		t.Errorf("parseRouteTimeouts = %+v, want %+v", rts, want)
+	}
+	for _, bad := range []string{"system=1s", "/system", "/system=soon", "/system=-1s"} {
+		if _, err := parseRouteTimeouts(bad); err == nil {
+			t.Errorf("parseRouteTimeouts(%q): no error", bad)
+		}
+	}
+
+	s := &Server{requestTimeout: 30 * time.Second, routeTimeouts: rts}
+	for path, want := range map[string]time.Duration{
+		"/containers":         30 * time.Second,
+		"/system/df":          2 * time.Minute,
+		"/system/cpus":        10 * time.Second,
+		"/job/abc":            0,
+		"/auto-update/events": 0,
+		"/image/abc/export":   0,
+		"/apps/export":        30 * time.Second,
+	} {
+		if got := s.timeoutFor(path); got != want {
+			t.Errorf("timeoutFor(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestAPIEndpoint(t *testing.T) {
+	t.Parallel()
+	for path, want := range map[string]string{
+		"/containers/json?all=true":          "/containers/json",
+		"/containers/abc123/json":            "/containers/{id}/json",
+		"/images/ghcr.io/org/web:1.2/json":   "/images/{id}/json",
+		"/containers/stats?stream=false":     "/containers/stats",
+		"/containers/abc/stats?stream=false": "/containers/{id}/stats",
+	} {
+		if got := apiEndpoint(path); got != want {
+			t.Errorf("apiEndpoint(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestSlowCalls(t *testing.T) {
+	t.Parallel()
+	sc := newSlowCalls(time.Second)
+	ctx := t.Context()
+	sc.observe(ctx, "/containers/a/json", 2*time.Second, 100)
+	sc.observe(ctx, "/containers/b/json", 4*time.Second, 200)
+	sc.observe(ctx, "/info", 3*time.Second, 50)
+	sc.observe(ctx, "/version", time.Millisecond, 10)
+	got := sc.top(10)
+	want := []SlowEndpoint{
+		{Endpoint: "/containers/{id}/json", Count: 2, Total: 6 * time.Second, Max: 4 * time.Second, Bytes: 200},
+		{Endpoint: "/info", Count: 1, Total: 3 * time.Second, Max: 3 * time.Second, Bytes: 50},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("top = %+v, want %+v", got, want)
+	}
+	if got[0].Mean() != 3*time.Second {
+		t.Errorf("Mean = %v", got[0].Mean())
+	}
+	var nilCalls *slowCalls
+	nilCalls.observe(ctx, "/info", time.Hour, 0)
+	if nilCalls.top(10) != nil {
+		t.Error("nil slowCalls returned endpoints")
+	}
+}
+
+func TestRequestDeadline(t *testing.T) {
+	t.Parallel()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer api.Close()
+	s := newTestServer(t, api)
+	s.requestTimeout = 50 * time.Millisecond
+	s.slowCalls = newSlowCalls(10 * time.Millisecond)
+	app := httptest.NewServer(s.withDeadline(s.newMux("podman")))
+	defer app.Close()
+
+	start := time.Now()
+	resp, err := http.Get(app.URL + "/containers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || time.Since(start) > 2*time.Second {
+		t.Errorf("hanging API: status %d after %v, want 500 at the deadline", resp.StatusCode, time.Since(start))
+	}
+	if top := s.slowCalls.top(1); len(top) != 1 || top[0].Endpoint != "/containers/json" {
+		t.Errorf("slow calls = %+v", top)
+	}
+
+	resp, err = http.Get(app.URL + "/diagnostics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "Slow API Calls") || !strings.Contains(string(body), "/containers/json") {
+		t.Error("diagnostics page does not list the slow endpoint")
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A deploy pulls a container's image, restarts the container's systemd unit
+// so it is recreated from the new image, waits for it to become healthy and
+// rolls back to the previous image if it does not. This is what
+// `podman auto-update` does for the registry policy, for a single container
+// and on demand.
+
+// systemdUnitLabel is set by Podman on containers managed by systemd
+// (Quadlet, `podman generate systemd --new`, podman-compose systemd units).
+const systemdUnitLabel = "PODMAN_SYSTEMD_UNIT"
+
+// Timing of the health wait after restarting a unit. Variables for tests.
+var (
+	deployHealthTimeout  = 2 * time.Minute
+	deployHealthInterval = 2 * time.Second
+)
+
+// errDeployUnsupported is returned for containers that cannot be deployed.
+var errDeployUnsupported = errors.New("deploy requires a systemd-managed container with a tagged image on the Podman backend")
+
+// startDeploy validates that the container can be deployed and starts a
+// deploy job for it.
+func (s *Server) startDeploy(ctx context.Context, id string) (*job, error) {
+	if s.backend != backendPodman {
+		return nil, errDeployUnsupported
+	}
+	c, err := s.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	unit := s.containerUnit(c)
+	if unit == "" || strings.Contains(c.ImageName, "@") {
+		return nil, errDeployUnsupported
+	}
+
+	j := s.jobs.start("Deploy " + c.Name)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+		defer cancel()
+		j.finish(s.deploy(ctx, j, c, unit))
+	}()
+	return j, nil
+}
+
+func (s *Server) deploy(ctx context.Context, j *job, c ContainerInspect, unit string) error {
+	ref := c.ImageName
+	j.logf("==> Pulling %s", ref)
+	if err := s.pullImage(ctx, ref, j); err != nil {
+		return err
+	}
+	img, err := s.inspectImage(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", ref, err)
+	}
+	if img.ID == c.Image {
+		j.logf("==> %s is up to date (%s)", ref, shortID(img.ID))
+		return nil
+	}
+
+	j.logf("==> Updating %s from %s to %s", c.Name, shortID(c.Image), shortID(img.ID))
+	err = s.restartUnit(ctx, j, unit)
+	if err == nil {
+		j.logf("==> Waiting for %s to become healthy", c.Name)
+		err = s.waitHealthy(ctx, c.Name, img.ID)
+	}
+	if err == nil {
+		j.logf("==> Deployed %s", shortID(img.ID))
+		return nil
+	}
+
+	j.logf("==> Deploy failed: %v", err)
+	j.logf("==> Rolling back to %s", shortID(c.Image))
+	repo, tag := splitImageRef(ref)
+	q := url.Values{"repo": {repo}, "tag": {tag}}
+	rerr := s.podmanPost(ctx, "/images/"+c.Image+"/tag?"+q.Encode())
+	s.images.invalidate()
+	if rerr != nil {
+		return fmt.Errorf("deploy failed (%v), rollback failed: %w", err, rerr)
+	}
+	if rerr := s.restartUnit(ctx, j, unit); rerr != nil {
+		return fmt.Errorf("deploy failed (%v), rollback failed: %w", err, rerr)
+	}
+	if rerr := s.waitHealthy(ctx, c.Name, c.Image); rerr != nil {
+		return fmt.Errorf("deploy failed (%v), rolled back but container is not healthy: %w", err, rerr)
+	}
+	return fmt.Errorf("deploy failed, rolled back to %s: %w", shortID(c.Image), err)
+}
+
+func (s *Server) restartUnit(ctx context.Context, j *job, unit string) error {
+	j.logf("==> systemctl --user restart %s", unit)
+	return runCommand(ctx, j, s.systemctlBin, "--user", "restart", "--", unit)
+}
+
+// waitHealthy polls the container until it runs the wanted image and is
+// healthy (or running, if it has no healthcheck).
+func (s *Server) waitHealthy(ctx context.Context, name, wantImage string) error {
+	deadline := time.Now().Add(deployHealthTimeout)
+	for {
+		c, err := s.inspectContainer(ctx, name)
+		if err != nil && !errors.Is(err, errNotFound) {
+			return err
+		}
+		if err == nil && c.Image == wantImage {
+			switch {
+			case c.State.Running && (c.State.Health == nil || c.State.Health.Status == "" || c.State.Health.Status == "healthy"):
+				return nil
+			case c.State.Health != nil && c.State.Health.Status == "unhealthy":
+				return fmt.Errorf("container %s is unhealthy", name)
+			case c.State.Status == "exited":
+				return fmt.Errorf("container %s exited with code %d", name, c.State.ExitCode)
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s to become healthy", name)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(deployHealthInterval):
+		}
+	}
+}
+
+// splitImageRef splits "registry/repo:tag" into repository and tag.
+func splitImageRef(ref string) (repo, tag string) {
+	if i := strings.LastIndexByte(ref, ':'); i > strings.LastIndexByte(ref, '/') {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, "latest"
+}
+
+func (s *Server) handleContainerDeploy(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid container ID", http.StatusBadRequest)
+		return
+	}
+	j, err := s.startDeploy(r.Context(), id)
+	if err == nil || !errors.Is(err, errNotFound) {
+		// A failed deploy rolls back by itself, there is nothing to undo.
+		s.actions.record(r, "deploy", id, err, "", nil)
+	}
+	switch {
+	case errors.Is(err, errNotFound):
+		http.Error(w, "Container Not Found", http.StatusNotFound)
+		return
+	case errors.Is(err, errDeployUnsupported):
+		http.Error(w, "Deploy requires a systemd-managed container with a tagged image", http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("[%s] deploy %s: %v", reqID(r.Context()), id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, s.basePath+"/job/"+j.ID, http.StatusSeeOther)
+}
+
+func (s *Server) hookDeploy(w http.ResponseWriter, r *http.Request) (string, error) {
+	name := r.PathValue("name")
+	if !validID.MatchString(name) {
+		return "", hookError{http.StatusBadRequest, "invalid container name"}
+	}
+	j, err := s.startDeploy(r.Context(), name)
+	switch {
+	case errors.Is(err, errNotFound):
+		return "", hookError{http.StatusNotFound, "container not found"}
+	case errors.Is(err, errDeployUnsupported):
+		return "", hookError{http.StatusBadRequest, errDeployUnsupported.Error()}
+	case err != nil:
+		return "", err
+	}
+	w.WriteHeader(http.StatusAccepted)
+	return "deploy started: " + s.basePath + "/job/" + j.ID, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newMockDeployAPI mocks the libpod endpoints used by a deploy. The pull
+// switches the container to the "new" image; tagging "old" switches it back.
+func newMockDeployAPI(t *testing.T, newHealth string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	image := "old"
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		p := strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod")
+		calls = append(calls, r.Method+" "+p)
+		switch {
+		case r.Method == http.MethodPost && p == "/images/pull":
+			image = "new"
+			w.Write([]byte(`{"stream":"Writing manifest to image destination\n"}`))
+		case r.Method == http.MethodPost && p == "/images/old/tag":
+			image = "old"
+			w.WriteHeader(http.StatusCreated)
+		case p == "/images/docker.io/library/web:latest/json":
+			w.Write([]byte(`{"Id":"new"}`))
+		case p == "/containers/web/json":
+			health := "healthy"
+			if image == "new" {
+				health = newHealth
+			}
+			fmt.Fprintf(w, `{"Id":"c1","Name":"web","Image":%q,"ImageName":"docker.io/library/web:latest",
+				"State":{"Status":"running","Running":true,"Health":{"Status":%q}},
+				"Config":{"Labels":{"PODMAN_SYSTEMD_UNIT":"web.service"}}}`, image, health)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return srv, &calls
+}
+
+func waitJob(t *testing.T, j *job) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for j.Status() == "running" {
+		if time.Now().After(deadline) {
+			t.Fatal("job did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDeploy(t *testing.T) {
+	t.Parallel()
+	mock, _ := newMockDeployAPI(t, "healthy")
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.backend = backendPodman
+	s.systemctlBin = "true"
+
+	j, err := s.startDeploy(t.Context(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitJob(t, j)
+	out, _, errMsg := j.read(0)
+	if j.Status() != "done" {
+		t.Fatalf("status = %s (err %q), output:\n%s", j.Status(), errMsg, out)
+	}
+	for _, want := range []string{"Pulling docker.io/library/web:latest", "Updating web from old to new", "Deployed new"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDeployRollback(t *testing.T) {
+	t.Parallel()
+	mock, calls := newMockDeployAPI(t, "unhealthy")
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.backend = backendPodman
+	s.systemctlBin = "true"
+
+	j, err := s.startDeploy(t.Context(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitJob(t, j)
+	out, _, errMsg := j.read(0)
+	if j.Status() != "failed" {
+		t.Fatalf("status = %s, want failed, output:\n%s", j.Status(), out)
+	}
+	if !strings.Contains(errMsg, "rolled back to old") {
+		t.Errorf("err = %q, want rollback message", errMsg)
+	}
+	var tagged bool
+	for _, c := range *calls {
+		if strings.HasPrefix(c, "POST /images/old/tag") {
+			tagged = true
+		}
+	}
+	if !tagged {
+		t.Errorf("old image was not re-tagged, calls: %v", *calls)
+	}
+}
+
+func TestDeployUnsupported(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+
+	// Docker backend: not supported.
+	s.backend = backendDocker
+	if _, err := s.startDeploy(t.Context(), "jellyfin"); err != errDeployUnsupported {
+		t.Errorf("docker backend: err = %v, want errDeployUnsupported", err)
+	}
+
+	// Unit labels that are no service name or could pass systemctl options.
+	for _, unit := range []string{"--failed", "default.target", "web.service\nx"} {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"Id":"c1","Name":"web","ImageName":"docker.io/library/web:latest","Config":{"Labels":{"PODMAN_SYSTEMD_UNIT":%q}}}`, unit)
+		}))
+		s := newTestServer(t, api)
+		s.backend = backendPodman
+		if _, err := s.startDeploy(t.Context(), "web"); err != errDeployUnsupported {
+			t.Errorf("unit %q: err = %v, want errDeployUnsupported", unit, err)
+		}
+		api.Close()
+	}
+}
+
+func TestSplitImageRef(t *testing.T) {
+	t.Parallel()
+	tests := []struct{ ref, repo, tag string }{
+		{"docker.io/library/nginx:alpine", "docker.io/library/nginx", "alpine"},
+		{"localhost:5000/app", "localhost:5000/app", "latest"},
+		{"localhost:5000/app:v1", "localhost:5000/app", "v1"},
+		{"nginx", "nginx", "latest"},
+	}
+	for _, tt := range tests {
+		repo, tag := splitImageRef(tt.ref)
+		if repo != tt.repo || tag != tt.tag {
+			t.Errorf("splitImageRef(%q) = %q, %q, want %q, %q", tt.ref, repo, tag, tt.repo, tt.tag)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The energy meter samples the cumulative CPU time of running containers
+// and turns the deltas into an energy estimate using a fixed watts-per-core
+// factor. This ignores idle power, memory, disks and the network, so the
+// numbers are a rough relative measure, not a power meter reading.
+
+const (
+	energySampleInterval = 5 * time.Minute
+	energyDays           = 7
+)
+
+type energyMeter struct {
+	s            *Server
+	wattsPerCore float64
+	co2PerKWh    float64 // grams
+	path         string  // JSON file the daily totals are saved to
+
+	mu   sync.Mutex
+	last map[string]uint64 // container ID -> CPU nanoseconds at last sample
+	// Days maps a local date (2006-01-02) to the CPU seconds used per
+	// container name on that day.
+	days map[string]map[string]float64
+}
+
+func newEnergyMeter(s *Server, wattsPerCore, co2PerKWh float64) *energyMeter {
+	m := &energyMeter{
+		s:            s,
+		wattsPerCore: wattsPerCore,
+		co2PerKWh:    co2PerKWh,
+		path:         filepath.Join(s.dataDir, "energy.json"),
+		last:         map[string]uint64{},
+		days:         map[string]map[string]float64{},
+	}
+	data, err := os.ReadFile(m.path)
+	if err == nil {
+		err = json.Unmarshal(data, &m.days)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("energy: loading %s: %v", m.path, err)
+	}
+	return m
+}
+
+func (m *energyMeter) run(ctx context.Context) {
+	for {
+		if err := m.sample(ctx, time.Now()); err != nil {
+			log.Printf("energy: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(energySampleInterval):
+		}
+	}
+}
+
+// containerCPU is the cumulative CPU time of a running container.
+type containerCPU struct {
+	ID    string
+	Name  string
+	Nanos uint64
+}
+
+// containerCPUTimes returns the CPU time of all running containers.
+func (s *Server) containerCPUTimes(ctx context.Context) ([]containerCPU, error) {
+	if s.backend != backendDocker {
+		var resp struct {
+			Stats []struct {
+				ContainerID string `json:"ContainerID"`
+				Name        string `json:"Name"`
+				CPUNano     uint64 `json:"CPUNano"`
+			} `json:"Stats"`
+		}
+		if err := s.podmanGet(ctx, "/containers/stats?stream=false", &resp); err != nil {
+			return nil, err
+		}
+		out := make([]containerCPU, len(resp.Stats))
+		for i, st := range resp.Stats {
+			out[i] = containerCPU{st.ContainerID, st.Name, st.CPUNano}
+		}
+		return out, nil
+	}
+	// The compat API has no endpoint for all containers at once.
+	list, err := s.listContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []containerCPU
+	for _, c := range list {
+		if c.State != "running" {
+			continue
+		}
+		var st struct {
+			CPUStats struct {
+				CPUUsage struct {
+					TotalUsage uint64 `json:"total_usage"`
+				} `json:"cpu_usage"`
+			} `json:"cpu_stats"`
+		}
+		if err := s.podmanGet(ctx, "/containers/"+c.ID+"/stats?stream=false", &st); err != nil {
+			return nil, err
+		}
+		out = append(out, containerCPU{c.ID, firstName(c.Names), st.CPUStats.CPUUsage.TotalUsage})
+	}
+	return out, nil
+}
+
+// sample adds the CPU time used since the last sample to today's totals.
+// Containers seen for the first time only set a baseline, as their counter
+// covers an unknown period.
+func (m *energyMeter) sample(ctx context.Context, now time.Time) error {
+	times, err := m.s.containerCPUTimes(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	today := now.Format(time.DateOnly)
+	last := make(map[string]uint64, len(times))
+	for _, c := range times {
+		last[c.ID] = c.Nanos
+		prev, ok := m.last[c.ID]
+		if !ok || c.Nanos <= prev {
+			continue
+		}
+		if m.days[today] == nil {
+			m.days[today] = map[string]float64{}
+		}
+		m.days[today][c.Name] += float64(c.Nanos-prev) / 1e9
+	}
+	m.last = last
+
+	oldest := now.AddDate(0, 0, -energyDays+1).Format(time.DateOnly)
+	for day := range m.days {
+		if day < oldest {
+			delete(m.days, day)
+		}
+	}
+	return m.save()
+}
+
+func (m *energyMeter) save() error {
+	data, err := json.Marshal(m.days)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(m.path, data, 0o600)
+}
+
+// EnergyRow is the estimated usage of one container, or the total.
+type EnergyRow struct {
+	Name      string
+	TodayWh   float64
+	WeekWh    float64
+	WeekCO2   float64 // grams
+	CoreHours float64 // over the week
+}
+
+// report returns per-container rows sorted by weekly energy, and the total.
+func (m *energyMeter) report(now time.Time) ([]EnergyRow, EnergyRow) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	today := now.Format(time.DateOnly)
+	rows := map[string]*EnergyRow{}
+	for day, usage := range m.days {
+		for name, secs := range usage {
+			row := rows[name]
+			if row == nil {
+				row = &EnergyRow{Name: name}
+				rows[name] = row
+			}
+			wh := secs / 3600 * m.wattsPerCore
+			row.WeekWh += wh
+			row.CoreHours += secs / 3600
+			if day == today {
+				row.TodayWh += wh
+			}
+		}
+	}
+	total := EnergyRow{Name: "Total"}
+	out := make([]EnergyRow, 0, len(rows))
+	for _, row := range rows {
+		row.WeekCO2 = row.WeekWh / 1000 * m.co2PerKWh
+		total.TodayWh += row.TodayWh
+		total.WeekWh += row.WeekWh
+		total.WeekCO2 += row.WeekCO2
+		total.CoreHours += row.CoreHours
+		out = append(out, *row)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].WeekWh != out[j].WeekWh {
+			return out[i].WeekWh > out[j].WeekWh
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, total
+}
+
+func (s *Server) handleEnergy(w http.ResponseWriter, r *http.Request) {
+	if s.energy == nil {
+		s.render(w, r, "energy.html", map[string]any{
+			"Title":    "Energy",
+			"Disabled": true,
+		})
+		return
+	}
+	rows, total := s.energy.report(time.Now())
+	s.render(w, r, "energy.html", map[string]any{
+		"Title":        "Energy",
+		"Rows":         rows,
+		"Total":        total,
+		"WattsPerCore": s.energy.wattsPerCore,
+		"CO2PerKWh":    s.energy.co2PerKWh,
+		"Days":         energyDays,
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEnergyMeter(t *testing.T) {
+	var nanos atomic.Uint64
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v4.0.0/libpod/containers/stats" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		n := nanos.Load()
+		fmt.Fprintf(w, `{"Error":null,"Stats":[{"ContainerID":"a","Name":"game","CPUNano":%d},{"ContainerID":"b","Name":"web","CPUNano":%d}]}`, n, n/4)
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.dataDir = t.TempDir()
+
+	day1 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	m := newEnergyMeter(s, 10, 500)
+	for i, h := range []uint64{1, 3, 5} {
+		// 2 core-hours of "game" per sample after the baseline.
+		nanos.Store(h * 3600 * 1e9)
+		if err := m.sample(t.Context(), day1.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, total := m.report(day1)
+	if len(rows) != 2 || rows[0].Name != "game" {
+		t.Fatalf("rows = %+v", rows)
+	}
+	if rows[0].CoreHours != 4 || rows[0].TodayWh != 40 || rows[0].WeekCO2 != 20 {
+		t.Errorf("game = %+v, want 4 core-hours, 40 Wh, 20 g", rows[0])
+	}
+	if total.WeekWh != 50 {
+		t.Errorf("total = %+v, want 50 Wh", total)
+	}
+
+	// Totals survive a restart, but the first sample after it is a baseline.
+	m = newEnergyMeter(s, 10, 500)
+	nanos.Store(100 * 3600 * 1e9)
+	if err := m.sample(t.Context(), day1.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := m.report(day1.AddDate(0, 0, 1)); rows[0].TodayWh != 0 || rows[0].WeekWh != 40 {
+		t.Errorf("after restart: %+v", rows[0])
+	}
+
+	// Days older than a week are dropped.
+	if err := m.sample(t.Context(), day1.AddDate(0, 0, energyDays)); err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := m.report(day1); len(rows) != 0 {
+		t.Errorf("old days kept: %+v", rows)
+	}
+
+	s.energy = m
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+	resp, err := http.Get(app.URL + "/system/energy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "10 W per busy core") {
+		t.Errorf("GET /system/energy: status %d", resp.StatusCode)
+	}
+}
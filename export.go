@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html/template"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// The apps export is a single static HTML file with the apps that have a
+// URL, for use as a browser start page. It has no links back to podfather
+// and inlines the stylesheet, logo and image icons, so it keeps working when
+// podfather or the host is down. Container states are left out, as they
+// would be stale.
+
+var unsafeFilenameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// exportCategories returns the categories with only the apps that have a
+// URL, dropping empty categories.
+func exportCategories(categories []AppCategory) []AppCategory {
+	var out []AppCategory
+	for _, cat := range categories {
+		var apps []App
+		for _, app := range cat.Apps {
+			if app.URL != "" {
+				app.Containers = nil
+				apps = append(apps, app)
+			}
+		}
+		if len(apps) > 0 {
+			cat.Apps = apps
+			out = append(out, cat)
+		}
+	}
+	return out
+}
+
+// exportIcons returns the image icons of the categories and apps as data
+// URLs by key. Icons that cannot be fetched are left out.
+func (s *Server) exportIcons(r *http.Request, categories []AppCategory) map[string]template.URL {
+	icons := map[string]template.URL{}
+	if s.icons == nil {
+		return icons
+	}
+	add := func(icon string) {
+		key := iconKey(icon)
+		if key == "" || icons[key] != "" {
+			return
+		}
+		data, err := s.icons.get(r.Context(), icon)
+		if err != nil {
+			log.Printf("[%s] icon %s: %v", reqID(r.Context()), icon, err)
+			return
+		}
+		icons[key] = template.URL("data:" + iconContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data))
+	}
+	for _, cat := range categories {
+		add(cat.Icon)
+		for _, app := range cat.Apps {
+			add(app.Icon)
+		}
+	}
+	return icons
+}
+
+func (s *Server) handleAppsExport(w http.ResponseWriter, r *http.Request) {
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	logo, _ := templateFS.ReadFile("templates/logo.svg")
+	categories := exportCategories(s.buildAppCategories(list))
+	data := map[string]any{
+		"Hostname":   s.hostname,
+		"Theme":      s.theme(r),
+		"Categories": categories,
+		"Icons":      s.exportIcons(r, categories),
+		"Exported":   time.Now(),
+		"Logo":       template.URL("data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(logo)),
+	}
+	var buf bytes.Buffer
+	if err := pageTemplates["apps-export.html"].ExecuteTemplate(&buf, "export", data); err != nil {
+		log.Printf("[%s] render apps export: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	name := "podfather-apps"
+	if s.hostname != "" {
+		name += "-" + unsafeFilenameChars.ReplaceAllString(s.hostname, "_")
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.html"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportCategories(t *testing.T) {
+	in := []AppCategory{
+		{Name: "Media", Apps: []App{{Name: "Jellyfin", URL: "http://jf", Containers: []Container{{ID: "x"}}}, {Name: "Worker"}}},
+		{Name: "Jobs", Apps: []App{{Name: "Batch"}}},
+	}
+	out := exportCategories(in)
+	if len(out) != 1 || len(out[0].Apps) != 1 || out[0].Apps[0].Name != "Jellyfin" || out[0].Apps[0].Containers != nil {
+		t.Errorf("got %+v", out)
+	}
+}
+
+func TestAppsExport(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.hostname = "nas.lan"
+	s.categories = parseCategories([]string{
+		"PODFATHER_CATEGORY_MEDIA_NAME=Media",
+		"PODFATHER_CATEGORY_MEDIA_ICON=🎞️",
+		"PODFATHER_CATEGORY_MEDIA_DESCRIPTION=Movies and **music**",
+	})
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/apps/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="podfather-apps-nas.lan.html"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	page := string(body)
+	if !strings.Contains(page, `href="http://localhost:8096"`) {
+		t.Error("export does not link to the Jellyfin app")
+	}
+	if !strings.Contains(page, `🎞️ Media</h2>`) || !strings.Contains(page, `Movies and <strong>music</strong>`) {
+		t.Error("export lacks the category icon or description")
+	}
+	if !strings.Contains(page, `href="data:image/svg`) {
+		t.Error("logo is not inlined")
+	}
+	// Nothing may point back at podfather.
+	for _, ref := range []string{`href="/`, `src="/`, "/container/"} {
+		if strings.Contains(page, ref) {
+			t.Errorf("export references podfather: %s", ref)
+		}
+	}
+}
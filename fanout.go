@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// maxParallelCalls bounds the Podman API calls one fan-out makes at a time,
+// so pages over many containers do not flood the socket.
+const maxParallelCalls = 8
+
+// parallel calls fn for i in [0, n), up to maxParallelCalls at a time, and
+// returns the first error. After an error, the context passed to the other
+// calls is canceled and calls not started yet are skipped. Pages that need
+// several independent API calls use it instead of making them one by one,
+// which adds up on slow hosts.
+func parallel(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, maxParallelCalls)
+	var skipped error
+	for i := range n {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if skipped = ctx.Err(); skipped != nil {
+			break
+		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			if err := fn(ctx, i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		})
+	}
+	wg.Wait()
+	if firstErr == nil {
+		// The parent context ended before all calls started.
+		return skipped
+	}
+	return firstErr
+}
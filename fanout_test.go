@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallel(t *testing.T) {
+	t.Parallel()
+	var running, peak atomic.Int32
+	done := make([]bool, 20)
+	err := parallel(context.Background(), len(done), func(ctx context.Context, i int) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		done[i] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, d := range done {
+		if !d {
+			t.Errorf("call %d not made", i)
+		}
+	}
+	if p := peak.Load(); p < 2 || p > maxParallelCalls {
+		t.Errorf("%d calls at a time, want 2 to %d", p, maxParallelCalls)
+	}
+}
+
+func TestParallelError(t *testing.T) {
+	t.Parallel()
+	boom := errors.New("boom")
+	var started, canceled atomic.Int32
+	err := parallel(context.Background(), 100, func(ctx context.Context, i int) error {
+		started.Add(1)
+		if i == 0 {
+			return boom
+		}
+		<-ctx.Done()
+		canceled.Add(1)
+		return ctx.Err()
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("err = %v, want the first error", err)
+	}
+	if n := started.Load(); n > maxParallelCalls+1 {
+		t.Errorf("%d calls started after the error", n)
+	}
+	if canceled.Load() != started.Load()-1 {
+		t.Error("running calls not canceled")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := parallel(ctx, 3, func(context.Context, int) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled context: err = %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"unicode/utf8"
+)
+
+// Favorites are apps pinned to a section at the top of the apps page. Like
+// saved views they are stored in a cookie, so they are per browser and need
+// no server-side state. Pinned apps that are currently missing (e.g. their
+// containers were removed) are kept, so they return once the app is back.
+
+const favoritesCookieName = "favorites"
+
+const maxFavorites = 20
+
+// Name lists are the cookies holding app or category names. Their entries
+// are bounded in length and number to fit into a cookie.
+const (
+	maxListName       = 100
+	nameListCookieAge = 365 * 24 * 60 * 60
+)
+
+// favoritesCategory is the name of the section of pinned apps.
+const favoritesCategory = "Favorites"
+
+// readFavorites returns the names of the pinned apps from the request
+// cookie, in the order they were pinned.
+func readFavorites(r *http.Request) []string {
+	return readNameList(r, favoritesCookieName, maxFavorites)
+}
+
+// readNameList returns the first limit valid names in the name list cookie.
+func readNameList(r *http.Request, cookie string, limit int) []string {
+	c, err := r.Cookie(cookie)
+	if err != nil {
+		return nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil
+	}
+	out := names[:0]
+	for _, n := range names {
+		if validListName(n) && !slices.Contains(out, n) {
+			out = append(out, n)
+		}
+	}
+	return out[:min(len(out), limit)]
+}
+
+func validListName(name string) bool {
+	return name != "" && utf8.ValidString(name) && utf8.RuneCountInString(name) <= maxListName
+}
+
+// writeNameList stores names in the name list cookie, or deletes it if
+// names is empty.
+func (s *Server) writeNameList(w http.ResponseWriter, r *http.Request, name string, names []string) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Path:     s.basePath + "/",
+		MaxAge:   nameListCookieAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if len(names) == 0 {
+		cookie.MaxAge = -1
+	} else {
+		data, _ := json.Marshal(names)
+		cookie.Value = base64.RawURLEncoding.EncodeToString(data)
+	}
+	http.SetCookie(w, cookie)
+}
+
+// favoriteApps returns the apps of categories pinned in names, in that
+// order, as the favorites section, or nil if none of them exists.
+func favoriteApps(categories []AppCategory, names []string) *AppCategory {
+	apps := map[string]App{}
+	for _, cat := range categories {
+		for _, app := range cat.Apps {
+			apps[app.Name] = app
+		}
+	}
+	var fav AppCategory
+	for _, n := range names {
+		if app, ok := apps[n]; ok {
+			fav.Apps = append(fav.Apps, app)
+		}
+	}
+	if len(fav.Apps) == 0 {
+		return nil
+	}
+	fav.Name = favoritesCategory
+	return &fav
+}
+
+// handleFavorite pins (pin=on) or unpins the app named in the form.
+func (s *Server) handleFavorite(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("app")
+	if !validListName(name) {
+		http.Error(w, "Invalid app", http.StatusBadRequest)
+		return
+	}
+	names := slices.DeleteFunc(readFavorites(r), func(n string) bool { return n == name })
+	if r.FormValue("pin") == "on" {
+		if len(names) >= maxFavorites {
+			http.Error(w, "Too many favorites", http.StatusBadRequest)
+			return
+		}
+		names = append(names, name)
+	}
+	s.writeNameList(w, r, favoritesCookieName, names)
+	http.Redirect(w, r, s.basePath+"/apps", http.StatusSeeOther)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFavorites(t *testing.T) {
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	pin := func(app string, on bool, cookies []*http.Cookie) []*http.Cookie {
+		t.Helper()
+		form := url.Values{"app": {app}}
+		if on {
+			form.Set("pin", "on")
+		}
+		r := httptest.NewRequest(http.MethodPost, "/favorites", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		s.handleFavorite(w, r)
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/apps" {
+			t.Fatalf("pin %s: status %d location %q", app, w.Code, w.Header().Get("Location"))
+		}
+		return w.Result().Cookies()
+	}
+	get := func(cookies []*http.Cookie) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/apps", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		return r
+	}
+
+	cookies := pin("Grafana", true, nil)
+	cookies = pin("Jellyfin", true, cookies)
+	cookies = pin("Grafana", true, cookies) // pinning again moves it to the end
+	cookies = pin("Gone", true, cookies)
+	if got := readFavorites(get(cookies)); !slices.Equal(got, []string{"Jellyfin", "Grafana", "Gone"}) {
+		t.Fatalf("favorites = %q", got)
+	}
+
+	w := httptest.NewRecorder()
+	s.handleApps(w, get(cookies))
+	body := w.Body.String()
+	sections := categorySections(body)
+	if len(sections) == 0 || categoryHeading(sections[0]) != "Favorites" {
+		t.Fatal("apps page does not start with the favorites section")
+	}
+	if fav := sections[0]; strings.Count(fav, `class="app-card"`) != 2 || strings.Index(fav, ">Jellyfin<") > strings.Index(fav, ">Grafana<") {
+		t.Errorf("favorites section does not hold Jellyfin and Grafana in pinned order")
+	}
+	if n := strings.Count(body, ">unpin</button>"); n != 4 {
+		t.Errorf("%d unpin buttons, want 4 (2 favorites, 2 in their categories)", n)
+	}
+
+	cookies = pin("Jellyfin", false, cookies)
+	cookies = pin("Grafana", false, cookies)
+	cookies = pin("Gone", false, cookies)
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("unpinning all favorites does not delete the cookie: %+v", cookies)
+	}
+	w = httptest.NewRecorder()
+	s.handleApps(w, get(nil))
+	if strings.Contains(w.Body.String(), ">Favorites<") || strings.Contains(w.Body.String(), ">unpin<") {
+		t.Error("apps page without favorites shows them")
+	}
+
+	// A tampered cookie with invalid names is cleaned up.
+	bad := []*http.Cookie{{Name: favoritesCookieName, Value: "WyIiLCJhIiwiYSJd"}} // ["","a","a"]
+	if got := readFavorites(get(bad)); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("tampered favorites = %q, want [a]", got)
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// With ENABLE_FILE_DOWNLOAD, the container page can download a single file
+// (as is) or a directory (as tar) from a container, via the archive endpoint
+// of the API, so grabbing a config file or log does not need `podman cp` on
+// the host. With ENABLE_FILE_UPLOAD, it can put a file or extract a tar into
+// a directory of the container, the fastest way to hotfix a config before a
+// proper rebuild. Both are recorded in the action log. Secrets mounted into
+// the container (see secretPath) can neither be downloaded nor browsed.
+
+// maxDownloadSize bounds downloaded files and directory archives.
+// Directories are buffered to check the size before sending them.
+const maxDownloadSize = 32 << 20
+
+// maxUploadSize bounds uploaded files and archives.
+const maxUploadSize = 32 << 20
+
+// maxContainerPath bounds the length of container paths.
+const maxContainerPath = 4096
+
+// errTooLarge is returned for downloads over maxDownloadSize.
+var errTooLarge = fmt.Errorf("larger than %d MiB", maxDownloadSize>>20)
+
+// validContainerPath reports whether p is a clean absolute path in a
+// container other than the root directory.
+func validContainerPath(p string) bool {
+	return strings.HasPrefix(p, "/") && p != "/" && path.Clean(p) == p && len(p) <= maxContainerPath &&
+		!strings.ContainsFunc(p, func(r rune) bool { return r < 0x20 || r == 0x7f })
+}
+
+// secretsDir is where Podman and Docker (compose) mount secrets into
+// containers; /var/run is a symlink to /run in most images.
+const secretsDir = "/run/secrets"
+
+// secretPath reports whether p is in the secrets directory or, with parents,
+// a directory whose archive would contain it.
+func secretPath(p string, parents bool) bool {
+	if rest, ok := strings.CutPrefix(p, "/var/run"); ok && (rest == "" || rest[0] == '/') {
+		p = "/run" + rest
+	}
+	return p == secretsDir || strings.HasPrefix(p, secretsDir+"/") ||
+		parents && p != "" && (p == "/" || strings.HasPrefix(secretsDir, p+"/"))
+}
+
+// archiveLinkTarget returns the target of the path of an archive response
+// if it is a symlink, which the API follows, from the stat header.
+func archiveLinkTarget(p string, h http.Header) string {
+	b, err := base64.StdEncoding.DecodeString(h.Get("X-Docker-Container-Path-Stat"))
+	if err != nil {
+		return ""
+	}
+	var stat struct {
+		LinkTarget string `json:"linkTarget"`
+	}
+	if json.Unmarshal(b, &stat) != nil || stat.LinkTarget == "" {
+		return ""
+	}
+	return linkTarget(p, stat.LinkTarget)
+}
+
+// download is a file or directory archive read from a container.
+type download struct {
+	name        string
+	contentType string
+	size        int64
+	body        io.Reader
+}
+
+// spool buffers the archive of a directory, up to maxDownloadSize. It
+// stops buffering once off is set.
+type spool struct {
+	buf bytes.Buffer
+	off bool
+}
+
+func (sp *spool) Write(p []byte) (int, error) {
+	if sp.off {
+		return len(p), nil
+	}
+	if sp.buf.Len()+len(p) > maxDownloadSize {
+		return 0, errTooLarge
+	}
+	return sp.buf.Write(p)
+}
+
+// readDownload reads the tar stream of the archive endpoint. A regular file
+// is returned as its contents, a directory as the whole archive.
+func readDownload(archive io.Reader) (download, error) {
+	sp := &spool{}
+	tr := tar.NewReader(io.TeeReader(archive, sp))
+	hdr, err := tr.Next()
+	if err != nil {
+		return download{}, fmt.Errorf("reading archive: %w", err)
+	}
+	name := path.Base(hdr.Name)
+	switch hdr.Typeflag {
+	case tar.TypeReg:
+		if hdr.Size > maxDownloadSize {
+			return download{}, errTooLarge
+		}
+		sp.off = true
+		return download{name: name, contentType: "application/octet-stream", size: hdr.Size, body: tr}, nil
+	case tar.TypeDir:
+		for {
+			_, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if errors.Is(err, errTooLarge) {
+				return download{}, errTooLarge
+			}
+			if err != nil {
+				return download{}, fmt.Errorf("reading archive: %w", err)
+			}
+		}
+		// Keep the zero blocks after the last entry the tar reader stops at.
+		if _, err := io.Copy(io.Discard, io.TeeReader(archive, sp)); err != nil {
+			if errors.Is(err, errTooLarge) {
+				return download{}, errTooLarge
+			}
+			return download{}, fmt.Errorf("reading archive: %w", err)
+		}
+		return download{name: name + ".tar", contentType: "application/x-tar", size: int64(sp.buf.Len()), body: &sp.buf}, nil
+	case tar.TypeSymlink:
+		return download{}, fmt.Errorf("is a symlink to %s", hdr.Linkname)
+	}
+	return download{}, errors.New("not a regular file or directory")
+}
+
+func (s *Server) handleContainerDownload(w http.ResponseWriter, r *http.Request) {
+	if !s.enableFileDownload {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid container ID", http.StatusBadRequest)
+		return
+	}
+	p := r.URL.Query().Get("path")
+	if !validContainerPath(p) {
+		http.Error(w, "Invalid path, want a clean absolute path like /etc/app/config.yml", http.StatusBadRequest)
+		return
+	}
+	if secretPath(p, true) {
+		http.Error(w, "Secrets cannot be downloaded", http.StatusForbidden)
+		return
+	}
+	c, err := s.inspectContainer(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Container Not Found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	target := c.Name + ":" + p
+	resp, err := s.podmanDo(r.Context(), http.MethodGet, "/containers/"+c.ID+"/archive?path="+url.QueryEscape(p), nil)
+	if errors.Is(err, errNotFound) {
+		s.actions.record(r, "download", target, err, "", nil)
+		http.Error(w, "File Not Found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.actions.record(r, "download", target, err, "", nil)
+		log.Printf("[%s] download %s: %v", reqID(r.Context()), target, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+	if secretPath(archiveLinkTarget(p, resp.Header), true) {
+		http.Error(w, "Secrets cannot be downloaded", http.StatusForbidden)
+		return
+	}
+	d, err := readDownload(resp.Body)
+	s.actions.record(r, "download", target, err, "", nil)
+	if errors.Is(err, errTooLarge) {
+		http.Error(w, "File too large, the limit is "+strconv.Itoa(maxDownloadSize>>20)+" MiB", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		log.Printf("[%s] download %s: %v", reqID(r.Context()), target, err)
+		http.Error(w, "Cannot download this path: only regular files and directories are supported", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", d.contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(d.size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": d.name}))
+	io.CopyN(w, d.body, d.size)
+}
+
+// uploadName returns the base name of an uploaded file, or false if it has
+// none. Some browsers send Windows paths.
+func uploadName(filename string) (string, bool) {
+	name := path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if name == "." || name == ".." || name == "/" || strings.ContainsFunc(name, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+		return "", false
+	}
+	return name, true
+}
+
+// checkUploadArchive checks that the entries of a tar archive are regular
+// files, directories or symlinks that stay inside the target directory.
+// Symlinks must be relative and point inside it too, so later entries or
+// the container cannot write through them to e.g. /etc.
+func checkUploadArchive(archive io.Reader) error {
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(hdr.Name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("entry %q is outside the target directory", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeDir:
+		case tar.TypeSymlink:
+			target := path.Join(path.Dir(name), hdr.Linkname)
+			if hdr.Linkname == "" || path.IsAbs(hdr.Linkname) || target == ".." || strings.HasPrefix(target, "../") {
+				return fmt.Errorf("symlink %q points outside the target directory", hdr.Name)
+			}
+		default:
+			return fmt.Errorf("entry %q is not a regular file, directory or symlink", hdr.Name)
+		}
+	}
+}
+
+// singleFileArchive returns a tar archive with one file.
+func singleFileArchive(name string, size int64, content io.Reader) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: size, ModTime: time.Now()})
+	if err == nil {
+		_, err = io.CopyN(tw, content, size)
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	return &buf, err
+}
+
+func (s *Server) handleContainerUpload(w http.ResponseWriter, r *http.Request) {
+	if !s.enableFileUpload {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid container ID", http.StatusBadRequest)
+		return
+	}
+	dir := r.FormValue("path")
+	if !validContainerPath(dir) {
+		http.Error(w, "Invalid directory, want a clean absolute path like /etc/app", http.StatusBadRequest)
+		return
+	}
+	if r.FormValue("confirm") != "yes" {
+		http.Error(w, "Confirm that the upload may overwrite files in the container", http.StatusBadRequest)
+		return
+	}
+	f, fh, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "No file uploaded", http.StatusBadRequest)
+		return
+	}
+	defer f.Close()
+	if fh.Size > maxUploadSize {
+		http.Error(w, "File too large, the limit is "+strconv.Itoa(maxUploadSize>>20)+" MiB", http.StatusRequestEntityTooLarge)
+		return
+	}
+	name, ok := uploadName(fh.Filename)
+	if !ok {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+	extract := r.FormValue("extract") == "yes"
+	var archive io.Reader = f
+	target := path.Join(dir, name)
+	if extract {
+		if err := checkUploadArchive(f); err != nil {
+			log.Printf("[%s] upload %s: %v", reqID(r.Context()), name, err)
+			http.Error(w, "Invalid tar archive: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			log.Printf("[%s] upload %s: %v", reqID(r.Context()), name, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		target = dir + " (extracted " + name + ")"
+	} else if archive, err = singleFileArchive(name, fh.Size, f); err != nil {
+		log.Printf("[%s] upload %s: %v", reqID(r.Context()), name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	c, err := s.inspectContainer(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Container Not Found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	resp, err := s.podmanDo(r.Context(), http.MethodPut, "/containers/"+c.ID+"/archive?path="+url.QueryEscape(dir), archive)
+	if err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	s.actions.record(r, "upload", c.Name+":"+target, err, "", nil)
+	if errors.Is(err, errNotFound) {
+		http.Error(w, "Directory Not Found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[%s] upload %s: %v", reqID(r.Context()), target, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, s.basePath+"/container/"+c.ID, http.StatusSeeOther)
+}
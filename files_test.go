@@ -0,0 +1,325 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestValidContainerPath(t *testing.T) {
+	t.Parallel()
+	for p, want := range map[string]bool{
+		"/etc/app/config.yml": true,
+		"/var/log":            true,
+		"/":                   false,
+		"":                    false,
+		"etc/hosts":           false,
+		"/etc/../root":        false,
+		"/etc/":               false,
+		"/etc//hosts":         false,
+		"/tmp/a\nb":           false,
+		"/" + strings.Repeat("a", maxContainerPath): false,
+	} {
+		if got := validContainerPath(p); got != want {
+			t.Errorf("validContainerPath(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestSecretPath(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		p       string
+		parents bool
+		want    bool
+	}{
+		{"/run/secrets", false, true},
+		{"/run/secrets/db_password", false, true},
+		{"/var/run/secrets/db_password", false, true},
+		{"/run", false, false},
+		{"/run", true, true},
+		{"/var/run", true, true},
+		{"/run/secretsx", true, false},
+		{"/var/runner/secrets", false, false},
+		{"/etc/app.conf", true, false},
+		{"", true, false},
+	}
+	for _, tt := range tests {
+		if got := secretPath(tt.p, tt.parents); got != tt.want {
+			t.Errorf("secretPath(%q, %v) = %v, want %v", tt.p, tt.parents, got, tt.want)
+		}
+	}
+}
+
+func TestArchiveLinkTarget(t *testing.T) {
+	t.Parallel()
+	h := http.Header{}
+	h.Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(`{"name":"key","linkTarget":"../run/secrets/key"}`)))
+	if got := archiveLinkTarget("/etc/key", h); got != "/run/secrets/key" {
+		t.Errorf("archiveLinkTarget = %q", got)
+	}
+	if got := archiveLinkTarget("/etc/key", http.Header{}); got != "" {
+		t.Errorf("archiveLinkTarget without header = %q", got)
+	}
+}
+
+// testArchive returns a tar stream with the given headers, with contents of
+// the header size for regular files.
+func testArchive(t *testing.T, hdrs ...tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, h := range hdrs {
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Typeflag == tar.TypeReg {
+			tw.Write(bytes.Repeat([]byte("x"), int(h.Size)))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadDownload(t *testing.T) {
+	t.Parallel()
+	d, err := readDownload(bytes.NewReader(testArchive(t, tar.Header{Name: "config.yml", Typeflag: tar.TypeReg, Size: 5, Mode: 0o644})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(d.body); d.name != "config.yml" || d.size != 5 || string(body) != "xxxxx" {
+		t.Errorf("file download = %+v, %q", d, body)
+	}
+
+	archive := testArchive(t,
+		tar.Header{Name: "log/", Typeflag: tar.TypeDir, Mode: 0o755},
+		tar.Header{Name: "log/a.log", Typeflag: tar.TypeReg, Size: 3, Mode: 0o644},
+	)
+	d, err = readDownload(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(d.body); d.name != "log.tar" || !bytes.Equal(body, archive) {
+		t.Errorf("directory download = %s, %d bytes, want the whole archive (%d bytes)", d.name, len(body), len(archive))
+	}
+
+	if _, err := readDownload(bytes.NewReader(testArchive(t, tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/hosts"}))); err == nil {
+		t.Error("no error for a symlink")
+	}
+
+	var big bytes.Buffer
+	tw := tar.NewWriter(&big)
+	tw.WriteHeader(&tar.Header{Name: "big.img", Typeflag: tar.TypeReg, Size: maxDownloadSize + 1})
+	if _, err := readDownload(&big); !errors.Is(err, errTooLarge) {
+		t.Errorf("large file: err = %v, want errTooLarge", err)
+	}
+}
+
+func TestContainerDownload(t *testing.T) {
+	t.Parallel()
+	archive := testArchive(t, tar.Header{Name: "app.conf", Typeflag: tar.TypeReg, Size: 4, Mode: 0o644})
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4.0.0/libpod/containers/web/json":
+			w.Write([]byte(`{"Id":"c1","Name":"web","State":{"Status":"running"}}`))
+		case "/v4.0.0/libpod/containers/c1/archive":
+			if r.URL.Query().Get("path") == "/etc/key" {
+				// A symlink to a secret, followed by the API.
+				w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(`{"linkTarget":"/run/secrets/key"}`)))
+				w.Write(archive)
+				return
+			}
+			if r.URL.Query().Get("path") != "/etc/app.conf" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(archive)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+	if resp, _ := get("/container/web/download?path=/etc/app.conf"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("download disabled: status %d, want 404", resp.StatusCode)
+	}
+
+	s.enableFileDownload = true
+	resp, body := get("/container/web/download?path=/etc/app.conf")
+	if resp.StatusCode != http.StatusOK || body != "xxxx" || resp.Header.Get("Content-Disposition") != `attachment; filename=app.conf` {
+		t.Errorf("download: status %d, %q, Content-Disposition %q", resp.StatusCode, body, resp.Header.Get("Content-Disposition"))
+	}
+	if resp, _ := get("/container/web/download?path=/etc/../etc/app.conf"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unclean path: status %d, want 400", resp.StatusCode)
+	}
+	if resp, _ := get("/container/web/download?path=/etc/missing"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing file: status %d, want 404", resp.StatusCode)
+	}
+	for _, p := range []string{"/run/secrets/db_password", "/run", "/etc/key"} {
+		if resp, _ := get("/container/web/download?path=" + p); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("secret %s: status %d, want 403", p, resp.StatusCode)
+		}
+	}
+	if entries := s.actions.list(); len(entries) != 2 || entries[0].Name != "download" {
+		t.Errorf("action log = %+v, want 2 downloads", entries)
+	}
+}
+
+func TestUploadName(t *testing.T) {
+	t.Parallel()
+	for in, want := range map[string]string{
+		"app.conf":             "app.conf",
+		`C:\Users\me\app.conf`: "app.conf",
+		"../../etc/passwd":     "passwd",
+		"..":                   "",
+		"":                     "",
+		"bad\nname":            "",
+	} {
+		if got, ok := uploadName(in); got != want || ok != (want != "") {
+			t.Errorf("uploadName(%q) = %q, %v, want %q", in, got, ok, want)
+		}
+	}
+}
+
+func TestCheckUploadArchive(t *testing.T) {
+	t.Parallel()
+	ok := testArchive(t,
+		tar.Header{Name: "conf/", Typeflag: tar.TypeDir, Mode: 0o755},
+		tar.Header{Name: "conf/app.conf", Typeflag: tar.TypeReg, Size: 2, Mode: 0o644},
+		tar.Header{Name: "conf/current", Typeflag: tar.TypeSymlink, Linkname: "app.conf"},
+		tar.Header{Name: "conf/self", Typeflag: tar.TypeSymlink, Linkname: "../conf/app.conf"},
+	)
+	if err := checkUploadArchive(bytes.NewReader(ok)); err != nil {
+		t.Errorf("valid archive: %v", err)
+	}
+	for _, h := range []tar.Header{
+		{Name: "/etc/passwd", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "conf/../../x", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "dev", Typeflag: tar.TypeChar, Mode: 0o644},
+		{Name: "link", Typeflag: tar.TypeLink, Linkname: "conf/app.conf"},
+		{Name: "shadow", Typeflag: tar.TypeSymlink, Linkname: "/etc/shadow"},
+		{Name: "conf/up", Typeflag: tar.TypeSymlink, Linkname: "../../etc"},
+		{Name: "conf/up", Typeflag: tar.TypeSymlink, Linkname: "app/../../.."},
+	} {
+		if err := checkUploadArchive(bytes.NewReader(testArchive(t, h))); err == nil {
+			t.Errorf("no error for entry %q", h.Name)
+		}
+	}
+}
+
+func TestContainerUpload(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var got []string // name=contents of the uploaded entries
+	var dir string
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v4.0.0/libpod/containers/web/json":
+			w.Write([]byte(`{"Id":"c1","Name":"web","State":{"Status":"running"}}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/v4.0.0/libpod/containers/c1/archive":
+			mu.Lock()
+			defer mu.Unlock()
+			dir = r.URL.Query().Get("path")
+			tr := tar.NewReader(r.Body)
+			for {
+				hdr, err := tr.Next()
+				if err != nil {
+					break
+				}
+				b, _ := io.ReadAll(tr)
+				got = append(got, hdr.Name+"="+string(b))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableFileUpload = true
+	app := httptest.NewServer(s.csrfProtect(s.newMux("podman")))
+	defer app.Close()
+	const token = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	upload := func(fields map[string]string, filename string, content []byte) *http.Response {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField(csrfFormField, token)
+		for k, v := range fields {
+			mw.WriteField(k, v)
+		}
+		fw, _ := mw.CreateFormFile("file", filename)
+		fw.Write(content)
+		mw.Close()
+		req, _ := http.NewRequest(http.MethodPost, app.URL+"/container/web/upload", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: token})
+		resp, err := noRedirect.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := upload(map[string]string{"path": "/etc/app"}, "app.conf", []byte("a=1")); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unconfirmed upload: status %d, want 400", resp.StatusCode)
+	}
+	if resp := upload(map[string]string{"path": "/etc/app", "confirm": "yes"}, "app.conf", []byte("a=1")); resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("upload: status %d, want 303", resp.StatusCode)
+	}
+	archive := testArchive(t, tar.Header{Name: "x.conf", Typeflag: tar.TypeReg, Size: 1, Mode: 0o644})
+	if resp := upload(map[string]string{"path": "/etc", "confirm": "yes", "extract": "yes"}, "conf.tar", archive); resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("archive upload: status %d, want 303", resp.StatusCode)
+	}
+	mu.Lock()
+	if strings.Join(got, ",") != "app.conf=a=1,x.conf=x" || dir != "/etc" {
+		t.Errorf("uploaded %v to %s", got, dir)
+	}
+	mu.Unlock()
+	if entries := s.actions.list(); len(entries) != 2 || entries[0].Target != "web:/etc (extracted conf.tar)" {
+		t.Errorf("action log = %+v", entries)
+	}
+
+	// Bodies over the limit are refused while parsing.
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("--b\r\nContent-Disposition: form-data; name=\"file\"; filename=\"big\"\r\n\r\n"))
+		pw.Write(make([]byte, maxPostBody))
+		pw.Close()
+	}()
+	req, _ := http.NewRequest(http.MethodPost, app.URL+"/container/web/upload", pr)
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=b")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("large upload: status %d, want 413", resp.StatusCode)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Port forwards are temporary TCP proxies from a port on the podfather host
+// to a port on a container's IP address, for debugging containers without
+// published ports. They expire on their own and are not restored after a
+// restart. The container IP must be reachable from podfather, which is not
+// the case for rootless containers using slirp4netns or pasta.
+
+const (
+	defaultForwardTTL = 15 * time.Minute
+	maxForwardTTL     = 4 * time.Hour
+)
+
+// portForward is an active port forward.
+type portForward struct {
+	ID        string
+	Container string // container name
+	Target    string // container IP:port
+	Listen    string // local address
+	Expires   time.Time
+
+	ln    net.Listener
+	timer *time.Timer
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// forwardStore holds the active port forwards by ID.
+type forwardStore struct {
+	mu       sync.Mutex
+	forwards map[string]*portForward
+}
+
+// start listens on listenAddr and proxies connections to target until ttl
+// has passed or the forward is stopped.
+func (fs *forwardStore) start(container, target, listenAddr string, ttl time.Duration) (*portForward, error) {
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, err
+	}
+	var b [8]byte
+	rand.Read(b[:])
+	f := &portForward{
+		ID:        fmt.Sprintf("%x", b),
+		Container: container,
+		Target:    target,
+		Listen:    ln.Addr().String(),
+		Expires:   time.Now().Add(ttl),
+		ln:        ln,
+		conns:     map[net.Conn]struct{}{},
+	}
+
+	fs.mu.Lock()
+	if fs.forwards == nil {
+		fs.forwards = map[string]*portForward{}
+	}
+	fs.forwards[f.ID] = f
+	fs.mu.Unlock()
+
+	f.timer = time.AfterFunc(ttl, func() {
+		log.Printf("port forward %s -> %s (%s) expired", f.Listen, f.Target, f.Container)
+		fs.stop(f.ID)
+	})
+	go f.serve()
+	return f, nil
+}
+
+// stop closes the forward and all its connections. It returns false if
+// there is no forward with that ID.
+func (fs *forwardStore) stop(id string) bool {
+	fs.mu.Lock()
+	f := fs.forwards[id]
+	delete(fs.forwards, id)
+	fs.mu.Unlock()
+	if f == nil {
+		return false
+	}
+	f.timer.Stop()
+	f.ln.Close()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for c := range f.conns {
+		c.Close()
+	}
+	return true
+}
+
+// list returns the active forwards, soonest expiring first.
+func (fs *forwardStore) list() []*portForward {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	out := make([]*portForward, 0, len(fs.forwards))
+	for _, f := range fs.forwards {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Expires.Before(out[j].Expires) })
+	return out
+}
+
+// Conns returns the number of open connections.
+func (f *portForward) Conns() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.conns)
+}
+
+func (f *portForward) serve() {
+	for {
+		c, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.proxy(c)
+	}
+}
+
+// track adds or removes a connection. Adding fails once the forward is
+// stopped, so no connection outlives it.
+func (f *portForward) track(c net.Conn, add bool) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !add {
+		delete(f.conns, c)
+		return true
+	}
+	if f.closed {
+		return false
+	}
+	f.conns[c] = struct{}{}
+	return true
+}
+
+func (f *portForward) proxy(client net.Conn) {
+	defer client.Close()
+	upstream, err := net.DialTimeout("tcp", f.Target, 10*time.Second)
+	if err != nil {
+		log.Printf("port forward %s -> %s: %v", f.Listen, f.Target, err)
+		return
+	}
+	defer upstream.Close()
+	if !f.track(client, true) || !f.track(upstream, true) {
+		return
+	}
+	defer f.track(client, false)
+	defer f.track(upstream, false)
+
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(upstream, client); done <- struct{}{} }()
+	go func() { io.Copy(client, upstream); done <- struct{}{} }()
+	<-done
+}
+
+// containerIP returns the first IP address of the container on any network.
+func containerIP(c ContainerInspect) string {
+	if c.NetworkSettings == nil {
+		return ""
+	}
+	if c.NetworkSettings.IPAddress != "" {
+		return c.NetworkSettings.IPAddress
+	}
+	names := make([]string, 0, len(c.NetworkSettings.Networks))
+	for name := range c.NetworkSettings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ip := c.NetworkSettings.Networks[name].IPAddress; ip != "" {
+			return ip
+		}
+	}
+	return ""
+}
+
+var errNoContainerIP = errors.New("container has no reachable IP address")
+
+// handleContainerForward starts a port forward to the container port from
+// the form, on the chosen local port (or a random one) on the host podfather
+// listens on.
+func (s *Server) handleContainerForward(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid container ID", http.StatusBadRequest)
+		return
+	}
+	port, err := strconv.ParseUint(r.FormValue("port"), 10, 16)
+	if err != nil || port == 0 {
+		http.Error(w, "Invalid container port", http.StatusBadRequest)
+		return
+	}
+	var listenPort uint64
+	if v := r.FormValue("listen"); v != "" {
+		if listenPort, err = strconv.ParseUint(v, 10, 16); err != nil {
+			http.Error(w, "Invalid local port", http.StatusBadRequest)
+			return
+		}
+	}
+	ttl := defaultForwardTTL
+	if v := r.FormValue("minutes"); v != "" {
+		m, err := strconv.Atoi(v)
+		if err != nil || m <= 0 || time.Duration(m)*time.Minute > maxForwardTTL {
+			http.Error(w, fmt.Sprintf("Invalid duration, want 1 to %d minutes", int(maxForwardTTL.Minutes())), http.StatusBadRequest)
+			return
+		}
+		ttl = time.Duration(m) * time.Minute
+	}
+
+	c, err := s.inspectContainer(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Container Not Found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	ip := containerIP(c)
+	if ip == "" {
+		s.actions.record(r, "port forward", c.Name, errNoContainerIP, "", nil)
+		http.Error(w, "Container has no reachable IP address (rootless networking?)", http.StatusConflict)
+		return
+	}
+	target := net.JoinHostPort(ip, strconv.FormatUint(port, 10))
+	f, err := s.forwards.start(c.Name, target, net.JoinHostPort(s.listenHost, strconv.FormatUint(listenPort, 10)), ttl)
+	if err != nil {
+		s.actions.record(r, "port forward", c.Name, err, "", nil)
+		http.Error(w, "Could not listen on the local port", http.StatusConflict)
+		return
+	}
+	s.actions.record(r, "port forward", c.Name+" "+f.Listen+" -> "+target, nil, "stop forward", func(context.Context) error {
+		if !s.forwards.stop(f.ID) {
+			return errors.New("port forward already stopped")
+		}
+		return nil
+	})
+	http.Redirect(w, r, s.basePath+"/forwards", http.StatusSeeOther)
+}
+
+func (s *Server) handleForwards(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	s.render(w, r, "forwards.html", map[string]any{
+		"Title":    "Port Forwards",
+		"Forwards": s.forwards.list(),
+	})
+}
+
+func (s *Server) handleForwardStop(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if !s.forwards.stop(id) {
+		http.Error(w, "Port Forward Not Found", http.StatusNotFound)
+		return
+	}
+	s.actions.record(r, "stop port forward", id, nil, "", nil)
+	http.Redirect(w, r, s.basePath+"/forwards", http.StatusSeeOther)
+}
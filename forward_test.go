@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPortForward(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			c, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() { io.Copy(c, c); c.Close() }()
+		}
+	}()
+
+	var fs forwardStore
+	f, err := fs.start("web", echo.Addr().String(), "127.0.0.1:0", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := net.Dial("tcp", f.Listen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	io.WriteString(c, "ping\n")
+	line, err := bufio.NewReader(c).ReadString('\n')
+	if err != nil || line != "ping\n" {
+		t.Fatalf("echo = %q, %v", line, err)
+	}
+	if n := len(fs.list()); n != 1 {
+		t.Errorf("list() has %d forwards, want 1", n)
+	}
+
+	if !fs.stop(f.ID) {
+		t.Fatal("stop() = false")
+	}
+	if fs.stop(f.ID) {
+		t.Error("second stop() = true")
+	}
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.Read(make([]byte, 1)); err == nil {
+		t.Error("connection still open after stop")
+	}
+	if _, err := net.Dial("tcp", f.Listen); err == nil {
+		t.Error("listener still open after stop")
+	}
+
+	f, err = fs.start("web", echo.Addr().String(), "127.0.0.1:0", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(fs.list()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(fs.list()) > 0 {
+		t.Error("forward did not expire")
+	}
+}
+
+func TestContainerIP(t *testing.T) {
+	c := ContainerInspect{NetworkSettings: &NetworkSettings{
+		Networks: map[string]NetworkEndpoint{"b": {IPAddress: "10.89.0.3"}, "a": {}},
+	}}
+	if got := containerIP(c); got != "10.89.0.3" {
+		t.Errorf("containerIP = %q", got)
+	}
+	c.NetworkSettings.IPAddress = "10.88.0.2"
+	if got := containerIP(c); got != "10.88.0.2" {
+		t.Errorf("containerIP = %q", got)
+	}
+	if got := containerIP(ContainerInspect{}); got != "" {
+		t.Errorf("containerIP = %q, want empty", got)
+	}
+}
+
+func TestContainerForwardHandler(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	s.listenHost = "127.0.0.1"
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	tests := []struct {
+		form       url.Values
+		wantStatus int
+	}{
+		{url.Values{"port": {"0"}}, http.StatusBadRequest},
+		{url.Values{"port": {"80"}, "minutes": {"100000"}}, http.StatusBadRequest},
+		{url.Values{"port": {"80"}, "listen": {"http"}}, http.StatusBadRequest},
+		{url.Values{"port": {"80"}, "minutes": {"5"}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp, err := http.PostForm(app.URL+"/container/jellyfin/forward", tt.form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("POST %v: status = %d, want %d", tt.form, resp.StatusCode, tt.wantStatus)
+		}
+	}
+
+	forwards := s.forwards.list()
+	if len(forwards) != 1 || forwards[0].Target != "10.89.0.43:80" || !strings.HasPrefix(forwards[0].Listen, "127.0.0.1:") {
+		t.Fatalf("forwards = %+v", forwards)
+	}
+	resp, err := http.Get(app.URL + "/forwards")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), forwards[0].Listen) {
+		t.Error("forwards page does not list the forward")
+	}
+
+	resp, err = http.Post(app.URL+"/forwards/"+forwards[0].ID+"/stop", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(s.forwards.list()) != 0 {
+		t.Errorf("stop: status = %d, %d forwards left", resp.StatusCode, len(s.forwards.list()))
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The container page offers the container, or the pod it belongs to, as
+// Kubernetes YAML generated by `podman generate kube`, to migrate it or to
+// share a reproducible config, and as a Quadlet .container file to move it
+// under systemd. Environment variable values are redacted, as everywhere in
+// the UI.
+
+// maxKubeYAML bounds the generated YAML read from the API.
+const maxKubeYAML = 1 << 20
+
+// redactKubeEnv replaces the values of the env entries in kube YAML with an
+// empty string and a comment.
+func redactKubeEnv(yaml string) string {
+	lines := strings.Split(yaml, "\n")
+	out := lines[:0]
+	envIndent := -1   // of the current env: key, -1 outside of one
+	blockIndent := -1 // of a value: key with a block scalar being dropped
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+		if blockIndent >= 0 {
+			if trimmed == "" || indent > blockIndent {
+				continue
+			}
+			blockIndent = -1
+		}
+		// Sequences may be indented as far as their key.
+		if envIndent >= 0 && trimmed != "" && (indent < envIndent || indent == envIndent && !strings.HasPrefix(trimmed, "- ")) {
+			envIndent = -1
+		}
+		if envIndent < 0 {
+			if trimmed == "env:" {
+				envIndent = indent
+			}
+			out = append(out, line)
+			continue
+		}
+		key := strings.TrimPrefix(trimmed, "- ")
+		value, ok := strings.CutPrefix(key, "value:")
+		if !ok {
+			out = append(out, line)
+			continue
+		}
+		keyIndent := len(line) - len(key)
+		if v := strings.TrimSpace(value); strings.HasPrefix(v, "|") || strings.HasPrefix(v, ">") {
+			blockIndent = keyIndent
+		}
+		out = append(out, line[:keyIndent]+`value: "" # redacted by podfather`)
+	}
+	return strings.Join(out, "\n")
+}
+
+func (s *Server) handleContainerKube(w http.ResponseWriter, r *http.Request) {
+	if s.backend == backendDocker {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	c, ok := s.inspectForAction(w, r)
+	if !ok {
+		return
+	}
+	name := strings.TrimPrefix(c.Name, "/")
+	if r.URL.Query().Get("pod") != "" {
+		if c.Pod == "" {
+			http.Error(w, "The container is not in a pod", http.StatusBadRequest)
+			return
+		}
+		name = c.Pod
+	}
+	resp, err := s.podmanDo(r.Context(), http.MethodGet, "/generate/kube?"+url.Values{"names": {name}}.Encode(), nil)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[%s] generate kube %s: %v", reqID(r.Context()), name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+	yaml, err := io.ReadAll(io.LimitReader(resp.Body, maxKubeYAML))
+	if err != nil {
+		log.Printf("[%s] generate kube %s: %v", reqID(r.Context()), name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	file := unsafeFilenameChars.ReplaceAllString(name, "_") + ".yaml"
+	if name == c.Pod {
+		file = "pod-" + shortID(c.Pod) + ".yaml"
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+file+`"`)
+	io.WriteString(w, redactKubeEnv(string(yaml)))
+}
+
+// quadletRestart maps restart policies to systemd Restart= values.
+var quadletRestart = map[string]string{
+	"always":         "always",
+	"unless-stopped": "always",
+	"on-failure":     "on-failure",
+}
+
+// quadletValue quotes s for a Quadlet key if it contains spaces, quotes or
+// backslashes.
+func quadletValue(s string) string {
+	if strings.ContainsAny(s, " \t\n\"'\\") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// generateQuadlet returns a Quadlet .container file that recreates c from
+// its inspect data: image, published ports, volumes and bind mounts,
+// networks, labels (apart from the image's own), secrets and the restart
+// policy. Environment variables are never read, so they are left out.
+func generateQuadlet(c ContainerInspect, imageLabels map[string]string) string {
+	name := strings.TrimPrefix(c.Name, "/")
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s.container, generated by podfather from the inspect data of the\n", name)
+	b.WriteString("# container. Environment variables are not included; add them with\n")
+	b.WriteString("# Environment= or EnvironmentFile=. Review it before use, e.g. with\n")
+	b.WriteString("# /usr/libexec/podman/quadlet -dryrun.\n\n")
+	fmt.Fprintf(&b, "[Unit]\nDescription=%s\n\n[Container]\n", quadletValue(name))
+	fmt.Fprintf(&b, "ContainerName=%s\n", quadletValue(name))
+	fmt.Fprintf(&b, "Image=%s\n", quadletValue(c.ImageName))
+
+	labels := maps.Clone(c.Config.Labels)
+	if policy := labels["io.containers.autoupdate"]; policy != "" {
+		fmt.Fprintf(&b, "AutoUpdate=%s\n", quadletValue(policy))
+	}
+	// Set by Podman and Quadlet themselves.
+	delete(labels, "io.containers.autoupdate")
+	delete(labels, systemdUnitLabel)
+
+	if ns := c.NetworkSettings; ns != nil {
+		var ports []string
+		for port, bindings := range ns.Ports {
+			containerPort, proto, _ := strings.Cut(port, "/")
+			if proto != "" && proto != "tcp" {
+				containerPort += "/" + proto
+			}
+			for _, hp := range bindings {
+				host := hp.HostPort
+				if hp.HostIP != "" && hp.HostIP != "0.0.0.0" && hp.HostIP != "::" {
+					host = hp.HostIP + ":" + host
+				}
+				ports = append(ports, host+":"+containerPort)
+			}
+		}
+		slices.Sort(ports)
+		for _, p := range ports {
+			fmt.Fprintf(&b, "PublishPort=%s\n", p)
+		}
+	}
+	for _, m := range c.Mounts {
+		src := m.Source
+		switch m.Type {
+		case "volume":
+			if anonymousVolume.MatchString(m.Name) {
+				src = "" // a new anonymous volume
+			} else {
+				src = m.Name
+			}
+		case "bind":
+		default:
+			continue
+		}
+		v := m.Destination
+		if src != "" {
+			v = src + ":" + v
+		}
+		if !m.RW {
+			v += ":ro"
+		}
+		fmt.Fprintf(&b, "Volume=%s\n", quadletValue(v))
+	}
+	if hc := c.HostConfig; hc != nil && (hc.NetworkMode == "host" || hc.NetworkMode == "none") {
+		fmt.Fprintf(&b, "Network=%s\n", hc.NetworkMode)
+	} else if c.NetworkSettings != nil {
+		for _, n := range slices.Sorted(maps.Keys(c.NetworkSettings.Networks)) {
+			if !slices.Contains(defaultNetworks, n) {
+				fmt.Fprintf(&b, "Network=%s\n", quadletValue(n))
+			}
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		if v, ok := imageLabels[k]; ok && v == labels[k] {
+			continue
+		}
+		fmt.Fprintf(&b, "Label=%s\n", quadletValue(k+"="+labels[k]))
+	}
+	for _, sec := range c.Config.Secrets {
+		fmt.Fprintf(&b, "Secret=%s\n", quadletValue(sec.Name))
+	}
+	if c.HostConfig != nil && c.HostConfig.ReadonlyRootfs {
+		b.WriteString("ReadOnly=true\n")
+	}
+
+	if c.HostConfig != nil {
+		if restart := quadletRestart[c.HostConfig.RestartPolicy.Name]; restart != "" {
+			fmt.Fprintf(&b, "\n[Service]\nRestart=%s\n", restart)
+		}
+	}
+	b.WriteString("\n[Install]\nWantedBy=default.target\n")
+	return b.String()
+}
+
+func (s *Server) handleContainerQuadlet(w http.ResponseWriter, r *http.Request) {
+	if s.backend == backendDocker {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	c, ok := s.inspectForAction(w, r)
+	if !ok {
+		return
+	}
+	// Containers inherit the labels of their image; those need not be set.
+	var imageLabels map[string]string
+	if img, err := s.inspectImage(r.Context(), c.Image); err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+	} else {
+		imageLabels = img.Labels
+	}
+	file := unsafeFilenameChars.ReplaceAllString(strings.TrimPrefix(c.Name, "/"), "_") + ".container"
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+file+`"`)
+	io.WriteString(w, generateQuadlet(c, imageLabels))
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testKubeYAML = `apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - name: web
+    env:
+    - name: DB_PASSWORD
+      value: hunter2
+    - name: CERT
+      value: |-
+        -----BEGIN CERTIFICATE-----
+        MIIB
+    - value: inline
+      name: FIRST
+    image: docker.io/library/nginx:latest
+    ports:
+    - containerPort: 80
+`
+
+func TestRedactKubeEnv(t *testing.T) {
+	t.Parallel()
+	got := redactKubeEnv(testKubeYAML)
+	for _, secret := range []string{"hunter2", "CERTIFICATE", "MIIB", "inline"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("redacted YAML contains %q:\n%s", secret, got)
+		}
+	}
+	for _, want := range []string{
+		"    - name: DB_PASSWORD\n      value: \"\" # redacted by podfather\n    - name: CERT\n",
+		"    - value: \"\" # redacted by podfather\n      name: FIRST\n    image: docker.io/library/nginx:latest\n",
+		"    - containerPort: 80\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("redacted YAML lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestContainerKube(t *testing.T) {
+	t.Parallel()
+	var names []string
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/generate/kube") {
+			names = append(names, r.URL.Query().Get("names"))
+			w.Write([]byte(testKubeYAML))
+			return
+		}
+		json.NewEncoder(w).Encode(ContainerInspect{ID: "abc123", Name: "web", Pod: strings.Repeat("9", 64)})
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	for _, tt := range []struct{ query, file string }{
+		{"", "web.yaml"},
+		{"?pod=1", "pod-999999999999.yaml"},
+	} {
+		resp, err := http.Get(app.URL + "/container/abc123/kube" + tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if strings.Contains(string(body), "hunter2") || !strings.Contains(string(body), "kind: Pod") {
+			t.Errorf("%q: body not redacted:\n%s", tt.query, body)
+		}
+		if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="`+tt.file+`"` {
+			t.Errorf("%q: Content-Disposition = %q", tt.query, got)
+		}
+	}
+	if len(names) != 2 || names[0] != "web" || names[1] != strings.Repeat("9", 64) {
+		t.Errorf("generated for %v", names)
+	}
+	resp, err := http.Get(app.URL + "/container/abc123/quadlet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="web.container"` || !strings.Contains(string(body), "ContainerName=web\n") {
+		t.Errorf("quadlet: Content-Disposition = %q, body:\n%s", got, body)
+	}
+}
+
+func TestGenerateQuadlet(t *testing.T) {
+	t.Parallel()
+	c := ContainerInspect{
+		Name:      "web",
+		ImageName: "docker.io/library/nginx:latest",
+		Config: ContainerConfig{
+			Labels: map[string]string{
+				"io.containers.autoupdate":      "registry",
+				"maintainer":                    "NGINX Docker Maintainers",
+				"ch.jo-m.go.podfather.app.name": "My Web",
+			},
+			Secrets: []SecretRef{{Name: "tls-key"}},
+		},
+		Mounts: []Mount{
+			{Type: "volume", Name: "web-data", Destination: "/data", RW: true},
+			{Type: "volume", Name: strings.Repeat("ab", 32), Destination: "/cache", RW: true},
+			{Type: "bind", Source: "/srv/conf", Destination: "/etc/nginx/conf.d"},
+			{Type: "tmpfs", Destination: "/tmp"},
+		},
+		NetworkSettings: &NetworkSettings{
+			Ports: map[string][]HostPort{
+				"80/tcp":  {{HostPort: "8080"}},
+				"53/udp":  {{HostIP: "127.0.0.1", HostPort: "5353"}},
+				"443/tcp": nil,
+			},
+			Networks: map[string]NetworkEndpoint{"podman": {}, "frontend": {}},
+		},
+		HostConfig: &HostConfig{RestartPolicy: RestartPolicy{Name: "unless-stopped"}, ReadonlyRootfs: true},
+	}
+	got := generateQuadlet(c, map[string]string{"maintainer": "NGINX Docker Maintainers"})
+	want := `[Container]
+ContainerName=web
+Image=docker.io/library/nginx:latest
+AutoUpdate=registry
+PublishPort=127.0.0.1:5353:53/udp
+PublishPort=8080:80
+Volume=web-data:/data
+Volume=/cache
+Volume=/srv/conf:/etc/nginx/conf.d:ro
+Network=frontend
+Label="ch.jo-m.go.podfather.app.name=My Web"
+Secret=tls-key
+ReadOnly=true
+
+[Service]
+Restart=always
+
+[Install]
+WantedBy=default.target
+`
+	if _, body, _ := strings.Cut(got, "\n[Container]\n"); "[Container]\n"+body != want {
+		t.Errorf("generateQuadlet =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gitConfig reads declarative dashboard configuration from a file in a Git
+// repository. The file uses env-file syntax with the same variables as the
+// environment (PODFATHER_APP_* and PODFATHER_CATEGORY_*), so a config can be moved between
+// the two unchanged. The checkout is reset to the remote on every sync, so
+// local edits to it are discarded.
+type gitConfig struct {
+	url      string
+	branch   string
+	file     string
+	dir      string
+	interval time.Duration
+	gitBin   string
+
+	syncMu sync.Mutex // serializes syncs
+
+	mu         sync.Mutex
+	apps       []App
+	categories map[string]Category
+	commit     string
+	synced     time.Time
+	err        error
+}
+
+// gitConfigStatus is a snapshot of the sync state for display.
+type gitConfigStatus struct {
+	URL    string
+	Branch string
+	File   string
+	Commit string
+	Synced time.Time
+	Err    string
+}
+
+func (g *gitConfig) status() gitConfigStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	st := gitConfigStatus{
+		URL:    redactURL(g.url),
+		Branch: g.branch,
+		File:   g.file,
+		Commit: g.commit,
+		Synced: g.synced,
+	}
+	if g.err != nil {
+		st.Err = g.err.Error()
+	}
+	return st
+}
+
+// externalApps returns the apps from the last successful sync.
+func (g *gitConfig) externalApps() []App {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.apps
+}
+
+// externalCategories returns the categories from the last successful sync.
+func (g *gitConfig) externalCategories() map[string]Category {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.categories
+}
+
+// sync clones or updates the checkout and re-reads the config file. On
+// failure the previously loaded config stays active.
+func (g *gitConfig) sync(ctx context.Context) error {
+	g.syncMu.Lock()
+	defer g.syncMu.Unlock()
+
+	err := g.fetch(ctx)
+	var commit string
+	var apps []App
+	var categories map[string]Category
+	if err == nil {
+		commit, err = g.git(ctx, "-C", g.dir, "rev-parse", "HEAD")
+	}
+	if err == nil {
+		apps, categories, err = g.load()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.err = err
+	if err != nil {
+		return err
+	}
+	g.apps = apps
+	g.categories = categories
+	g.commit = commit
+	g.synced = time.Now()
+	return nil
+}
+
+func (g *gitConfig) fetch(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(g.dir, ".git")); err != nil {
+		if err := os.MkdirAll(filepath.Dir(g.dir), 0o700); err != nil {
+			return err
+		}
+		args := []string{"clone", "--quiet", "--depth", "1"}
+		if g.branch != "" {
+			args = append(args, "--branch", g.branch)
+		}
+		_, err := g.git(ctx, append(args, "--", g.url, g.dir)...)
+		return err
+	}
+	if _, err := g.git(ctx, "-C", g.dir, "remote", "set-url", "origin", g.url); err != nil {
+		return err
+	}
+	args := []string{"-C", g.dir, "fetch", "--quiet", "--depth", "1", "origin"}
+	if g.branch != "" {
+		args = append(args, g.branch)
+	}
+	if _, err := g.git(ctx, args...); err != nil {
+		return err
+	}
+	_, err := g.git(ctx, "-C", g.dir, "reset", "--quiet", "--hard", "FETCH_HEAD")
+	return err
+}
+
+func (g *gitConfig) load() ([]App, map[string]Category, error) {
+	data, err := os.ReadFile(filepath.Join(g.dir, g.file))
+	if err != nil {
+		return nil, nil, err
+	}
+	environ, err := parseEnvFile(data)
+	if err == nil {
+		err = errors.Join(validateExternalApps(environ), validateCategories(environ))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", g.file, err)
+	}
+	return parseExternalApps(environ), parseCategories(environ), nil
+}
+
+func (g *gitConfig) git(ctx context.Context, args ...string) (string, error) {
+	var out bytes.Buffer
+	if err := runCommand(ctx, &out, g.gitBin, args...); err != nil {
+		// The URL may contain credentials; never log it.
+		return "", fmt.Errorf("git %s: %s", args[0], redactURL(strings.TrimSpace(out.String())))
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// run syncs immediately and then every interval until ctx is done. With a
+// zero interval, only the initial sync (and webhook-triggered syncs) run.
+func (g *gitConfig) run(ctx context.Context) {
+	for {
+		if err := g.sync(ctx); err != nil {
+			log.Printf("config git sync: %v", err)
+		}
+		if g.interval <= 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(g.interval):
+		}
+	}
+}
+
+// parseEnvFile parses KEY=VALUE lines. Blank lines and lines starting with
+// '#' are ignored, an optional "export " prefix is stripped and values may be
+// wrapped in single or double quotes.
+func parseEnvFile(data []byte) ([]string, error) {
+	var environ []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			if value[0] == '"' {
+				if uq, err := strconv.Unquote(value); err == nil {
+					value = uq
+				} else {
+					value = value[1 : len(value)-1]
+				}
+			} else {
+				value = value[1 : len(value)-1]
+			}
+		}
+		environ = append(environ, key+"="+value)
+	}
+	return environ, sc.Err()
+}
+
+// redactURL removes the password from any URLs in s.
+func redactURL(s string) string {
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		t := strings.Trim(f, `'"`)
+		if u, err := url.Parse(t); err == nil && u.User != nil {
+			fields[i] = strings.Replace(f, t, u.Redacted(), 1)
+		}
+	}
+	if len(fields) == 0 {
+		return s
+	}
+	return strings.Join(fields, " ")
+}
+
+func (s *Server) hookConfigSync(w http.ResponseWriter, r *http.Request) (string, error) {
+	if s.gitConfig == nil {
+		return "", hookError{http.StatusNotFound, "git config not enabled"}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+	if err := s.gitConfig.sync(ctx); err != nil {
+		return "", err
+	}
+	return "synced config at commit " + s.gitConfig.status().Commit, nil
+}
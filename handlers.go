@@ -1,24 +1,26 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"embed"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
-	"os"
-	"os/exec"
+	"net/url"
 	"regexp"
+	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -27,17 +29,26 @@ var templateFS embed.FS
 
 var funcMap = template.FuncMap{
 	"shortID":            shortID,
+	"asset":              assetName,
 	"humanSize":          humanSize,
 	"formatUnix":         formatUnix,
 	"formatTime":         formatTime,
 	"formatPorts":        formatPorts,
 	"formatExposedPorts": formatExposedPorts,
 	"firstName":          firstName,
+	"iconKey":            iconKey,
+	"markdown":           markdown,
 	"join":               joinStrings,
 	"mapKeys":            mapKeys,
 	"envName":            envName,
 	"envValue":           envValue,
-	"appState":           appState,
+	"appHealth":          appHealth,
+	"logTime":            logTime,
+	"pathEscape":         url.PathEscape,
+	"imageLabel":         imageLabel,
+	"themeCSS":           themeCSS,
+	"styleCSS":           styleCSS,
+	"hotZones":           hotZones,
 }
 
 func joinStrings(elems any, sep string) string {
@@ -96,14 +107,22 @@ func formatUnix(ts int64) template.HTML {
 		return "-"
 	}
 	t := time.Unix(ts, 0)
-	return template.HTML(fmt.Sprintf(`<span title="%s">%s</span>`, t.Format("2006-01-02 15:04:05 MST"), timeAgo(t)))
+	return template.HTML(fmt.Sprintf(`<span class="ago" title="%s">%s</span>`, t.Format("2006-01-02 15:04:05 MST"), timeAgo(t)))
 }
 
 func formatTime(t time.Time) template.HTML {
 	if t.IsZero() {
 		return "-"
 	}
-	return template.HTML(fmt.Sprintf(`<span title="%s">%s</span>`, t.Format("2006-01-02 15:04:05 MST"), timeAgo(t)))
+	return template.HTML(fmt.Sprintf(`<span class="ago" title="%s">%s</span>`, t.Format("2006-01-02 15:04:05 MST"), timeAgo(t)))
+}
+
+// logTime formats a log timestamp in local time with milliseconds.
+func logTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04:05.000")
 }
 
 func formatPorts(ports []Port) string {
@@ -176,12 +195,52 @@ var pageTemplates map[string]*template.Template
 
 func init() {
 	pages := []string{
+		"actions.html",
+		"app.html",
+		"apps-export.html",
+		"apps-import.html",
 		"apps.html",
-		"autoupdate.html",
+		"autolabels.html",
+		"bulk.html",
+		"compare.html",
+		"config.html",
+		"container-remove.html",
 		"container.html",
+		"containers-prune.html",
 		"containers.html",
+		"cpus.html",
+		"df.html",
+		"diagnostics.html",
+		"energy.html",
+		"files.html",
+		"forwards.html",
+		"history.html",
+		"image-remove.html",
+		"image-tags.html",
 		"image.html",
+		"images-search.html",
 		"images.html",
+		"job.html",
+		"jobs.html",
+		"layers.html",
+		"login.html",
+		"logs.html",
+		"overview.html",
+		"policy.html",
+		"ports.html",
+		"problems.html",
+		"prune.html",
+		"raw.html",
+		"requests.html",
+		"schedules.html",
+		"secrets.html",
+		"security.html",
+		"stacks.html",
+		"startorder.html",
+		"sysprune.html",
+		"templatedoc.html",
+		"themes.html",
+		"views.html",
 	}
 	pageTemplates = make(map[string]*template.Template, len(pages))
 	for _, page := range pages {
@@ -206,8 +265,25 @@ func generateCSRFToken() string {
 	return fmt.Sprintf("%x", b)
 }
 
+// maxPostBody bounds POST bodies apart from image loads, file uploads are the
+// largest. Multipart bodies over maxPostMemory are spooled to temporary files
+// while parsing.
+const (
+	maxPostBody   = maxUploadSize + 1<<20
+	maxPostMemory = 1 << 20
+)
+
+// csrfProtect checks the CSRF token of POST requests, parsing their body. It
+// must wrap the handler inside requireLogin and limitPosts, so bodies are
+// only parsed for signed-in users within their rate limit.
 func (s *Server) csrfProtect(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Webhooks authenticate with a bearer token and carry no cookies.
+		if strings.HasPrefix(r.URL.Path, s.basePath+hooksPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		var token string
 		if c, err := r.Cookie(csrfCookieName); err == nil && len(c.Value) == 64 {
 			token = c.Value
@@ -218,11 +294,30 @@ func (s *Server) csrfProtect(next http.Handler) http.Handler {
 				Value:    token,
 				Path:     s.basePath + "/",
 				HttpOnly: true,
+				Secure:   r.TLS != nil,
 				SameSite: http.SameSiteStrictMode,
 			})
 		}
 
 		if r.Method == http.MethodPost {
+			limit := int64(maxPostBody)
+			if r.URL.Path == s.basePath+"/images/load" {
+				limit = maxImageLoadSize + 1<<20
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			err := r.ParseMultipartForm(maxPostMemory)
+			if r.MultipartForm != nil {
+				defer r.MultipartForm.RemoveAll()
+			}
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+				return
+			case err != nil && !errors.Is(err, http.ErrNotMultipart):
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
 			if subtle.ConstantTimeCompare([]byte(r.FormValue(csrfFormField)), []byte(token)) != 1 {
 				http.Error(w, "Forbidden", http.StatusForbidden)
 				return
@@ -242,12 +337,8 @@ func (s *Server) render(w http.ResponseWriter, r *http.Request, page string, dat
 		return
 	}
 	if m, ok := data.(map[string]any); ok {
-		if token, ok := r.Context().Value(csrfTokenKey).(string); ok {
-			m["CSRFToken"] = token
-		}
-		m["BasePath"] = s.basePath
-		m["Hostname"] = s.hostname
-		m["EnableAutoUpdate"] = s.enableAutoUpdate
+		s.addPageData(r, m)
+		s.templateTypes.record(page, m)
 	}
 	var buf bytes.Buffer
 	if err := t.ExecuteTemplate(&buf, "base", data); err != nil {
@@ -255,28 +346,133 @@ func (s *Server) render(w http.ResponseWriter, r *http.Request, page string, dat
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	body := buf.Bytes()
+	if m := s.pageMasker(r); m != nil {
+		body = []byte(m.maskHTML(buf.String()))
+	}
+	writePage(w, r, body)
+}
+
+// relativeTime matches the relative times of formatTime and formatUnix,
+// whose text changes on every load; the title keeps the absolute time.
+var relativeTime = regexp.MustCompile(`(<span class="ago" title="[^"]*">)[^<]*`)
+
+// pageETag returns the ETag of a rendered page, ignoring relative times.
+func pageETag(body []byte) string {
+	sum := sha256.Sum256(relativeTime.ReplaceAll(body, []byte("$1")))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// writePage writes a rendered page with an ETag of its content, apart from
+// relative times like "5s ago". Browsers keep the page but revalidate it on
+// every load, so clients reloading an unchanged page get a 304 without the
+// body.
+func writePage(w http.ResponseWriter, r *http.Request, body []byte) {
+	etag := pageETag(body)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Header().Set("Cache-Control", "no-store")
-	w.Write(buf.Bytes())
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("ETag", etag)
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(body)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, ignoring
+// the weak prefix (W/) as the comparison for GET requests allows.
+func etagMatches(header, etag string) bool {
+	for tag := range strings.SplitSeq(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// addPageData adds the data every page template gets to m.
+func (s *Server) addPageData(r *http.Request, m map[string]any) {
+	if token, ok := r.Context().Value(csrfTokenKey).(string); ok {
+		m["CSRFToken"] = token
+	}
+	m["BasePath"] = s.basePath
+	m["Hostname"] = s.hostname
+	m["EnableAutoUpdate"] = s.enableAutoUpdate
+	m["EnableActions"] = s.enableActions
+	m["SavedViews"] = readViews(r)
+	m["Theme"] = s.theme(r)
+	m["CustomCSS"] = s.customCSS != ""
+	m["User"] = currentUser(r.Context())
+	m["Privacy"] = privacyMode(r)
+	if snap, ok := s.poller.current(); ok {
+		m["DataAsOf"] = snap.at
+	}
+	if r.Method == http.MethodGet {
+		m["ReturnPath"] = s.basePath + r.URL.RequestURI()
+	}
+}
+
+// returnPathField is the form field with the page a form was sent from,
+// filled from .ReturnPath. Browsers send no Referer (Referrer-Policy:
+// no-referrer).
+const returnPathField = "return"
+
+// returnPath returns the page a form was sent from, or the home page.
+func (s *Server) returnPath(r *http.Request) string {
+	if p := r.FormValue(returnPathField); p != "" {
+		return localPath(p)
+	}
+	return s.basePath + "/"
 }
 
-func appState(containers []Container) string {
+// containerHealth returns the healthcheck status of a listed container:
+// "healthy", "unhealthy", "starting" or "" without healthcheck. Podman lists
+// it as the status, Docker at the end of it, e.g. "Up 2 hours (healthy)".
+func containerHealth(c Container) string {
+	status := c.Status
+	if i := strings.LastIndexByte(status, '('); i >= 0 && strings.HasSuffix(status, ")") {
+		status = strings.TrimPrefix(status[i+1:len(status)-1], "health: ")
+	}
+	switch status {
+	case "healthy", "unhealthy", "starting":
+		return status
+	}
+	return ""
+}
+
+// appHealth aggregates the containers of an app: "down" if none runs,
+// "healthy" if all run and none is unhealthy or starting, "degraded"
+// otherwise. Containers without healthcheck count as healthy while they
+// run. It is "unknown" without containers.
+func appHealth(containers []Container) string {
+	if len(containers) == 0 {
+		return "unknown"
+	}
+	running, healthy := 0, 0
 	for _, c := range containers {
-		if c.State == "running" {
-			return "running"
+		if c.State != "running" {
+			continue
+		}
+		running++
+		if h := containerHealth(c); h == "" || h == "healthy" {
+			healthy++
 		}
 	}
-	if len(containers) > 0 {
-		return containers[0].State
+	switch {
+	case running == 0:
+		return "down"
+	case healthy == len(containers):
+		return "healthy"
 	}
-	return "unknown"
+	return "degraded"
 }
 
-// parseExternalApps reads PODFATHER_APP_<KEY>_<FIELD> environment variables
+// parseExternalApps reads PODFATHER_APP_<KEY>_<FIELD> variables from environ
 // and returns App structs for each unique key that has at least a NAME field.
 // Known suffixes: _NAME, _URL, _ICON, _CATEGORY, _SORT_INDEX, _DESCRIPTION.
 // The <KEY> portion may contain underscores; suffixes are matched from the end.
-func parseExternalApps() []App {
+func parseExternalApps(environ []string) []App {
 	const prefix = "PODFATHER_APP_"
 	suffixes := []struct {
 		suffix string
@@ -291,7 +487,7 @@ func parseExternalApps() []App {
 	}
 
 	fields := make(map[string]map[string]string)
-	for _, env := range os.Environ() {
+	for _, env := range environ {
 		eqIdx := strings.IndexByte(env, '=')
 		if eqIdx < 0 {
 			continue
@@ -343,6 +539,26 @@ func parseExternalApps() []App {
 	return apps
 }
 
+// currentExternalApps returns the external apps from the environment merged
+// with those from the git config, which take priority on name collision.
+func (s *Server) currentExternalApps() []App {
+	if s.gitConfig == nil {
+		return s.externalApps
+	}
+	gitApps := s.gitConfig.externalApps()
+	names := make(map[string]bool, len(gitApps))
+	for _, a := range gitApps {
+		names[a.Name] = true
+	}
+	apps := append([]App(nil), gitApps...)
+	for _, a := range s.externalApps {
+		if !names[a.Name] {
+			apps = append(apps, a)
+		}
+	}
+	return apps
+}
+
 func (s *Server) buildAppCategories(containers []Container) []AppCategory {
 	appMap := make(map[string]*App)
 
@@ -374,9 +590,8 @@ func (s *Server) buildAppCategories(containers []Container) []AppCategory {
 	}
 
 	// Merge external apps (container-based apps take priority on name collision).
-	for i := range s.externalApps {
-		if _, exists := appMap[s.externalApps[i].Name]; !exists {
-			a := s.externalApps[i]
+	for _, a := range s.currentExternalApps() {
+		if _, exists := appMap[a.Name]; !exists {
 			appMap[a.Name] = &a
 		}
 	}
@@ -385,7 +600,7 @@ func (s *Server) buildAppCategories(containers []Container) []AppCategory {
 	for _, app := range appMap {
 		cat := app.Category
 		if cat == "" {
-			cat = "Uncategorized"
+			cat = uncategorized
 		}
 		catMap[cat] = append(catMap[cat], *app)
 	}
@@ -405,26 +620,18 @@ func (s *Server) buildAppCategories(containers []Container) []AppCategory {
 	for cat, apps := range catMap {
 		categories = append(categories, AppCategory{Name: cat, Apps: apps})
 	}
-	sort.Slice(categories, func(i, j int) bool {
-		if categories[i].Name == "Uncategorized" {
-			return false
-		}
-		if categories[j].Name == "Uncategorized" {
-			return true
-		}
-		return categories[i].Name < categories[j].Name
-	})
+	sortCategories(categories, s.currentCategories())
 
 	return categories
 }
 
 func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
-	if len(s.externalApps) > 0 {
+	if len(s.currentExternalApps()) > 0 {
 		http.Redirect(w, r, s.basePath+"/apps", http.StatusTemporaryRedirect)
 		return
 	}
-	var list []Container
-	if err := s.podmanGet("/containers/json?all=true", &list); err != nil {
+	list, err := s.listContainers(r.Context())
+	if err != nil {
 		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -439,33 +646,157 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleApps(w http.ResponseWriter, r *http.Request) {
-	var list []Container
-	if err := s.podmanGet("/containers/json?all=true", &list); err != nil {
+	data, err := s.appsData(r)
+	if err != nil {
 		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	s.render(w, r, "apps.html", data)
+}
+
+// appsData returns the data of the apps page.
+func (s *Server) appsData(r *http.Request) (map[string]any, error) {
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		return nil, err
+	}
 	categories := s.buildAppCategories(list)
-	s.render(w, r, "apps.html", map[string]any{
+	if err := s.applyAppConditions(r.Context(), categories); err != nil {
+		log.Printf("[%s] app conditions: %v", reqID(r.Context()), err)
+	}
+	collapsed := map[string]bool{}
+	for _, n := range readCollapsed(r) {
+		collapsed[n] = true
+	}
+	favorites := readFavorites(r)
+	pinned := map[string]bool{}
+	for _, n := range favorites {
+		pinned[n] = true
+	}
+	if fav := favoriteApps(categories, favorites); fav != nil {
+		categories = append([]AppCategory{*fav}, categories...)
+	}
+	now := s.calendar.now()
+	schedules := map[string]*ScheduleStatus{}
+	for _, c := range list {
+		if st := scheduleStatus(c, now); st != nil {
+			schedules[c.ID] = st
+		}
+	}
+	data := map[string]any{
 		"Title":      "Apps",
 		"Categories": categories,
-	})
+		"Pinned":     pinned,
+		"Collapsed":  collapsed,
+		"Updates":    s.updates.available(),
+		"Probes":     s.probes.all(),
+		"Reach":      s.probes.reachability(),
+		"Schedules":  schedules,
+		"Crashes":    s.crashes.recentCrashStats(list, time.Now()),
+	}
+	s.addLiveData(r, data, "/apps")
+	return data, nil
 }
 
 func (s *Server) handleContainers(w http.ResponseWriter, r *http.Request) {
-	var list []Container
-	if err := s.podmanGet("/containers/json?all=true", &list); err != nil {
+	data, err := s.containersData(r)
+	if err != nil {
 		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	sort.Slice(list, func(i, j int) bool {
-		return list[i].Created.After(list[j].Created)
-	})
-	s.render(w, r, "containers.html", map[string]any{
+	s.render(w, r, "containers.html", data)
+}
+
+// containersData returns the data of the containers page for the filters
+// and sort order in the query of r.
+func (s *Server) containersData(r *http.Request) (map[string]any, error) {
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	teams, owners := ownershipValues(list)
+	states := containerStates(list)
+	query := r.URL.Query()
+	order := parseTableSort(query, tableSort{Key: "created", Desc: true})
+	sortContainers(list, order)
+	team, owner := query.Get("team"), query.Get("owner")
+	q, state, label := strings.TrimSpace(query.Get("q")), query.Get("state"), strings.TrimSpace(query.Get("label"))
+	list = filterOwnership(list, team, owner)
+	list = filterContainers(list, q, state, label)
+	rows, err := s.containerRows(r.Context(), list)
+	if err != nil {
+		return nil, err
+	}
+	colspan := 6 + len(s.containerColumns)
+	if len(teams) > 0 || len(owners) > 0 {
+		colspan++
+	}
+	if s.enableActions {
+		colspan++
+	}
+	data := map[string]any{
 		"Title":      "Containers",
-		"Containers": list,
-	})
+		"Containers": rows,
+		"Columns":    s.containerColumns,
+		"Colspan":    colspan,
+		"Teams":      teams,
+		"Owners":     owners,
+		"Team":       team,
+		"Owner":      owner,
+		"States":     states,
+		"Q":          q,
+		"State":      state,
+		"Label":      label,
+		"Sort":       order.headers(s.basePath+"/containers", query),
+		"Order":      order,
+		"Sorted":     query.Has("sort"),
+		"ViewPage":   "containers",
+		"Query":      r.URL.RawQuery,
+		"Bulk":       bulkActions,
+	}
+	s.addLiveData(r, data, "/containers")
+	return data, nil
+}
+
+// filterContainers returns the containers matching all non-empty filters: q
+// is a case-insensitive substring of a name or the image, or a prefix of the
+// ID; state is the exact state; label is "key" (the label is set) or
+// "key=value".
+func filterContainers(list []Container, q, state, label string) []Container {
+	q = strings.ToLower(q)
+	key, value, hasValue := strings.Cut(label, "=")
+	out := make([]Container, 0, len(list))
+	for _, c := range list {
+		if state != "" && c.State != state {
+			continue
+		}
+		if label != "" {
+			if v, ok := c.Labels[key]; !ok || (hasValue && v != value) {
+				continue
+			}
+		}
+		if q != "" && !strings.HasPrefix(c.ID, q) && !strings.Contains(strings.ToLower(c.Image), q) &&
+			!slices.ContainsFunc(c.Names, func(n string) bool { return strings.Contains(strings.ToLower(n), q) }) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// containerStates returns the sorted distinct states of the containers, for
+// the filter form.
+func containerStates(list []Container) []string {
+	var states []string
+	for _, c := range list {
+		if !slices.Contains(states, c.State) {
+			states = append(states, c.State)
+		}
+	}
+	slices.Sort(states)
+	return states
 }
 
 func (s *Server) handleContainer(w http.ResponseWriter, r *http.Request) {
@@ -474,8 +805,8 @@ func (s *Server) handleContainer(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid container ID", http.StatusBadRequest)
 		return
 	}
-	var c ContainerInspect
-	if err := s.podmanGet("/containers/"+id+"/json", &c); err != nil {
+	c, err := s.inspectContainer(r.Context(), id)
+	if err != nil {
 		if errors.Is(err, errNotFound) {
 			http.Error(w, "Container Not Found", http.StatusNotFound)
 			return
@@ -484,36 +815,137 @@ func (s *Server) handleContainer(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
+	s.renderContainer(w, r, c, map[string]any{})
+}
+
+// renderContainer renders the container page for c, adding to data.
+func (s *Server) renderContainer(w http.ResponseWriter, r *http.Request, c ContainerInspect, data map[string]any) {
 	name := c.Name
 	if name == "" {
 		name = shortID(c.ID)
 	}
-	s.render(w, r, "container.html", map[string]any{
-		"Title":     "Container: " + name,
-		"Container": c,
-	})
+	// All containers are inspected for related containers and, if c is
+	// pinned, for shared CPUs. Only the latter is needed to render the page.
+	var overlaps []CPUOverlap
+	list, err := s.listContainers(r.Context())
+	var all []ContainerInspect
+	if err == nil {
+		all, err = s.inspectAllContainers(r.Context())
+	}
+	switch {
+	case err != nil && len(pinnedCPUs(c)) > 0:
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	case err != nil:
+		log.Printf("[%s] related containers: %v", reqID(r.Context()), err)
+	default:
+		overlaps = cpuOverlaps(c, all)
+		data["Relations"] = containerRelations(c, list, all)
+	}
+	data["Title"] = "Container: " + name
+	data["Container"] = c
+	data["CPUOverlaps"] = overlaps
+	data["CanHealthcheck"] = s.backend != backendDocker && c.State.Health != nil
+	data["CanDownload"] = s.enableFileDownload
+	data["CanGenerate"] = s.backend != backendDocker
+	data["KillSignals"] = killSignals
+	data["CanUpload"] = s.enableFileUpload
+	data["CanPin"] = s.backend == backendPodman && s.quadletDir != "" && c.Config.Labels[systemdUnitLabel] != ""
+	data["Update"] = s.updates.status(c.ImageName)
+	if st, ok := s.crashes.stats(c.Name, time.Now()); ok {
+		data["Crashes"] = st
+	}
+	if unit := s.containerUnit(c); unit != "" {
+		st, err := s.unitStatus(r.Context(), unit)
+		if err != nil {
+			// E.g. podfather runs in a container without systemctl.
+			log.Printf("[%s] unit %s: %v", reqID(r.Context()), unit, err)
+		} else {
+			data["Unit"] = st
+		}
+	}
+	if c.Config.Labels[watchdogLabel] == "true" {
+		st, _ := s.watchdog.status(c.ID)
+		data["Watchdog"] = st
+		data["WatchdogActive"] = s.watchdog != nil
+	}
+	s.render(w, r, "container.html", data)
+}
+
+// handleContainerHealthcheck runs the container's healthcheck once and shows
+// the result on the container page.
+func (s *Server) handleContainerHealthcheck(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions || s.backend == backendDocker {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid container ID", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+	data := map[string]any{}
+	resp, err := s.podmanDo(ctx, http.MethodGet, "/containers/"+id+"/healthcheck", nil)
+	switch {
+	case errors.Is(err, errNotFound):
+		http.Error(w, "Container Not Found", http.StatusNotFound)
+		return
+	case err != nil:
+		// Podman answers 409 if the container has no healthcheck or is not
+		// running.
+		log.Printf("[%s] healthcheck %s: %v", reqID(r.Context()), id, err)
+		data["HealthcheckErr"] = "Healthcheck could not be run. Is the container running and does it define a healthcheck?"
+	default:
+		var h Health
+		err := json.NewDecoder(resp.Body).Decode(&h)
+		resp.Body.Close()
+		if err != nil {
+			log.Printf("[%s] healthcheck %s: %v", reqID(r.Context()), id, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if len(h.Log) > 0 {
+			data["HealthcheckRun"] = h.Log[len(h.Log)-1]
+		}
+		data["HealthcheckStatus"] = h.Status
+	}
+	c, err := s.inspectContainer(ctx, id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Container Not Found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.renderContainer(w, r, c, data)
 }
 
 func (s *Server) handleImages(w http.ResponseWriter, r *http.Request) {
-	var list []ImageSummary
-	if err := s.podmanGet("/images/json", &list); err != nil {
+	list, err := s.imageRows(r.Context())
+	if err != nil {
 		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	sort.Slice(list, func(i, j int) bool {
-		a, b := "", ""
-		if len(list[i].RepoTags) > 0 {
-			a = list[i].RepoTags[0]
-		}
-		if len(list[j].RepoTags) > 0 {
-			b = list[j].RepoTags[0]
-		}
-		return a < b
-	})
+	for i := range list {
+		list[i].Missing = s.imagePolicy.missing(list[i].ImageSummary)
+	}
+	query := r.URL.Query()
+	order := parseTableSort(query, tableSort{Key: "name"})
+	sortImages(list, order)
 	s.render(w, r, "images.html", map[string]any{
-		"Title":  "Images",
-		"Images": list,
+		"Title":    "Images",
+		"Images":   list,
+		"Sort":     order.headers(s.basePath+"/images", query),
+		"ViewPage": "images",
+		"Query":    r.URL.RawQuery,
+		"CanLoad":  s.enableFileUpload,
+		"LoadMax":  maxImageLoadSize >> 30,
 	})
 }
 
@@ -523,8 +955,11 @@ func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid image ID", http.StatusBadRequest)
 		return
 	}
-	var img ImageInspect
-	if err := s.podmanGet("/images/"+id+"/json", &img); err != nil {
+	if r.URL.Query().Get("refresh") != "" {
+		s.images.invalidate(id)
+	}
+	img, err := s.inspectImage(r.Context(), id)
+	if err != nil {
 		if errors.Is(err, errNotFound) {
 			http.Error(w, "Image Not Found", http.StatusNotFound)
 			return
@@ -533,17 +968,247 @@ func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	name := ""
-	if len(img.RepoTags) > 0 {
-		name = img.RepoTags[0]
-	}
-	if name == "" {
-		name = shortID(img.ID)
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
 	s.render(w, r, "image.html", map[string]any{
-		"Title": "Image: " + name,
-		"Image": img,
+		"Title":        "Image: " + imageName(img),
+		"Image":        img,
+		"UsedBy":       containersUsingImage(list, img.ID),
+		"CanExport":    s.enableFileDownload,
+		"CanExportOCI": s.enableFileDownload && s.backend != backendDocker,
+	})
+}
+
+// containersUsingImage returns the containers created from the image with
+// the given ID, sorted by name. The Docker API prefixes IDs with "sha256:".
+func containersUsingImage(list []Container, imageID string) []Container {
+	imageID = strings.TrimPrefix(imageID, "sha256:")
+	var out []Container
+	for _, c := range list {
+		if strings.TrimPrefix(c.ImageID, "sha256:") == imageID {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return firstName(out[i].Names) < firstName(out[j].Names) })
+	return out
+}
+
+// buildSecretUsage pairs each secret with the containers referencing it,
+// sorted by secret name.
+func buildSecretUsage(secrets []Secret, containers []ContainerInspect) []SecretUsage {
+	usage := make([]SecretUsage, len(secrets))
+	for i, sec := range secrets {
+		usage[i].Secret = sec
+		for _, c := range containers {
+			for _, ref := range c.Config.Secrets {
+				if ref.ID == sec.ID || (ref.ID == "" && ref.Name == sec.Spec.Name) {
+					usage[i].Containers = append(usage[i].Containers, c)
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Secret.Spec.Name < usage[j].Secret.Spec.Name
+	})
+	return usage
+}
+
+// missingSecrets returns the secret references of the containers that match
+// none of the secrets, sorted by container name. Such containers fail to
+// start.
+func missingSecrets(secrets []Secret, containers []ContainerInspect) []MissingSecret {
+	var out []MissingSecret
+	for _, c := range containers {
+		for _, ref := range c.Config.Secrets {
+			found := false
+			for _, sec := range secrets {
+				if ref.ID == sec.ID || (ref.ID == "" && ref.Name == sec.Spec.Name) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				out = append(out, MissingSecret{Container: c, Ref: ref})
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Container.Name < out[j].Container.Name })
+	return out
+}
+
+// inspectAllContainers inspects every container, in parallel. Containers
+// removed meanwhile are skipped.
+func (s *Server) inspectAllContainers(ctx context.Context) ([]ContainerInspect, error) {
+	list, err := s.listContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inspected := make([]ContainerInspect, len(list))
+	found := make([]bool, len(list))
+	err = parallel(ctx, len(list), func(ctx context.Context, i int) error {
+		ci, err := s.inspectContainer(ctx, list[i].ID)
+		if errors.Is(err, errNotFound) {
+			return nil
+		}
+		inspected[i], found[i] = ci, err == nil
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ContainerInspect, 0, len(list))
+	for i, ci := range inspected {
+		if found[i] {
+			out = append(out, ci)
+		}
+	}
+	return out, nil
+}
+
+func (s *Server) handleSecrets(w http.ResponseWriter, r *http.Request) {
+	if s.backend == backendDocker {
+		s.render(w, r, "secrets.html", map[string]any{
+			"Title":       "Secrets",
+			"Unsupported": true,
+		})
+		return
+	}
+	var secrets []Secret
+	if err := s.podmanGet(r.Context(), "/secrets/json", &secrets); err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	containers, err := s.inspectAllContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	usage := buildSecretUsage(secrets, containers)
+	unused := 0
+	for _, u := range usage {
+		if len(u.Containers) == 0 {
+			unused++
+		}
+	}
+	s.render(w, r, "secrets.html", map[string]any{
+		"Title":   "Secrets",
+		"Secrets": usage,
+		"Unused":  unused,
+		"Missing": missingSecrets(secrets, containers),
+	})
+}
+
+// summarizeDF computes per-type totals the way `podman system df` does:
+// images and volumes without containers and stopped containers' writable
+// layers are reclaimable. The last row is the grand total.
+func summarizeDF(df SystemDF) []DFSummary {
+	images := DFSummary{Type: "Images", Total: len(df.Images)}
+	for _, i := range df.Images {
+		images.Size += i.Size
+		if i.Containers > 0 {
+			images.Active++
+		} else {
+			images.Reclaimable += i.Size
+		}
+	}
+	containers := DFSummary{Type: "Containers", Total: len(df.Containers)}
+	for _, c := range df.Containers {
+		containers.Size += c.RWSize
+		if c.Status == "running" {
+			containers.Active++
+		} else {
+			containers.Reclaimable += c.RWSize
+		}
+	}
+	volumes := DFSummary{Type: "Volumes", Total: len(df.Volumes)}
+	for _, v := range df.Volumes {
+		volumes.Size += v.Size
+		volumes.Reclaimable += v.ReclaimableSize
+		if v.Links > 0 {
+			volumes.Active++
+		}
+	}
+	total := DFSummary{Type: "Total"}
+	rows := []DFSummary{images, containers, volumes}
+	for _, r := range rows {
+		total.Total += r.Total
+		total.Active += r.Active
+		total.Size += r.Size
+		total.Reclaimable += r.Reclaimable
+	}
+	return append(rows, total)
+}
+
+func (s *Server) handleDF(w http.ResponseWriter, r *http.Request) {
+	if s.backend == backendDocker {
+		s.render(w, r, "df.html", map[string]any{
+			"Title":       "Disk Usage",
+			"Unsupported": true,
+		})
+		return
+	}
+	var df SystemDF
+	if err := s.podmanGet(r.Context(), "/system/df", &df); err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	// Largest first, so the biggest wins are at the top.
+	sort.Slice(df.Images, func(i, j int) bool { return df.Images[i].Size > df.Images[j].Size })
+	sort.Slice(df.Containers, func(i, j int) bool { return df.Containers[i].RWSize > df.Containers[j].RWSize })
+	sort.Slice(df.Volumes, func(i, j int) bool { return df.Volumes[i].Size > df.Volumes[j].Size })
+	s.render(w, r, "df.html", map[string]any{
+		"Title":   "Disk Usage",
+		"Summary": summarizeDF(df),
+		"DF":      df,
+	})
+}
+
+func (s *Server) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	var version struct {
+		Version    string `json:"Version"`
+		APIVersion string `json:"ApiVersion"`
+	}
+	var versionErr string
+	if err := s.podmanGet(r.Context(), "/version", &version); err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		versionErr = "API unreachable"
+	}
+	data := map[string]any{
+		"Title":      "Diagnostics",
+		"Backend":    s.backend,
+		"Socket":     s.socketPath,
+		"Version":    version.Version,
+		"APIVersion": version.APIVersion,
+		"VersionErr": versionErr,
+		"GoVersion":  runtime.Version(),
+		"Started":    s.started,
+		"DebugPage":  s.enableDebugPage,
+	}
+	if s.gitConfig != nil {
+		data["GitConfig"] = s.gitConfig.status()
+	}
+	if s.slowCalls != nil {
+		data["SlowThreshold"] = s.slowCalls.threshold
+		data["SlowCalls"] = s.slowCalls.top(10)
+	}
+	if s.supervisesAPI() {
+		units, err := s.apiUnits(r.Context())
+		if err != nil {
+			log.Printf("[%s] %v", reqID(r.Context()), err)
+			data["APIUnitsErr"] = "systemd user instance unreachable"
+		}
+		data["SupervisesAPI"] = true
+		data["APIUnits"] = units
+	}
+	s.render(w, r, "diagnostics.html", data)
 }
 
 func handleLogo(w http.ResponseWriter, r *http.Request) {
@@ -553,13 +1218,6 @@ func handleLogo(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-type autoUpdateResult struct {
-	mu   sync.Mutex
-	buf  []byte
-	done bool
-	err  string
-}
-
 func (s *Server) handleAutoUpdatePost(podmanBin string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if !s.enableAutoUpdate {
@@ -567,64 +1225,32 @@ func (s *Server) handleAutoUpdatePost(podmanBin string) http.HandlerFunc {
 			return
 		}
 
-		if !s.autoUpdateMu.TryLock() {
-			http.Redirect(w, r, s.basePath+"/auto-update", http.StatusSeeOther)
-			return
-		}
+		s.startAutoUpdate(podmanBin)
+		http.Redirect(w, r, s.basePath+"/auto-update", http.StatusSeeOther)
+	}
+}
 
-		result := &autoUpdateResult{}
-		s.currentAutoUpdate.Store(result)
+// startAutoUpdate runs `podman auto-update` in the background. It returns
+// false if a run is already in progress.
+func (s *Server) startAutoUpdate(podmanBin string) bool {
+	if !s.autoUpdateMu.TryLock() {
+		return false
+	}
 
-		go func() {
-			defer s.autoUpdateMu.Unlock()
+	j := s.jobs.start("Auto Update")
+	s.currentAutoUpdate.Store(j)
 
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-			defer cancel()
+	go func() {
+		defer s.autoUpdateMu.Unlock()
 
-			cmd := exec.CommandContext(ctx, podmanBin, "auto-update")
-			stdoutR, stdoutW, err := os.Pipe()
-			if err != nil {
-				result.mu.Lock()
-				result.err = err.Error()
-				result.done = true
-				result.mu.Unlock()
-				return
-			}
-			cmd.Stdout = stdoutW
-			cmd.Stderr = stdoutW
-
-			if err := cmd.Start(); err != nil {
-				stdoutW.Close()
-				stdoutR.Close()
-				result.mu.Lock()
-				result.err = err.Error()
-				result.done = true
-				result.mu.Unlock()
-				return
-			}
-			stdoutW.Close()
-
-			scanner := bufio.NewScanner(stdoutR)
-			for scanner.Scan() {
-				result.mu.Lock()
-				result.buf = append(result.buf, scanner.Bytes()...)
-				result.buf = append(result.buf, '\n')
-				result.mu.Unlock()
-			}
-			stdoutR.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
 
-			if err := cmd.Wait(); err != nil {
-				result.mu.Lock()
-				result.err = err.Error()
-				result.mu.Unlock()
-			}
-			result.mu.Lock()
-			result.done = true
-			result.mu.Unlock()
-		}()
+		j.finish(runCommand(ctx, j, podmanBin, "auto-update"))
+		s.autoUpdateHistory.add(j)
+	}()
 
-		http.Redirect(w, r, s.basePath+"/auto-update", http.StatusSeeOther)
-	}
+	return true
 }
 
 func (s *Server) handleAutoUpdatePage(w http.ResponseWriter, r *http.Request) {
@@ -632,8 +1258,10 @@ func (s *Server) handleAutoUpdatePage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Not Found", http.StatusNotFound)
 		return
 	}
-	s.render(w, r, "autoupdate.html", map[string]any{
-		"Title": "Auto Update",
+	s.render(w, r, "job.html", map[string]any{
+		"Title":     "Auto Update",
+		"EventsURL": s.basePath + "/auto-update/events",
+		"BackURL":   s.basePath + "/",
 	})
 }
 
@@ -642,62 +1270,5 @@ func (s *Server) handleAutoUpdateEvents(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Not Found", http.StatusNotFound)
 		return
 	}
-
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-
-	result := s.currentAutoUpdate.Load()
-
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-
-	if result == nil {
-		fmt.Fprintf(w, "event: done\ndata: no-update\n\n")
-		flusher.Flush()
-		return
-	}
-
-	offset := 0
-	for {
-		result.mu.Lock()
-		data := make([]byte, len(result.buf)-offset)
-		copy(data, result.buf[offset:])
-		isDone := result.done
-		errMsg := result.err
-		result.mu.Unlock()
-
-		if len(data) > 0 {
-			text := string(data)
-			for len(text) > 0 {
-				idx := strings.IndexByte(text, '\n')
-				if idx < 0 {
-					fmt.Fprintf(w, "data: %s\n\n", text)
-					break
-				}
-				fmt.Fprintf(w, "data: %s\n\n", text[:idx])
-				text = text[idx+1:]
-			}
-			offset += len(data)
-			flusher.Flush()
-		}
-
-		if isDone {
-			if errMsg != "" {
-				fmt.Fprintf(w, "event: err\ndata: %s\n\n", errMsg)
-			}
-			fmt.Fprintf(w, "event: done\ndata: \n\n")
-			flusher.Flush()
-			return
-		}
-
-		select {
-		case <-r.Context().Done():
-			return
-		case <-time.After(200 * time.Millisecond):
-		}
-	}
+	streamJob(w, r, s.currentAutoUpdate.Load(), s.pageMasker(r))
 }
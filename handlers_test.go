@@ -1,13 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -187,44 +191,38 @@ func TestBuildAppCategoriesEmpty(t *testing.T) {
 	}
 }
 
-func TestAppState(t *testing.T) {
+func TestAppHealth(t *testing.T) {
 	t.Parallel()
 	s := &Server{}
 	list := loadTestContainers(t)
 	categories := s.buildAppCategories(list)
 
-	// All demo containers are running, so appState should return "running".
+	// All demo containers run without healthcheck, so all apps are healthy.
 	for _, cat := range categories {
 		for _, app := range cat.Apps {
-			got := appState(app.Containers)
-			if got != "running" {
-				t.Errorf("appState(%s) = %q, want running", app.Name, got)
+			if got := appHealth(app.Containers); got != "healthy" {
+				t.Errorf("appHealth(%s) = %q, want healthy", app.Name, got)
 			}
 		}
 	}
 
-	// Mixed states: running wins.
-	mixed := []Container{
-		{State: "exited"},
-		{State: "running"},
-		{State: "exited"},
-	}
-	if got := appState(mixed); got != "running" {
-		t.Errorf("appState(mixed) = %q, want running", got)
-	}
-
-	// All exited: returns first container's state.
-	exited := []Container{
-		{State: "exited"},
-		{State: "created"},
-	}
-	if got := appState(exited); got != "exited" {
-		t.Errorf("appState(exited) = %q, want exited", got)
+	tests := []struct {
+		name       string
+		containers []Container
+		want       string
+	}{
+		{"healthy", []Container{{State: "running", Status: "healthy"}, {State: "running"}}, "healthy"},
+		{"docker healthy", []Container{{State: "running", Status: "Up 2 hours (healthy)"}}, "healthy"},
+		{"unhealthy", []Container{{State: "running", Status: "unhealthy"}, {State: "running"}}, "degraded"},
+		{"docker starting", []Container{{State: "running", Status: "Up 3 seconds (health: starting)"}}, "degraded"},
+		{"some running", []Container{{State: "exited"}, {State: "running"}}, "degraded"},
+		{"none running", []Container{{State: "exited"}, {State: "created"}}, "down"},
+		{"no containers", nil, "unknown"},
 	}
-
-	// Empty: returns "unknown".
-	if got := appState(nil); got != "unknown" {
-		t.Errorf("appState(nil) = %q, want unknown", got)
+	for _, tt := range tests {
+		if got := appHealth(tt.containers); got != tt.want {
+			t.Errorf("appHealth(%s) = %q, want %q", tt.name, got, tt.want)
+		}
 	}
 }
 
@@ -340,14 +338,54 @@ func newMockPodmanAPI(t *testing.T) *httptest.Server {
 	containerInspect := loadTestFixture(t, "testdata/container_inspect.json")
 	images := loadTestFixture(t, "testdata/images.json")
 	imageInspect := loadTestFixture(t, "testdata/image_inspect.json")
+	secrets := loadTestFixture(t, "testdata/secrets.json")
+	systemDF := loadTestFixture(t, "testdata/system_df.json")
 
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		p := r.URL.Path
 		w.Header().Set("Content-Type", "application/json")
 
 		switch {
+		case r.Method == http.MethodPost && strings.HasPrefix(p, "/v4.0.0/libpod/containers/") &&
+			(strings.HasSuffix(p, "/restart") || strings.HasSuffix(p, "/start") || strings.HasSuffix(p, "/stop") ||
+				strings.HasSuffix(p, "/pause") || strings.HasSuffix(p, "/unpause")):
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && p == "/v4.0.0/libpod/images/pull":
+			if r.URL.Query().Get("reference") == "example.com/missing:latest" {
+				w.Write([]byte(`{"error":"manifest unknown"}`))
+				return
+			}
+			w.Write([]byte(`{"stream":"Trying to pull example.com/app:latest...\n"}` + "\n" + `{"images":["b76de378d572"],"id":"b76de378d572"}`))
+		case r.Method == http.MethodPost && p == "/v4.0.0/libpod/images/prune":
+			if r.URL.Query().Get("all") != "true" {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[{"Id":"c76d5d4e71e8","Size":199082086},{"Id":"271836597d6f","Size":267590241}]`))
+		case p == "/v4.0.0/libpod/secrets/json":
+			w.Write(secrets)
+		case p == "/v4.0.0/libpod/system/df":
+			w.Write(systemDF)
+		case p == "/v4.0.0/libpod/networks/json":
+			w.Write([]byte(`[{"name":"podman","driver":"bridge"},{"name":"podfather_default","driver":"bridge"},{"name":"old_net","driver":"bridge"}]`))
+		case p == "/v4.0.0/libpod/events":
+			now := time.Now().UnixNano()
+			for i := range 4 {
+				fmt.Fprintf(w, `{"Type":"container","Action":"died","Actor":{"ID":"crashy"},"timeNano":%d}`+"\n", now-int64(i)*int64(20*time.Minute))
+			}
+		case p == "/v4.0.0/libpod/version":
+			w.Write([]byte(`{"Version":"4.9.3","ApiVersion":"4.9.3"}`))
 		case p == "/v4.0.0/libpod/containers/json":
 			w.Write(containers)
+		case p == "/v4.0.0/libpod/containers/jellyfin/healthcheck":
+			w.Write([]byte(`{"Status":"unhealthy","FailingStreak":1,"Log":[{"Start":"2026-02-17T21:21:00Z","End":"2026-02-17T21:21:00.2Z","ExitCode":7,"Output":"probe refused"}]}`))
+		case strings.HasSuffix(p, "/healthcheck"):
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"container has no defined healthcheck"}`))
+		case strings.HasPrefix(p, "/v4.0.0/libpod/containers/") && strings.HasSuffix(p, "/logs"):
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(logFrame(1, "2025-01-11T12:00:00.000000001Z {\"level\":\"info\",\"msg\":\"request served\",\"path\":\"/\",\"status\":200}\n"))
+			w.Write(logFrame(2, "2025-01-11T12:00:01.000000001Z 2025/01/11 12:00:01 [error] 29#29: upstream timed out\n"))
 		case strings.HasSuffix(p, "/json") && strings.HasPrefix(p, "/v4.0.0/libpod/containers/"):
 			// /v4.0.0/libpod/containers/{id}/json
 			id := strings.TrimPrefix(p, "/v4.0.0/libpod/containers/")
@@ -415,11 +453,22 @@ func TestEndToEnd(t *testing.T) {
 		{"apps page", "GET", "/apps", http.StatusOK, "Jellyfin"},
 		{"containers page", "GET", "/containers", http.StatusOK, "jellyfin"},
 		{"container detail", "GET", "/container/jellyfin", http.StatusOK, "jellyfin"},
+		{"container health history", "GET", "/container/jellyfin", http.StatusOK, "Failed to connect to localhost port 80"},
+		{"container limits", "GET", "/container/jellyfin", http.StatusOK, "RLIMIT_NOFILE=524288:524288"},
+		{"container networks", "GET", "/container/jellyfin", http.StatusOK, "<td class=\"mono\">10.89.0.43/24</td>"},
+		{"container network aliases", "GET", "/container/jellyfin", http.StatusOK, "jellyfin, e69755008ef4"},
+		{"container user namespace", "GET", "/container/jellyfin", http.StatusOK, "host UID 1"},
+		{"container pod members", "GET", "/container/jellyfin", http.StatusOK, "pod_podfather"},
 		{"container not found", "GET", "/container/nonexistent", http.StatusNotFound, ""},
 		{"container invalid id", "GET", "/container/!!!invalid", http.StatusBadRequest, ""},
 		{"images page", "GET", "/images", http.StatusOK, "nginx"},
 		{"image detail", "GET", "/image/b76de378d572", http.StatusOK, "nginx"},
+		{"image used by", "GET", "/image/b76de378d572", http.StatusOK, "/container/"},
 		{"image not found", "GET", "/image/nonexistent", http.StatusNotFound, ""},
+		{"secrets page", "GET", "/secrets", http.StatusOK, "gitea_db_password"},
+		{"diagnostics page", "GET", "/diagnostics", http.StatusOK, "4.9.3"},
+		{"cpu allocation page", "GET", "/system/cpus", http.StatusOK, "jellyfin"},
+		{"disk usage page", "GET", "/system/df", http.StatusOK, "orphaned-data"},
 		{"auto-update disabled", "POST", "/auto-update", http.StatusNotFound, ""},
 	}
 
@@ -686,7 +735,7 @@ func TestParseExternalApps(t *testing.T) {
 		t.Setenv(k, v)
 	}
 
-	apps := parseExternalApps()
+	apps := parseExternalApps(os.Environ())
 
 	// Should have 3 apps: Router, NAS, My App (NONAME and empty key skipped).
 	if len(apps) != 3 {
@@ -1016,3 +1065,431 @@ func TestCSRFProtection(t *testing.T) {
 		}
 	})
 }
+
+func TestDockerContainerConversion(t *testing.T) {
+	t.Parallel()
+	data := loadTestFixture(t, "testdata/docker_containers.json")
+	var list []dockerContainer
+	if err := json.Unmarshal(data, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 containers, got %d", len(list))
+	}
+
+	c := list[0].toContainer()
+	if firstName(c.Names) != "vaultwarden" {
+		t.Errorf("name = %q, want vaultwarden (leading slash stripped)", firstName(c.Names))
+	}
+	if c.Created.Unix() != 1771305473 {
+		t.Errorf("created = %v", c.Created)
+	}
+	if len(c.Command) != 1 || c.Command[0] != "/start.sh" {
+		t.Errorf("command = %v", c.Command)
+	}
+	got := formatPorts(c.Ports)
+	want := "0.0.0.0:8222->80/tcp, :::8222->80/tcp, 3012/tcp"
+	if got != want {
+		t.Errorf("formatPorts = %q, want %q", got, want)
+	}
+	if c.Labels[appLabelPrefix+"name"] != "Vaultwarden" {
+		t.Errorf("app name label = %q", c.Labels[appLabelPrefix+"name"])
+	}
+	if len(c.Networks) != 1 || c.Networks[0] != "bridge" {
+		t.Errorf("networks = %v, want [bridge]", c.Networks)
+	}
+}
+
+// newMockDockerAPI creates an httptest.Server that mocks the Docker Engine
+// compat API. The libpod ping endpoint is not served.
+func newMockDockerAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	containers := loadTestFixture(t, "testdata/docker_containers.json")
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_ping":
+			w.Write([]byte("OK"))
+		case "/v1.41/containers/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(containers)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"page not found"}`))
+		}
+	}))
+}
+
+func TestDetectBackend(t *testing.T) {
+	t.Parallel()
+	podman := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v4.0.0/libpod/_ping" || r.URL.Path == "/_ping" {
+			w.Write([]byte("OK"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer podman.Close()
+	docker := newMockDockerAPI(t)
+	defer docker.Close()
+
+	if got := detectBackend(podman.Client(), podman.URL); got != backendPodman {
+		t.Errorf("detectBackend(podman) = %q, want %q", got, backendPodman)
+	}
+	if got := detectBackend(docker.Client(), docker.URL); got != backendDocker {
+		t.Errorf("detectBackend(docker) = %q, want %q", got, backendDocker)
+	}
+}
+
+func TestEndToEndDocker(t *testing.T) {
+	t.Parallel()
+	mock := newMockDockerAPI(t)
+	defer mock.Close()
+
+	s := &Server{
+		backend:       backendDocker,
+		podmanClient:  mock.Client(),
+		podmanBaseURL: mock.URL + apiPath(backendDocker),
+	}
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	for _, path := range []string{"/containers", "/apps"} {
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s status = %d, want %d", path, resp.StatusCode, http.StatusOK)
+		}
+		if strings.Contains(string(body), "/vaultwarden") {
+			t.Errorf("GET %s: container name not normalized", path)
+		}
+	}
+}
+
+func TestBuildSecretUsage(t *testing.T) {
+	t.Parallel()
+	var secrets []Secret
+	if err := json.Unmarshal(loadTestFixture(t, "testdata/secrets.json"), &secrets); err != nil {
+		t.Fatal(err)
+	}
+	containers := []ContainerInspect{
+		{ID: "c1", Name: "gitea-db", Config: ContainerConfig{Secrets: []SecretRef{{Name: "gitea_db_password", ID: "3f1c9a2b7d8e4f5a6b7c8d9e0"}}}},
+		{ID: "c2", Name: "gitea-web", Config: ContainerConfig{Secrets: []SecretRef{{Name: "gitea_db_password"}}}},
+		{ID: "c3", Name: "redis"},
+	}
+	usage := buildSecretUsage(secrets, containers)
+	if len(usage) != 2 {
+		t.Fatalf("got %d secrets, want 2", len(usage))
+	}
+	if usage[0].Secret.Spec.Name != "gitea_db_password" || usage[1].Secret.Spec.Name != "old_api_token" {
+		t.Errorf("secrets not sorted by name: %q, %q", usage[0].Secret.Spec.Name, usage[1].Secret.Spec.Name)
+	}
+	if len(usage[0].Containers) != 2 {
+		t.Errorf("gitea_db_password used by %d containers, want 2", len(usage[0].Containers))
+	}
+	if len(usage[1].Containers) != 0 {
+		t.Errorf("old_api_token used by %d containers, want 0", len(usage[1].Containers))
+	}
+	if usage[0].Secret.Spec.Driver.Name != "file" {
+		t.Errorf("driver = %q, want file", usage[0].Secret.Spec.Driver.Name)
+	}
+}
+
+func TestSummarizeDF(t *testing.T) {
+	var df SystemDF
+	if err := json.Unmarshal(loadTestFixture(t, "testdata/system_df.json"), &df); err != nil {
+		t.Fatal(err)
+	}
+	got := summarizeDF(df)
+	want := []DFSummary{
+		{Type: "Images", Total: 3, Active: 1, Size: 429000000, Reclaimable: 237000000},
+		{Type: "Containers", Total: 2, Active: 1, Size: 14096, Reclaimable: 10000},
+		{Type: "Volumes", Total: 2, Active: 1, Size: 3072000, Reclaimable: 1024000},
+		{Type: "Total", Total: 7, Active: 3, Size: 432086096, Reclaimable: 238034000},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summarizeDF() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestHealthRecent(t *testing.T) {
+	var c ContainerInspect
+	if err := json.Unmarshal(loadTestFixture(t, "testdata/container_inspect.json"), &c); err != nil {
+		t.Fatal(err)
+	}
+	h := c.State.Health
+	if h == nil || len(h.Log) != 3 {
+		t.Fatalf("health = %+v, want 3 log entries", h)
+	}
+	recent := h.Recent()
+	if recent[0].Output != "ok" || recent[2].ExitCode != 1 {
+		t.Errorf("Recent() not newest first: %+v", recent)
+	}
+	if d := recent[2].Duration(); d != 81*time.Millisecond {
+		t.Errorf("Duration() = %v, want 81ms", d)
+	}
+
+	h.Log = make([]HealthLog, 25)
+	if n := len(h.Recent()); n != healthLogMax {
+		t.Errorf("len(Recent()) = %d, want %d", n, healthLogMax)
+	}
+}
+
+func TestHostConfigLimits(t *testing.T) {
+	var c ContainerInspect
+	if err := json.Unmarshal(loadTestFixture(t, "testdata/container_inspect.json"), &c); err != nil {
+		t.Fatal(err)
+	}
+	h := c.HostConfig
+	if h.Pids() != 2048 || len(h.Ulimits) != 2 || h.Ulimits[0] != (Ulimit{Name: "RLIMIT_NOFILE", Soft: 524288, Hard: 524288}) || h.CPULimit() != "" || h.SwapLimit() != "" {
+		t.Errorf("limits of fixture = %+v", h)
+	}
+
+	unlimited := int64(-1)
+	for _, tt := range []struct {
+		h          HostConfig
+		cpus, swap string
+		pids       int64
+	}{
+		{HostConfig{NanoCpus: 1_500_000_000, Memory: 1 << 30, MemorySwap: 2 << 30}, "1.5", "1.0 GB", 0},
+		{HostConfig{CpuQuota: 50000, CpuPeriod: 100000, MemorySwap: -1, PidsLimit: &unlimited}, "0.5", "unlimited", 0},
+		{HostConfig{CpuQuota: 50000}, "", "", 0},
+	} {
+		if got := tt.h.CPULimit(); got != tt.cpus {
+			t.Errorf("%+v: CPULimit() = %q, want %q", tt.h, got, tt.cpus)
+		}
+		if got := tt.h.SwapLimit(); got != tt.swap {
+			t.Errorf("%+v: SwapLimit() = %q, want %q", tt.h, got, tt.swap)
+		}
+		if got := tt.h.Pids(); got != tt.pids {
+			t.Errorf("%+v: Pids() = %d, want %d", tt.h, got, tt.pids)
+		}
+	}
+
+	opts := HostConfig{SecurityOpt: []string{"label=disable", `seccomp={"defaultAction":"SCMP_ACT_ERRNO"}`}}.SecurityOpts()
+	if !slices.Equal(opts, []string{"label=disable", "seccomp=(custom profile)"}) {
+		t.Errorf("SecurityOpts() = %q", opts)
+	}
+}
+
+func TestIDMappings(t *testing.T) {
+	var c ContainerInspect
+	if err := json.Unmarshal(loadTestFixture(t, "testdata/container_inspect.json"), &c); err != nil {
+		t.Fatal(err)
+	}
+	m := c.HostConfig.IDMappings
+	if m == nil || c.HostConfig.UsernsMode != "auto" {
+		t.Fatalf("user namespace of fixture = %q, %+v", c.HostConfig.UsernsMode, m)
+	}
+	if uids := m.UIDs(); len(uids) != 3 || uids[1] != (IDMapping{Container: 1000, Host: 0, Size: 1}) || m.RootUID() != "1" {
+		t.Errorf("UIDs() = %+v, RootUID() = %q", uids, m.RootUID())
+	}
+
+	m = &IDMappings{UIDMap: []string{"1:100000:65535", "bad", "0:-1:1", "0:1:2:3"}}
+	if uids := m.UIDs(); len(uids) != 1 || m.RootUID() != "" {
+		t.Errorf("UIDs() = %+v, RootUID() = %q", uids, m.RootUID())
+	}
+	if !(ContainerConfig{User: "0:0"}).RunsAsRoot() || (ContainerConfig{User: "1000"}).RunsAsRoot() {
+		t.Error("RunsAsRoot() is wrong")
+	}
+}
+
+func TestContainerHealthcheck(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/container/jellyfin/healthcheck", http.StatusOK, "probe refused"},
+		{"/container/other/healthcheck", http.StatusOK, "Healthcheck could not be run"},
+		{"/container/!!!/healthcheck", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		resp, err := http.Post(app.URL+tt.path, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("POST %s status = %d, want %d", tt.path, resp.StatusCode, tt.wantStatus)
+		}
+		if !strings.Contains(string(body), tt.wantBody) {
+			t.Errorf("POST %s body does not contain %q", tt.path, tt.wantBody)
+		}
+	}
+
+	s.enableActions = false
+	resp, err := http.Post(app.URL+"/container/jellyfin/healthcheck", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("disabled: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestContainersUsingImage(t *testing.T) {
+	list := loadTestContainers(t)
+	got := containersUsingImage(list, "b76de378d57272a1dd9091a05dd548a3639dfb792ebdbf95d06704d2950afdea")
+	if len(got) < 2 {
+		t.Fatalf("got %d containers, want at least 2", len(got))
+	}
+	for i, c := range got {
+		if c.ImageID != "b76de378d57272a1dd9091a05dd548a3639dfb792ebdbf95d06704d2950afdea" {
+			t.Errorf("container %s has image %s", firstName(c.Names), c.ImageID)
+		}
+		if i > 0 && firstName(got[i-1].Names) > firstName(c.Names) {
+			t.Error("containers not sorted by name")
+		}
+	}
+	docker := []Container{{ImageID: "sha256:abc", Names: []string{"web"}}}
+	if got := containersUsingImage(docker, "sha256:abc"); len(got) != 1 {
+		t.Errorf("docker IDs: got %d containers, want 1", len(got))
+	}
+	if got := containersUsingImage(list, "nonexistent"); len(got) != 0 {
+		t.Errorf("unknown image: got %d containers, want 0", len(got))
+	}
+}
+
+func TestMissingSecrets(t *testing.T) {
+	t.Parallel()
+	var secrets []Secret
+	if err := json.Unmarshal(loadTestFixture(t, "testdata/secrets.json"), &secrets); err != nil {
+		t.Fatal(err)
+	}
+	containers := []ContainerInspect{
+		{ID: "c1", Name: "gitea-web", Config: ContainerConfig{Secrets: []SecretRef{{Name: "gitea_db_password"}, {Name: "smtp_password"}}}},
+		// Recreated secret: same name, different ID.
+		{ID: "c2", Name: "gitea-db", Config: ContainerConfig{Secrets: []SecretRef{{Name: "gitea_db_password", ID: "0000"}}}},
+		{ID: "c3", Name: "redis"},
+	}
+	missing := missingSecrets(secrets, containers)
+	if len(missing) != 2 {
+		t.Fatalf("got %d missing secrets, want 2: %+v", len(missing), missing)
+	}
+	if missing[0].Container.Name != "gitea-db" || missing[0].Ref.ID != "0000" {
+		t.Errorf("missing[0] = %s %+v", missing[0].Container.Name, missing[0].Ref)
+	}
+	if missing[1].Container.Name != "gitea-web" || missing[1].Ref.Name != "smtp_password" {
+		t.Errorf("missing[1] = %s %+v", missing[1].Container.Name, missing[1].Ref)
+	}
+}
+
+func TestFilterContainers(t *testing.T) {
+	t.Parallel()
+	list := []Container{
+		{ID: "a1", Names: []string{"Web"}, Image: "nginx:alpine", State: "running", Labels: map[string]string{"tier": "front"}},
+		{ID: "b2", Names: []string{"db"}, Image: "postgres:16", State: "exited", Labels: map[string]string{"tier": "back"}},
+		{ID: "c3", Names: []string{"cache"}, Image: "redis", State: "running"},
+	}
+	tests := []struct {
+		q, state, label string
+		want            []string
+	}{
+		{"", "", "", []string{"a1", "b2", "c3"}},
+		{"web", "", "", []string{"a1"}},
+		{"POSTGRES", "", "", []string{"b2"}},
+		{"c3", "", "", []string{"c3"}},
+		{"", "running", "", []string{"a1", "c3"}},
+		{"", "", "tier", []string{"a1", "b2"}},
+		{"", "", "tier=back", []string{"b2"}},
+		{"", "running", "tier=back", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, c := range filterContainers(list, tt.q, tt.state, tt.label) {
+			got = append(got, c.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("filterContainers(%q, %q, %q) = %v, want %v", tt.q, tt.state, tt.label, got, tt.want)
+		}
+	}
+	if got := containerStates(list); !slices.Equal(got, []string{"exited", "running"}) {
+		t.Errorf("containerStates = %v", got)
+	}
+}
+
+func TestContainersSearch(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	app := httptest.NewServer(newTestServer(t, mock).newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/containers?q=gitea&state=running")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), ">gitea-web<") || !strings.Contains(string(body), ">gitea-db<") || strings.Contains(string(body), ">jellyfin<") {
+		t.Error("search for gitea does not show exactly the gitea containers")
+	}
+	if !strings.Contains(string(body), `value="gitea"`) {
+		t.Error("search form does not keep the query")
+	}
+}
+
+func TestPageETagIgnoresRelativeTimes(t *testing.T) {
+	t.Parallel()
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	page := func(ago string) []byte {
+		return []byte(`<td><span class="ago" title="` + started.Format("2006-01-02 15:04:05 MST") + `">` + ago + `</span></td>`)
+	}
+	if pageETag(page("5s ago")) != pageETag(page("2m ago")) {
+		t.Error("ETag changes with the relative time")
+	}
+	if pageETag(page("5s ago")) == pageETag(bytes.Replace(page("5s ago"), []byte("12:00"), []byte("12:01"), 1)) {
+		t.Error("ETag ignores the absolute time")
+	}
+	if !relativeTime.Match([]byte(formatTime(started))) {
+		t.Errorf("formatTime output %q does not match relativeTime", formatTime(started))
+	}
+}
+
+func TestPageETag(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	app := httptest.NewServer(newTestServer(t, mock).newMux("podman"))
+	defer app.Close()
+
+	get := func(ifNoneMatch string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, app.URL+"/containers", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp
+	}
+	resp := get("")
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(etag, `"`) || resp.Header.Get("Cache-Control") != "private, no-cache" {
+		t.Fatalf("first load: %d, ETag %q, Cache-Control %q", resp.StatusCode, etag, resp.Header.Get("Cache-Control"))
+	}
+	if resp := get(`"other", W/` + etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("unchanged page: status %d, want 304", resp.StatusCode)
+	}
+	if resp := get(`"other"`); resp.StatusCode != http.StatusOK {
+		t.Errorf("other ETag: status %d, want 200", resp.StatusCode)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auto-update runs are kept in a JSON file in the data dir, so their
+// outcome can still be looked up after the job has been evicted or
+// podfather restarted.
+
+const (
+	maxAutoUpdateRuns   = 100
+	maxAutoUpdateOutput = 64 << 10
+)
+
+// AutoUpdateRun is a finished `podman auto-update` run.
+type AutoUpdateRun struct {
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Err        string    `json:"err,omitempty"`
+	Updated    []string  `json:"updated,omitempty"`
+	RolledBack []string  `json:"rolled_back,omitempty"`
+	Failed     []string  `json:"failed,omitempty"`
+	Output     string    `json:"output"`
+}
+
+// Status returns "done" or "failed", matching the job badges.
+func (run AutoUpdateRun) Status() string {
+	if run.Err != "" || len(run.Failed) > 0 || len(run.RolledBack) > 0 {
+		return "failed"
+	}
+	return "done"
+}
+
+var columnSep = regexp.MustCompile(`\s{2,}`)
+
+// parseAutoUpdateOutput sorts the units in the table printed by
+// `podman auto-update` by their UPDATED column:
+//
+//	UNIT           CONTAINER            IMAGE                 POLICY      UPDATED
+//	web.service    0a1b2c3d4e5f (web)   docker.io/nginx:1     registry    true
+func parseAutoUpdateOutput(out string, run *AutoUpdateRun) {
+	inTable := false
+	for line := range strings.Lines(out) {
+		fields := columnSep.Split(strings.TrimSpace(line), -1)
+		if len(fields) >= 5 && fields[0] == "UNIT" && fields[len(fields)-1] == "UPDATED" {
+			inTable = true
+			continue
+		}
+		if !inTable || len(fields) < 5 {
+			continue
+		}
+		unit := fields[0]
+		switch fields[len(fields)-1] {
+		case "true":
+			run.Updated = append(run.Updated, unit)
+		case "rolled back":
+			run.RolledBack = append(run.RolledBack, unit)
+		case "failed":
+			run.Failed = append(run.Failed, unit)
+		}
+	}
+}
+
+// autoUpdateHistory stores finished runs, newest last. With an empty path
+// it is kept in memory only.
+type autoUpdateHistory struct {
+	path string
+
+	mu   sync.Mutex
+	runs []AutoUpdateRun
+}
+
+func (h *autoUpdateHistory) load() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	data, err := os.ReadFile(h.path)
+	if err == nil {
+		err = json.Unmarshal(data, &h.runs)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("auto-update history: loading %s: %v", h.path, err)
+	}
+}
+
+// add records the run of the finished job j.
+func (h *autoUpdateHistory) add(j *job) {
+	out, _, errMsg := j.read(0)
+	run := AutoUpdateRun{
+		Started:  j.Started,
+		Finished: time.Now(),
+		Err:      errMsg,
+		Output:   string(out),
+	}
+	parseAutoUpdateOutput(run.Output, &run)
+	if len(run.Output) > maxAutoUpdateOutput {
+		run.Output = "[...]\n" + run.Output[len(run.Output)-maxAutoUpdateOutput:]
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.runs = append(h.runs, run)
+	if len(h.runs) > maxAutoUpdateRuns {
+		h.runs = h.runs[len(h.runs)-maxAutoUpdateRuns:]
+	}
+	if h.path == "" {
+		return
+	}
+	if err := h.save(); err != nil {
+		log.Printf("auto-update history: %v", err)
+	}
+}
+
+func (h *autoUpdateHistory) save() error {
+	data, err := json.Marshal(h.runs)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(h.path, data, 0o600)
+}
+
+// list returns the runs, newest first.
+func (h *autoUpdateHistory) list() []AutoUpdateRun {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]AutoUpdateRun, len(h.runs))
+	for i, run := range h.runs {
+		out[len(out)-1-i] = run
+	}
+	return out
+}
+
+func (s *Server) handleAutoUpdateHistory(w http.ResponseWriter, r *http.Request) {
+	if !s.enableAutoUpdate {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	s.render(w, r, "history.html", map[string]any{
+		"Title": "Auto Update History",
+		"Runs":  s.autoUpdateHistory.list(),
+	})
+}
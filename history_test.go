@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const autoUpdateOutput = `Trying to pull docker.io/library/nginx:1...
+Writing manifest to image destination
+UNIT            CONTAINER              IMAGE                      POLICY      UPDATED
+web.service     0a1b2c3d4e5f (web)     docker.io/library/nginx:1  registry    true
+db.service      1a2b3c4d5e6f (db)      docker.io/library/pg:16    registry    false
+cache.service   2a3b4c5d6e7f (cache)   docker.io/library/redis:7  registry    rolled back
+app.service     3a4b5c6d7e8f (app)     ghcr.io/org/app:latest     registry    failed
+`
+
+func TestParseAutoUpdateOutput(t *testing.T) {
+	var run AutoUpdateRun
+	parseAutoUpdateOutput(autoUpdateOutput, &run)
+	if !reflect.DeepEqual(run.Updated, []string{"web.service"}) {
+		t.Errorf("Updated = %v", run.Updated)
+	}
+	if !reflect.DeepEqual(run.RolledBack, []string{"cache.service"}) {
+		t.Errorf("RolledBack = %v", run.RolledBack)
+	}
+	if !reflect.DeepEqual(run.Failed, []string{"app.service"}) {
+		t.Errorf("Failed = %v", run.Failed)
+	}
+	if run.Status() != "failed" {
+		t.Errorf("Status() = %q, want failed", run.Status())
+	}
+}
+
+func TestAutoUpdateHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	h := &autoUpdateHistory{path: path}
+
+	var js jobStore
+	j := js.start("Auto Update")
+	io.WriteString(j, autoUpdateOutput)
+	j.finish(nil)
+	h.add(j)
+	j = js.start("Auto Update")
+	j.finish(errors.New("exit status 125"))
+	h.add(j)
+
+	h = &autoUpdateHistory{path: path}
+	h.load()
+	runs := h.list()
+	if len(runs) != 2 {
+		t.Fatalf("len = %d, want 2", len(runs))
+	}
+	if runs[0].Err != "exit status 125" || len(runs[1].Updated) != 1 {
+		t.Errorf("runs not newest first: %+v", runs)
+	}
+
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.autoUpdateHistory.runs = h.runs
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/auto-update/history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("disabled: status = %d, want 404", resp.StatusCode)
+	}
+
+	s.enableAutoUpdate = true
+	resp, err = http.Get(app.URL + "/auto-update/history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "cache.service") || !strings.Contains(string(body), "exit status 125") {
+		t.Error("history page is missing runs")
+	}
+}
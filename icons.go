@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// App icons are an emoji, shown as text, an image URL, or the name of an
+// icon of the dashboard-icons set (https://github.com/homarr-labs/dashboard-icons),
+// e.g. "jellyfin" or "jellyfin.svg". Images are fetched on first use, kept in
+// $DATA_DIR/icons and served from /icons/{key}, as the pages may only load
+// images from podfather. Only the icons of current apps are fetched, so the
+// route cannot make podfather fetch arbitrary URLs.
+
+const (
+	dashboardIconsURL = "https://cdn.jsdelivr.net/gh/homarr-labs/dashboard-icons"
+	maxIconSize       = 1 << 20
+	iconRetry         = time.Hour // until a failed fetch is tried again
+)
+
+var (
+	dashboardIconName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*(\.(png|svg|webp))?$`)
+	validIconKey      = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// iconSource returns the URL to fetch an image icon from, or "" if icon is
+// not an image, e.g. an emoji. Dashboard icons are fetched from base.
+func iconSource(icon, base string) string {
+	if u, err := url.Parse(icon); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		return icon
+	}
+	if !dashboardIconName.MatchString(icon) {
+		return ""
+	}
+	name, ext := icon, "png"
+	if i := strings.LastIndexByte(icon, '.'); i >= 0 {
+		name, ext = icon[:i], icon[i+1:]
+	}
+	return base + "/" + ext + "/" + name + "." + ext
+}
+
+// iconKey returns the key of an image icon in the cache and the /icons/
+// route, or "" if icon is not an image.
+func iconKey(icon string) string {
+	if iconSource(icon, dashboardIconsURL) == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(icon))
+	return hex.EncodeToString(sum[:16])
+}
+
+// iconContentType returns the type of an image, or "" if data is not one.
+func iconContentType(data []byte) string {
+	if ct := http.DetectContentType(data); strings.HasPrefix(ct, "image/") {
+		return ct
+	}
+	if bytes.Contains(data[:min(len(data), 512)], []byte("<svg")) {
+		return "image/svg+xml"
+	}
+	return ""
+}
+
+// iconCache fetches image icons and keeps them in dir.
+type iconCache struct {
+	dir     string
+	baseURL string // of the dashboard icons
+	client  *http.Client
+
+	mu     sync.Mutex // serializes fetches
+	failed map[string]time.Time
+}
+
+func newIconCache(dir string) *iconCache {
+	return &iconCache{
+		dir:     dir,
+		baseURL: dashboardIconsURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		failed:  map[string]time.Time{},
+	}
+}
+
+// get returns the image of icon, fetching it unless it is cached. Fetching
+// is not retried for iconRetry after a failure.
+func (c *iconCache) get(ctx context.Context, icon string) ([]byte, error) {
+	key := iconKey(icon)
+	if key == "" {
+		return nil, errors.New("not an image icon")
+	}
+	path := filepath.Join(c.dir, key)
+	if data, err := os.ReadFile(path); err == nil {
+		return data, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if data, err := os.ReadFile(path); err == nil {
+		return data, nil // fetched while waiting
+	}
+	if t, ok := c.failed[key]; ok && time.Since(t) < iconRetry {
+		return nil, errors.New("fetching failed recently")
+	}
+	data, err := c.fetch(ctx, iconSource(icon, c.baseURL))
+	if err == nil {
+		err = writeFileAtomic(path, data, 0o600)
+	}
+	if err != nil {
+		c.failed[key] = time.Now()
+		return nil, err
+	}
+	delete(c.failed, key)
+	return data, nil
+}
+
+func (c *iconCache) fetch(ctx context.Context, src string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", src, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIconSize+1))
+	switch {
+	case err != nil:
+		return nil, err
+	case len(data) > maxIconSize:
+		return nil, fmt.Errorf("%s: larger than %d bytes", src, maxIconSize)
+	case iconContentType(data) == "":
+		return nil, fmt.Errorf("%s: not an image", src)
+	}
+	return data, nil
+}
+
+// appIcon returns the icon of a current app or category with the key, if
+// any.
+func (s *Server) appIcon(ctx context.Context, key string) (string, error) {
+	list, err := s.listContainers(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, cat := range s.buildAppCategories(list) {
+		if iconKey(cat.Icon) == key {
+			return cat.Icon, nil
+		}
+		for _, app := range cat.Apps {
+			if iconKey(app.Icon) == key {
+				return app.Icon, nil
+			}
+		}
+	}
+	return "", errNotFound
+}
+
+func (s *Server) handleIcon(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if s.icons == nil || !validIconKey.MatchString(key) {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	icon, err := s.appIcon(r.Context(), key)
+	if errors.Is(err, errNotFound) {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	data, err := s.icons.get(r.Context(), icon)
+	if err != nil {
+		log.Printf("[%s] icon %s: %v", reqID(r.Context()), icon, err)
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", iconContentType(data))
+	// SVGs may contain scripts, which must not run if one is opened directly.
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(data)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const testIconSVG = `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1 1"></svg>`
+
+func TestIconSource(t *testing.T) {
+	t.Parallel()
+	const base = "https://cdn.example.com/icons"
+	for _, tt := range []struct{ icon, want string }{
+		{"☁️", ""},
+		{"", ""},
+		{"Router", ""},
+		{"mdi:home", ""},
+		{"jellyfin", base + "/png/jellyfin.png"},
+		{"home-assistant.svg", base + "/svg/home-assistant.svg"},
+		{"https://example.com/a.png", "https://example.com/a.png"},
+		{"ftp://example.com/a.png", ""},
+	} {
+		if got := iconSource(tt.icon, base); got != tt.want {
+			t.Errorf("iconSource(%q) = %q, want %q", tt.icon, got, tt.want)
+		}
+		if key := iconKey(tt.icon); (key != "") != (tt.want != "") || (key != "" && !validIconKey.MatchString(key)) {
+			t.Errorf("iconKey(%q) = %q", tt.icon, key)
+		}
+	}
+}
+
+func TestIconCache(t *testing.T) {
+	t.Parallel()
+	var fetches atomic.Int32
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		switch r.URL.Path {
+		case "/svg/gitea.svg":
+			w.Write([]byte(testIconSVG))
+		case "/png/page.png":
+			w.Write([]byte("<html>not found</html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer cdn.Close()
+	c := newIconCache(t.TempDir())
+	c.baseURL = cdn.URL
+	ctx := context.Background()
+
+	for range 2 {
+		data, err := c.get(ctx, "gitea.svg")
+		if err != nil || string(data) != testIconSVG {
+			t.Fatalf("get = %q, %v", data, err)
+		}
+	}
+	if iconContentType([]byte(testIconSVG)) != "image/svg+xml" {
+		t.Errorf("content type = %q", iconContentType([]byte(testIconSVG)))
+	}
+	for _, icon := range []string{"page", "missing", "missing"} {
+		if _, err := c.get(ctx, icon); err == nil {
+			t.Errorf("get(%q) succeeded", icon)
+		}
+	}
+	// The second miss is not fetched again.
+	if n := fetches.Load(); n != 3 {
+		t.Errorf("fetches = %d, want 3", n)
+	}
+}
+
+func TestIconRoute(t *testing.T) {
+	t.Parallel()
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testIconSVG))
+	}))
+	defer cdn.Close()
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]Container{{ID: "abc123", Names: []string{"git"}, State: "running",
+			Labels: map[string]string{appLabelPrefix + "name": "Gitea", appLabelPrefix + "icon": "gitea.svg", appLabelPrefix + "url": "https://git.example.com"}}})
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.icons = newIconCache(t.TempDir())
+	s.icons.baseURL = cdn.URL
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/apps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	src := "/icons/" + iconKey("gitea.svg")
+	if !strings.Contains(string(body), `<img class="app-icon" src="`+src+`"`) {
+		t.Errorf("apps page lacks the icon %s", src)
+	}
+
+	for _, tt := range []struct {
+		path string
+		want int
+	}{
+		{src, http.StatusOK},
+		{"/icons/" + iconKey("https://internal.example.com/secret.png"), http.StatusNotFound}, // not an app's icon
+		{"/icons/nothex", http.StatusNotFound},
+	} {
+		resp, err := http.Get(app.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.path, resp.StatusCode, tt.want)
+		}
+		if tt.want == http.StatusOK && (resp.Header.Get("Content-Type") != "image/svg+xml" || !strings.Contains(resp.Header.Get("Content-Security-Policy"), "sandbox")) {
+			t.Errorf("%s: headers = %v", tt.path, resp.Header)
+		}
+	}
+
+	resp, err = http.Get(app.URL + "/apps/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `src="data:image/svg`) || strings.Contains(string(body), src) {
+		t.Error("export does not inline the icon")
+	}
+}
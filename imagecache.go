@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Image IDs are the digest of the image config, so the inspect data of an
+// image never changes, except for the names pointing to it (RepoTags,
+// RepoDigests, NamesHistory). The image cache keeps inspect results by full
+// ID in $DATA_DIR/image-cache.json. Pulls, tags and prunes done by podfather
+// invalidate it; changes made outside podfather only show after a refresh of
+// the image page.
+
+const maxCachedImages = 500
+
+var fullImageID = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type cachedImage struct {
+	Image ImageInspect `json:"image"`
+	Used  time.Time    `json:"used"`
+}
+
+// imageCache caches image inspects. With an empty path it is kept in memory
+// only.
+type imageCache struct {
+	path string
+
+	mu     sync.Mutex
+	loaded bool
+	images map[string]cachedImage // by ID without the "sha256:" prefix
+}
+
+// get returns the cached inspect of the image with the given ID.
+func (ic *imageCache) get(id string) (ImageInspect, bool) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.load()
+	id = strings.TrimPrefix(id, "sha256:")
+	ci, ok := ic.images[id]
+	if !ok {
+		return ImageInspect{}, false
+	}
+	ci.Used = time.Now()
+	ic.images[id] = ci
+	return ci.Image, true
+}
+
+// put adds an inspect result, evicting the least recently used entries.
+func (ic *imageCache) put(img ImageInspect) {
+	id := strings.TrimPrefix(img.ID, "sha256:")
+	if !fullImageID.MatchString(id) {
+		return
+	}
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.load()
+	ic.images[id] = cachedImage{Image: img, Used: time.Now()}
+	if len(ic.images) > maxCachedImages {
+		ids := make([]string, 0, len(ic.images))
+		for id := range ic.images {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ic.images[ids[i]].Used.Before(ic.images[ids[j]].Used) })
+		for _, id := range ids[:len(ids)-maxCachedImages] {
+			delete(ic.images, id)
+		}
+	}
+	ic.save()
+}
+
+// invalidate drops the given images, or all if none are given.
+func (ic *imageCache) invalidate(ids ...string) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.load()
+	if len(ids) == 0 {
+		clear(ic.images)
+	}
+	for _, id := range ids {
+		delete(ic.images, strings.TrimPrefix(id, "sha256:"))
+	}
+	ic.save()
+}
+
+// load reads the cache file on first use. Callers must hold mu.
+func (ic *imageCache) load() {
+	if ic.loaded {
+		return
+	}
+	ic.loaded = true
+	ic.images = map[string]cachedImage{}
+	if ic.path == "" {
+		return
+	}
+	data, err := os.ReadFile(ic.path)
+	if err == nil {
+		err = json.Unmarshal(data, &ic.images)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("image cache: loading %s: %v", ic.path, err)
+	}
+}
+
+// save writes the cache file. Callers must hold mu.
+func (ic *imageCache) save() {
+	if ic.path == "" {
+		return
+	}
+	data, err := json.Marshal(ic.images)
+	if err == nil {
+		err = writeFileAtomic(ic.path, data, 0o600)
+	}
+	if err != nil {
+		log.Printf("image cache: %v", err)
+	}
+}
+
+// inspectImage returns the inspect data of the image with the given ID or
+// reference. Only lookups by full ID are served from the cache, as
+// references can move to other images.
+func (s *Server) inspectImage(ctx context.Context, id string) (ImageInspect, error) {
+	cacheable := fullImageID.MatchString(strings.TrimPrefix(id, "sha256:"))
+	if cacheable {
+		if img, ok := s.images.get(id); ok {
+			return img, nil
+		}
+	}
+	var img ImageInspect
+	if err := s.podmanGet(ctx, "/images/"+id+"/json", &img); err != nil {
+		return img, err
+	}
+	if cacheable {
+		s.images.put(img)
+	}
+	return img, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestImageCache(t *testing.T) {
+	t.Parallel()
+	const id = "1d13701a5f9f3fb01aaa88cef2344d65b6b5bf6b7d9fa4cf0dca557a8d7702ba"
+	var calls atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/v4.0.0/libpod/images/" + id + "/json", "/v4.0.0/libpod/images/nginx:alpine/json":
+			fmt.Fprintf(w, `{"Id":%q,"RepoTags":["docker.io/library/nginx:alpine"]}`, id)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	path := filepath.Join(t.TempDir(), "image-cache.json")
+	s := newTestServer(t, api)
+	s.images.path = path
+	for range 3 {
+		img, err := s.inspectImage(t.Context(), id)
+		if err != nil || img.ID != id {
+			t.Fatalf("inspectImage = %+v, %v", img, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("%d API calls for 3 inspects by ID, want 1", n)
+	}
+
+	// References can move, so they are never cached.
+	s.inspectImage(t.Context(), "nginx:alpine")
+	s.inspectImage(t.Context(), "nginx:alpine")
+	if n := calls.Load(); n != 3 {
+		t.Errorf("%d API calls, want 3", n)
+	}
+
+	// The cache survives a restart.
+	s2 := newTestServer(t, api)
+	s2.images.path = path
+	if _, err := s2.inspectImage(t.Context(), "sha256:"+id); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("%d API calls after restart, want 3", n)
+	}
+
+	s2.images.invalidate(id)
+	s2.inspectImage(t.Context(), id)
+	if n := calls.Load(); n != 4 {
+		t.Errorf("%d API calls after invalidate, want 4", n)
+	}
+
+	if _, err := s2.inspectImage(t.Context(), strings.Repeat("0", 64)); err == nil {
+		t.Error("missing image: no error")
+	}
+}
+
+func TestImageCacheEviction(t *testing.T) {
+	var ic imageCache
+	for i := range maxCachedImages + 10 {
+		ic.put(ImageInspect{ID: fmt.Sprintf("%064x", i)})
+	}
+	if len(ic.images) != maxCachedImages {
+		t.Errorf("%d cached images, want %d", len(ic.images), maxCachedImages)
+	}
+	if _, ok := ic.get(fmt.Sprintf("%064x", 0)); ok {
+		t.Error("oldest image was not evicted")
+	}
+	if _, ok := ic.get(fmt.Sprintf("%064x", maxCachedImages+9)); !ok {
+		t.Error("newest image was evicted")
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Images can be downloaded as a tarball, e.g. to copy them to a host
+// without registry access, and such tarballs loaded again. The archive is
+// streamed from the API as is, so it is not size limited like file
+// downloads, and the export route has no request deadline. Loads run as a
+// job that reports how much of the upload was sent to the API.
+
+// imageArchiveFormats maps the format query values of an export to the
+// libpod archive formats.
+var imageArchiveFormats = map[string]string{
+	"docker": "docker-archive",
+	"oci":    "oci-archive",
+}
+
+// isImageExport reports whether path (without BASE_PATH) is an image
+// export.
+func isImageExport(path string) bool {
+	return strings.HasPrefix(path, "/image/") && strings.HasSuffix(path, "/export")
+}
+
+func (s *Server) handleImageExport(w http.ResponseWriter, r *http.Request) {
+	if !s.enableFileDownload {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid image ID", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "docker"
+	}
+	archive, ok := imageArchiveFormats[format]
+	if !ok || (s.backend == backendDocker && format != "docker") {
+		http.Error(w, "Invalid format, want docker or oci (Podman only)", http.StatusBadRequest)
+		return
+	}
+	img, err := s.inspectImage(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Image Not Found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	path := "/images/" + img.ID + "/get"
+	if s.backend != backendDocker {
+		path += "?" + url.Values{"format": {archive}}.Encode()
+	}
+	target := imageName(img) + " (" + archive + ")"
+	resp, err := s.podmanDo(r.Context(), http.MethodGet, path, nil)
+	s.actions.record(r, "export image", target, err, "", nil)
+	if err != nil {
+		log.Printf("[%s] export %s: %v", reqID(r.Context()), target, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+	name := unsafeFilenameChars.ReplaceAllString(imageName(img), "_") + "-" + format + ".tar"
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	if resp.ContentLength >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		// Headers are sent, the client sees a truncated download.
+		log.Printf("[%s] export %s: %v", reqID(r.Context()), target, err)
+	}
+}
+
+// maxImageLoadSize bounds uploaded image tarballs. Uploads are spooled to a
+// temporary file while the form is parsed.
+const maxImageLoadSize = 4 << 30
+
+// progressReader writes to out how much of size bytes were read, in steps
+// of 10%.
+type progressReader struct {
+	r    io.Reader
+	out  io.Writer
+	size int64
+	read int64
+	next int64 // percentage to report next
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if pct := p.read * 100 / max(p.size, 1); pct >= p.next && p.next <= 100 {
+		fmt.Fprintf(p.out, "sent %s of %s (%d%%)\n", humanSize(p.read), humanSize(p.size), pct)
+		p.next = pct - pct%10 + 10
+	}
+	return n, err
+}
+
+// loadImage loads the image tarball body, writing progress lines to out,
+// and returns the names of the loaded images. The libpod API answers with
+// the names, the compat API streams JSON objects like a pull.
+func (s *Server) loadImage(ctx context.Context, body io.Reader, out io.Writer) ([]string, error) {
+	resp, err := s.podmanDo(ctx, http.MethodPost, "/images/load", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	defer s.images.invalidate()
+	var names []string
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Names  []string `json:"Names"`
+			Stream string   `json:"stream"`
+			Error  string   `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return names, nil
+		} else if err != nil {
+			return names, fmt.Errorf("load: %w", err)
+		}
+		if msg.Error != "" {
+			return names, fmt.Errorf("load: %s", msg.Error)
+		}
+		names = append(names, msg.Names...)
+		if line := strings.TrimSpace(msg.Stream); line != "" {
+			fmt.Fprintln(out, line)
+			if name, ok := strings.CutPrefix(line, "Loaded image: "); ok {
+				names = append(names, name)
+			}
+		}
+	}
+}
+
+func (s *Server) handleImageLoad(w http.ResponseWriter, r *http.Request) {
+	if !s.enableFileUpload {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	f, fh, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "No file uploaded", http.StatusBadRequest)
+		return
+	}
+	if fh.Size > maxImageLoadSize {
+		f.Close()
+		http.Error(w, "File too large, the limit is "+strconv.Itoa(maxImageLoadSize>>30)+" GiB", http.StatusRequestEntityTooLarge)
+		return
+	}
+	name := unsafeFilenameChars.ReplaceAllString(fh.Filename, "_")
+	s.actions.record(r, "load image", name, nil, "", nil)
+	j := s.jobs.start("Load image " + name)
+	// The spooled upload is removed when this handler returns; the open file
+	// stays readable until the job closes it.
+	go func() {
+		defer f.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		j.logf("==> Sending %s (%s)", name, humanSize(fh.Size))
+		names, err := s.loadImage(ctx, &progressReader{r: f, out: j, size: fh.Size, next: 10}, j)
+		for _, n := range names {
+			j.logf("==> Loaded %s", n)
+		}
+		j.finish(err)
+	}()
+	http.Redirect(w, r, s.basePath+"/job/"+j.ID, http.StatusSeeOther)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestImageExport(t *testing.T) {
+	t.Parallel()
+	imageID := strings.Repeat("34", 32)
+	var gets []string
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/get") {
+			gets = append(gets, strings.TrimPrefix(r.URL.RequestURI(), "/v4.0.0/libpod"))
+			w.Write([]byte("tarball"))
+			return
+		}
+		json.NewEncoder(w).Encode(ImageInspect{ID: imageID, RepoTags: []string{"ghcr.io/org/web:1.2"}})
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	get := func(query string) *http.Response {
+		t.Helper()
+		resp, err := http.Get(app.URL + "/image/" + imageID + "/export" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	if resp := get(""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("without ENABLE_FILE_DOWNLOAD: status = %d", resp.StatusCode)
+	}
+
+	s.enableFileDownload = true
+	if resp := get("?format=zip"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("format=zip: status = %d", resp.StatusCode)
+	}
+	resp := get("?format=oci")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "tarball" {
+		t.Errorf("body = %q", body)
+	}
+	if got, want := resp.Header.Get("Content-Disposition"), `attachment; filename="ghcr.io_org_web_1.2-oci.tar"`; got != want {
+		t.Errorf("Content-Disposition = %q, want %q", got, want)
+	}
+	if len(gets) != 1 || gets[0] != "/images/"+imageID+"/get?format=oci-archive" {
+		t.Errorf("export calls = %v", gets)
+	}
+	if got := s.actions.list(); len(got) != 1 || got[0].Target != "ghcr.io/org/web:1.2 (oci-archive)" {
+		t.Errorf("actions = %+v", got)
+	}
+}
+
+func TestProgressReader(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	p := &progressReader{r: iotest.OneByteReader(strings.NewReader(strings.Repeat("x", 20))), out: &out, size: 20, next: 10}
+	if _, err := io.Copy(io.Discard, p); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 10 || lines[0] != "sent 2 B of 20 B (10%)" || lines[9] != "sent 20 B of 20 B (100%)" {
+		t.Errorf("progress:\n%s", out.String())
+	}
+}
+
+func TestImageLoad(t *testing.T) {
+	t.Parallel()
+	var loaded []byte
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/images/load") {
+			loaded, _ = io.ReadAll(r.Body)
+			w.Write([]byte(`{"Names":["localhost/tool:1"]}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableFileUpload = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "tool.tar")
+	fw.Write([]byte("tarball"))
+	mw.Close()
+	resp, err := http.Post(app.URL+"/images/load", mw.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	id, ok := strings.CutPrefix(resp.Request.URL.Path, "/job/")
+	if !ok {
+		t.Fatalf("redirected to %s, want a job", resp.Request.URL.Path)
+	}
+	j := s.jobs.get(id)
+	waitJob(t, j)
+	out, _, errMsg := j.read(0)
+	if j.Status() != "done" {
+		t.Fatalf("status = %s (err %q), output:\n%s", j.Status(), errMsg, out)
+	}
+	for _, want := range []string{"sent 7 B of 7 B (100%)", "==> Loaded localhost/tool:1"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if string(loaded) != "tarball" {
+		t.Errorf("loaded %q", loaded)
+	}
+	if got := s.actions.list(); len(got) != 1 || got[0].Name != "load image" || got[0].Target != "tool.tar" {
+		t.Errorf("actions = %+v", got)
+	}
+}
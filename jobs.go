@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxJobs bounds the number of finished jobs kept in memory.
+const maxJobs = 50
+
+// job is a background task whose output is streamed to the browser via SSE.
+// It implements io.Writer so command output can be attached directly.
+type job struct {
+	ID      string
+	Title   string
+	Started time.Time
+
+	mu       sync.Mutex
+	buf      []byte
+	done     bool
+	err      string
+	finished time.Time
+}
+
+func (j *job) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	j.buf = append(j.buf, p...)
+	j.mu.Unlock()
+	return len(p), nil
+}
+
+// logf appends a line to the job output.
+func (j *job) logf(format string, args ...any) {
+	fmt.Fprintf(j, format+"\n", args...)
+}
+
+// finish marks the job as done, recording err if non-nil.
+func (j *job) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err != nil {
+		j.err = err.Error()
+	}
+	j.done = true
+	j.finished = time.Now()
+}
+
+// read returns the complete output lines written after offset. Partial
+// lines are held back until the job is done.
+func (j *job) read(offset int) (data []byte, done bool, errMsg string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	data = j.buf[offset:]
+	if !j.done {
+		data = data[:bytes.LastIndexByte(data, '\n')+1]
+	}
+	return bytes.Clone(data), j.done, j.err
+}
+
+// Status returns "running", "done" or "failed".
+func (j *job) Status() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case !j.done:
+		return "running"
+	case j.err != "":
+		return "failed"
+	default:
+		return "done"
+	}
+}
+
+// jobStore keeps recent jobs by ID.
+type jobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// start registers a new job. The caller runs it and calls finish.
+func (js *jobStore) start(title string) *job {
+	var b [8]byte
+	rand.Read(b[:])
+	j := &job{ID: fmt.Sprintf("%x", b), Title: title, Started: time.Now()}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if js.jobs == nil {
+		js.jobs = make(map[string]*job)
+	}
+	js.jobs[j.ID] = j
+	if len(js.jobs) > maxJobs {
+		// Evict the oldest finished job.
+		var oldest *job
+		for _, o := range js.jobs {
+			if o.Status() != "running" && (oldest == nil || o.Started.Before(oldest.Started)) {
+				oldest = o
+			}
+		}
+		if oldest != nil {
+			delete(js.jobs, oldest.ID)
+		}
+	}
+	return j
+}
+
+func (js *jobStore) get(id string) *job {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	return js.jobs[id]
+}
+
+// list returns all jobs, newest first.
+func (js *jobStore) list() []*job {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	out := make([]*job, 0, len(js.jobs))
+	for _, j := range js.jobs {
+		out = append(out, j)
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i].Started.After(out[k].Started) })
+	return out
+}
+
+// runCommand runs a command, writing its combined output to out.
+func runCommand(ctx context.Context, out io.Writer, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// streamJob streams the output of j as server-sent events until it is done
+// or the client disconnects, masking it with m in privacy mode. A nil job
+// produces a single "no-update" done event.
+func streamJob(w http.ResponseWriter, r *http.Request, j *job, m *masker) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	if j == nil {
+		fmt.Fprintf(w, "event: done\ndata: no-update\n\n")
+		flusher.Flush()
+		return
+	}
+
+	offset := 0
+	for {
+		data, isDone, errMsg := j.read(offset)
+
+		if len(data) > 0 {
+			text := string(data)
+			for len(text) > 0 {
+				idx := strings.IndexByte(text, '\n')
+				if idx < 0 {
+					fmt.Fprintf(w, "data: %s\n\n", m.mask(text))
+					break
+				}
+				fmt.Fprintf(w, "data: %s\n\n", m.mask(text[:idx]))
+				text = text[idx+1:]
+			}
+			offset += len(data)
+			flusher.Flush()
+		}
+
+		if isDone {
+			if errMsg != "" {
+				fmt.Fprintf(w, "event: err\ndata: %s\n\n", m.mask(errMsg))
+			}
+			fmt.Fprintf(w, "event: done\ndata: \n\n")
+			flusher.Flush()
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	s.render(w, r, "jobs.html", map[string]any{
+		"Title": "Jobs",
+		"Jobs":  s.jobs.list(),
+	})
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	j := s.jobs.get(r.PathValue("id"))
+	if j == nil {
+		http.Error(w, "Job Not Found", http.StatusNotFound)
+		return
+	}
+	s.render(w, r, "job.html", map[string]any{
+		"Title":     j.Title,
+		"EventsURL": s.basePath + "/job/" + j.ID + "/events",
+		"BackURL":   s.basePath + "/jobs",
+	})
+}
+
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	j := s.jobs.get(r.PathValue("id"))
+	if j == nil {
+		http.Error(w, "Job Not Found", http.StatusNotFound)
+		return
+	}
+	streamJob(w, r, j, s.pageMasker(r))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJobRead(t *testing.T) {
+	t.Parallel()
+	j := &job{}
+	j.Write([]byte("line 1\nparti"))
+	data, done, _ := j.read(0)
+	if string(data) != "line 1\n" || done {
+		t.Errorf("read = %q, %v; want complete lines only", data, done)
+	}
+	j.Write([]byte("al"))
+	j.finish(errors.New("boom"))
+	data, done, errMsg := j.read(len("line 1\n"))
+	if string(data) != "partial" || !done || errMsg != "boom" {
+		t.Errorf("read = %q, %v, %q; want rest after finish", data, done, errMsg)
+	}
+	if j.Status() != "failed" {
+		t.Errorf("status = %q, want failed", j.Status())
+	}
+}
+
+func TestJobPages(t *testing.T) {
+	t.Parallel()
+	s := &Server{}
+	j := s.jobs.start("Test Job")
+	j.logf("hello")
+	j.finish(nil)
+
+	app := httptest.NewServer(s.newMux("true"))
+	defer app.Close()
+
+	for path, want := range map[string]int{
+		"/jobs":                    http.StatusOK,
+		"/job/" + j.ID:             http.StatusOK,
+		"/job/" + j.ID + "/events": http.StatusOK,
+		"/job/nonexistent":         http.StatusNotFound,
+		"/job/nonexistent/events":  http.StatusNotFound,
+	} {
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// The journal of a systemd-managed container's unit has what the container
+// logs miss: systemd starting, stopping and restarting the unit, and Podman
+// failing before the container runs. It is read with `journalctl --user -o
+// json`, bounded by the ?tail= lines and an optional ?since= duration, and
+// shown like container logs.
+
+// maxJournalSince bounds how far back ?since= reaches.
+const maxJournalSince = 31 * 24 * time.Hour
+
+// journalLevels maps syslog priorities to log levels.
+var journalLevels = []string{"fatal", "fatal", "fatal", "error", "warn", "info", "info", "debug"}
+
+// parseJournal parses `journalctl -o json` output. Messages keep a level
+// parsed from their text; otherwise the priority is used. Lines are tagged
+// with the syslog identifier, e.g. systemd, podman or the container.
+func parseJournal(out []byte) []LogLine {
+	var lines []LogLine
+	colors := map[string]int{}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var e struct {
+			Realtime   string          `json:"__REALTIME_TIMESTAMP"` // microseconds
+			Message    json.RawMessage `json:"MESSAGE"`
+			Priority   string          `json:"PRIORITY"`
+			Identifier string          `json:"SYSLOG_IDENTIFIER"`
+		}
+		if json.Unmarshal(sc.Bytes(), &e) != nil {
+			continue
+		}
+		// Messages that are not valid UTF-8 are arrays of bytes.
+		var msg string
+		if json.Unmarshal(e.Message, &msg) != nil {
+			var b []byte
+			var ints []int
+			json.Unmarshal(e.Message, &ints)
+			for _, i := range ints {
+				b = append(b, byte(i))
+			}
+			msg = string(bytes.ToValidUTF8(b, []byte("�")))
+		}
+		l := parseLogLine(msg)
+		if p, err := strconv.Atoi(e.Priority); l.Level == "" && err == nil && p >= 0 && p < len(journalLevels) {
+			l.Level = journalLevels[p]
+		}
+		if usec, err := strconv.ParseInt(e.Realtime, 10, 64); err == nil {
+			l.Time = time.UnixMicro(usec)
+		}
+		l.Source = e.Identifier
+		if _, ok := colors[e.Identifier]; !ok {
+			colors[e.Identifier] = len(colors) % logTagColors
+		}
+		l.Color = colors[e.Identifier]
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// unitJournal returns the last tail journal entries of the user unit, from
+// since on if it is not zero.
+func (s *Server) unitJournal(ctx context.Context, unit string, tail int, since time.Time) ([]LogLine, error) {
+	args := []string{"--user", "--unit=" + unit, "--output=json", "--no-pager", "--lines=" + strconv.Itoa(tail)}
+	if !since.IsZero() {
+		args = append(args, "--since="+since.Format("2006-01-02 15:04:05"))
+	}
+	out, err := exec.CommandContext(ctx, s.journalctlBin, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("journalctl: %w", err)
+	}
+	return parseJournal(out), nil
+}
+
+func (s *Server) handleContainerJournal(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.inspectForAction(w, r)
+	if !ok {
+		return
+	}
+	unit := s.containerUnit(c)
+	if unit == "" {
+		http.Error(w, "The container is not managed by systemd", http.StatusBadRequest)
+		return
+	}
+	var since time.Time
+	sinceParam := r.URL.Query().Get("since")
+	if sinceParam != "" {
+		d, err := time.ParseDuration(sinceParam)
+		if err != nil || d <= 0 || d > maxJournalSince {
+			http.Error(w, "Invalid since, want a duration like 1h, at most 744h", http.StatusBadRequest)
+			return
+		}
+		since = time.Now().Add(-d)
+	}
+	lines, err := s.unitJournal(r.Context(), unit, logTail(r), since)
+	if err != nil {
+		log.Printf("[%s] journal %s: %v", reqID(r.Context()), unit, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	data := logPageData(r, lines)
+	data["Title"] = "Journal: " + unit
+	data["Name"] = unit
+	data["BackURL"] = s.basePath + "/container/" + c.ID
+	data["BackName"] = c.Name
+	data["Journal"] = true
+	data["Since"] = sinceParam
+	s.render(w, r, "logs.html", data)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testJournal = `{"__REALTIME_TIMESTAMP":"1700000000000000","PRIORITY":"6","SYSLOG_IDENTIFIER":"systemd","MESSAGE":"Started web.service."}
+{"__REALTIME_TIMESTAMP":"1700000001000000","PRIORITY":"3","SYSLOG_IDENTIFIER":"web","MESSAGE":"level=warn msg=\"disk almost full\""}
+{"__REALTIME_TIMESTAMP":"1700000002000000","PRIORITY":"4","SYSLOG_IDENTIFIER":"systemd","MESSAGE":[104,105,255]}
+not json
+`
+
+func TestParseJournal(t *testing.T) {
+	t.Parallel()
+	got := parseJournal([]byte(testJournal))
+	if len(got) != 3 {
+		t.Fatalf("parseJournal = %+v", got)
+	}
+	for i, want := range []struct {
+		level, source string
+		color         int
+	}{
+		{"info", "systemd", 0},
+		{"warn", "web", 1}, // parsed from the message
+		{"warn", "systemd", 0},
+	} {
+		if l := got[i]; l.Level != want.level || l.Source != want.source || l.Color != want.color {
+			t.Errorf("line %d = %+v, want %+v", i, l, want)
+		}
+	}
+	if !got[0].Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("time = %v", got[0].Time)
+	}
+	if got[2].Message != "hi�" {
+		t.Errorf("byte array message = %q", got[2].Message)
+	}
+}
+
+func TestContainerJournal(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	bin, args := filepath.Join(dir, "journalctl"), filepath.Join(dir, "args")
+	err := os.WriteFile(bin, []byte("#!/bin/sh\necho \"$@\" >"+args+"\ncat <<'EOF'\n"+testJournal+"EOF\n"), 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := map[string]string{systemdUnitLabel: "web.service"}
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ContainerInspect{ID: "abc123", Name: "web", Config: ContainerConfig{Labels: labels}})
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.backend = backendPodman
+	s.journalctlBin = bin
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{"?since=yesterday", http.StatusBadRequest},
+		{"?since=-1h", http.StatusBadRequest},
+		{"?since=2h&tail=50", http.StatusOK},
+	} {
+		resp, err := http.Get(app.URL + "/container/abc123/journal" + tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.query, resp.StatusCode, tt.want)
+		}
+		if tt.want == http.StatusOK && !strings.Contains(string(body), "Started web.service.") {
+			t.Errorf("journal page lacks the entries")
+		}
+	}
+	got, _ := os.ReadFile(args)
+	if !strings.HasPrefix(string(got), "--user --unit=web.service --output=json --no-pager --lines=50 --since=") {
+		t.Errorf("journalctl args = %q", got)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+)
+
+// The layer report splits the size of each image into the layers only it
+// uses, which removing it frees, and the layers it shares with other
+// images, which stay. Layer sizes come from the history endpoint, matched
+// to the layers of the inspect data.
+
+// maxUniqueLayers bounds the list of the largest unique layers.
+const maxUniqueLayers = 20
+
+// layer is a layer of an image.
+type layer struct {
+	id        string // diff ID
+	size      int64
+	createdBy string
+}
+
+// imageLayers pairs the layers of img with the steps of its history that
+// created them. Steps without a layer are told apart by the inspect history
+// or, where it is missing (Docker), by their zero size. It returns false if
+// the steps do not match the layers.
+func imageLayers(img ImageInspect, hist []ImageHistoryEntry) ([]layer, bool) {
+	steps := slices.Clone(hist)
+	slices.Reverse(steps)
+	var created []ImageHistoryEntry
+	if len(img.History) == len(steps) {
+		for i, h := range img.History {
+			if !h.Empty {
+				created = append(created, steps[i])
+			}
+		}
+	} else {
+		for _, st := range steps {
+			if st.Size > 0 {
+				created = append(created, st)
+			}
+		}
+	}
+	if len(created) != len(img.RootFS.Layers) {
+		return nil, false
+	}
+	out := make([]layer, len(created))
+	for i, st := range created {
+		out[i] = layer{id: img.RootFS.Layers[i], size: st.Size, createdBy: st.CreatedBy}
+	}
+	return out, true
+}
+
+// layeredImage is an image with its layers.
+type layeredImage struct {
+	row    ImageRow
+	layers []layer
+	ok     bool // false if the layer sizes are unknown
+}
+
+// ImageLayerUsage is the split of the size of an image.
+type ImageLayerUsage struct {
+	ImageRow
+	Name    string
+	Layers  int
+	Unique  int64 // in layers no other image uses
+	Shared  int64
+	Unknown bool // the layer sizes could not be matched
+}
+
+// UniqueLayer is a layer only one image uses.
+type UniqueLayer struct {
+	ID        string
+	Size      int64
+	CreatedBy string
+	ImageID   string
+	Image     string
+}
+
+// LayerTotals sums up the layers of all images.
+type LayerTotals struct {
+	Layers int
+	Stored int64 // each layer counted once
+	Saved  int64 // by sharing layers instead of storing them per image
+}
+
+// analyzeLayers splits the size of each image, largest unique size first,
+// and returns the largest layers used by only one image.
+func analyzeLayers(images []layeredImage) ([]ImageLayerUsage, []UniqueLayer, LayerTotals) {
+	users := map[string]int{} // by layer ID
+	sizes := map[string]int64{}
+	for _, img := range images {
+		seen := map[string]bool{}
+		for _, l := range img.layers {
+			if !seen[l.id] {
+				seen[l.id] = true
+				users[l.id]++
+				sizes[l.id] = max(sizes[l.id], l.size)
+			}
+		}
+	}
+	var totals LayerTotals
+	for id, n := range users {
+		totals.Layers++
+		totals.Stored += sizes[id]
+		totals.Saved += int64(n-1) * sizes[id]
+	}
+
+	usage := make([]ImageLayerUsage, len(images))
+	var unique []UniqueLayer
+	for i, img := range images {
+		u := ImageLayerUsage{ImageRow: img.row, Name: imageLabel(img.row.ImageSummary), Layers: len(img.layers), Unknown: !img.ok}
+		for _, l := range img.layers {
+			if users[l.id] > 1 {
+				u.Shared += l.size
+				continue
+			}
+			u.Unique += l.size
+			if l.size > 0 {
+				unique = append(unique, UniqueLayer{ID: l.id, Size: l.size, CreatedBy: l.createdBy, ImageID: img.row.ID, Image: u.Name})
+			}
+		}
+		usage[i] = u
+	}
+	sort.SliceStable(usage, func(i, j int) bool {
+		if usage[i].Unique != usage[j].Unique {
+			return usage[i].Unique > usage[j].Unique
+		}
+		return usage[i].Name < usage[j].Name
+	})
+	sort.SliceStable(unique, func(i, j int) bool { return unique[i].Size > unique[j].Size })
+	if len(unique) > maxUniqueLayers {
+		unique = unique[:maxUniqueLayers]
+	}
+	return usage, unique, totals
+}
+
+// layeredImages returns the layers of all images, read in parallel. Images
+// removed meanwhile are skipped.
+func (s *Server) layeredImages(ctx context.Context) ([]layeredImage, error) {
+	rows, err := s.imageRows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	images := make([]*layeredImage, len(rows))
+	err = parallel(ctx, len(rows), func(ctx context.Context, i int) error {
+		img, err := s.inspectImage(ctx, rows[i].ID)
+		var hist []ImageHistoryEntry
+		if err == nil {
+			err = s.podmanGet(ctx, "/images/"+rows[i].ID+"/history", &hist)
+		}
+		if errors.Is(err, errNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		li := &layeredImage{row: rows[i]}
+		li.layers, li.ok = imageLayers(img, hist)
+		if !li.ok {
+			// Still count the layers, so other images do not claim them as
+			// unique.
+			for _, id := range img.RootFS.Layers {
+				li.layers = append(li.layers, layer{id: id})
+			}
+		}
+		images[i] = li
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]layeredImage, 0, len(rows))
+	for _, li := range images {
+		if li != nil {
+			out = append(out, *li)
+		}
+	}
+	return out, nil
+}
+
+func (s *Server) handleImageLayers(w http.ResponseWriter, r *http.Request) {
+	images, err := s.layeredImages(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	usage, unique, totals := analyzeLayers(images)
+	s.render(w, r, "layers.html", map[string]any{
+		"Title":   "Image Layers",
+		"Images":  usage,
+		"Largest": unique,
+		"Totals":  totals,
+	})
+}
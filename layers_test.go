@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestImageLayers(t *testing.T) {
+	t.Parallel()
+	img := ImageInspect{
+		RootFS:  RootFS{Layers: []string{"sha256:base", "sha256:app"}},
+		History: []ImageHistory{{CreatedBy: "ADD rootfs.tar /"}, {CreatedBy: "ENV A=1", Empty: true}, {CreatedBy: "COPY app /app"}},
+	}
+	hist := []ImageHistoryEntry{{CreatedBy: "COPY app /app", Size: 30}, {CreatedBy: "ENV A=1"}, {CreatedBy: "ADD rootfs.tar /", Size: 100}}
+	layers, ok := imageLayers(img, hist)
+	if !ok || len(layers) != 2 || layers[0] != (layer{id: "sha256:base", size: 100, createdBy: "ADD rootfs.tar /"}) || layers[1].size != 30 {
+		t.Errorf("imageLayers = %+v, %v", layers, ok)
+	}
+
+	// Docker has no history in the inspect data.
+	img.History = nil
+	if layers, ok := imageLayers(img, hist); !ok || layers[1].createdBy != "COPY app /app" {
+		t.Errorf("imageLayers without inspect history = %+v, %v", layers, ok)
+	}
+	if _, ok := imageLayers(img, hist[:1]); ok {
+		t.Error("imageLayers matched a history with too few steps")
+	}
+}
+
+func TestAnalyzeLayers(t *testing.T) {
+	t.Parallel()
+	base := layer{id: "base", size: 100}
+	images := []layeredImage{
+		{row: ImageRow{ImageSummary: ImageSummary{ID: "a", RepoTags: []string{"app:1"}}}, layers: []layer{base, {id: "a1", size: 30}}, ok: true},
+		{row: ImageRow{ImageSummary: ImageSummary{ID: "b", RepoTags: []string{"app:2"}}}, layers: []layer{base, {id: "b1", size: 50}, {id: "b2"}}, ok: true},
+		{row: ImageRow{ImageSummary: ImageSummary{ID: "c"}}, layers: []layer{{id: "a1"}}},
+	}
+	usage, unique, totals := analyzeLayers(images)
+	got := ""
+	for _, u := range usage {
+		got += fmt.Sprintf("%s:%d/%d ", u.Name, u.Unique, u.Shared)
+	}
+	if got != "app:2:50/100 app:1:0/130 c:0/0 " {
+		t.Errorf("usage = %s", got)
+	}
+	if !usage[2].Unknown {
+		t.Error("image without layer sizes not marked unknown")
+	}
+	if len(unique) != 1 || unique[0].ID != "b1" || unique[0].Image != "app:2" {
+		t.Errorf("unique = %+v", unique)
+	}
+	if totals != (LayerTotals{Layers: 4, Stored: 180, Saved: 130}) {
+		t.Errorf("totals = %+v", totals)
+	}
+}
+
+func TestImageLayersPage(t *testing.T) {
+	t.Parallel()
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4.0.0/libpod/images/json":
+			w.Write([]byte(`[{"Id":"aaa","RepoTags":["web:1"]},{"Id":"bbb","RepoTags":["web:2"]}]`))
+		case "/v4.0.0/libpod/containers/json":
+			w.Write([]byte(`[]`))
+		case "/v4.0.0/libpod/images/aaa/json":
+			w.Write([]byte(`{"Id":"aaa","RootFS":{"Layers":["sha256:base","sha256:old"]}}`))
+		case "/v4.0.0/libpod/images/bbb/json":
+			w.Write([]byte(`{"Id":"bbb","RootFS":{"Layers":["sha256:base","sha256:new"]}}`))
+		case "/v4.0.0/libpod/images/aaa/history":
+			w.Write([]byte(`[{"CreatedBy":"COPY old /app","Size":3000000},{"CreatedBy":"ADD base","Size":7000000}]`))
+		case "/v4.0.0/libpod/images/bbb/history":
+			w.Write([]byte(`[{"CreatedBy":"COPY new /app","Size":4000000},{"CreatedBy":"ADD base","Size":7000000}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mock.Close()
+	app := httptest.NewServer(newTestServer(t, mock).newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/images/layers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	page := string(body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, page)
+	}
+	if w2, w1 := strings.Index(page, ">web:2<"), strings.Index(page, ">web:1<"); w2 < 0 || w1 < 0 || w2 > w1 {
+		t.Error("images not sorted by unique size")
+	}
+	for _, want := range []string{"COPY new /app", "Saved by sharing", humanSize(7000000)} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+	if strings.Contains(page, "ADD base</td>") {
+		t.Error("shared layer listed as unique")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// With ENABLE_LIVE_MODE, the containers and apps pages offer a live mode
+// (?live=1). A small script then follows <page>/events, which re-renders the
+// "live" template of the page after each container event and sends it as a
+// server-sent event. Without ?live=1, the pages stay free of JavaScript.
+
+// liveDebounce is how long to wait for more events before re-rendering, as
+// a container change comes as a burst of events (e.g. init, start).
+const liveDebounce = 300 * time.Millisecond
+
+// addLiveData adds the live mode fields to the data of the page at path.
+func (s *Server) addLiveData(r *http.Request, data map[string]any, path string) {
+	if !s.enableLiveMode {
+		return
+	}
+	live := r.URL.Query().Get("live") == "1"
+	q := r.URL.Query()
+	if live {
+		q.Del("live")
+	} else {
+		q.Set("live", "1")
+	}
+	toggle := s.basePath + path
+	if len(q) > 0 {
+		toggle += "?" + q.Encode()
+	}
+	data["LiveMode"] = true
+	data["Live"] = live
+	data["LiveToggle"] = toggle
+	data["LiveURL"] = s.basePath + path + "/events?" + r.URL.RawQuery
+}
+
+// renderFragment renders the "live" template of page, masked in privacy
+// mode.
+func (s *Server) renderFragment(r *http.Request, page string, data map[string]any) (string, error) {
+	t := pageTemplates[page]
+	if t == nil {
+		return "", fmt.Errorf("unknown template %s", page)
+	}
+	s.addPageData(r, data)
+	var buf strings.Builder
+	if err := t.ExecuteTemplate(&buf, "live", data); err != nil {
+		return "", err
+	}
+	if m := s.pageMasker(r); m != nil {
+		return m.maskHTML(buf.String()), nil
+	}
+	return buf.String(), nil
+}
+
+// handleLiveEvents returns a handler streaming the "live" template of page,
+// filled by load, as "fragment" events whenever a container changes. A
+// fragment is only sent if it differs from the previous one.
+func (s *Server) handleLiveEvents(page string, load func(*http.Request) (map[string]any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.enableLiveMode {
+			http.NotFound(w, r)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		changed := make(chan struct{}, 1)
+		go s.watchContainerEvents(r.Context(), changed)
+
+		var last string
+		for {
+			data, err := load(r)
+			var html string
+			if err == nil {
+				html, err = s.renderFragment(r, page, data)
+			}
+			switch {
+			case err != nil:
+				log.Printf("[%s] live %s: %v", reqID(r.Context()), page, err)
+				fmt.Fprintf(w, "event: err\ndata: update failed\n\n")
+			case html != last:
+				last = html
+				fmt.Fprintf(w, "event: fragment\n")
+				for line := range strings.SplitSeq(html, "\n") {
+					fmt.Fprintf(w, "data: %s\n", line)
+				}
+				fmt.Fprintf(w, "\n")
+			}
+			flusher.Flush()
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-changed:
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(liveDebounce):
+			}
+			select {
+			case <-changed:
+			default:
+			}
+		}
+	}
+}
+
+// watchContainerEvents signals on changed after container events until ctx
+// is done. The events stream ends with the API client timeout, so it is
+// reopened, including the events since the last connect.
+func (s *Server) watchContainerEvents(ctx context.Context, changed chan<- struct{}) {
+	filters, _ := json.Marshal(map[string][]string{"type": {"container"}})
+	since := time.Now()
+	for {
+		q := url.Values{
+			"stream":  {"true"},
+			"filters": {string(filters)},
+			"since":   {strconv.FormatInt(since.Unix(), 10)},
+		}
+		since = time.Now()
+		if resp, err := s.podmanDo(ctx, http.MethodGet, "/events?"+q.Encode(), nil); err == nil {
+			dec := json.NewDecoder(resp.Body)
+			for {
+				var ev containerEvent
+				if dec.Decode(&ev) != nil {
+					break
+				}
+				s.dropCaches()
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAddLiveData(t *testing.T) {
+	t.Parallel()
+	s := &Server{basePath: "/pf"}
+	r := httptest.NewRequest(http.MethodGet, "/containers?state=running", nil)
+	data := map[string]any{}
+	s.addLiveData(r, data, "/containers")
+	if len(data) != 0 {
+		t.Errorf("disabled live mode added %v", data)
+	}
+
+	s.enableLiveMode = true
+	s.addLiveData(r, data, "/containers")
+	if data["Live"] != false || data["LiveToggle"] != "/pf/containers?live=1&state=running" || data["LiveURL"] != "/pf/containers/events?state=running" {
+		t.Errorf("data = %v", data)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/apps?live=1", nil)
+	s.addLiveData(r, data, "/apps")
+	if data["Live"] != true || data["LiveToggle"] != "/pf/apps" {
+		t.Errorf("live data = %v", data)
+	}
+}
+
+func TestLivePages(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	if _, page := get("/containers?live=1"); strings.Contains(page, "<script") || strings.Contains(page, "Live updates") {
+		t.Error("live mode offered while disabled")
+	}
+	if code, _ := get("/containers/events"); code != http.StatusNotFound {
+		t.Errorf("events while disabled: status %d, want 404", code)
+	}
+
+	s.enableLiveMode = true
+	for _, path := range []string{"/containers", "/apps"} {
+		_, page := get(path)
+		if strings.Contains(page, "<script") || !strings.Contains(page, `href="`+path+`?live=1"`) {
+			t.Errorf("%s: want a live mode link and no script", path)
+		}
+		_, page = get(path + "?live=1")
+		if !strings.Contains(page, "EventSource") || !strings.Contains(page, `id="live"`) {
+			t.Errorf("%s?live=1: no live script", path)
+		}
+	}
+}
+
+func TestLiveEvents(t *testing.T) {
+	t.Parallel()
+	var state atomic.Value
+	state.Store("running")
+	events := make(chan struct{})
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4.0.0/libpod/containers/json":
+			w.Write([]byte(`[{"Id":"abc123","Names":["web"],"Image":"nginx","State":"` + state.Load().(string) + `"}]`))
+		case "/v4.0.0/libpod/events":
+			if f := r.URL.Query().Get("filters"); !strings.Contains(f, `"container"`) {
+				t.Errorf("events filters = %q", f)
+			}
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case <-r.Context().Done():
+					return
+				case <-events:
+					w.Write([]byte(`{"Action":"died","Actor":{"ID":"abc123"}}` + "\n"))
+					w.(http.Flusher).Flush()
+				}
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableLiveMode = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, app.URL+"/containers/events?q="+url.QueryEscape("web"), nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	br := bufio.NewReader(resp.Body)
+	fragment := func() string {
+		t.Helper()
+		var b strings.Builder
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line == "\n" {
+				break
+			}
+			b.WriteString(line)
+		}
+		ev := b.String()
+		if !strings.HasPrefix(ev, "event: fragment\n") {
+			t.Fatalf("event = %q", ev)
+		}
+		return ev
+	}
+
+	if ev := fragment(); !strings.Contains(ev, ">web<") || !strings.Contains(ev, "badge-running") || strings.Contains(ev, "<form") {
+		t.Errorf("first fragment = %q", ev)
+	}
+	state.Store("exited")
+	events <- struct{}{}
+	if ev := fragment(); !strings.Contains(ev, "badge-exited") {
+		t.Errorf("fragment after event = %q", ev)
+	}
+}
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Log lines are fetched from the API and parsed server-side into a level,
+// message and structured fields, so they can be colored and filtered
+// without JavaScript.
+
+const (
+	defaultLogTail = 200
+	maxLogTail     = 5000
+	// maxLogLine truncates pathological lines (e.g. minified dumps).
+	maxLogLine = 16 << 10
+	// maxGrepContext bounds the ?context= lines around each match.
+	maxGrepContext = 20
+	maxGrepPattern = 256
+)
+
+// logLevels are the normalized severities, lowest first.
+var logLevels = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+// levelAliases maps level spellings found in the wild to logLevels.
+var levelAliases = map[string]string{
+	"trace": "trace", "trc": "trace", "trac": "trace",
+	"debug": "debug", "dbg": "debug", "debu": "debug",
+	"info": "info", "inf": "info", "information": "info", "notice": "info",
+	"warn": "warn", "warning": "warn", "wrn": "warn",
+	"error": "error", "err": "error", "eror": "error", "erro": "error",
+	"fatal": "fatal", "fata": "fatal", "crit": "fatal", "critical": "fatal",
+	"panic": "fatal", "pani": "fatal", "alert": "fatal", "emerg": "fatal",
+}
+
+// levelRank returns the index of level in logLevels, or -1.
+func levelRank(level string) int {
+	for i, l := range logLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeLevel maps a level token like "[WARN]", "error:" or
+// "ERRO[0001]" (logrus) to a normalized level, or "" if it is not one.
+func normalizeLevel(s string) string {
+	if i := strings.IndexByte(s, '['); i > 0 {
+		s = s[:i]
+	}
+	s = strings.Trim(s, "[]<>():|")
+	return levelAliases[strings.ToLower(s)]
+}
+
+var (
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+	// timestampPrefix matches common timestamps at the start of a message,
+	// e.g. "2024-01-02T15:04:05.000Z", "2024/01/02 15:04:05",
+	// "[2024-01-02 15:04:05,123 +0000]".
+	timestampPrefix = regexp.MustCompile(`^\[?\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|\s?[+-]\d{2}:?\d{2})?\]?\s*`)
+)
+
+// parseLogLine parses the text of a log line (without the API timestamp)
+// as JSON, logfmt or plain text with an optional timestamp and level
+// prefix.
+func parseLogLine(text string) LogLine {
+	text = ansiEscape.ReplaceAllString(text, "")
+	if len(text) > maxLogLine {
+		text = text[:maxLogLine] + "…"
+	}
+	l := LogLine{Raw: text, Message: text}
+	if strings.HasPrefix(text, "{") {
+		if parseJSONLog(&l) {
+			return l
+		}
+	}
+	if parseLogfmt(&l) {
+		return l
+	}
+	rest := timestampPrefix.ReplaceAllString(text, "")
+	// The level is usually one of the first two tokens, e.g.
+	// "INFO msg", "[error] 29#29: msg", "app | WARN msg".
+	for i, tok := range strings.Fields(rest) {
+		if i >= 2 {
+			break
+		}
+		if lvl := normalizeLevel(tok); lvl != "" {
+			l.Level = lvl
+			break
+		}
+	}
+	return l
+}
+
+func parseJSONLog(l *LogLine) bool {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(l.Raw), &obj); err != nil {
+		return false
+	}
+	l.Format = "json"
+	l.Message = ""
+	for _, k := range []string{"level", "lvl", "severity", "log.level", "levelname"} {
+		if v, ok := obj[k].(string); ok {
+			l.Level = normalizeLevel(v)
+			delete(obj, k)
+			break
+		}
+	}
+	for _, k := range []string{"msg", "message", "MESSAGE"} {
+		if v, ok := obj[k].(string); ok {
+			l.Message = v
+			delete(obj, k)
+			break
+		}
+	}
+	for k, v := range obj {
+		var val string
+		if s, ok := v.(string); ok {
+			val = s
+		} else {
+			b, _ := json.Marshal(v)
+			val = string(b)
+		}
+		l.Fields = append(l.Fields, LogField{Key: k, Value: val})
+	}
+	sort.Slice(l.Fields, func(i, j int) bool { return l.Fields[i].Key < l.Fields[j].Key })
+	return true
+}
+
+// parseLogfmt parses lines of key=value pairs, with optionally quoted
+// values. It requires at least two pairs and nothing else on the line.
+func parseLogfmt(l *LogLine) bool {
+	var fields []LogField
+	s := l.Raw
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			break
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || strings.ContainsAny(s[:eq], " \"") {
+			return false
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			end := 1
+			for end < len(s) && (s[end] != '"' || s[end-1] == '\\') {
+				end++
+			}
+			if end == len(s) {
+				return false
+			}
+			uq, err := strconv.Unquote(s[:end+1])
+			if err != nil {
+				uq = s[1:end]
+			}
+			val, s = uq, s[end+1:]
+		} else {
+			sp := strings.IndexByte(s, ' ')
+			if sp < 0 {
+				sp = len(s)
+			}
+			val, s = s[:sp], s[sp:]
+		}
+		fields = append(fields, LogField{Key: key, Value: val})
+	}
+	if len(fields) < 2 {
+		return false
+	}
+	l.Format = "logfmt"
+	l.Message = ""
+	for _, f := range fields {
+		switch {
+		case l.Level == "" && (f.Key == "level" || f.Key == "lvl"):
+			l.Level = normalizeLevel(f.Value)
+		case l.Message == "" && (f.Key == "msg" || f.Key == "message"):
+			l.Message = f.Value
+		default:
+			l.Fields = append(l.Fields, f)
+		}
+	}
+	return true
+}
+
+// demuxLogs reads a log stream and calls fn for every line. Unless raw is
+// set, the stream is multiplexed: each frame has an 8 byte header with the
+// stream (1 stdout, 2 stderr) and the big-endian payload length. Frames may
+// split lines, so output is buffered per stream until a newline.
+func demuxLogs(r io.Reader, raw bool, fn func(stream, line string)) error {
+	if raw {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			fn("stdout", sc.Text())
+		}
+		return sc.Err()
+	}
+	var partial [3]bytes.Buffer
+	names := [3]string{"stdout", "stdout", "stderr"}
+	br := bufio.NewReader(r)
+	var hdr [8]byte
+	for {
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		stream := int(hdr[0])
+		if stream > 2 {
+			return fmt.Errorf("invalid log stream %d", stream)
+		}
+		size := binary.BigEndian.Uint32(hdr[4:])
+		buf := &partial[stream]
+		if _, err := io.CopyN(buf, br, int64(size)); err != nil {
+			return err
+		}
+		for {
+			i := bytes.IndexByte(buf.Bytes(), '\n')
+			if i < 0 {
+				break
+			}
+			fn(names[stream], string(buf.Next(i + 1)[:i]))
+		}
+	}
+	for i := range partial {
+		if partial[i].Len() > 0 {
+			fn(names[i], partial[i].String())
+		}
+	}
+	return nil
+}
+
+// containerLogs fetches the last tail lines of a container's stdout and
+// stderr and parses them. tty must be the container's Config.Tty.
+func (s *Server) containerLogs(ctx context.Context, id string, tty bool, tail int) ([]LogLine, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	path := fmt.Sprintf("/containers/%s/logs?stdout=true&stderr=true&timestamps=true&tail=%d", id, tail)
+	resp, err := s.podmanDo(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var lines []LogLine
+	// libpod always multiplexes; the compat API only without a TTY.
+	raw := tty && s.backend == backendDocker
+	err = demuxLogs(resp.Body, raw, func(stream, text string) {
+		var ts time.Time
+		if t, rest, ok := strings.Cut(text, " "); ok {
+			if parsed, err := time.Parse(time.RFC3339Nano, t); err == nil {
+				ts, text = parsed, rest
+			}
+		}
+		l := parseLogLine(strings.TrimRight(text, "\r"))
+		l.Stream = stream
+		l.Time = ts
+		lines = append(lines, l)
+	})
+	return lines, err
+}
+
+// logTagColors is the number of .log-tag-N color classes in base.html.
+const logTagColors = 6
+
+// appLogs fetches the last tail lines of each container in parallel and
+// merges them by timestamp. Containers removed meanwhile are skipped.
+func (s *Server) appLogs(ctx context.Context, containers []Container, tail int) ([]LogLine, error) {
+	results := make([][]LogLine, len(containers))
+	err := parallel(ctx, len(containers), func(ctx context.Context, i int) error {
+		c := containers[i]
+		tty := false
+		if s.backend == backendDocker {
+			// The compat API only multiplexes without a TTY.
+			ci, err := s.inspectContainer(ctx, c.ID)
+			if errors.Is(err, errNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			tty = ci.Config.Tty
+		}
+		lines, err := s.containerLogs(ctx, c.ID, tty, tail)
+		if errors.Is(err, errNotFound) {
+			return nil
+		}
+		for j := range lines {
+			lines[j].Source = firstName(c.Names)
+			lines[j].Color = i % logTagColors
+		}
+		results[i] = lines
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var merged []LogLine
+	for _, lines := range results {
+		merged = append(merged, lines...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Time.Before(merged[j].Time) })
+	return merged, nil
+}
+
+// filterLogLevel returns the lines at or above level. Lines without a
+// recognized level are dropped.
+func filterLogLevel(lines []LogLine, level string) []LogLine {
+	min := levelRank(level)
+	if min <= 0 {
+		return lines
+	}
+	out := lines[:0:0]
+	for _, l := range lines {
+		if levelRank(l.Level) >= min {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// grepLogs returns the lines whose raw text matches, each with up to
+// context lines before and after it, and the number of matching lines.
+// Lines following left-out lines are marked as Gap.
+func grepLogs(lines []LogLine, match func(string) bool, context int) ([]LogLine, int) {
+	keep := make([]bool, len(lines))
+	matches := 0
+	for i := range lines {
+		if lines[i].Match = match(lines[i].Raw); lines[i].Match {
+			matches++
+			for j := max(0, i-context); j <= min(len(lines)-1, i+context); j++ {
+				keep[j] = true
+			}
+		}
+	}
+	out := lines[:0:0]
+	for i, l := range lines {
+		if !keep[i] {
+			continue
+		}
+		l.Gap = len(out) > 0 && !keep[i-1]
+		out = append(out, l)
+	}
+	return out, matches
+}
+
+// logMatcher returns the matcher for ?grep=: a substring, or a regular
+// expression with ?regex=1.
+func logMatcher(pattern string, regex bool) (func(string) bool, error) {
+	if len(pattern) > maxGrepPattern {
+		return nil, fmt.Errorf("pattern longer than %d characters", maxGrepPattern)
+	}
+	if !regex {
+		return func(s string) bool { return strings.Contains(s, pattern) }, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+// logPageData filters lines by the ?level=, ?grep= and ?context= query
+// parameters and returns the data logs.html needs for them.
+func logPageData(r *http.Request, lines []LogLine) map[string]any {
+	q := r.URL.Query()
+	level := q.Get("level")
+	if levelRank(level) < 0 {
+		level = ""
+	}
+	data := map[string]any{
+		"Total":  len(lines),
+		"Tail":   logTail(r),
+		"Level":  level,
+		"Levels": logLevels,
+	}
+	lines = filterLogLevel(lines, level)
+	if grep := q.Get("grep"); grep != "" {
+		regex := q.Get("regex") == "1"
+		context, _ := strconv.Atoi(q.Get("context"))
+		context = min(max(context, 0), maxGrepContext)
+		data["Grep"], data["Regex"], data["Context"] = grep, regex, context
+		if match, err := logMatcher(grep, regex); err != nil {
+			data["GrepErr"] = err.Error()
+		} else {
+			lines, data["Matches"] = grepLogs(lines, match, context)
+		}
+	}
+	data["Lines"] = lines
+	return data
+}
+
+// logTail parses the ?tail= query parameter.
+func logTail(r *http.Request) int {
+	n, err := strconv.Atoi(r.URL.Query().Get("tail"))
+	if err != nil || n <= 0 {
+		return defaultLogTail
+	}
+	return min(n, maxLogTail)
+}
+
+func (s *Server) handleContainerLogs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid container ID", http.StatusBadRequest)
+		return
+	}
+	c, err := s.inspectContainer(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Container Not Found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	lines, err := s.containerLogs(r.Context(), c.ID, c.Config.Tty, logTail(r))
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	data := logPageData(r, lines)
+	data["Title"] = "Logs: " + c.Name
+	data["Name"] = c.Name
+	data["BackURL"] = s.basePath + "/container/" + c.ID
+	data["BackName"] = c.Name
+	s.render(w, r, "logs.html", data)
+}
+
+func (s *Server) handleAppLogs(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var members []Container
+	for _, c := range list {
+		if c.Labels[appLabelPrefix+"name"] == name {
+			members = append(members, c)
+		}
+	}
+	if len(members) == 0 {
+		http.Error(w, "App Not Found", http.StatusNotFound)
+		return
+	}
+	// Sorted so each container keeps its color across reloads.
+	sort.Slice(members, func(i, j int) bool { return firstName(members[i].Names) < firstName(members[j].Names) })
+
+	lines, err := s.appLogs(r.Context(), members, logTail(r))
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	data := logPageData(r, lines)
+	data["Title"] = "Logs: " + name
+	data["Name"] = name
+	data["BackURL"] = s.basePath + "/apps"
+	data["BackName"] = "apps"
+	data["Merged"] = true
+	s.render(w, r, "logs.html", data)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		name, in           string
+		format, level, msg string
+		fields             []LogField
+	}{
+		{"plain", "hello world", "", "", "hello world", nil},
+		{"level prefix", "INFO starting server", "", "info", "INFO starting server", nil},
+		{"bracketed level after timestamp", "2024/01/02 15:04:05 [error] 29#29: open() failed", "", "error", "2024/01/02 15:04:05 [error] 29#29: open() failed", nil},
+		{"python style", "2024-01-02 15:04:05,123 WARNING disk almost full", "", "warn", "2024-01-02 15:04:05,123 WARNING disk almost full", nil},
+		{"logrus text", "ERRO[0001] boom", "", "error", "ERRO[0001] boom", nil},
+		{"level word later is ignored", "user said error is fine", "", "", "user said error is fine", nil},
+		{"ansi stripped", "\x1b[32mINFO\x1b[0m ready", "", "info", "INFO ready", nil},
+		{
+			"json", `{"level":"warn","msg":"slow query","ms":1200,"db":{"name":"app"}}`,
+			"json", "warn", "slow query",
+			[]LogField{{"db", `{"name":"app"}`}, {"ms", "1200"}},
+		},
+		{"invalid json", `{"level":`, "", "", `{"level":`, nil},
+		{
+			"logfmt", `time=2024-01-02T15:04:05Z level=error msg="connection refused" host=db`,
+			"logfmt", "error", "connection refused",
+			[]LogField{{"time", "2024-01-02T15:04:05Z"}, {"host", "db"}},
+		},
+		{"single pair is not logfmt", "answer=42", "", "", "answer=42", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseLogLine(tt.in)
+			if got.Format != tt.format || got.Level != tt.level || got.Message != tt.msg {
+				t.Errorf("parseLogLine(%q) = format %q level %q msg %q, want %q %q %q",
+					tt.in, got.Format, got.Level, got.Message, tt.format, tt.level, tt.msg)
+			}
+			if !reflect.DeepEqual(got.Fields, tt.fields) {
+				t.Errorf("fields = %v, want %v", got.Fields, tt.fields)
+			}
+		})
+	}
+}
+
+// logFrame encodes a multiplexed log stream frame.
+func logFrame(stream byte, payload string) []byte {
+	hdr := make([]byte, 8, 8+len(payload))
+	hdr[0] = stream
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(payload)))
+	return append(hdr, payload...)
+}
+
+func TestDemuxLogs(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(logFrame(1, "one\ntw"))
+	stream.Write(logFrame(2, "err\n"))
+	stream.Write(logFrame(1, "o\nthree"))
+
+	var got []string
+	err := demuxLogs(&stream, false, func(s, line string) {
+		got = append(got, s+": "+line)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"stdout: one", "stderr: err", "stdout: two", "stdout: three"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("demuxLogs() = %q, want %q", got, want)
+	}
+
+	got = nil
+	demuxLogs(strings.NewReader("a\nb\n"), true, func(s, line string) {
+		got = append(got, s+": "+line)
+	})
+	if want := []string{"stdout: a", "stdout: b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("demuxLogs(raw) = %q, want %q", got, want)
+	}
+
+	if err := demuxLogs(bytes.NewReader(logFrame(7, "x")), false, func(string, string) {}); err == nil {
+		t.Error("demuxLogs() accepted invalid stream")
+	}
+}
+
+func TestFilterLogLevel(t *testing.T) {
+	lines := []LogLine{{Level: "debug"}, {Level: "info"}, {Level: ""}, {Level: "warn"}, {Level: "fatal"}}
+	if got := filterLogLevel(lines, ""); len(got) != 5 {
+		t.Errorf("no filter: %d lines, want 5", len(got))
+	}
+	if got := filterLogLevel(lines, "warn"); len(got) != 2 {
+		t.Errorf("warn filter: %d lines, want 2", len(got))
+	}
+}
+
+func TestGrepLogs(t *testing.T) {
+	var lines []LogLine
+	for _, raw := range []string{"a", "b", "match 1", "c", "d", "e", "match 2", "f"} {
+		lines = append(lines, LogLine{Raw: raw})
+	}
+	match, err := logMatcher("match", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, n := grepLogs(lines, match, 1)
+	var raws []string
+	for _, l := range got {
+		s := l.Raw
+		if l.Gap {
+			s = "-- " + s
+		}
+		if l.Match {
+			s += " *"
+		}
+		raws = append(raws, s)
+	}
+	if want := "b,match 1 *,c,-- e,match 2 *,f"; n != 2 || strings.Join(raws, ",") != want {
+		t.Errorf("grepLogs = %d %q, want 2 %q", n, strings.Join(raws, ","), want)
+	}
+
+	match, err = logMatcher(`^match \d$`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, n := grepLogs(lines, match, 0); n != 2 || len(got) != 2 {
+		t.Errorf("regex grep = %d lines, %d matches", len(got), n)
+	}
+	if _, err := logMatcher("(", true); err == nil {
+		t.Error("invalid regex accepted")
+	}
+	if _, err := logMatcher(strings.Repeat("x", maxGrepPattern+1), false); err == nil {
+		t.Error("overlong pattern accepted")
+	}
+}
+
+func TestContainerLogsPage(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	app := httptest.NewServer(newTestServer(t, mock).newMux("podman"))
+	defer app.Close()
+
+	get := func(path string) string {
+		t.Helper()
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s status = %d", path, resp.StatusCode)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	body := get("/container/jellyfin/logs")
+	for _, want := range []string{"log-info", "log-error", "log-stderr", "request served", "2 fields"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("logs page does not contain %q", want)
+		}
+	}
+	body = get("/container/jellyfin/logs?level=error")
+	if strings.Contains(body, "request served") || !strings.Contains(body, "upstream timed out") {
+		t.Error("level filter not applied")
+	}
+	body = get("/container/jellyfin/logs?grep=upstream")
+	if strings.Contains(body, "request served") || !strings.Contains(body, "log-match") || !strings.Contains(body, "1 matching line.") {
+		t.Error("grep not applied")
+	}
+	if body = get("/container/jellyfin/logs?grep=%28&regex=1"); !strings.Contains(body, "Invalid search") {
+		t.Error("invalid regex not reported")
+	}
+}
+
+func TestAppLogs(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+
+	list, err := s.listContainers(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var members []Container
+	for _, c := range list {
+		if c.Labels[appLabelPrefix+"name"] == "Gitea" {
+			members = append(members, c)
+		}
+	}
+	if len(members) != 2 {
+		t.Fatalf("fixture has %d Gitea containers, want 2", len(members))
+	}
+	lines, err := s.appLogs(t.Context(), members, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("len(lines) = %d, want 4", len(lines))
+	}
+	sources := map[string]int{}
+	for i, l := range lines {
+		if i > 0 && l.Time.Before(lines[i-1].Time) {
+			t.Errorf("line %d out of order", i)
+		}
+		sources[l.Source] = l.Color
+	}
+	if len(sources) != 2 {
+		t.Errorf("sources = %v, want 2 containers", sources)
+	}
+
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+	for path, want := range map[string]int{
+		"/app/Gitea/logs":   http.StatusOK,
+		"/app/Missing/logs": http.StatusNotFound,
+	} {
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
@@ -8,11 +8,16 @@ import (
 	"crypto/rand"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -30,48 +35,331 @@ func reqID(ctx context.Context) string {
 
 // Server holds all per-instance state for the podfather web server.
 type Server struct {
-	basePath          string
-	hostname          string
-	enableAutoUpdate  bool
-	externalApps      []App
-	podmanClient      *http.Client
-	podmanBaseURL     string
-	autoUpdateMu      sync.Mutex
-	currentAutoUpdate atomic.Pointer[autoUpdateResult]
+	basePath           string
+	trustedProxies     []netip.Prefix // see forwardedClient
+	backend            string
+	hostname           string
+	listenHost         string
+	socketPath         string
+	started            time.Time
+	dataDir            string
+	enableAutoUpdate   bool
+	enableActions      bool
+	enableDebugPage    bool
+	enableFileDownload bool
+	enableFileUpload   bool
+	enableLiveMode     bool // containers and apps pages may follow events
+	systemctlBin       string
+	journalctlBin      string
+	quadletDir         string // .container files digest pinning may edit, empty if disabled
+	thermalDir         string // empty to not read temperatures
+	webhookToken       string
+	webhookLimiter     *rateLimiter
+	postLimiter        *keyedLimiter // nil if POST requests are not rate limited
+	requestTimeout     time.Duration // deadline of requests, 0 for none
+	routeTimeouts      []routeTimeout
+	slowCalls          *slowCalls     // nil if slow API calls are not tracked
+	apiCache           *apiCache      // nil if API responses are not cached
+	poller             *statePoller   // nil unless POLL_INTERVAL is set
+	templateTypes      *templateTypes // nil unless the debug page is enabled
+	externalApps       []App
+	categories         map[string]Category
+	configSettings     []ConfigSetting
+	containerColumns   []listColumn
+	defaultTheme       string
+	customCSS          string // user stylesheet served after the embedded one, empty if none
+	imagePolicy        imagePolicy
+	gitConfig          *gitConfig
+	updates            *updateChecker
+	probes             *prober         // nil if disabled
+	registry           *registryClient // nil in tests
+	icons              *iconCache      // nil in tests
+	calendar           *calendar
+	cronJobs           []*cronJob
+	energy             *energyMeter
+	uptime             *uptimeTracker
+	stops              *stopLog            // nil in tests
+	crashes            *crashTracker       // nil in tests
+	watchdog           *watchdog           // nil unless actions are enabled
+	startOrder         *startOrderRecorder // nil unless actions are enabled
+	oidc               *oidcProvider       // nil unless OIDC login is configured
+	podmanClient       *http.Client
+	podmanBaseURL      string
+	autoUpdateMu       sync.Mutex
+	currentAutoUpdate  atomic.Pointer[job]
+	autoUpdateHistory  autoUpdateHistory
+	images             imageCache
+	jobs               jobStore
+	actions            actionLog
+	forwards           forwardStore
+	accessLog          accessLog
 }
 
 func (s *Server) newMux(podmanBin string) *http.ServeMux {
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /{$}", s.handleRoot)
 	mux.HandleFunc("GET /apps", s.handleApps)
+	mux.HandleFunc("GET /apps/export", s.handleAppsExport)
+	mux.HandleFunc("GET /apps/import", s.handleAppsImport)
+	mux.HandleFunc("POST /apps/import", s.handleAppsImport)
+	mux.HandleFunc("POST /apps/collapse", s.handleCollapse)
+	mux.HandleFunc("GET /apps/events", s.handleLiveEvents("apps.html", s.appsData))
+	mux.HandleFunc("GET /overview", s.handleOverview)
 	mux.HandleFunc("GET /containers", s.handleContainers)
+	mux.HandleFunc("GET /containers/events", s.handleLiveEvents("containers.html", s.containersData))
+	mux.HandleFunc("POST /containers/bulk", s.handleContainersBulk)
+	mux.HandleFunc("GET /containers/prune", s.handleContainerPruneConfirm)
+	mux.HandleFunc("POST /containers/prune", s.handleContainerPrune)
+	mux.HandleFunc("GET /stacks", s.handleStacks)
+	mux.HandleFunc("GET /ports", s.handlePorts)
+	mux.HandleFunc("GET /security", s.handleSecurity)
+	mux.HandleFunc("POST /stack/{name}/restart", s.handleStackRestart)
+	mux.HandleFunc("GET /app/{name}", s.handleApp)
+	mux.HandleFunc("GET /app/{name}/logs", s.handleAppLogs)
 	mux.HandleFunc("GET /container/{id}", s.handleContainer)
+	mux.HandleFunc("GET /container/{id}/logs", s.handleContainerLogs)
+	mux.HandleFunc("GET /container/{id}/journal", s.handleContainerJournal)
+	mux.HandleFunc("GET /container/{id}/kube", s.handleContainerKube)
+	mux.HandleFunc("GET /container/{id}/quadlet", s.handleContainerQuadlet)
+	mux.HandleFunc("GET /container/{id}/raw", s.handleRaw("container"))
+	mux.HandleFunc("GET /container/{id}/download", s.handleContainerDownload)
+	mux.HandleFunc("GET /container/{id}/files", s.handleContainerFiles)
+	mux.HandleFunc("POST /container/{id}/upload", s.handleContainerUpload)
+	mux.HandleFunc("POST /container/{id}/deploy", s.handleContainerDeploy)
+	mux.HandleFunc("POST /container/{id}/unit/{action}", s.handleContainerUnit)
+	mux.HandleFunc("POST /container/{id}/healthcheck", s.handleContainerHealthcheck)
+	mux.HandleFunc("POST /container/{id}/pin", s.handleContainerPin)
+	mux.HandleFunc("POST /container/{id}/kill", s.handleContainerKill)
+	mux.HandleFunc("GET /container/{id}/remove", s.handleContainerRemoveConfirm)
+	mux.HandleFunc("POST /container/{id}/remove", s.handleContainerRemove)
+	for name := range containerActions {
+		mux.HandleFunc("POST /container/{id}/"+name, s.handleContainerAction(name))
+	}
+	mux.HandleFunc("POST /container/{id}/forward", s.handleContainerForward)
+	mux.HandleFunc("GET /forwards", s.handleForwards)
+	mux.HandleFunc("POST /forwards/{id}/stop", s.handleForwardStop)
+	mux.HandleFunc("GET /schedules", s.handleSchedules)
+	mux.HandleFunc("GET /actions", s.handleActions)
+	mux.HandleFunc("POST /actions/{id}/undo", s.handleActionUndo)
 	mux.HandleFunc("GET /images", s.handleImages)
+	mux.HandleFunc("GET /images/compare", s.handleImageCompare)
+	mux.HandleFunc("POST /images/load", s.handleImageLoad)
+	mux.HandleFunc("GET /images/layers", s.handleImageLayers)
+	mux.HandleFunc("GET /images/policy", s.handleImagePolicy)
+	mux.HandleFunc("POST /images/pull", s.handleImagePull)
+	mux.HandleFunc("GET /images/search", s.handleImageSearch)
+	mux.HandleFunc("GET /images/prune", s.handleImagePruneConfirm)
+	mux.HandleFunc("POST /images/prune", s.handleImagePrune)
 	mux.HandleFunc("GET /image/{id}", s.handleImage)
+	mux.HandleFunc("GET /image/{id}/export", s.handleImageExport)
+	mux.HandleFunc("GET /image/{id}/tags", s.handleImageTags)
+	mux.HandleFunc("GET /image/{id}/raw", s.handleRaw("image"))
+	mux.HandleFunc("GET /image/{id}/remove", s.handleImageRemoveConfirm)
+	mux.HandleFunc("POST /image/{id}/remove", s.handleImageRemove)
+	mux.HandleFunc("GET /secrets", s.handleSecrets)
+	mux.HandleFunc("GET /diagnostics", s.handleDiagnostics)
+	mux.HandleFunc("POST /diagnostics/restart-api", s.handleRestartAPI)
+	mux.HandleFunc("GET /config", s.handleConfig)
+	mux.HandleFunc("GET /debug/requests", s.handleDebugRequests)
+	mux.HandleFunc("GET /debug/templates", s.handleDebugTemplates)
+	mux.HandleFunc("GET /views", s.handleViews)
+	mux.HandleFunc("POST /views", s.handleSaveView)
+	mux.HandleFunc("POST /views/delete", s.handleDeleteView)
+	mux.HandleFunc("POST /favorites", s.handleFavorite)
+	mux.HandleFunc("GET /system/df", s.handleDF)
+	mux.HandleFunc("GET /system/cpus", s.handleCPUs)
+	mux.HandleFunc("GET /system/energy", s.handleEnergy)
+	mux.HandleFunc("GET /system/problems", s.handleProblems)
+	mux.HandleFunc("GET /system/prune", s.handleSystemPruneConfirm)
+	mux.HandleFunc("POST /system/prune", s.handleSystemPrune)
+	mux.HandleFunc("GET /start-order", s.handleStartOrder)
+	mux.HandleFunc("GET /themes", s.handleThemes)
+	mux.HandleFunc("POST /themes", s.handleSetTheme)
+	mux.HandleFunc("POST /privacy", s.handlePrivacy)
+	mux.HandleFunc("POST /refresh", s.handleRefresh)
+	mux.HandleFunc("POST /start-order/record", s.handleStartOrderRecord)
+	mux.HandleFunc("POST /start-order/replay", s.handleStartOrderReplay)
 	mux.HandleFunc("GET /logo.svg", handleLogo)
+	mux.HandleFunc("GET /static/{name}", handleStatic)
+	mux.HandleFunc("GET /icons/{key}", s.handleIcon)
+	mux.HandleFunc("GET /custom.css", s.handleCustomCSS)
+	mux.HandleFunc("GET /login", s.handleLogin)
+	mux.HandleFunc("GET /auth/start", s.handleAuthStart)
+	mux.HandleFunc("GET /auth/callback", s.handleAuthCallback)
+	mux.HandleFunc("POST /logout", s.handleLogout)
 	mux.HandleFunc("POST /auto-update", s.handleAutoUpdatePost(podmanBin))
 	mux.HandleFunc("GET /auto-update", s.handleAutoUpdatePage)
 	mux.HandleFunc("GET /auto-update/events", s.handleAutoUpdateEvents)
+	mux.HandleFunc("GET /auto-update/history", s.handleAutoUpdateHistory)
+	mux.HandleFunc("GET /auto-update/labels", s.handleAutoUpdateLabels)
+	mux.HandleFunc("POST /auto-update/labels", s.handleAutoUpdateLabelsApply)
+	mux.HandleFunc("POST /auto-update/labels/preview", s.handleAutoUpdateLabelsPreview)
+	mux.HandleFunc("GET /jobs", s.handleJobs)
+	mux.HandleFunc("GET /job/{id}", s.handleJob)
+	mux.HandleFunc("GET /job/{id}/events", s.handleJobEvents)
+	mux.HandleFunc("POST /hooks/auto-update", s.hook("auto-update", s.hookAutoUpdate(podmanBin)))
+	mux.HandleFunc("POST /hooks/app/{name}/restart", s.hook("app-restart", s.hookAppRestart))
+	mux.HandleFunc("POST /hooks/pull", s.hook("pull", s.hookPull))
+	mux.HandleFunc("POST /hooks/container/{name}/deploy", s.hook("deploy", s.hookDeploy))
+	mux.HandleFunc("POST /hooks/config-sync", s.hook("config-sync", s.hookConfigSync))
 	return mux
 }
 
+// shutdownTimeout bounds how long in-flight requests may take to finish on
+// shutdown. Open event streams are cut off after it.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
-	sock := socketPath()
+	cfg, err := loadConfig(os.Environ())
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	backend := cfg.Backend
+	sock := cfg.Socket
+	if sock == "" {
+		sock = socketPath(backend)
+	}
+	client := newPodmanClient(sock)
+	if backend == "" {
+		backend = detectBackend(client, apiHost)
+	}
 
-	addr := "127.0.0.1:8080"
-	if a := os.Getenv("LISTEN_ADDR"); a != "" {
-		addr = a
+	enableAutoUpdate := cfg.EnableAutoUpdate
+	if enableAutoUpdate && backend != backendPodman {
+		log.Printf("auto-update is only supported with the %s backend, disabling", backendPodman)
+		enableAutoUpdate = false
 	}
 
 	hostname, _ := os.Hostname()
+	// Port forwards listen on the same host as the web UI.
+	listenHost, _, _ := net.SplitHostPort(cfg.ListenAddr)
 
 	s := &Server{
-		basePath:         strings.TrimRight(os.Getenv("BASE_PATH"), "/"),
-		hostname:         hostname,
-		enableAutoUpdate: os.Getenv("ENABLE_AUTOUPDATE_BUTTON") == "true",
-		externalApps:     parseExternalApps(),
-		podmanClient:     newPodmanClient(sock),
-		podmanBaseURL:    "http://d/v4.0.0/libpod",
+		basePath:           cfg.BasePath,
+		trustedProxies:     cfg.TrustedProxies,
+		backend:            backend,
+		hostname:           hostname,
+		listenHost:         listenHost,
+		socketPath:         sock,
+		started:            time.Now(),
+		stops:              newStopLog(),
+		dataDir:            cfg.DataDir,
+		enableAutoUpdate:   enableAutoUpdate,
+		enableActions:      cfg.EnableActions,
+		enableDebugPage:    cfg.EnableDebugPage,
+		enableFileDownload: cfg.EnableFileDownload,
+		enableFileUpload:   cfg.EnableFileUpload,
+		enableLiveMode:     cfg.EnableLiveMode,
+		systemctlBin:       "systemctl",
+		journalctlBin:      "journalctl",
+		quadletDir:         cfg.QuadletDir,
+		requestTimeout:     cfg.RequestTimeout,
+		routeTimeouts:      cfg.RouteTimeouts,
+		thermalDir:         "/sys/class/thermal",
+		webhookToken:       cfg.WebhookToken,
+		webhookLimiter:     newRateLimiter(0.1, 5),
+		externalApps:       cfg.ExternalApps,
+		categories:         cfg.Categories,
+		configSettings:     cfg.settings(backend, sock),
+		containerColumns:   cfg.ContainerColumns,
+		defaultTheme:       cfg.Theme,
+		customCSS:          cfg.CustomCSS,
+		imagePolicy:        cfg.ImagePolicy,
+		registry:           newRegistryClient(),
+		icons:              newIconCache(filepath.Join(cfg.DataDir, "icons")),
+		podmanClient:       client,
+		podmanBaseURL:      apiHost + apiPath(backend),
+	}
+
+	if cfg.SlowAPIThreshold > 0 {
+		s.slowCalls = newSlowCalls(cfg.SlowAPIThreshold)
+	}
+	if cfg.APICacheTTL > 0 {
+		s.apiCache = newAPICache(cfg.APICacheTTL)
+	}
+	if cfg.PollInterval > 0 {
+		s.poller = newStatePoller(s, cfg.PollInterval)
+	}
+	if cfg.EnableDebugPage {
+		s.templateTypes = newTemplateTypes()
+	}
+	if cfg.ActionRateLimit > 0 {
+		s.postLimiter = newKeyedLimiter(cfg.ActionRateLimit, cfg.ActionRateBurst)
+	}
+
+	s.images.path = filepath.Join(s.dataDir, "image-cache.json")
+
+	if s.enableAutoUpdate {
+		s.autoUpdateHistory.path = filepath.Join(s.dataDir, "auto-update-history.json")
+		s.autoUpdateHistory.load()
+	}
+
+	if cfg.GitURL != "" {
+		s.gitConfig = &gitConfig{
+			url:      cfg.GitURL,
+			branch:   cfg.GitBranch,
+			file:     cfg.GitFile,
+			dir:      filepath.Join(s.dataDir, "config-repo"),
+			interval: cfg.GitInterval,
+			gitBin:   "git",
+		}
+		go s.gitConfig.run(ctx)
+	}
+
+	s.calendar = &calendar{loc: cfg.ScheduleTimezone, skip: cfg.ScheduleSkipDates, jitter: cfg.ScheduleJitter}
+	s.cronJobs = s.newCronJobs("podman", cfg.AutoUpdateSchedule, cfg.PruneSchedule)
+	for _, j := range s.cronJobs {
+		go s.runCronJob(ctx, j)
+	}
+
+	if cfg.UpdateCheckInterval > 0 {
+		s.updates = newUpdateChecker(s, cfg.UpdateCheckInterval)
+		go s.updates.run(ctx)
+	}
+
+	if cfg.AppProbeInterval > 0 {
+		s.probes = newProber(s, cfg.AppProbeInterval)
+		go s.probes.run(ctx)
+	}
+
+	if s.poller != nil {
+		go s.poller.run(ctx)
+	}
+
+	s.uptime = newUptimeTracker(s)
+	go s.uptime.run(ctx)
+
+	if cfg.EnergyWattsPerCore > 0 {
+		s.energy = newEnergyMeter(s, cfg.EnergyWattsPerCore, cfg.EnergyCO2PerKWh)
+		go s.energy.run(ctx)
+	}
+
+	var senders []notifier
+	notifyClient := &http.Client{Timeout: 30 * time.Second}
+	if cfg.NtfyURL != "" {
+		senders = append(senders, ntfySender{url: cfg.NtfyURL, token: cfg.NtfyToken, client: notifyClient})
+	}
+	if cfg.GotifyURL != "" {
+		senders = append(senders, gotifySender{url: cfg.GotifyURL, token: cfg.GotifyToken, client: notifyClient})
+	}
+	s.crashes = newCrashTracker(s.dataDir, s.stops)
+	if s.enableActions {
+		s.watchdog = newWatchdog(s)
+	}
+	go newEventWatcher(s, senders).run(ctx)
+
+	if cfg.OIDCIssuer != "" {
+		s.oidc = newOIDCProvider(cfg.OIDCIssuer, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL)
+	}
+
+	if s.enableActions {
+		go (&scheduler{s: s}).run(ctx)
+		s.startOrder = newStartOrderRecorder(s)
+		go s.startOrder.run(ctx)
 	}
 
 	mux := s.newMux("podman")
@@ -81,18 +369,60 @@ func main() {
 		handler = http.StripPrefix(s.basePath, mux)
 	}
 
+	addr := cfg.ListenAddr
 	host := addr
 	if strings.HasPrefix(host, ":") {
 		host = "localhost" + host
 	}
-	log.Printf("podfather listening on http://%s%s (socket: %s)", host, s.basePath, sock)
-	handler = s.csrfProtect(handler)
-	log.Fatal(http.ListenAndServe(addr, logRequests(handler)))
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	scheme := "http"
+	srv := &http.Server{Handler: s.logRequests(s.requireLogin(s.limitPosts(s.csrfProtect(s.dropCachesOnPost(s.withDeadline(handler))))))}
+	if cfg.TLSCert != "" {
+		certs, err := newCertReloader(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+		srv.TLSConfig = tlsConfig(certs)
+		scheme = "https"
+	}
+	log.Printf("podfather listening on %s://%s%s (socket: %s, backend: %s)", scheme, host, s.basePath, sock, backend)
+	serveErr := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			serveErr <- srv.ServeTLS(ln, "", "")
+		} else {
+			serveErr <- srv.Serve(ln)
+		}
+	}()
+	if err := sdNotify("READY=1"); err != nil {
+		log.Printf("sd_notify: %v", err)
+	}
+
+	select {
+	case err := <-serveErr:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop()
+	log.Printf("shutting down")
+	if err := sdNotify("STOPPING=1"); err != nil {
+		log.Printf("sd_notify: %v", err)
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown: %v", err)
+		srv.Close()
+	}
 }
 
 type statusWriter struct {
 	http.ResponseWriter
 	status int
+	bytes  int64
 }
 
 func (w *statusWriter) WriteHeader(code int) {
@@ -100,28 +430,45 @@ func (w *statusWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+func (w *statusWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
 func (w *statusWriter) Flush() {
 	if f, ok := w.ResponseWriter.(http.Flusher); ok {
 		f.Flush()
 	}
 }
 
-func logRequests(next http.Handler) http.Handler {
+func (s *Server) logRequests(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var buf [4]byte
 		rand.Read(buf[:])
 		id := fmt.Sprintf("%x", buf)
 		ctx := context.WithValue(r.Context(), reqIDKey, id)
-		r = r.WithContext(ctx)
+		r = s.withClientIP(r.WithContext(ctx))
 
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		w.Header().Set("X-Frame-Options", "DENY")
 		w.Header().Set("Referrer-Policy", "no-referrer")
-		w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; img-src 'self'; connect-src 'self'; form-action 'self'")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self'; connect-src 'self'; form-action 'self'")
 
 		start := time.Now()
 		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
 		next.ServeHTTP(sw, r)
-		log.Printf("[%s] %s %s %d %s", id, r.Method, r.URL.Path, sw.status, time.Since(start).Round(time.Millisecond))
+		e := AccessLogEntry{
+			ID:       id,
+			Time:     start,
+			Client:   clientIP(r),
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			Status:   sw.status,
+			Bytes:    sw.bytes,
+			Duration: time.Since(start).Round(time.Millisecond),
+		}
+		s.accessLog.add(e)
+		log.Printf("[%s] %s %s %s %d %dB %s", id, e.Client, e.Method, e.Path, e.Status, e.Bytes, e.Duration)
 	})
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+)
+
+// App descriptions may use a small subset of Markdown for setup notes:
+// [links](https://example.com), **bold** and `code`. Everything else is
+// text. The renderer escapes all text and only emits the tags it creates
+// itself, so HTML in a label never reaches the page, and links other than
+// http(s) stay text. Unclosed markup is shown as typed.
+
+// markdown renders s as HTML.
+func markdown(s string) template.HTML {
+	var b strings.Builder
+	renderMarkdown(&b, s, true)
+	return template.HTML(b.String())
+}
+
+// renderMarkdown writes s to b. Links are only rendered if links is set,
+// which is not the case within link texts.
+func renderMarkdown(b *strings.Builder, s string, links bool) {
+	text := 0 // start of the pending text
+	flush := func(end int) {
+		b.WriteString(template.HTMLEscapeString(s[text:end]))
+	}
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '`':
+			end := strings.IndexByte(s[i+1:], '`')
+			if end <= 0 {
+				break
+			}
+			flush(i)
+			b.WriteString("<code>" + template.HTMLEscapeString(s[i+1:i+1+end]) + "</code>")
+			i += end + 2
+			text = i
+			continue
+		case strings.HasPrefix(s[i:], "**"):
+			end := strings.Index(s[i+2:], "**")
+			if end <= 0 {
+				break
+			}
+			flush(i)
+			b.WriteString("<strong>")
+			renderMarkdown(b, s[i+2:i+2+end], links)
+			b.WriteString("</strong>")
+			i += end + 4
+			text = i
+			continue
+		case s[i] == '[' && links:
+			label, target, n, ok := markdownLink(s[i:])
+			if !ok {
+				break
+			}
+			flush(i)
+			b.WriteString(`<a href="` + template.HTMLEscapeString(target) + `" target="_blank" rel="noopener">`)
+			renderMarkdown(b, label, false)
+			b.WriteString("</a>")
+			i += n
+			text = i
+			continue
+		}
+		i++
+	}
+	flush(len(s))
+}
+
+// markdownLink parses a link of the form [label](target) at the start of s
+// and returns its length. Only absolute http(s) targets are accepted.
+func markdownLink(s string) (label, target string, n int, ok bool) {
+	label, rest, found := strings.Cut(s[1:], "](")
+	if !found || label == "" || strings.Contains(label, "[") {
+		return "", "", 0, false
+	}
+	target, _, found = strings.Cut(rest, ")")
+	if !found || strings.ContainsAny(target, " \t\n") {
+		return "", "", 0, false
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", 0, false
+	}
+	return label, target, len(label) + len(target) + 4, true
+}
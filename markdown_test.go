@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"Stream your media", "Stream your media"},
+		{"Log in as **admin**, see `~/.config`", "Log in as <strong>admin</strong>, see <code>~/.config</code>"},
+		{"[Docs](https://example.com/a?b=1&c=2) here", `<a href="https://example.com/a?b=1&amp;c=2" target="_blank" rel="noopener">Docs</a> here`},
+		{"**[Setup](http://wiki.lan/setup)**", `<strong><a href="http://wiki.lan/setup" target="_blank" rel="noopener">Setup</a></strong>`},
+		{"[**bold** `code`](https://x.example)", `<a href="https://x.example" target="_blank" rel="noopener"><strong>bold</strong> <code>code</code></a>`},
+		{"`**not bold**`", "<code>**not bold**</code>"},
+		{"<script>alert(1)</script>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"`<b>`", "<code>&lt;b&gt;</code>"},
+		{"[x](javascript:alert(1))", "[x](javascript:alert(1))"},
+		{"[x](/relative)", "[x](/relative)"},
+		{`[x](https://a.example/"onmouseover=")`, `<a href="https://a.example/&#34;onmouseover=&#34;" target="_blank" rel="noopener">x</a>`},
+		{"[x](https://a.example/a b)", "[x](https://a.example/a b)"},
+		{"[[x](https://a.example)](https://b.example)", `[<a href="https://a.example" target="_blank" rel="noopener">x</a>](https://b.example)`},
+		{"unclosed **bold and `code", "unclosed **bold and `code"},
+		{"empty **** and ``", "empty **** and ``"},
+	} {
+		if got := string(markdown(tt.in)); got != tt.want {
+			t.Errorf("markdown(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
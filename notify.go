@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The event watcher follows the container events stream and sends push
+// notifications to ntfy and/or Gotify when a container crashes, is OOM-killed
+// or becomes unhealthy. Containers can opt out with the label
+//
+//	ch.jo-m.go.podfather.notify=false
+
+const (
+	notifyLabel = "ch.jo-m.go.podfather.notify"
+	// notifyThrottle limits notifications per container and kind, so a
+	// crash loop does not send one per restart.
+	notifyThrottle = 10 * time.Minute
+)
+
+// notification is a message to send. Priority is on the ntfy scale of 1
+// (min) to 5 (max).
+type notification struct {
+	Title    string
+	Message  string
+	Priority int
+	Tags     []string
+}
+
+type notifier interface {
+	notify(ctx context.Context, n notification) error
+}
+
+// ntfySender publishes to an ntfy topic URL, e.g. https://ntfy.sh/mytopic.
+type ntfySender struct {
+	url    string
+	token  string // optional access token
+	client *http.Client
+}
+
+func (ns ntfySender) notify(ctx context.Context, n notification) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ns.url, strings.NewReader(n.Message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", n.Title)
+	req.Header.Set("Priority", strconv.Itoa(n.Priority))
+	if len(n.Tags) > 0 {
+		req.Header.Set("Tags", strings.Join(n.Tags, ","))
+	}
+	if ns.token != "" {
+		req.Header.Set("Authorization", "Bearer "+ns.token)
+	}
+	return sendNotification(ns.client, req, "ntfy")
+}
+
+// gotifySender posts to the message endpoint of a Gotify server with an
+// application token.
+type gotifySender struct {
+	url    string // server URL
+	token  string
+	client *http.Client
+}
+
+func (gs gotifySender) notify(ctx context.Context, n notification) error {
+	body, err := json.Marshal(map[string]any{
+		"title":    n.Title,
+		"message":  n.Message,
+		"priority": n.Priority * 2, // Gotify uses 0 to 10
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(gs.url, "/")+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", gs.token)
+	return sendNotification(gs.client, req, "gotify")
+}
+
+func sendNotification(client *http.Client, req *http.Request, service string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		// The error contains the URL, which may contain credentials.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("%s: %w", service, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", service, resp.Status)
+	}
+	return nil
+}
+
+// containerEvent is a container event from the libpod or compat events API.
+type containerEvent struct {
+	Action   string `json:"Action"`
+	TimeNano int64  `json:"timeNano"`
+	Actor    struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+// eventNotification returns the notification for an event and a key for
+// throttling, or ok=false if the event is not worth a notification. Exits
+// with code 0 or 143 (SIGTERM) and exits after a stop or kill in stops are
+// regular stops.
+func eventNotification(ev containerEvent, hostname string, stops *stopLog, now time.Time) (key string, n notification, ok bool) {
+	attrs := ev.Actor.Attributes
+	if attrs[notifyLabel] == "false" {
+		return "", n, false
+	}
+	name := cmp.Or(attrs["name"], shortID(ev.Actor.ID))
+	on := ""
+	if hostname != "" {
+		on = " on " + hostname
+	}
+	switch ev.Action {
+	case "die", "died":
+		code, crashed := stops.crashed(ev, now)
+		if !crashed {
+			return "", n, false
+		}
+		n = notification{
+			Title:    name + " crashed",
+			Message:  fmt.Sprintf("Container %s exited with code %s%s.", name, code, on),
+			Priority: 4,
+			Tags:     []string{"rotating_light"},
+		}
+	case "oom":
+		n = notification{
+			Title:    name + " ran out of memory",
+			Message:  fmt.Sprintf("Container %s was OOM-killed%s.", name, on),
+			Priority: 4,
+			Tags:     []string{"rotating_light"},
+		}
+	case "health_status", "health_status: unhealthy":
+		// Podman reports the status as an attribute, Docker in the action.
+		if ev.Action == "health_status" && attrs["health_status"] != "unhealthy" {
+			return "", n, false
+		}
+		n = notification{
+			Title:    name + " is unhealthy",
+			Message:  fmt.Sprintf("The healthcheck of container %s is failing%s.", name, on),
+			Priority: 3,
+			Tags:     []string{"warning"},
+		}
+	default:
+		return "", n, false
+	}
+	return ev.Actor.ID + "/" + n.Title, n, true
+}
+
+// eventWatcher records crashes, passes container events to the watchdog and
+// sends notifications for them to all senders.
+type eventWatcher struct {
+	s       *Server
+	senders []notifier
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time // throttle key -> time
+	lastNano int64                // time of the last event seen, for reconnects
+}
+
+func newEventWatcher(s *Server, senders []notifier) *eventWatcher {
+	return &eventWatcher{s: s, senders: senders, lastSent: map[string]time.Time{}}
+}
+
+// run watches events until ctx is done, reconnecting with backoff.
+func (w *eventWatcher) run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := w.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		log.Printf("events: stream ended: %v, reconnecting in %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 5*time.Minute)
+	}
+}
+
+// watch follows the events stream until it ends. After a reconnect, it
+// replays the events it missed in the meantime.
+func (w *eventWatcher) watch(ctx context.Context) error {
+	filters, _ := json.Marshal(map[string][]string{
+		"type":  {"container"},
+		"event": {"die", "died", "oom", "health_status", "stop", "kill"},
+	})
+	q := url.Values{"stream": {"true"}, "filters": {string(filters)}}
+	w.mu.Lock()
+	if w.lastNano > 0 {
+		q.Set("since", strconv.FormatInt(w.lastNano/1e9, 10))
+	}
+	w.mu.Unlock()
+	resp, err := w.s.podmanDo(ctx, http.MethodGet, "/events?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev containerEvent
+		if err := dec.Decode(&ev); err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("closed by the API")
+			}
+			return err
+		}
+		w.handle(ctx, ev, time.Now())
+	}
+}
+
+// handle records crashes, passes ev to the watchdog and sends the
+// notification for ev, unless it was seen already or the same notification
+// was sent recently.
+func (w *eventWatcher) handle(ctx context.Context, ev containerEvent, now time.Time) {
+	w.mu.Lock()
+	if ev.TimeNano != 0 && ev.TimeNano <= w.lastNano {
+		w.mu.Unlock()
+		return
+	}
+	w.lastNano = max(w.lastNano, ev.TimeNano)
+	w.s.stops.record(ev, now)
+	w.s.crashes.record(ev, now)
+	w.s.watchdog.handle(ctx, ev, now)
+	key, n, ok := eventNotification(ev, w.s.hostname, w.s.stops, now)
+	if ok && now.Sub(w.lastSent[key]) < notifyThrottle {
+		ok = false
+	}
+	if ok {
+		for k, t := range w.lastSent {
+			if now.Sub(t) >= notifyThrottle {
+				delete(w.lastSent, k)
+			}
+		}
+		w.lastSent[key] = now
+	}
+	w.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	for _, sender := range w.senders {
+		if err := sender.notify(ctx, n); err != nil {
+			log.Printf("notify: %v", err)
+		}
+	}
+}
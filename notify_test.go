@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testEvent(action string, attrs map[string]string) containerEvent {
+	var ev containerEvent
+	ev.Action = action
+	ev.Actor.ID = "4b9d1f0c2e3a"
+	ev.Actor.Attributes = attrs
+	return ev
+}
+
+func TestEventNotification(t *testing.T) {
+	tests := []struct {
+		ev        containerEvent
+		wantTitle string // "" if no notification
+	}{
+		{testEvent("died", map[string]string{"name": "jellyfin", "containerExitCode": "139"}), "jellyfin crashed"},
+		{testEvent("die", map[string]string{"name": "jellyfin", "exitCode": "1"}), "jellyfin crashed"},
+		{testEvent("died", map[string]string{"name": "jellyfin", "containerExitCode": "0"}), ""},
+		{testEvent("died", map[string]string{"name": "jellyfin", "containerExitCode": "143"}), ""},
+		{testEvent("died", map[string]string{"name": "jellyfin", "containerExitCode": "1", notifyLabel: "false"}), ""},
+		{testEvent("oom", map[string]string{"name": "jellyfin"}), "jellyfin ran out of memory"},
+		{testEvent("health_status", map[string]string{"name": "jellyfin", "health_status": "unhealthy"}), "jellyfin is unhealthy"},
+		{testEvent("health_status", map[string]string{"name": "jellyfin", "health_status": "healthy"}), ""},
+		{testEvent("health_status: unhealthy", map[string]string{"name": "jellyfin"}), "jellyfin is unhealthy"},
+		{testEvent("oom", nil), "4b9d1f0c2e3a ran out of memory"},
+		{testEvent("start", map[string]string{"name": "jellyfin"}), ""},
+	}
+	for _, tt := range tests {
+		_, n, ok := eventNotification(tt.ev, "nas", nil, time.Now())
+		if got := map[bool]string{true: n.Title}[ok]; got != tt.wantTitle {
+			t.Errorf("eventNotification(%s %v) = %q, want %q", tt.ev.Action, tt.ev.Actor.Attributes, got, tt.wantTitle)
+		}
+	}
+
+	_, n, _ := eventNotification(testEvent("died", map[string]string{"name": "jellyfin", "containerExitCode": "139"}), "nas", nil, time.Now())
+	if want := "Container jellyfin exited with code 139 on nas."; n.Message != want {
+		t.Errorf("message = %q, want %q", n.Message, want)
+	}
+
+	// podman stop ends in SIGKILL after the stop timeout, podman kill too.
+	now := time.Now()
+	for _, action := range []string{"stop", "kill"} {
+		stops := newStopLog()
+		stops.record(testEvent(action, map[string]string{"name": "jellyfin"}), now)
+		died := testEvent("died", map[string]string{"name": "jellyfin", "containerExitCode": "137"})
+		if _, n, ok := eventNotification(died, "nas", stops, now.Add(10*time.Second)); ok {
+			t.Errorf("%s, died 137: notification %q, want none", action, n.Title)
+		}
+		if _, _, ok := eventNotification(died, "nas", stops, now.Add(stopExitWindow+time.Second)); !ok {
+			t.Errorf("%s, died 137 after %v: no notification", action, stopExitWindow)
+		}
+	}
+}
+
+func TestNtfySender(t *testing.T) {
+	t.Parallel()
+	var got *http.Request
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got, body = r, string(b)
+	}))
+	defer srv.Close()
+
+	ns := ntfySender{url: srv.URL + "/homelab", token: "tk_secret", client: srv.Client()}
+	err := ns.notify(context.Background(), notification{Title: "jellyfin crashed", Message: "exited", Priority: 4, Tags: []string{"rotating_light"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.URL.Path != "/homelab" || body != "exited" {
+		t.Errorf("request = %s %q", got.URL.Path, body)
+	}
+	for header, want := range map[string]string{
+		"Title":         "jellyfin crashed",
+		"Priority":      "4",
+		"Tags":          "rotating_light",
+		"Authorization": "Bearer tk_secret",
+	} {
+		if v := got.Header.Get(header); v != want {
+			t.Errorf("%s = %q, want %q", header, v, want)
+		}
+	}
+}
+
+func TestGotifySender(t *testing.T) {
+	t.Parallel()
+	var msg struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/message" || r.Header.Get("X-Gotify-Key") != "AbCd" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+	}))
+	defer srv.Close()
+
+	gs := gotifySender{url: srv.URL + "/", token: "AbCd", client: srv.Client()}
+	if err := gs.notify(context.Background(), notification{Title: "t", Message: "m", Priority: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Title != "t" || msg.Message != "m" || msg.Priority != 8 {
+		t.Errorf("message = %+v", msg)
+	}
+
+	gs.token = "wrong"
+	if err := gs.notify(context.Background(), notification{}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("err = %v, want 401", err)
+	}
+}
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []notification
+}
+
+func (rn *recordingNotifier) notify(_ context.Context, n notification) error {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	rn.sent = append(rn.sent, n)
+	return nil
+}
+
+func TestEventWatcher(t *testing.T) {
+	t.Parallel()
+	now := time.Now().UnixNano()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v4.0.0/libpod/events" || r.URL.Query().Get("stream") != "true" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// A crash loop, a regular stop, a kill and an unhealthy container.
+		for i, code := range []string{"139", "139", "0"} {
+			fmt.Fprintf(w, `{"Type":"container","Action":"died","Actor":{"ID":"aaa","Attributes":{"name":"jellyfin","containerExitCode":%q}},"timeNano":%d}`+"\n", code, now+int64(i))
+		}
+		for i, action := range []string{"kill", "died"} {
+			fmt.Fprintf(w, `{"Type":"container","Action":%q,"Actor":{"ID":"ccc","Attributes":{"name":"web","containerExitCode":"137"}},"timeNano":%d}`+"\n", action, now+5+int64(i))
+		}
+		fmt.Fprintf(w, `{"Type":"container","Action":"health_status","Actor":{"ID":"bbb","Attributes":{"name":"db","health_status":"unhealthy"}},"timeNano":%d}`+"\n", now+10)
+	}))
+	defer api.Close()
+
+	rn := &recordingNotifier{}
+	s := newTestServer(t, api)
+	s.stops = newStopLog()
+	w := newEventWatcher(s, []notifier{rn})
+	if err := w.watch(context.Background()); err == nil {
+		t.Fatal("watch returned nil")
+	}
+	if len(rn.sent) != 2 || rn.sent[0].Title != "jellyfin crashed" || rn.sent[1].Title != "db is unhealthy" {
+		t.Fatalf("sent = %+v", rn.sent)
+	}
+
+	// A reconnect replays the same events, which must not be sent again.
+	w.watch(context.Background())
+	if len(rn.sent) != 2 {
+		t.Errorf("sent %d notifications after reconnect, want 2", len(rn.sent))
+	}
+}
@@ -0,0 +1,638 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenID Connect login (OIDC_*). With an issuer configured, every page
+// requires a session, which is created by the authorization code flow (with
+// PKCE) against the issuer. Sessions are kept in memory, so a restart logs
+// everyone out. Webhooks keep authenticating with their token.
+//
+// Only the parts of OIDC podfather needs are implemented: discovery, the
+// code flow with client_secret_basic, and ID tokens signed with RS256 or
+// ES256.
+
+const (
+	sessionCookieName = "session"
+	loginStateCookie  = "oidc_state"
+	sessionMaxAge     = 12 * time.Hour
+	loginStateMaxAge  = 10 * time.Minute
+	// maxPendingLogins bounds the logins in progress; the oldest are
+	// dropped beyond it.
+	maxPendingLogins = 1000
+	// oidcKeysMinAge rate limits JWKS refetches for unknown key IDs.
+	oidcKeysMinAge = time.Minute
+	oidcTimeout    = 10 * time.Second
+)
+
+const userKey ctxKey = 2
+
+// loginPaths are reachable without a session, as are static assets.
+var loginPaths = []string{"/login", "/auth/start", "/auth/callback", "/logo.svg", "/custom.css"}
+
+// oidcDiscovery is the subset of the provider metadata podfather uses.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idClaims are the ID token claims podfather checks or shows.
+type idClaims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	Expiry            int64    `json:"exp"`
+	Nonce             string   `json:"nonce"`
+	PreferredUsername string   `json:"preferred_username"`
+	Email             string   `json:"email"`
+	Name              string   `json:"name"`
+}
+
+// user returns the name to show for the claims.
+func (c idClaims) user() string {
+	for _, v := range []string{c.PreferredUsername, c.Email, c.Name} {
+		if v != "" {
+			return v
+		}
+	}
+	return c.Subject
+}
+
+// audience is the aud claim, which is a string or a list of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// loginState is a login in progress, keyed by its state parameter.
+type loginState struct {
+	nonce    string
+	verifier string // PKCE code verifier
+	next     string
+	expires  time.Time
+}
+
+type session struct {
+	user    string
+	expires time.Time
+}
+
+type oidcProvider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	client       *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]crypto.PublicKey
+	keysTime  time.Time
+	pending   map[string]loginState
+	sessions  map[string]session
+}
+
+func newOIDCProvider(issuer, clientID, clientSecret, redirectURL string) *oidcProvider {
+	return &oidcProvider{
+		issuer:       strings.TrimRight(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		client:       &http.Client{Timeout: oidcTimeout},
+		pending:      map[string]loginState{},
+		sessions:     map[string]session{},
+	}
+}
+
+// randomToken returns n random bytes, base64url encoded.
+func randomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// metadata returns the provider metadata, fetching it on first use so an
+// unreachable issuer does not prevent startup.
+func (p *oidcProvider) metadata(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	d := p.discovery
+	p.mu.Unlock()
+	if d != nil {
+		return d, nil
+	}
+	d = &oidcDiscovery{}
+	if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", d); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if strings.TrimRight(d.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("discovery: issuer %q does not match %q", d.Issuer, p.issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("discovery: missing endpoints")
+	}
+	p.mu.Lock()
+	p.discovery = d
+	p.mu.Unlock()
+	return d, nil
+}
+
+// jwk is a JSON Web Key. Only RSA and P-256 keys are used.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey returns the key, or nil if it is of an unsupported type.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.Use != "" && k.Use != "sig":
+		return nil, nil
+	case k.Kty == "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() > 1<<31 {
+			return nil, errors.New("RSA exponent too large")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("bad P-256 coordinates")
+		}
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+	}
+	return nil, nil
+}
+
+// key returns the signing key with the given ID, refetching the key set if
+// it is unknown (the provider may have rotated its keys).
+func (p *oidcProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	k, ok := p.keys[kid]
+	fresh := time.Since(p.keysTime) < oidcKeysMinAge
+	p.mu.Unlock()
+	if ok {
+		return k, nil
+	}
+	if fresh {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	d, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jk := range set.Keys {
+		pub, err := jk.publicKey()
+		if err != nil {
+			log.Printf("oidc: skipping key %q: %v", jk.Kid, err)
+			continue
+		}
+		if pub != nil {
+			keys[jk.Kid] = pub
+		}
+	}
+	p.mu.Lock()
+	p.keys, p.keysTime = keys, time.Now()
+	p.mu.Unlock()
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// verifyIDToken checks the signature and claims of a compact JWS ID token.
+func (p *oidcProvider) verifyIDToken(ctx context.Context, token, nonce string, now time.Time) (idClaims, error) {
+	var claims idClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return claims, fmt.Errorf("header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, fmt.Errorf("signature: %w", err)
+	}
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return claims, err
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" {
+			return claims, fmt.Errorf("unsupported algorithm %q for RSA key", header.Alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig); err != nil {
+			return claims, errors.New("bad signature")
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" || len(sig) != 64 {
+			return claims, fmt.Errorf("unsupported algorithm %q for EC key", header.Alg)
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, hash[:], r, s) {
+			return claims, errors.New("bad signature")
+		}
+	default:
+		return claims, errors.New("unsupported key type")
+	}
+
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return claims, fmt.Errorf("claims: %w", err)
+	}
+	switch {
+	case strings.TrimRight(claims.Issuer, "/") != p.issuer:
+		return claims, fmt.Errorf("issuer %q", claims.Issuer)
+	case !slices.Contains(claims.Audience, p.clientID):
+		return claims, fmt.Errorf("audience %q", claims.Audience)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.clientID:
+		return claims, fmt.Errorf("authorized party %q", claims.AuthorizedParty)
+	case now.After(time.Unix(claims.Expiry, 0)):
+		return claims, errors.New("token expired")
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return claims, errors.New("nonce mismatch")
+	case claims.Subject == "":
+		return claims, errors.New("no subject")
+	}
+	return claims, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// exchange redeems an authorization code for an ID token.
+func (p *oidcProvider) exchange(ctx context.Context, code, verifier string) (string, error) {
+	d, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("token endpoint: %s: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.IDToken == "" {
+		return "", fmt.Errorf("token endpoint: %s %s", resp.Status, body.Error)
+	}
+	return body.IDToken, nil
+}
+
+// sessionUser returns the user of a valid session, or "".
+func (p *oidcProvider) sessionUser(id string, now time.Time) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sess, ok := p.sessions[id]
+	if !ok {
+		return ""
+	}
+	if now.After(sess.expires) {
+		delete(p.sessions, id)
+		return ""
+	}
+	return sess.user
+}
+
+// newSession stores a session for user and returns its ID. Expired sessions
+// and logins are dropped.
+func (p *oidcProvider) newSession(user string, now time.Time) string {
+	id := randomToken(32)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, sess := range p.sessions {
+		if now.After(sess.expires) {
+			delete(p.sessions, k)
+		}
+	}
+	p.sessions[id] = session{user: user, expires: now.Add(sessionMaxAge)}
+	return id
+}
+
+func (p *oidcProvider) endSession(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sessions, id)
+}
+
+// startLogin stores a login in progress and returns its state.
+func (p *oidcProvider) startLogin(next string, now time.Time) (string, loginState) {
+	state := randomToken(16)
+	ls := loginState{
+		nonce:    randomToken(16),
+		verifier: randomToken(32),
+		next:     next,
+		expires:  now.Add(loginStateMaxAge),
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	oldest := ""
+	for k, v := range p.pending {
+		if now.After(v.expires) {
+			delete(p.pending, k)
+		} else if oldest == "" || v.expires.Before(p.pending[oldest].expires) {
+			oldest = k
+		}
+	}
+	if len(p.pending) >= maxPendingLogins {
+		delete(p.pending, oldest)
+	}
+	p.pending[state] = ls
+	return state, ls
+}
+
+// finishLogin removes and returns the login with the given state.
+func (p *oidcProvider) finishLogin(state string, now time.Time) (loginState, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ls, ok := p.pending[state]
+	delete(p.pending, state)
+	if !ok || now.After(ls.expires) {
+		return loginState{}, false
+	}
+	return ls, true
+}
+
+// localPath returns next if it is a path on this server, or "/".
+func localPath(next string) string {
+	// Browsers drop control characters and read backslashes as slashes in
+	// Location headers, so "/\t/evil.com" and "/\\evil.com" lead off-site.
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") ||
+		strings.ContainsFunc(next, func(r rune) bool { return r < 0x20 || r == 0x7f || r == '\\' }) {
+		return "/"
+	}
+	if u, err := url.Parse(next); err != nil || u.Scheme != "" || u.Host != "" || u.Opaque != "" {
+		return "/"
+	}
+	return next
+}
+
+// currentUser returns the logged-in user of the request, or "".
+func currentUser(ctx context.Context) string {
+	u, _ := ctx.Value(userKey).(string)
+	return u
+}
+
+// requireLogin redirects requests without a session to the login page. It
+// must wrap the handler outside of BASE_PATH stripping.
+func (s *Server) requireLogin(next http.Handler) http.Handler {
+	if s.oidc == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.Path, s.basePath)
+		if strings.HasPrefix(p, hooksPrefix) || strings.HasPrefix(p, "/static/") || slices.Contains(loginPaths, p) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if c, err := r.Cookie(sessionCookieName); err == nil {
+			if user := s.oidc.sessionUser(c.Value, time.Now()); user != "" {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+				return
+			}
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, s.basePath+"/login?"+url.Values{"next": {p + querySuffix(r.URL.RawQuery)}}.Encode(), http.StatusSeeOther)
+	})
+}
+
+func querySuffix(q string) string {
+	if q == "" {
+		return ""
+	}
+	return "?" + q
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	provider := s.oidc.issuer
+	if u, err := url.Parse(s.oidc.issuer); err == nil {
+		provider = u.Host
+	}
+	s.render(w, r, "login.html", map[string]any{
+		"Title":    "Sign in",
+		"Provider": provider,
+		"Next":     localPath(r.URL.Query().Get("next")),
+		"Failed":   r.URL.Query().Has("failed"),
+	})
+}
+
+func (s *Server) handleAuthStart(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	d, err := s.oidc.metadata(r.Context())
+	if err != nil {
+		log.Printf("[%s] oidc: %v", reqID(r.Context()), err)
+		http.Error(w, "Login provider unavailable", http.StatusBadGateway)
+		return
+	}
+	state, ls := s.oidc.startLogin(localPath(r.URL.Query().Get("next")), time.Now())
+	// The state cookie binds the login to this browser. It must be sent on
+	// the cross-site redirect back from the provider, hence Lax.
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginStateCookie,
+		Value:    state,
+		Path:     s.basePath + "/auth/",
+		MaxAge:   int(loginStateMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	challenge := sha256.Sum256([]byte(ls.verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.oidc.clientID},
+		"redirect_uri":          {s.oidc.redirectURL},
+		"scope":                 {"openid profile email"},
+		"state":                 {state},
+		"nonce":                 {ls.nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	http.Redirect(w, r, d.AuthorizationEndpoint+sep+q.Encode(), http.StatusSeeOther)
+}
+
+func (s *Server) handleAuthCallback(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	id := reqID(r.Context())
+	fail := func(format string, args ...any) {
+		log.Printf("[%s] oidc: login failed: %s", id, fmt.Sprintf(format, args...))
+		http.Redirect(w, r, s.basePath+"/login?failed=1", http.StatusSeeOther)
+	}
+	http.SetCookie(w, &http.Cookie{Name: loginStateCookie, Path: s.basePath + "/auth/", MaxAge: -1})
+
+	q := r.URL.Query()
+	state := q.Get("state")
+	c, err := r.Cookie(loginStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(c.Value), []byte(state)) != 1 {
+		fail("state does not match the browser's")
+		return
+	}
+	ls, ok := s.oidc.finishLogin(state, time.Now())
+	if !ok {
+		fail("unknown or expired state")
+		return
+	}
+	if e := q.Get("error"); e != "" {
+		fail("provider returned %s", e)
+		return
+	}
+	token, err := s.oidc.exchange(r.Context(), q.Get("code"), ls.verifier)
+	if err != nil {
+		fail("%v", err)
+		return
+	}
+	claims, err := s.oidc.verifyIDToken(r.Context(), token, ls.nonce, time.Now())
+	if err != nil {
+		fail("id token: %v", err)
+		return
+	}
+	user := claims.user()
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    s.oidc.newSession(user, time.Now()),
+		Path:     s.basePath + "/",
+		MaxAge:   int(sessionMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	log.Printf("[%s] audit: login %s from %s", id, user, clientIP(r))
+	http.Redirect(w, r, s.basePath+ls.next, http.StatusSeeOther)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	if c, err := r.Cookie(sessionCookieName); err == nil {
+		s.oidc.endSession(c.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Path: s.basePath + "/", MaxAge: -1})
+	log.Printf("[%s] audit: logout %s from %s", reqID(r.Context()), currentUser(r.Context()), clientIP(r))
+	http.Redirect(w, r, s.basePath+"/login", http.StatusSeeOther)
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeIdP is a minimal OpenID provider that signs ID tokens with an RSA key.
+type fakeIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]url.Values // code -> authorization request
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &fakeIdP{key: key, codes: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                idp.URL,
+			AuthorizationEndpoint: idp.URL + "/authorize",
+			TokenEndpoint:         idp.URL + "/token",
+			JWKSURI:               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []jwk{{
+			Kty: "RSA",
+			Kid: "k1",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("GET /authorize", func(w http.ResponseWriter, r *http.Request) {
+		code := randomToken(8)
+		idp.mu.Lock()
+		idp.codes[code] = r.URL.Query()
+		idp.mu.Unlock()
+		q := url.Values{"code": {code}, "state": {r.URL.Query().Get("state")}}
+		http.Redirect(w, r, r.URL.Query().Get("redirect_uri")+"?"+q.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		idp.mu.Lock()
+		auth, ok := idp.codes[r.FormValue("code")]
+		delete(idp.codes, r.FormValue("code"))
+		idp.mu.Unlock()
+		challenge := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || id != "podfather" || secret != "s3cret" ||
+			auth.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.sign(t, map[string]any{
+			"iss":                idp.URL,
+			"sub":                "1234",
+			"aud":                "podfather",
+			"exp":                time.Now().Add(time.Hour).Unix(),
+			"nonce":              auth.Get("nonce"),
+			"preferred_username": "alice",
+		})})
+	})
+	idp.Server = httptest.NewServer(mux)
+	return idp
+}
+
+func (idp *fakeIdP) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	t.Parallel()
+	idp := newFakeIdP(t)
+	defer idp.Close()
+	p := newOIDCProvider(idp.URL, "podfather", "s3cret", "http://podfather/auth/callback")
+	now := time.Now()
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss":   idp.URL,
+			"sub":   "1234",
+			"aud":   []string{"podfather"},
+			"exp":   now.Add(time.Hour).Unix(),
+			"nonce": "n1",
+			"email": "alice@example.com",
+		}
+	}
+
+	claims, err := p.verifyIDToken(context.Background(), idp.sign(t, valid()), "n1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := claims.user(); got != "alice@example.com" {
+		t.Errorf("user = %q", got)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(map[string]any)
+	}{
+		{"issuer", func(c map[string]any) { c["iss"] = "https://evil.example.com" }},
+		{"audience", func(c map[string]any) { c["aud"] = "other" }},
+		{"authorized party", func(c map[string]any) { c["aud"] = []string{"podfather", "other"} }},
+		{"expired", func(c map[string]any) { c["exp"] = now.Add(-time.Minute).Unix() }},
+		{"nonce", func(c map[string]any) { c["nonce"] = "n2" }},
+	}
+	for _, tt := range tests {
+		claims := valid()
+		tt.modify(claims)
+		if _, err := p.verifyIDToken(context.Background(), idp.sign(t, claims), "n1", now); err == nil {
+			t.Errorf("%s: token accepted", tt.name)
+		}
+	}
+
+	token := idp.sign(t, valid())
+	tampered := token[:strings.LastIndex(token, ".")] + ".AAAA"
+	if _, err := p.verifyIDToken(context.Background(), tampered, "n1", now); err == nil {
+		t.Error("tampered signature accepted")
+	}
+}
+
+func TestJWKECKey(t *testing.T) {
+	t.Parallel()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := key.PublicKey.Bytes() // 0x04 || x || y
+	pub, err := jwk{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(raw[1:33]),
+		Y:   base64.RawURLEncoding.EncodeToString(raw[33:]),
+	}.publicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.PublicKey.Equal(pub) {
+		t.Error("decoded key differs")
+	}
+	if pub, _ := (jwk{Kty: "RSA", Use: "enc"}).publicKey(); pub != nil {
+		t.Error("encryption key used for signatures")
+	}
+}
+
+func TestPendingLoginsBounded(t *testing.T) {
+	p := newOIDCProvider("https://sso.example.com", "podfather", "s3cret", "https://podfather.example.com/auth/callback")
+	now := time.Now()
+	first, _ := p.startLogin("/", now)
+	for i := range maxPendingLogins {
+		p.startLogin("/", now.Add(time.Duration(i+1)*time.Millisecond))
+	}
+	if len(p.pending) != maxPendingLogins {
+		t.Errorf("%d pending logins, want %d", len(p.pending), maxPendingLogins)
+	}
+	if _, ok := p.finishLogin(first, now); ok {
+		t.Error("oldest login was kept")
+	}
+	last, _ := p.startLogin("/next", now.Add(time.Second))
+	if ls, ok := p.finishLogin(last, now.Add(time.Second)); !ok || ls.next != "/next" {
+		t.Errorf("newest login = %+v, %v", ls, ok)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                    "/",
+		"/containers?q=web":   "/containers?q=web",
+		"//evil.example.com":  "/",
+		"/\\evil.example.com": "/",
+		"https://evil.com/":   "/",
+		"/\t/x":               "/",
+		"/a\\b":               "/",
+		"/\x7f/evil.com":      "/",
+	} {
+		if got := localPath(in); got != want {
+			t.Errorf("localPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+	// next arrives query-decoded.
+	for _, next := range []string{"/%09/evil.com", "/%5Cevil.com", "/%0d%0a/evil.com"} {
+		q, _ := url.ParseQuery("next=" + next)
+		if got := localPath(q.Get("next")); got != "/" {
+			t.Errorf("localPath(%q) = %q, want /", q.Get("next"), got)
+		}
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	idp := newFakeIdP(t)
+	defer idp.Close()
+
+	s := newTestServer(t, mock)
+	app := httptest.NewUnstartedServer(nil)
+	app.Start()
+	defer app.Close()
+	s.oidc = newOIDCProvider(idp.URL, "podfather", "s3cret", app.URL+"/auth/callback")
+	app.Config.Handler = s.requireLogin(s.csrfProtect(s.newMux("podman")))
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	// Pages redirect to the login page, which links to the provider.
+	resp, err := client.Get(app.URL + "/containers?state=running")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Request.URL.Path; got != "/login" {
+		t.Fatalf("landed on %s, want /login", got)
+	}
+	if got := resp.Request.URL.Query().Get("next"); got != "/containers?state=running" {
+		t.Errorf("next = %q", got)
+	}
+
+	// Following the flow through the provider ends on the original page.
+	resp, err = client.Get(app.URL + "/auth/start?next=" + url.QueryEscape("/containers?state=running"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	body := string(b)
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.Path != "/containers" {
+		t.Fatalf("landed on %s with %d", resp.Request.URL, resp.StatusCode)
+	}
+	if !strings.Contains(body, "alice") || !strings.Contains(body, "Sign out") {
+		t.Error("page does not show the user")
+	}
+
+	// A callback without the state cookie is rejected.
+	replay := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = replay.Get(app.URL + "/auth/callback?code=x&state=y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if loc := resp.Header.Get("Location"); loc != "/login?failed=1" {
+		t.Errorf("forged callback redirected to %q", loc)
+	}
+
+	// Logging out ends the session.
+	var csrf string
+	for _, c := range jar.Cookies(mustParseURL(t, app.URL)) {
+		if c.Name == csrfCookieName {
+			csrf = c.Value
+		}
+	}
+	resp, err = client.PostForm(app.URL+"/logout", url.Values{csrfFormField: {csrf}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	noFollow := &http.Client{Jar: jar, CheckRedirect: replay.CheckRedirect}
+	resp, err = noFollow.Get(app.URL + "/containers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("request without session: %d", resp.StatusCode)
+	}
+	resp, err = replay.Post(app.URL+"/container/abc/stop", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// The session is checked before the CSRF token and the body is parsed.
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("POST without session: %d", resp.StatusCode)
+	}
+}
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The overview page combines data from several API endpoints. They are
+// fetched concurrently and each section fails on its own, so a slow or
+// broken endpoint only blanks its part of the page.
+
+const (
+	overviewTimeout   = 10 * time.Second
+	overviewEventsAge = 24 * time.Hour
+	overviewMaxEvents = 20
+)
+
+// HostInfo is the host part of the info endpoint.
+type HostInfo struct {
+	Hostname string
+	OS       string
+	Kernel   string
+	CPUs     int
+	MemTotal int64
+	Version  string // Podman or Docker version
+}
+
+// OverviewEvent is a recent container event.
+type OverviewEvent struct {
+	Time      time.Time
+	Container string
+	Action    string
+}
+
+// Overview is the data of the overview page. Errs maps a section
+// ("containers", "images", "disk", "host", "events") to a message shown
+// instead of it.
+type Overview struct {
+	States     map[string]int // containers by state
+	Containers int
+	Unhealthy  []Container // containers failing their healthcheck
+	// Updates are the containers whose image has an update, if
+	// UpdateChecks is set.
+	Updates      []Container
+	UpdateChecks bool
+	Images       int
+	ImagesSize   int64
+	Dangling     int
+	Unused       int
+	Disk         []DFSummary
+	Host         HostInfo
+	Thermal      []ThermalZone
+	Events       []OverviewEvent
+	Errs         map[string]string
+}
+
+// loadOverview fetches all sections concurrently. Errors are logged with
+// the request ID and turned into "unavailable" messages.
+func (s *Server) loadOverview(ctx context.Context) Overview {
+	ctx, cancel := context.WithTimeout(ctx, overviewTimeout)
+	defer cancel()
+
+	var (
+		wg         sync.WaitGroup
+		containers []Container
+		images     []ImageSummary
+		df         SystemDF
+		host       HostInfo
+		events     []OverviewEvent
+		errs       = map[string]error{}
+		errsMu     sync.Mutex
+	)
+	fetch := func(section string, fn func() error) {
+		wg.Go(func() {
+			if err := fn(); err != nil {
+				errsMu.Lock()
+				errs[section] = err
+				errsMu.Unlock()
+			}
+		})
+	}
+	fetch("containers", func() (err error) {
+		containers, err = s.listContainers(ctx)
+		return err
+	})
+	fetch("images", func() (err error) {
+		images, err = s.listImages(ctx)
+		return err
+	})
+	if s.backend != backendDocker {
+		fetch("disk", func() error { return s.podmanGet(ctx, "/system/df", &df) })
+	}
+	fetch("host", func() (err error) {
+		host, err = s.hostInfo(ctx)
+		return err
+	})
+	fetch("events", func() (err error) {
+		events, err = s.recentEvents(ctx, time.Now().Add(-overviewEventsAge))
+		return err
+	})
+	wg.Wait()
+
+	o := Overview{States: map[string]int{}, Errs: map[string]string{}}
+	for section, err := range errs {
+		log.Printf("[%s] overview %s: %v", reqID(ctx), section, err)
+		o.Errs[section] = section + " unavailable"
+	}
+	if s.backend == backendDocker {
+		o.Errs["disk"] = "disk usage is not supported with the Docker backend"
+	}
+
+	updates := s.updates.available()
+	for _, c := range containers {
+		o.States[c.State]++
+		if containerHealth(c) == "unhealthy" {
+			o.Unhealthy = append(o.Unhealthy, c)
+		}
+		if updates[c.Image] {
+			o.Updates = append(o.Updates, c)
+		}
+	}
+	o.UpdateChecks = s.updates != nil
+	o.Containers = len(containers)
+	if errs["images"] == nil {
+		// Without containers, usage is unknown, so nothing counts as unused.
+		rows := imageRows(images, containers)
+		o.Images = len(rows)
+		for _, row := range rows {
+			o.ImagesSize += row.Size
+			if row.Dangling {
+				o.Dangling++
+			}
+			if row.Unused && errs["containers"] == nil {
+				o.Unused++
+			}
+		}
+	}
+	if errs["disk"] == nil && s.backend != backendDocker {
+		o.Disk = summarizeDF(df)
+	}
+	o.Host = host
+	thermal, err := readThermalZones(s.thermalDir)
+	if err != nil {
+		log.Printf("[%s] overview thermal: %v", reqID(ctx), err)
+	}
+	o.Thermal = thermal
+	o.Events = events
+	return o
+}
+
+// hostInfo returns host information from the libpod or compat info
+// endpoint.
+func (s *Server) hostInfo(ctx context.Context) (HostInfo, error) {
+	var info struct {
+		// libpod
+		Host struct {
+			Hostname     string `json:"hostname"`
+			Distribution struct {
+				Distribution string `json:"distribution"`
+				Version      string `json:"version"`
+			} `json:"distribution"`
+			Kernel   string `json:"kernel"`
+			CPUs     int    `json:"cpus"`
+			MemTotal int64  `json:"memTotal"`
+		} `json:"host"`
+		Version struct {
+			Version string `json:"Version"`
+		} `json:"version"`
+		// Docker
+		Name            string `json:"Name"`
+		OperatingSystem string `json:"OperatingSystem"`
+		KernelVersion   string `json:"KernelVersion"`
+		NCPU            int    `json:"NCPU"`
+		MemTotalDocker  int64  `json:"MemTotal"`
+		ServerVersion   string `json:"ServerVersion"`
+	}
+	if err := s.podmanGet(ctx, "/info", &info); err != nil {
+		return HostInfo{}, err
+	}
+	if s.backend == backendDocker {
+		return HostInfo{
+			Hostname: info.Name,
+			OS:       info.OperatingSystem,
+			Kernel:   info.KernelVersion,
+			CPUs:     info.NCPU,
+			MemTotal: info.MemTotalDocker,
+			Version:  "Docker " + info.ServerVersion,
+		}, nil
+	}
+	h := info.Host
+	osName := h.Distribution.Distribution
+	if h.Distribution.Version != "" {
+		osName += " " + h.Distribution.Version
+	}
+	return HostInfo{
+		Hostname: h.Hostname,
+		OS:       osName,
+		Kernel:   h.Kernel,
+		CPUs:     h.CPUs,
+		MemTotal: h.MemTotal,
+		Version:  "Podman " + info.Version.Version,
+	}, nil
+}
+
+// recentEvents returns the container lifecycle events since the given
+// time, newest first, at most overviewMaxEvents.
+func (s *Server) recentEvents(ctx context.Context, since time.Time) ([]OverviewEvent, error) {
+	filters, _ := json.Marshal(map[string][]string{
+		"type":  {"container"},
+		"event": {"create", "start", "stop", "die", "died", "oom", "remove"},
+	})
+	q := url.Values{
+		"stream":  {"false"},
+		"since":   {strconv.FormatInt(since.Unix(), 10)},
+		"until":   {strconv.FormatInt(time.Now().Unix(), 10)},
+		"filters": {string(filters)},
+	}
+	resp, err := s.podmanDo(ctx, http.MethodGet, "/events?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out []OverviewEvent
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev containerEvent
+		if err := dec.Decode(&ev); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		name := ev.Actor.Attributes["name"]
+		if name == "" {
+			name = shortID(ev.Actor.ID)
+		}
+		out = append(out, OverviewEvent{Time: time.Unix(0, ev.TimeNano), Container: name, Action: ev.Action})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.After(out[j].Time) })
+	return out[:min(len(out), overviewMaxEvents)], nil
+}
+
+func (s *Server) handleOverview(w http.ResponseWriter, r *http.Request) {
+	o := s.loadOverview(r.Context())
+	s.render(w, r, "overview.html", map[string]any{
+		"Title":    "Overview",
+		"Overview": o,
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOverviewPartialFailure(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	app := httptest.NewServer(newTestServer(t, mock).newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/overview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	page := string(body)
+	// The mock API has no info endpoint; the other sections must still
+	// render.
+	for _, want := range []string{"host unavailable", "badge-running", "Dangling", "Reclaimable", "crashy", "died"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("overview does not contain %q", want)
+		}
+	}
+	for _, unwanted := range []string{"containers unavailable", "images unavailable", "events unavailable"} {
+		if strings.Contains(page, unwanted) {
+			t.Errorf("overview contains %q", unwanted)
+		}
+	}
+}
+
+func TestOverviewAPIDown(t *testing.T) {
+	t.Parallel()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer api.Close()
+	s := newTestServer(t, api)
+
+	o := s.loadOverview(t.Context())
+	for _, section := range []string{"containers", "images", "disk", "host", "events"} {
+		if o.Errs[section] != section+" unavailable" {
+			t.Errorf("Errs[%s] = %q", section, o.Errs[section])
+		}
+	}
+}
+
+func TestOverviewHealthAndUpdates(t *testing.T) {
+	t.Parallel()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/containers/json") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode([]Container{
+			{ID: "aaa", Names: []string{"web"}, Image: "nginx:1", State: "running", Status: "healthy"},
+			{ID: "bbb", Names: []string{"db"}, Image: "postgres:16", State: "running", Status: "unhealthy"},
+			{ID: "ccc", Names: []string{"cron"}, Image: "nginx:1", State: "exited"},
+		})
+	}))
+	defer api.Close()
+	s := newTestServer(t, api)
+
+	o := s.loadOverview(t.Context())
+	if len(o.Unhealthy) != 1 || o.Unhealthy[0].ID != "bbb" || o.UpdateChecks || o.Updates != nil {
+		t.Errorf("without update checks: unhealthy %v, updates %v (%v)", o.Unhealthy, o.Updates, o.UpdateChecks)
+	}
+
+	s.updates = newUpdateChecker(s, time.Hour)
+	s.updates.results = map[string]UpdateStatus{"nginx:1": {Available: true}, "postgres:16": {}}
+	o = s.loadOverview(t.Context())
+	if !o.UpdateChecks || len(o.Updates) != 2 || o.Updates[0].ID != "aaa" || o.Updates[1].ID != "ccc" {
+		t.Errorf("updates = %v", o.Updates)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"slices"
+)
+
+// Ownership labels record who is responsible for a container on shared
+// hosts. They are shown on the containers list, which can be filtered by
+// them. They do not restrict actions, even with OIDC login: whoever creates
+// a container sets its labels and could claim any team, so they are no
+// access boundary.
+const (
+	ownerLabel = "ch.jo-m.go.podfather.owner"
+	teamLabel  = "ch.jo-m.go.podfather.team"
+)
+
+// Owner returns the value of the owner label.
+func (c Container) Owner() string { return c.Labels[ownerLabel] }
+
+// Team returns the value of the team label.
+func (c Container) Team() string { return c.Labels[teamLabel] }
+
+// Owner returns the value of the owner label.
+func (c ContainerInspect) Owner() string { return c.Config.Labels[ownerLabel] }
+
+// Team returns the value of the team label.
+func (c ContainerInspect) Team() string { return c.Config.Labels[teamLabel] }
+
+// filterOwnership returns the containers with the given team and owner.
+// Empty values match all containers, "-" matches containers without the
+// label.
+func filterOwnership(list []Container, team, owner string) []Container {
+	match := func(want, got string) bool {
+		return want == "" || want == got || (want == "-" && got == "")
+	}
+	out := make([]Container, 0, len(list))
+	for _, c := range list {
+		if match(team, c.Team()) && match(owner, c.Owner()) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// ownershipValues returns the sorted distinct teams and owners of the
+// containers, for the filter form.
+func ownershipValues(list []Container) (teams, owners []string) {
+	for _, c := range list {
+		if t := c.Team(); t != "" && !slices.Contains(teams, t) {
+			teams = append(teams, t)
+		}
+		if o := c.Owner(); o != "" && !slices.Contains(owners, o) {
+			owners = append(owners, o)
+		}
+	}
+	slices.Sort(teams)
+	slices.Sort(owners)
+	return teams, owners
+}
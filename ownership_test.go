@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFilterOwnership(t *testing.T) {
+	list := []Container{
+		{ID: "a", Labels: map[string]string{teamLabel: "data", ownerLabel: "alice"}},
+		{ID: "b", Labels: map[string]string{teamLabel: "data", ownerLabel: "bob"}},
+		{ID: "c", Labels: map[string]string{teamLabel: "web"}},
+		{ID: "d"},
+	}
+	tests := []struct {
+		team, owner string
+		want        []string
+	}{
+		{"", "", []string{"a", "b", "c", "d"}},
+		{"data", "", []string{"a", "b"}},
+		{"data", "bob", []string{"b"}},
+		{"-", "", []string{"d"}},
+		{"", "-", []string{"c", "d"}},
+		{"ops", "", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, c := range filterOwnership(list, tt.team, tt.owner) {
+			got = append(got, c.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("filterOwnership(%q, %q) = %v, want %v", tt.team, tt.owner, got, tt.want)
+		}
+	}
+
+	teams, owners := ownershipValues(list)
+	if !slices.Equal(teams, []string{"data", "web"}) || !slices.Equal(owners, []string{"alice", "bob"}) {
+		t.Errorf("ownershipValues = %v, %v", teams, owners)
+	}
+}
+
+func TestContainersTeamFilter(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	app := httptest.NewServer(newTestServer(t, mock).newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/containers?team=nobody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "No containers found.") {
+		t.Error("filter by unknown team did not hide all containers")
+	}
+}
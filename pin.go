@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Digest pinning rewrites the Image= line of a Quadlet .container file to
+// the digest of the newly pulled tag (Image=repo:tag@sha256:...) and
+// restarts the unit, so the running image only changes on purpose. This is
+// the workflow `podman auto-update` cannot express. podfather only writes
+// .container files below QUADLET_DIR, and restores the old file if the
+// container does not come up healthy.
+
+// errPinUnsupported is returned for containers that cannot be pinned.
+var errPinUnsupported = errors.New("pinning requires a Quadlet-managed container whose .container file is in QUADLET_DIR, on the Podman backend")
+
+// quadletFile returns the .container file below dir that generates unit.
+func quadletFile(dir, unit string) (string, error) {
+	name, ok := strings.CutSuffix(unit, ".service")
+	if !ok || name == "" || strings.ContainsAny(name, "@/") {
+		return "", errPinUnsupported
+	}
+	var found string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && d.Name() == name+".container" {
+			found = p
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if found == "" {
+		return "", errPinUnsupported
+	}
+	return found, nil
+}
+
+// quadletImage returns the value of the Image= key in the [Container]
+// section and the index of its line.
+func quadletImage(content []byte) (image string, line int, ok bool) {
+	section := ""
+	for i, l := range strings.Split(string(content), "\n") {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "[") && strings.HasSuffix(l, "]") {
+			section = l
+			continue
+		}
+		key, value, found := strings.Cut(l, "=")
+		if found && section == "[Container]" && strings.TrimSpace(key) == "Image" {
+			image, line, ok = strings.TrimSpace(value), i, true
+		}
+	}
+	return image, line, ok
+}
+
+// setQuadletImage replaces the given line with Image=image.
+func setQuadletImage(content []byte, line int, image string) []byte {
+	lines := bytes.Split(content, []byte("\n"))
+	lines[line] = []byte("Image=" + image)
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// pinRefs returns the tag reference to pull for a Quadlet Image= value
+// (without a digest) and the repository of it.
+func pinRefs(image string) (pull, repo string, err error) {
+	pull, digest, _ := strings.Cut(image, "@")
+	if strings.HasSuffix(pull, ".image") || strings.HasSuffix(pull, ".build") {
+		return "", "", fmt.Errorf("Image=%s refers to a Quadlet unit, not a registry image", image)
+	}
+	repo, tag := splitImageRef(pull)
+	if digest != "" && repo == pull {
+		return "", "", fmt.Errorf("Image=%s has no tag to look for newer digests", image)
+	}
+	return repo + ":" + tag, repo, nil
+}
+
+// repoDigest returns the digest of img in repo. Short names like "nginx"
+// match their fully qualified form.
+func repoDigest(img ImageInspect, repo string) (string, bool) {
+	for _, d := range img.RepoDigests {
+		r, digest, ok := strings.Cut(d, "@")
+		if ok && (r == repo || strings.HasSuffix(r, "/"+repo)) {
+			return digest, true
+		}
+	}
+	return "", false
+}
+
+// startPin validates that the container can be pinned and starts a job for
+// it.
+func (s *Server) startPin(ctx context.Context, id string) (*job, error) {
+	if s.backend != backendPodman || s.quadletDir == "" {
+		return nil, errPinUnsupported
+	}
+	c, err := s.inspectContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	unit := s.containerUnit(c)
+	if unit == "" {
+		return nil, errPinUnsupported
+	}
+	file, err := quadletFile(s.quadletDir, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	j := s.jobs.start("Pin digest of " + c.Name)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+		defer cancel()
+		j.finish(s.pinDigest(ctx, j, c, unit, file))
+	}()
+	return j, nil
+}
+
+func (s *Server) pinDigest(ctx context.Context, j *job, c ContainerInspect, unit, file string) error {
+	old, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	image, line, ok := quadletImage(old)
+	if !ok {
+		return fmt.Errorf("%s has no Image= in [Container]", file)
+	}
+	pull, repo, err := pinRefs(image)
+	if err != nil {
+		return err
+	}
+	j.logf("==> Pulling %s", pull)
+	if err := s.pullImage(ctx, pull, j); err != nil {
+		return err
+	}
+	img, err := s.inspectImage(ctx, pull)
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", pull, err)
+	}
+	digest, ok := repoDigest(img, repo)
+	if !ok {
+		return fmt.Errorf("%s has no digest for %s", shortID(img.ID), repo)
+	}
+	pinned := pull + "@" + digest
+	if pinned == image {
+		j.logf("==> %s is already pinned to the latest digest", file)
+		return nil
+	}
+
+	j.logf("==> Setting Image=%s in %s (was %s)", pinned, file, image)
+	if err := writeFileKeepMode(file, setQuadletImage(old, line, pinned)); err != nil {
+		return err
+	}
+	err = s.reloadAndRestart(ctx, j, unit)
+	if err == nil {
+		j.logf("==> Waiting for %s to become healthy", c.Name)
+		err = s.waitHealthy(ctx, c.Name, img.ID)
+	}
+	if err == nil {
+		j.logf("==> Pinned %s to %s", c.Name, digest)
+		return nil
+	}
+
+	j.logf("==> Failed: %v", err)
+	j.logf("==> Restoring %s", file)
+	if rerr := writeFileKeepMode(file, old); rerr != nil {
+		return fmt.Errorf("pin failed (%v), restoring %s failed: %w", err, file, rerr)
+	}
+	if rerr := s.reloadAndRestart(ctx, j, unit); rerr != nil {
+		return fmt.Errorf("pin failed (%v), rollback failed: %w", err, rerr)
+	}
+	if rerr := s.waitHealthy(ctx, c.Name, c.Image); rerr != nil {
+		return fmt.Errorf("pin failed (%v), restored %s but container is not healthy: %w", err, file, rerr)
+	}
+	return fmt.Errorf("pin failed, restored Image=%s: %w", image, err)
+}
+
+// reloadAndRestart regenerates the Quadlet units and restarts unit.
+func (s *Server) reloadAndRestart(ctx context.Context, j *job, unit string) error {
+	j.logf("==> systemctl --user daemon-reload")
+	if err := runCommand(ctx, j, s.systemctlBin, "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	return s.restartUnit(ctx, j, unit)
+}
+
+// writeFileKeepMode replaces path atomically, keeping its permissions.
+func writeFileKeepMode(path string, data []byte) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	// Quadlet ignores files without a known extension, so the temporary
+	// file is never picked up as a unit.
+	return writeFileAtomic(path, data, st.Mode().Perm())
+}
+
+func (s *Server) handleContainerPin(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions || s.quadletDir == "" {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid container ID", http.StatusBadRequest)
+		return
+	}
+	j, err := s.startPin(r.Context(), id)
+	if err == nil || !errors.Is(err, errNotFound) {
+		// A failed pin restores the file by itself, there is nothing to undo.
+		s.actions.record(r, "pin digest", id, err, "", nil)
+	}
+	switch {
+	case errors.Is(err, errNotFound):
+		http.Error(w, "Container Not Found", http.StatusNotFound)
+		return
+	case errors.Is(err, errPinUnsupported):
+		http.Error(w, "Pinning requires a Quadlet-managed container with its .container file in QUADLET_DIR", http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("[%s] pin %s: %v", reqID(r.Context()), id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, s.basePath+"/job/"+j.ID, http.StatusSeeOther)
+}
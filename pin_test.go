@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testQuadlet = `[Unit]
+Description=Web
+
+[Container]
+ContainerName=web
+Image=ghcr.io/org/web:1.2@sha256:aaa
+Label=Image=not-this-one
+
+[Install]
+WantedBy=default.target
+`
+
+// newMockPinAPI mocks the libpod endpoints used by pinning. The container
+// runs the "new" image (digest sha256:bbb) while the Quadlet file is pinned
+// to it, like after a restart of the unit.
+func newMockPinAPI(t *testing.T, newHealth, file string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		p := strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod")
+		switch {
+		case r.Method == http.MethodPost && p == "/images/pull":
+			if r.URL.Query().Get("reference") != "ghcr.io/org/web:1.2" {
+				t.Errorf("pulled %q", r.URL.Query().Get("reference"))
+			}
+			w.Write([]byte(`{"stream":"Writing manifest to image destination\n"}`))
+		case p == "/images/ghcr.io/org/web:1.2/json":
+			w.Write([]byte(`{"Id":"new","RepoDigests":["ghcr.io/org/web@sha256:bbb"]}`))
+		case p == "/containers/web/json":
+			image, health := "old", "healthy"
+			if data, _ := os.ReadFile(file); strings.Contains(string(data), "sha256:bbb") {
+				image = "new"
+				health = newHealth
+			}
+			fmt.Fprintf(w, `{"Id":"c1","Name":"web","Image":%q,"ImageName":"ghcr.io/org/web:1.2@sha256:aaa",
+				"State":{"Status":"running","Running":true,"Health":{"Status":%q}},
+				"Config":{"Labels":{"PODMAN_SYSTEMD_UNIT":"web.service"}}}`, image, health)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func writeQuadlet(t *testing.T) (dir, file string) {
+	t.Helper()
+	dir = t.TempDir()
+	file = filepath.Join(dir, "apps", "web.container")
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(testQuadlet), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	return dir, file
+}
+
+func TestQuadletImage(t *testing.T) {
+	t.Parallel()
+	image, line, ok := quadletImage([]byte(testQuadlet))
+	if !ok || image != "ghcr.io/org/web:1.2@sha256:aaa" || line != 5 {
+		t.Fatalf("quadletImage = %q, %d, %v", image, line, ok)
+	}
+	out := string(setQuadletImage([]byte(testQuadlet), line, "ghcr.io/org/web:1.2@sha256:bbb"))
+	if want := strings.Replace(testQuadlet, "sha256:aaa", "sha256:bbb", 1); out != want {
+		t.Errorf("setQuadletImage:\n%s\nwant:\n%s", out, want)
+	}
+	if _, _, ok := quadletImage([]byte("[Unit]\nImage=x\n")); ok {
+		t.Error("Image= outside [Container] was used")
+	}
+}
+
+func TestPinRefs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		image, pull, repo string
+		wantErr           bool
+	}{
+		{"ghcr.io/org/web:1.2@sha256:aaa", "ghcr.io/org/web:1.2", "ghcr.io/org/web", false},
+		{"localhost:5000/app:v1", "localhost:5000/app:v1", "localhost:5000/app", false},
+		{"nginx", "nginx:latest", "nginx", false},
+		{"nginx@sha256:aaa", "", "", true},
+		{"web.image", "", "", true},
+	}
+	for _, tt := range tests {
+		pull, repo, err := pinRefs(tt.image)
+		if pull != tt.pull || repo != tt.repo || (err != nil) != tt.wantErr {
+			t.Errorf("pinRefs(%q) = %q, %q, %v", tt.image, pull, repo, err)
+		}
+	}
+	img := ImageInspect{RepoDigests: []string{"docker.io/library/redis@sha256:ccc", "docker.io/library/nginx@sha256:aaa"}}
+	if d, ok := repoDigest(img, "nginx"); !ok || d != "sha256:aaa" {
+		t.Errorf("repoDigest = %q, %v", d, ok)
+	}
+}
+
+func TestQuadletFile(t *testing.T) {
+	t.Parallel()
+	dir, file := writeQuadlet(t)
+	if got, err := quadletFile(dir, "web.service"); err != nil || got != file {
+		t.Errorf("quadletFile = %q, %v", got, err)
+	}
+	for _, unit := range []string{"db.service", "web@1.service", "web.timer"} {
+		if _, err := quadletFile(dir, unit); err != errPinUnsupported {
+			t.Errorf("quadletFile(%q): err = %v, want errPinUnsupported", unit, err)
+		}
+	}
+}
+
+func TestPinDigest(t *testing.T) {
+	t.Parallel()
+	dir, file := writeQuadlet(t)
+	mock := newMockPinAPI(t, "healthy", file)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.backend = backendPodman
+	s.systemctlBin = "true"
+	s.quadletDir = dir
+
+	j, err := s.startPin(t.Context(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitJob(t, j)
+	out, _, errMsg := j.read(0)
+	if j.Status() != "done" {
+		t.Fatalf("status = %s (err %q), output:\n%s", j.Status(), errMsg, out)
+	}
+	data, _ := os.ReadFile(file)
+	if !strings.Contains(string(data), "\nImage=ghcr.io/org/web:1.2@sha256:bbb\n") {
+		t.Errorf("file not pinned:\n%s", data)
+	}
+	if st, _ := os.Stat(file); st.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want 0640", st.Mode().Perm())
+	}
+}
+
+func TestPinDigestRollback(t *testing.T) {
+	t.Parallel()
+	dir, file := writeQuadlet(t)
+	mock := newMockPinAPI(t, "unhealthy", file)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.backend = backendPodman
+	s.systemctlBin = "true"
+	s.quadletDir = dir
+
+	j, err := s.startPin(t.Context(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitJob(t, j)
+	out, _, errMsg := j.read(0)
+	if j.Status() != "failed" {
+		t.Fatalf("status = %s, want failed, output:\n%s", j.Status(), out)
+	}
+	if !strings.Contains(errMsg, "restored Image=ghcr.io/org/web:1.2@sha256:aaa") {
+		t.Errorf("err = %q, want restore message", errMsg)
+	}
+	if data, _ := os.ReadFile(file); string(data) != testQuadlet {
+		t.Errorf("file not restored:\n%s", data)
+	}
+}
@@ -6,19 +6,57 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
 	"time"
 )
 
 // errNotFound is returned when the Podman API responds with 404.
 var errNotFound = errors.New("not found")
 
-func socketPath() string {
+// Supported API backends. Podman is the native backend; Docker is supported
+// via the Docker Engine compat API, which Podman also implements.
+const (
+	backendPodman = "podman"
+	backendDocker = "docker"
+)
+
+const (
+	apiHost       = "http://d"
+	podmanAPIPath = "/v4.0.0/libpod"
+	dockerAPIPath = "/v1.41"
+)
+
+const dockerSocket = "/var/run/docker.sock"
+
+func socketPath(backend string) string {
 	if s := os.Getenv("PODMAN_SOCKET"); s != "" {
 		return s
 	}
+	if backend == backendDocker {
+		return dockerSocket
+	}
+	sock := userPodmanSocket()
+	if backend == "" {
+		// Auto-detect: fall back to the Docker socket if there is no
+		// Podman socket but a Docker one.
+		if _, err := os.Stat(sock); err != nil {
+			if _, err := os.Stat(dockerSocket); err == nil {
+				return dockerSocket
+			}
+		}
+	}
+	return sock
+}
+
+// userPodmanSocket returns the socket of the rootless Podman API service
+// (podman.socket in the systemd user instance).
+func userPodmanSocket() string {
 	xdg := os.Getenv("XDG_RUNTIME_DIR")
 	if xdg == "" {
 		xdg = fmt.Sprintf("/run/user/%d", os.Getuid())
@@ -38,21 +76,266 @@ func newPodmanClient(sock string) *http.Client {
 	}
 }
 
-func (s *Server) podmanGet(path string, result any) error {
-	resp, err := s.podmanClient.Get(s.podmanBaseURL + path)
+// detectBackend probes the libpod ping endpoint, which only Podman serves.
+// Anything else answering the compat ping endpoint is treated as Docker.
+func detectBackend(client *http.Client, host string) string {
+	ping := func(path string) bool {
+		resp, err := client.Get(host + path)
+		if err != nil {
+			return false
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}
+	if ping(podmanAPIPath + "/_ping") {
+		return backendPodman
+	}
+	if ping("/_ping") {
+		return backendDocker
+	}
+	log.Printf("could not detect API backend, assuming %s", backendPodman)
+	return backendPodman
+}
+
+// apiPath returns the URL path prefix for the given backend.
+func apiPath(backend string) string {
+	if backend == backendDocker {
+		return dockerAPIPath
+	}
+	return podmanAPIPath
+}
+
+// podmanGet decodes the JSON response of a GET request into result. The
+// call is bounded by the deadline of ctx, or apiTimeout if it has none.
+// Responses are served from the API cache while fresh.
+func (s *Server) podmanGet(ctx context.Context, path string, result any) error {
+	if data, ok := s.apiCache.get(path); ok {
+		return json.Unmarshal(data, result)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, apiTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.podmanBaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	client := &http.Client{Transport: s.podmanClient.Transport}
+	resp, err := client.Do(req)
 	if err != nil {
+		// Calls that ran into the deadline are the slowest of all.
+		s.slowCalls.observe(ctx, path, time.Since(start), 0)
 		return fmt.Errorf("podman API: %w", err)
 	}
 	defer resp.Body.Close()
+	body := &countingReader{r: resp.Body}
+	defer func() { s.slowCalls.observe(ctx, path, time.Since(start), body.n) }()
 	if resp.StatusCode == http.StatusNotFound {
-		io.Copy(io.Discard, resp.Body)
+		io.Copy(io.Discard, body)
 		return errNotFound
 	}
 	if resp.StatusCode != http.StatusOK {
-		io.Copy(io.Discard, resp.Body)
+		io.Copy(io.Discard, body)
 		return fmt.Errorf("podman API %s: %s", path, resp.Status)
 	}
-	err = json.NewDecoder(resp.Body).Decode(result)
+	if s.apiCache == nil {
+		err = json.NewDecoder(body).Decode(result)
+		io.Copy(io.Discard, body)
+		return err
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("podman API: %w", err)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return err
+	}
+	s.apiCache.put(path, data)
+	return nil
+}
+
+// podmanDo sends a request to the Podman API and returns the response for
+// the caller to consume. Unlike podmanGet it does not apply the client
+// timeout, so long-running calls (e.g. pulls) must be bounded via ctx. Calls
+// other than GET drop the caches.
+func (s *Server) podmanDo(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	if method != http.MethodGet {
+		defer s.dropCaches()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.podmanBaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: s.podmanClient.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("podman API: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, errNotFound
+	}
+	if resp.StatusCode >= 300 {
+		var msg struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&msg)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if msg.Message != "" {
+			return nil, fmt.Errorf("podman API %s %s: %s: %s", method, path, resp.Status, msg.Message)
+		}
+		return nil, fmt.Errorf("podman API %s %s: %s", method, path, resp.Status)
+	}
+	return resp, nil
+}
+
+// podmanPost sends a POST request without body to the Podman API and
+// discards the response.
+func (s *Server) podmanPost(ctx context.Context, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	resp, err := s.podmanDo(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return err
+	}
 	io.Copy(io.Discard, resp.Body)
-	return err
+	resp.Body.Close()
+	return nil
+}
+
+// pullImage pulls an image by reference, writing progress lines to out.
+// Both the libpod and the compat API stream JSON objects; an "error" field
+// in any of them means the pull failed.
+func (s *Server) pullImage(ctx context.Context, ref string, out io.Writer) error {
+	path := "/images/pull?reference=" + url.QueryEscape(ref)
+	if s.backend == backendDocker {
+		path = "/images/create?fromImage=" + url.QueryEscape(ref)
+	}
+	resp, err := s.podmanDo(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// The pull can move the tag away from a cached image.
+	defer s.images.invalidate()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Stream string `json:"stream"`
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("pull %s: %w", ref, err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("pull %s: %s", ref, msg.Error)
+		}
+		if line := strings.TrimSpace(msg.Stream + msg.Status); line != "" {
+			fmt.Fprintln(out, line)
+		}
+	}
+}
+
+// listContainers returns all containers, from the poller snapshot if there
+// is one.
+func (s *Server) listContainers(ctx context.Context) ([]Container, error) {
+	if list, ok := s.poller.containers(); ok {
+		return list, nil
+	}
+	return s.fetchContainers(ctx)
+}
+
+// listImages returns all images, from the poller snapshot if there is one.
+func (s *Server) listImages(ctx context.Context) ([]ImageSummary, error) {
+	if list, ok := s.poller.images(); ok {
+		return list, nil
+	}
+	var list []ImageSummary
+	err := s.podmanGet(ctx, "/images/json", &list)
+	return list, err
+}
+
+// fetchContainers asks the API for all containers, normalizing Docker compat
+// API responses to the libpod shape.
+func (s *Server) fetchContainers(ctx context.Context) ([]Container, error) {
+	if s.backend != backendDocker {
+		var list []Container
+		err := s.podmanGet(ctx, "/containers/json?all=true", &list)
+		return list, err
+	}
+	var list []dockerContainer
+	if err := s.podmanGet(ctx, "/containers/json?all=true", &list); err != nil {
+		return nil, err
+	}
+	out := make([]Container, len(list))
+	for i := range list {
+		out[i] = list[i].toContainer()
+	}
+	return out, nil
+}
+
+// inspectContainer returns the inspect data for a container, normalizing
+// Docker compat API responses to the libpod shape.
+func (s *Server) inspectContainer(ctx context.Context, id string) (ContainerInspect, error) {
+	var c ContainerInspect
+	if err := s.podmanGet(ctx, "/containers/"+id+"/json", &c); err != nil {
+		return c, err
+	}
+	if s.backend == backendDocker {
+		c.Name = strings.TrimPrefix(c.Name, "/")
+		if c.ImageName == "" {
+			c.ImageName = c.Config.Image
+		}
+	}
+	return c, nil
+}
+
+func (c *dockerContainer) toContainer() Container {
+	names := make([]string, len(c.Names))
+	for i, n := range c.Names {
+		names[i] = strings.TrimPrefix(n, "/")
+	}
+	var cmd []string
+	if c.Command != "" {
+		cmd = []string{c.Command}
+	}
+	ports := make([]Port, 0, len(c.Ports))
+	for _, p := range c.Ports {
+		hostIP := p.IP
+		if p.PublicPort == 0 {
+			hostIP = ""
+		}
+		ports = append(ports, Port{
+			HostIP:        hostIP,
+			HostPort:      p.PublicPort,
+			ContainerPort: p.PrivatePort,
+			Protocol:      p.Type,
+		})
+	}
+	networks := make([]string, 0, len(c.NetworkSettings.Networks))
+	for n := range c.NetworkSettings.Networks {
+		networks = append(networks, n)
+	}
+	slices.Sort(networks)
+	return Container{
+		ID:       c.ID,
+		Names:    names,
+		Image:    c.Image,
+		ImageID:  c.ImageID,
+		Command:  cmd,
+		Created:  time.Unix(c.Created, 0),
+		State:    c.State,
+		Status:   c.Status,
+		Ports:    ports,
+		Labels:   c.Labels,
+		Networks: networks,
+	}
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// The image policy lists labels every image must carry, e.g. the OCI
+// provenance labels of internally built images:
+//
+//	IMAGE_POLICY_LABELS=source,version,licenses
+//	IMAGE_POLICY_REPOS=registry.example.com/team/
+//
+// Names without a dot are short for org.opencontainers.image.<name>. With
+// IMAGE_POLICY_REPOS set, only images with a tag starting with one of the
+// prefixes are checked.
+
+const ociLabelPrefix = "org.opencontainers.image."
+
+type imagePolicy struct {
+	Labels []string
+	Repos  []string
+}
+
+// parseImagePolicy parses the IMAGE_POLICY_* values.
+func parseImagePolicy(labels, repos string) imagePolicy {
+	var p imagePolicy
+	for l := range strings.SplitSeq(labels, ",") {
+		if l = strings.TrimSpace(l); l == "" {
+			continue
+		}
+		if !strings.Contains(l, ".") {
+			l = ociLabelPrefix + l
+		}
+		p.Labels = append(p.Labels, l)
+	}
+	for r := range strings.SplitSeq(repos, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			p.Repos = append(p.Repos, r)
+		}
+	}
+	return p
+}
+
+func (p imagePolicy) enabled() bool { return len(p.Labels) > 0 }
+
+// applies reports whether img is subject to the policy.
+func (p imagePolicy) applies(img ImageSummary) bool {
+	if len(p.Repos) == 0 {
+		return true
+	}
+	for _, tag := range img.RepoTags {
+		for _, repo := range p.Repos {
+			if strings.HasPrefix(tag, repo) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// missing returns the required labels img does not have or has empty.
+func (p imagePolicy) missing(img ImageSummary) []string {
+	if !p.applies(img) {
+		return nil
+	}
+	var out []string
+	for _, l := range p.Labels {
+		if strings.TrimSpace(img.Labels[l]) == "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// checkImagePolicy returns the number of images checked and the images
+// missing required labels (with Missing set), sorted by tag.
+func checkImagePolicy(p imagePolicy, rows []ImageRow) (checked int, out []ImageRow) {
+	for _, row := range rows {
+		if !p.applies(row.ImageSummary) {
+			continue
+		}
+		checked++
+		if row.Missing = p.missing(row.ImageSummary); len(row.Missing) > 0 {
+			out = append(out, row)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return imageLabel(out[i].ImageSummary) < imageLabel(out[j].ImageSummary) })
+	return checked, out
+}
+
+func (s *Server) handleImagePolicy(w http.ResponseWriter, r *http.Request) {
+	if !s.imagePolicy.enabled() {
+		s.render(w, r, "policy.html", map[string]any{
+			"Title":    "Image Policy",
+			"Disabled": true,
+		})
+		return
+	}
+	rows, err := s.imageRows(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	checked, violations := checkImagePolicy(s.imagePolicy, rows)
+	s.render(w, r, "policy.html", map[string]any{
+		"Title":      "Image Policy",
+		"Policy":     s.imagePolicy,
+		"Checked":    checked,
+		"Violations": violations,
+	})
+}
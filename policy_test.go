@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseImagePolicy(t *testing.T) {
+	p := parseImagePolicy(" source, version ,,com.example.team", "registry.example.com/, ghcr.io/org/")
+	wantLabels := []string{"org.opencontainers.image.source", "org.opencontainers.image.version", "com.example.team"}
+	if !slices.Equal(p.Labels, wantLabels) {
+		t.Errorf("Labels = %v, want %v", p.Labels, wantLabels)
+	}
+	if !slices.Equal(p.Repos, []string{"registry.example.com/", "ghcr.io/org/"}) {
+		t.Errorf("Repos = %v", p.Repos)
+	}
+	if parseImagePolicy("", "").enabled() {
+		t.Error("empty policy is enabled")
+	}
+}
+
+func TestCheckImagePolicy(t *testing.T) {
+	var images []ImageSummary
+	if err := json.Unmarshal(loadTestFixture(t, "testdata/images.json"), &images); err != nil {
+		t.Fatal(err)
+	}
+	rows := imageRows(images, nil)
+
+	p := parseImagePolicy("source,version,licenses", "")
+	checked, violations := checkImagePolicy(p, rows)
+	if checked != len(images) {
+		t.Errorf("checked = %d, want %d", checked, len(images))
+	}
+	for _, v := range violations {
+		if strings.HasPrefix(imageLabel(v.ImageSummary), "ghcr.io/gchq/cyberchef") {
+			t.Errorf("cyberchef has all OCI labels but is flagged: %v", v.Missing)
+		}
+		if len(v.Missing) == 0 {
+			t.Errorf("%s flagged without missing labels", imageLabel(v.ImageSummary))
+		}
+	}
+
+	p = parseImagePolicy("com.example.owner", "ghcr.io/gchq/")
+	checked, violations = checkImagePolicy(p, rows)
+	if checked != 1 || len(violations) != 1 || !slices.Equal(violations[0].Missing, []string{"com.example.owner"}) {
+		t.Errorf("scoped policy: checked = %d, violations = %+v", checked, violations)
+	}
+}
+
+func TestImagePolicyPage(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	get := func(path string) string {
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: status = %d", path, resp.StatusCode)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	if page := get("/images/policy"); !strings.Contains(page, "No image policy configured") {
+		t.Error("disabled policy page missing hint")
+	}
+
+	s.imagePolicy = parseImagePolicy("com.example.owner", "")
+	if page := get("/images/policy"); !strings.Contains(page, "com.example.owner") || !strings.Contains(page, "cyberchef") {
+		t.Error("policy page does not list the violation")
+	}
+	if page := get("/images"); !strings.Contains(page, "non-compliant") {
+		t.Error("images page has no non-compliant badge")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// With POLL_INTERVAL, a background poller keeps snapshots of the container
+// and image lists, which listContainers and listImages return instead of
+// asking Podman on every request. Pages show how old the snapshot is and
+// offer to refresh it. Changes made through podfather invalidate it (see
+// dropCaches), so requests fall back to the API until the next poll, which
+// they trigger.
+
+// snapshot is the state read by the poller.
+type snapshot struct {
+	containers []Container
+	images     []ImageSummary
+	at         time.Time
+}
+
+// statePoller refreshes the snapshot on an interval. It is safe to use a
+// nil *statePoller, which has no snapshot.
+type statePoller struct {
+	s        *Server
+	interval time.Duration
+	wake     chan struct{}
+
+	mu    sync.Mutex
+	snap  snapshot
+	valid bool
+	gen   int // incremented by invalidate, so polls running meanwhile are dropped
+}
+
+func newStatePoller(s *Server, interval time.Duration) *statePoller {
+	return &statePoller{s: s, interval: interval, wake: make(chan struct{}, 1)}
+}
+
+// run polls until ctx is done.
+func (p *statePoller) run(ctx context.Context) {
+	for {
+		if err := p.refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("poller: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-time.After(p.interval):
+		}
+	}
+}
+
+// refresh reads a new snapshot. On errors, the snapshot is invalidated so
+// requests report them.
+func (p *statePoller) refresh(ctx context.Context) error {
+	p.mu.Lock()
+	gen := p.gen
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	var (
+		snap       snapshot
+		wg         sync.WaitGroup
+		errC, errI error
+	)
+	wg.Go(func() { snap.containers, errC = p.s.fetchContainers(ctx) })
+	wg.Go(func() { errI = p.s.podmanGet(ctx, "/images/json", &snap.images) })
+	wg.Wait()
+	snap.at = time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if gen != p.gen {
+		return nil
+	}
+	if err := cmp.Or(errC, errI); err != nil {
+		p.valid = false
+		return err
+	}
+	p.snap, p.valid = snap, true
+	return nil
+}
+
+// invalidate drops the snapshot and polls again.
+func (p *statePoller) invalidate() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.valid = false
+	p.gen++
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// current returns the snapshot, if there is a valid one.
+func (p *statePoller) current() (snapshot, bool) {
+	if p == nil {
+		return snapshot{}, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.snap, p.valid
+}
+
+// containers returns a copy of the snapshot of the container list.
+func (p *statePoller) containers() ([]Container, bool) {
+	snap, ok := p.current()
+	return slices.Clone(snap.containers), ok
+}
+
+// images returns a copy of the snapshot of the image list.
+func (p *statePoller) images() ([]ImageSummary, bool) {
+	snap, ok := p.current()
+	return slices.Clone(snap.images), ok
+}
+
+// handleRefresh drops the snapshot, so the page it came from shows the
+// current state, and returns to it.
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if s.poller == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	s.dropCaches()
+	http.Redirect(w, r, s.returnPath(r), http.StatusSeeOther)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStatePoller(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	var fail atomic.Bool
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4.0.0/libpod/containers/json":
+			calls.Add(1)
+			w.Write([]byte(`[{"Id":"abc123","Names":["web"],"State":"running"}]`))
+		case "/v4.0.0/libpod/images/json":
+			if fail.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`[{"Id":"img1","RepoTags":["nginx:latest"]}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.poller = newStatePoller(s, time.Hour)
+	ctx := context.Background()
+
+	if err := s.poller.refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		list, err := s.listContainers(ctx)
+		if err != nil || len(list) != 1 {
+			t.Fatalf("listContainers = %v, %v", list, err)
+		}
+		list[0].State = "changed"
+	}
+	if images, err := s.listImages(ctx); err != nil || len(images) != 1 {
+		t.Errorf("listImages = %v, %v", images, err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("%d API calls, want only the poll", n)
+	}
+	if snap, _ := s.poller.current(); snap.containers[0].State != "running" {
+		t.Error("callers modified the snapshot")
+	}
+
+	s.dropCaches()
+	if _, ok := s.poller.current(); ok {
+		t.Error("snapshot valid after dropCaches")
+	}
+	s.listContainers(ctx)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("%d API calls, want 2 without snapshot", n)
+	}
+
+	fail.Store(true)
+	if err := s.poller.refresh(ctx); err == nil {
+		t.Error("failed poll returned no error")
+	}
+	if _, ok := s.poller.current(); ok {
+		t.Error("snapshot valid after a failed poll")
+	}
+}
+
+func TestRefreshButton(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.poller = newStatePoller(s, time.Hour)
+	if err := s.poller.refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/containers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "data as of") || !strings.Contains(string(body), `action="/refresh"`) ||
+		!strings.Contains(string(body), `name="return" value="/containers"`) {
+		t.Error("page lacks the snapshot age and refresh button")
+	}
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	req, _ := http.NewRequest(http.MethodPost, app.URL+"/refresh", strings.NewReader(url.Values{"return": {"/containers?state=running"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err = noRedirect.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if loc := resp.Header.Get("Location"); resp.StatusCode != http.StatusSeeOther || loc != "/containers?state=running" {
+		t.Errorf("refresh: %d to %q", resp.StatusCode, loc)
+	}
+	if _, ok := s.poller.current(); ok {
+		t.Error("snapshot valid after refresh")
+	}
+}
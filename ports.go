@@ -0,0 +1,90 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// /ports lists the published host ports of all containers, stopped ones
+// included, as they claim their ports again when started. It answers
+// "what is already on 8080?" before adding a service.
+
+// PublishedPort is a host port published by a container.
+type PublishedPort struct {
+	HostIP        string // "" for all addresses
+	HostPort      uint16
+	ContainerPort uint16
+	Protocol      string
+	Container     Container
+	// Conflict is set if another container publishes the same port and
+	// protocol on an overlapping address, so both cannot run at once.
+	Conflict bool
+}
+
+// publishedPorts returns the published ports of the containers, sorted by
+// host port, protocol and host IP.
+func publishedPorts(list []Container) []PublishedPort {
+	var out []PublishedPort
+	for _, c := range list {
+		for _, p := range c.Ports {
+			if p.HostPort == 0 {
+				continue
+			}
+			hostIP := p.HostIP
+			if hostIP == "0.0.0.0" || hostIP == "::" {
+				hostIP = ""
+			}
+			out = append(out, PublishedPort{
+				HostIP:        hostIP,
+				HostPort:      p.HostPort,
+				ContainerPort: p.ContainerPort,
+				Protocol:      cmp.Or(p.Protocol, "tcp"),
+				Container:     c,
+			})
+		}
+	}
+	slices.SortStableFunc(out, func(a, b PublishedPort) int {
+		return cmp.Or(cmp.Compare(a.HostPort, b.HostPort), strings.Compare(a.Protocol, b.Protocol), strings.Compare(a.HostIP, b.HostIP))
+	})
+	// Docker lists a port published on all addresses for IPv4 and IPv6.
+	out = slices.CompactFunc(out, func(a, b PublishedPort) bool {
+		return a.HostIP == b.HostIP && a.HostPort == b.HostPort && a.ContainerPort == b.ContainerPort && a.Protocol == b.Protocol && a.Container.ID == b.Container.ID
+	})
+	for i := range out {
+		for j := i + 1; j < len(out) && out[j].HostPort == out[i].HostPort; j++ {
+			a, b := out[i], out[j]
+			if a.Protocol == b.Protocol && a.Container.ID != b.Container.ID && (a.HostIP == "" || b.HostIP == "" || a.HostIP == b.HostIP) {
+				out[i].Conflict, out[j].Conflict = true, true
+			}
+		}
+	}
+	return out
+}
+
+func (s *Server) handlePorts(w http.ResponseWriter, r *http.Request) {
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	ports := publishedPorts(list)
+	port := strings.TrimSpace(r.URL.Query().Get("port"))
+	if port != "" {
+		n, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			http.Error(w, "Invalid port", http.StatusBadRequest)
+			return
+		}
+		ports = slices.DeleteFunc(ports, func(p PublishedPort) bool { return p.HostPort != uint16(n) })
+	}
+	s.render(w, r, "ports.html", map[string]any{
+		"Title": "Ports",
+		"Ports": ports,
+		"Port":  port,
+	})
+}
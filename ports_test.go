@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPublishedPorts(t *testing.T) {
+	t.Parallel()
+	list := []Container{
+		{ID: "web", Ports: []Port{
+			{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+			{HostIP: "::", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+			{ContainerPort: 9090, Protocol: "tcp"}, // not published
+		}},
+		{ID: "dns", Ports: []Port{
+			{HostIP: "127.0.0.1", HostPort: 53, ContainerPort: 53, Protocol: "udp"},
+			{HostIP: "127.0.0.1", HostPort: 53, ContainerPort: 53, Protocol: "tcp"},
+		}},
+		{ID: "old", State: "exited", Ports: []Port{{HostIP: "127.0.0.1", HostPort: 8080, ContainerPort: 8080}}},
+		{ID: "lan", Ports: []Port{{HostIP: "192.168.1.2", HostPort: 53, ContainerPort: 53, Protocol: "udp"}}},
+	}
+	got := publishedPorts(list)
+	want := []struct {
+		port     uint16
+		proto    string
+		ip       string
+		id       string
+		conflict bool
+	}{
+		{53, "tcp", "127.0.0.1", "dns", false},
+		{53, "udp", "127.0.0.1", "dns", false},
+		{53, "udp", "192.168.1.2", "lan", false},
+		{8080, "tcp", "", "web", true},
+		{8080, "tcp", "127.0.0.1", "old", true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("publishedPorts = %+v, want %d ports", got, len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.HostPort != w.port || g.Protocol != w.proto || g.HostIP != w.ip || g.Container.ID != w.id || g.Conflict != w.conflict {
+			t.Errorf("port %d = %d/%s on %q of %s (conflict %v), want %+v", i, g.HostPort, g.Protocol, g.HostIP, g.Container.ID, g.Conflict, w)
+		}
+	}
+}
+
+func TestHandlePorts(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	app := httptest.NewServer(newTestServer(t, mock).newMux("podman"))
+	defer app.Close()
+
+	get := func(query string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(app.URL + "/ports" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode, string(body)
+	}
+	code, body := get("")
+	if code != http.StatusOK || strings.Index(body, ">3000<") > strings.Index(body, ">8096<") || !strings.Contains(body, ">8443<") {
+		t.Errorf("ports page: status %d, ports not listed in order", code)
+	}
+	code, body = get("?port=8096")
+	if code != http.StatusOK || !strings.Contains(body, ">8096<") || strings.Contains(body, ">3000<") {
+		t.Errorf("filtered ports page: status %d", code)
+	}
+	if code, _ := get("?port=http"); code != http.StatusBadRequest {
+		t.Errorf("invalid port: status %d, want 400", code)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Privacy mode replaces container names, the hostname, IP addresses and
+// URLs in rendered pages with pseudonyms, so screenshots and screen shares
+// do not show internal naming and addressing. It is toggled per browser
+// (stored in a cookie) and applied to the finished HTML, so templates need
+// no changes. Link targets on this server are left alone to keep the UI
+// usable; external links are masked like any other URL.
+
+const (
+	privacyCookieName = "privacy"
+	privacyCookieAge  = 365 * 24 * 60 * 60
+)
+
+// privacyKey keys the pseudonyms. It is random per process: pseudonyms are
+// stable until podfather restarts, and cannot be reversed by hashing
+// guessed names.
+var privacyKey = func() []byte {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return b
+}()
+
+const (
+	urlPattern  = `https?://[^\s"'<>]+`
+	ipv4Pattern = `\b(?:\d{1,3}\.){3}\d{1,3}\b`
+	ipv6Pattern = `[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`
+)
+
+// maskedAttr matches the attributes whose values are masked in tags.
+var maskedAttr = regexp.MustCompile(`\s(title|alt|href)="([^"]*)"`)
+
+// masker replaces sensitive values with pseudonyms. A nil masker leaves
+// everything unchanged.
+type masker struct {
+	re *regexp.Regexp
+	// names maps the names to mask to their kind ("host" or "ctr").
+	names map[string]string
+}
+
+func newMasker(names map[string]string) *masker {
+	alts := []string{urlPattern, ipv4Pattern, ipv6Pattern}
+	// Longer names first, so "web-db" is not masked as "web" + "-db".
+	sorted := slices.SortedFunc(func(yield func(string) bool) {
+		for n := range names {
+			if n != "" && !yield(n) {
+				return
+			}
+		}
+	}, func(a, b string) int { return cmp.Or(len(b)-len(a), strings.Compare(a, b)) })
+	if len(sorted) > 0 {
+		quoted := make([]string, len(sorted))
+		for i, n := range sorted {
+			quoted[i] = regexp.QuoteMeta(n)
+		}
+		alts = append(alts, strings.Join(quoted, "|"))
+	}
+	return &masker{re: regexp.MustCompile("(" + strings.Join(alts, ")|(") + ")"), names: names}
+}
+
+// pseudonym returns the replacement for value. IP addresses stay valid
+// addresses from ranges reserved for benchmarks and documentation.
+func pseudonym(kind, value string) string {
+	h := hmac.New(sha256.New, privacyKey)
+	h.Write([]byte(kind + "\x00" + value))
+	sum := h.Sum(nil)
+	switch kind {
+	case "ipv4":
+		// 198.18.0.0/15
+		n := binary.BigEndian.Uint32(sum) % (1 << 17)
+		return netip.AddrFrom4([4]byte{198, 18 + byte(n>>16), byte(n >> 8), byte(n)}).String()
+	case "ipv6":
+		// 2001:db8::/32
+		addr := [16]byte{0x20, 0x01, 0x0d, 0xb8}
+		copy(addr[4:], sum)
+		return netip.AddrFrom16(addr).String()
+	case "url":
+		return "https://site-" + hex.EncodeToString(sum[:3]) + ".example/"
+	}
+	return kind + "-" + hex.EncodeToString(sum[:3])
+}
+
+// isNameChar reports whether c can be part of a container name or hostname
+// label. Dots are not, so "web.service" still masks "web".
+func isNameChar(c byte) bool {
+	return c == '-' || c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// mask replaces URLs, IP addresses and names in text. Loopback and
+// unspecified addresses are kept, they reveal nothing.
+func (m *masker) mask(text string) string {
+	if m == nil {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range m.re.FindAllStringSubmatchIndex(text, -1) {
+		match := text[loc[0]:loc[1]]
+		repl := match
+		switch {
+		case loc[2] >= 0:
+			repl = pseudonym("url", match)
+		case loc[4] >= 0 || loc[6] >= 0:
+			if a, err := netip.ParseAddr(match); err == nil && !a.IsLoopback() && !a.IsUnspecified() {
+				kind := "ipv6"
+				if a.Is4() {
+					kind = "ipv4"
+				}
+				repl = pseudonym(kind, match)
+			}
+		default:
+			if (loc[0] == 0 || !isNameChar(text[loc[0]-1])) && (loc[1] == len(text) || !isNameChar(text[loc[1]])) {
+				repl = pseudonym(m.names[match], match)
+			}
+		}
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(repl)
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// maskHTML masks the text and the title, alt and external href attributes
+// of an HTML page. The contents of style and script elements are kept.
+func (m *masker) maskHTML(page string) string {
+	if m == nil {
+		return page
+	}
+	var b strings.Builder
+	for len(page) > 0 {
+		i := strings.IndexByte(page, '<')
+		if i < 0 {
+			b.WriteString(m.mask(page))
+			break
+		}
+		b.WriteString(m.mask(page[:i]))
+		page = page[i:]
+		j := strings.IndexByte(page, '>')
+		if j < 0 {
+			b.WriteString(page)
+			break
+		}
+		tag := page[:j+1]
+		page = page[j+1:]
+		b.WriteString(maskedAttr.ReplaceAllStringFunc(tag, func(attr string) string {
+			sub := maskedAttr.FindStringSubmatch(attr)
+			if sub[1] == "href" && !strings.HasPrefix(sub[2], "http") {
+				return attr
+			}
+			return attr[:len(attr)-len(sub[2])-1] + m.mask(sub[2]) + `"`
+		}))
+		name, _, _ := strings.Cut(strings.TrimPrefix(tag[1:len(tag)-1], "/"), " ")
+		if (name == "style" || name == "script") && !strings.HasPrefix(tag, "</") {
+			end := strings.Index(page, "</"+name)
+			if end < 0 {
+				end = len(page)
+			}
+			b.WriteString(page[:end])
+			page = page[end:]
+		}
+	}
+	return b.String()
+}
+
+// privacyMode reports whether the request's browser has privacy mode on.
+func privacyMode(r *http.Request) bool {
+	c, err := r.Cookie(privacyCookieName)
+	return err == nil && c.Value == "on"
+}
+
+// pageMasker returns the masker for the request, or nil if privacy mode is
+// off. It masks the hostname and the names of all containers.
+func (s *Server) pageMasker(r *http.Request) *masker {
+	if !privacyMode(r) {
+		return nil
+	}
+	names := map[string]string{}
+	if s.hostname != "" {
+		names[s.hostname] = "host"
+		if short, _, ok := strings.Cut(s.hostname, "."); ok {
+			names[short] = "host"
+		}
+	}
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		// The page itself reports the error if it needs the list.
+		log.Printf("[%s] privacy mode: podman API error: %v", reqID(r.Context()), err)
+	}
+	for _, c := range list {
+		for _, n := range c.Names {
+			if n = strings.TrimPrefix(n, "/"); names[n] == "" {
+				names[n] = "ctr"
+			}
+		}
+	}
+	return newMasker(names)
+}
+
+// handlePrivacy turns privacy mode on or off for this browser and returns to
+// the page the toggle was clicked on.
+func (s *Server) handlePrivacy(w http.ResponseWriter, r *http.Request) {
+	on := r.FormValue("privacy") == "on"
+	cookie := &http.Cookie{
+		Name:     privacyCookieName,
+		Value:    "on",
+		Path:     s.basePath + "/",
+		MaxAge:   privacyCookieAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if !on {
+		cookie.Value = ""
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+	http.Redirect(w, r, s.returnPath(r), http.StatusSeeOther)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	t.Parallel()
+	m := newMasker(map[string]string{"web": "ctr", "web-db": "ctr", "nas.lan": "host"})
+	in := "web-db on nas.lan (10.0.0.5:8080, fd00::1) for https://git.lan/a?b=1 via web.service, webhooks at 127.0.0.1 and 12:30:45"
+	out := m.mask(in)
+	for _, leak := range []string{"web-db", "web.service", "nas.lan", "10.0.0.5", "fd00::1", "git.lan"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("%q leaks in %q", leak, out)
+		}
+	}
+	for _, want := range []string{
+		pseudonym("ctr", "web-db") + " on " + pseudonym("host", "nas.lan"),
+		pseudonym("ipv4", "10.0.0.5") + ":8080",
+		pseudonym("ctr", "web") + ".service",
+		"hooks at 127.0.0.1 and 12:30:45",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%q not in %q", want, out)
+		}
+	}
+	if again := m.mask(in); again != out {
+		t.Errorf("not deterministic: %q != %q", again, out)
+	}
+	if ip := pseudonym("ipv4", "10.0.0.5"); !strings.HasPrefix(ip, "198.1") {
+		t.Errorf("pseudonym ipv4 = %q", ip)
+	}
+	var nilMasker *masker
+	if got := nilMasker.mask(in); got != in {
+		t.Errorf("nil masker changed text: %q", got)
+	}
+}
+
+func TestMaskHTML(t *testing.T) {
+	t.Parallel()
+	m := newMasker(map[string]string{"web": "ctr"})
+	in := `<style>.web { color: red }</style><a href="/container/web" title="web">web</a> <a href="https://web.example.com/">site</a>`
+	want := `<style>.web { color: red }</style><a href="/container/web" title="` + pseudonym("ctr", "web") + `">` + pseudonym("ctr", "web") +
+		`</a> <a href="` + pseudonym("url", "https://web.example.com/") + `">site</a>`
+	if got := m.maskHTML(in); got != want {
+		t.Errorf("maskHTML:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPrivacyMode(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.hostname = "nas.lan"
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	get := func() string {
+		t.Helper()
+		resp, err := client.Get(app.URL + "/containers")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	toggle := func(value string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, app.URL+"/privacy", strings.NewReader(url.Values{"privacy": {value}, "return": {"/containers"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.Request.URL.Path != "/containers" {
+			t.Errorf("redirected to %s, want /containers", resp.Request.URL.Path)
+		}
+	}
+
+	if body := get(); !strings.Contains(body, ">jellyfin<") || !strings.Contains(body, "nas.lan") {
+		t.Fatal("container list without privacy mode lacks names")
+	} else if !strings.Contains(body, `name="return" value="/containers"`) {
+		t.Error("privacy toggle lacks the return path")
+	}
+	toggle("on")
+	body := get()
+	if strings.Contains(body, ">jellyfin<") || strings.Contains(body, "nas.lan") {
+		t.Error("privacy mode shows container names or the hostname")
+	}
+	if !strings.Contains(body, pseudonym("ctr", "jellyfin")) || !strings.Contains(body, "Privacy mode: on") {
+		t.Error("privacy mode page lacks pseudonyms or the toggle state")
+	}
+	toggle("")
+	if body := get(); !strings.Contains(body, ">jellyfin<") {
+		t.Error("names still masked after turning privacy mode off")
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The prober sends a HEAD request to the URL of every app, container-based
+// and external, every APP_PROBE_INTERVAL, so the dashboard shows whether a
+// service answers and not only whether its container runs. Redirects are
+// not followed, as any answer shows the service is serving. Changes are
+// recorded in the reachability history.
+
+const (
+	probeTimeout = 5 * time.Second
+	probeSlow    = time.Second // answers slower than this are amber
+)
+
+// ProbeResult is the outcome of the last probe of an app URL.
+type ProbeResult struct {
+	// State is "up" for an answer below 500 within probeSlow, "slow" for
+	// a slower one or an untrusted certificate, and "down" otherwise.
+	State   string
+	Status  int
+	Latency time.Duration
+	Err     string
+	Checked time.Time
+}
+
+// LatencyText returns the latency in milliseconds, or "" without answer.
+func (p ProbeResult) LatencyText() string {
+	if p.Status == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d ms", p.Latency.Milliseconds())
+}
+
+// Reason describes the result for the dot's tooltip.
+func (p ProbeResult) Reason() string {
+	if p.Err != "" {
+		return p.Err
+	}
+	return fmt.Sprintf("HTTP %d in %s, checked %s", p.Status, p.LatencyText(), p.Checked.Format(time.TimeOnly))
+}
+
+type prober struct {
+	s        *Server
+	interval time.Duration
+	client   *http.Client
+
+	history *reachHistory
+
+	mu      sync.Mutex
+	results map[string]ProbeResult // by URL
+}
+
+func newProber(s *Server, interval time.Duration) *prober {
+	return &prober{
+		s:        s,
+		interval: interval,
+		client: &http.Client{
+			Timeout: probeTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		results: map[string]ProbeResult{},
+		history: newReachHistory(filepath.Join(s.dataDir, "app-reachability.json")),
+	}
+}
+
+// all returns the last results by URL.
+func (p *prober) all() map[string]*ProbeResult {
+	out := map[string]*ProbeResult{}
+	if p == nil {
+		return out
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for u, r := range p.results {
+		out[u] = &r
+	}
+	return out
+}
+
+func (p *prober) run(ctx context.Context) {
+	for {
+		if err := p.probeAll(ctx); err != nil {
+			log.Printf("app probes: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.interval):
+		}
+	}
+}
+
+// probeAll probes the URLs of all apps and forgets those of removed apps.
+func (p *prober) probeAll(ctx context.Context) error {
+	list, err := p.s.listContainers(ctx)
+	if err != nil {
+		return err
+	}
+	var urls []string
+	seen := map[string]bool{}
+	appURLs := map[string]string{} // app name -> URL
+	for _, cat := range p.s.buildAppCategories(list) {
+		for _, app := range cat.Apps {
+			if app.URL == "" {
+				continue
+			}
+			appURLs[app.Name] = app.URL
+			if !seen[app.URL] {
+				seen[app.URL] = true
+				urls = append(urls, app.URL)
+			}
+		}
+	}
+	results := make([]ProbeResult, len(urls))
+	parallel(ctx, len(urls), func(ctx context.Context, i int) error {
+		results[i] = p.probe(ctx, urls[i])
+		return nil
+	})
+	p.mu.Lock()
+	p.results = make(map[string]ProbeResult, len(urls))
+	for i, u := range urls {
+		p.results[u] = results[i]
+	}
+	states := make(map[string]bool, len(appURLs))
+	for name, u := range appURLs {
+		states[name] = p.results[u].State != "down"
+	}
+	p.mu.Unlock()
+	return p.history.update(states, time.Now())
+}
+
+// reachability returns the reachability bars by app name.
+func (p *prober) reachability() map[string]*ReachBar {
+	if p == nil {
+		return map[string]*ReachBar{}
+	}
+	return p.history.bars(time.Now())
+}
+
+// probe sends a HEAD request to u, falling back to GET for servers that
+// do not implement HEAD.
+func (p *prober) probe(ctx context.Context, u string) ProbeResult {
+	res := ProbeResult{Checked: time.Now()}
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, u, nil)
+		if err != nil {
+			res.State, res.Err = "down", err.Error()
+			return res
+		}
+		start := time.Now()
+		resp, err := p.client.Do(req)
+		res.Latency = time.Since(start)
+		if err != nil {
+			res.State, res.Err = "down", err.Error()
+			var certErr *tls.CertificateVerificationError
+			if errors.As(err, &certErr) {
+				res.State, res.Err = "slow", "untrusted certificate: "+certErr.Err.Error()
+			}
+			return res
+		}
+		resp.Body.Close()
+		res.Status = resp.StatusCode
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+			break
+		}
+	}
+	switch {
+	case res.Status >= 500:
+		res.State = "down"
+	case res.Latency > probeSlow:
+		res.State = "slow"
+	default:
+		res.State = "up"
+	}
+	return res
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/nohead":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/login":
+			http.Redirect(w, r, "/broken", http.StatusFound)
+		}
+	}))
+	defer web.Close()
+	tlsWeb := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsWeb.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	p := newProber(&Server{dataDir: t.TempDir()}, time.Minute)
+	for _, tt := range []struct {
+		url, state string
+		status     int
+	}{
+		{web.URL + "/", "up", http.StatusOK},
+		{web.URL + "/broken", "down", http.StatusBadGateway},
+		{web.URL + "/nohead", "up", http.StatusOK},
+		{web.URL + "/login", "up", http.StatusFound}, // not followed
+		{tlsWeb.URL, "slow", 0},                      // self-signed
+		{closed.URL, "down", 0},
+	} {
+		res := p.probe(context.Background(), tt.url)
+		if res.State != tt.state || res.Status != tt.status {
+			t.Errorf("probe(%s) = %+v, want %s with status %d", tt.url, res, tt.state, tt.status)
+		}
+		if (res.Status == 0) != (res.Err != "") {
+			t.Errorf("probe(%s): err = %q with status %d", tt.url, res.Err, res.Status)
+		}
+	}
+}
+
+func TestProbeAll(t *testing.T) {
+	t.Parallel()
+	web := httptest.NewServer(http.NotFoundHandler())
+	defer web.Close()
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]Container{{ID: "abc123", Names: []string{"wiki"}, State: "running",
+			Labels: map[string]string{appLabelPrefix + "name": "Wiki", appLabelPrefix + "url": web.URL}}})
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.externalApps = []App{{Name: "Router", URL: web.URL}, {Name: "NAS"}}
+	s.dataDir = t.TempDir()
+	s.probes = newProber(s, time.Minute)
+	s.probes.results["http://removed.example.com"] = ProbeResult{State: "up"}
+	if err := s.probes.probeAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.probes.all(); len(got) != 1 || got[web.URL].Status != http.StatusNotFound || got[web.URL].State != "up" {
+		t.Errorf("results = %+v", got)
+	}
+
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+	resp, err := http.Get(app.URL + "/apps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if n := strings.Count(string(body), `<span class="probe `); n != 2 {
+		t.Errorf("apps page has %d probe dots, want 2", n)
+	}
+	if n := strings.Count(string(body), `<span class="probe probe-up" title="HTTP 404 in `); n != 2 {
+		t.Errorf("apps page has %d probe dots, want 2", n)
+	}
+	if n := strings.Count(string(body), `class="reach-bar"`); n != 2 {
+		t.Errorf("apps page has %d reachability bars, want 2", n)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The problems page lists leftovers that are easy to miss: containers whose
+// image was removed, volumes and networks no container uses, and systemd
+// units for containers that no longer exist. It only suggests commands to
+// fix them and never changes anything itself.
+
+const problemsTimeout = 10 * time.Second
+
+// defaultNetworks are created by Podman or Docker and never reported as
+// unused.
+var defaultNetworks = []string{"podman", "bridge", "host", "none"}
+
+// Problem is a finding on the problems page.
+type Problem struct {
+	Name   string
+	Link   string // path of the related page, if any
+	Detail string
+	Fix    string // suggested command
+}
+
+// Problems is the data of the problems page. Errs maps a section
+// ("images", "volumes", "networks", "units") to a message shown instead of
+// it.
+type Problems struct {
+	MissingImages  []Problem
+	UnusedVolumes  []Problem
+	UnusedNetworks []Problem
+	OrphanedUnits  []Problem
+	Errs           map[string]string
+}
+
+// Count returns the total number of problems.
+func (p Problems) Count() int {
+	return len(p.MissingImages) + len(p.UnusedVolumes) + len(p.UnusedNetworks) + len(p.OrphanedUnits)
+}
+
+// ProblemSection is a card on the problems page.
+type ProblemSection struct {
+	Heading string
+	Empty   string // shown when there are no problems
+	Err     string
+	Rows    []Problem
+}
+
+// Sections returns the sections in display order.
+func (p Problems) Sections() []ProblemSection {
+	return []ProblemSection{
+		{"Containers with missing images", "All containers have their image.", p.Errs["images"], p.MissingImages},
+		{"Unused volumes", "All volumes are used by a container.", p.Errs["volumes"], p.UnusedVolumes},
+		{"Unused networks", "All networks are used by a container.", p.Errs["networks"], p.UnusedNetworks},
+		{"Systemd units without containers", "All podman units have their container.", p.Errs["units"], p.OrphanedUnits},
+	}
+}
+
+// NetworkSummary is a network list entry. The libpod ("name") and compat
+// ("Name") spellings both decode into it.
+type NetworkSummary struct {
+	Name   string `json:"name"`
+	Driver string `json:"driver"`
+}
+
+// systemdUnit is a service unit running a podman command.
+type systemdUnit struct {
+	ID          string
+	ActiveState string
+	Container   string // container name from --name or podman start
+	Start       bool   // the unit starts an existing container (podman start)
+}
+
+// missingImageProblems returns the containers whose image does not exist.
+func missingImageProblems(cli string, containers []Container, images []ImageSummary) []Problem {
+	ids := map[string]bool{}
+	for _, img := range images {
+		ids[strings.TrimPrefix(img.ID, "sha256:")] = true
+	}
+	var out []Problem
+	for _, c := range containers {
+		if ids[strings.TrimPrefix(c.ImageID, "sha256:")] {
+			continue
+		}
+		name := firstName(c.Names)
+		out = append(out, Problem{
+			Name:   name,
+			Link:   "/container/" + c.ID,
+			Detail: fmt.Sprintf("image %s (%s) no longer exists, so the container cannot be recreated", c.Image, shortID(c.ImageID)),
+			Fix:    fmt.Sprintf("%s pull %s, or %s rm %s", cli, c.Image, cli, name),
+		})
+	}
+	return out
+}
+
+// unusedVolumeProblems returns the volumes without containers.
+func unusedVolumeProblems(cli string, volumes []DFVolume) []Problem {
+	var out []Problem
+	for _, v := range volumes {
+		if v.Links > 0 {
+			continue
+		}
+		out = append(out, Problem{
+			Name:   v.VolumeName,
+			Detail: fmt.Sprintf("used by no container, %s", humanSize(v.Size)),
+			Fix:    fmt.Sprintf("%s volume rm %s (check its contents first)", cli, v.VolumeName),
+		})
+	}
+	return out
+}
+
+// unusedNetworkProblems returns the networks without containers, except for
+// the default ones.
+func unusedNetworkProblems(cli string, networks []NetworkSummary, containers []Container) []Problem {
+	used := map[string]bool{}
+	for _, c := range containers {
+		for _, n := range c.Networks {
+			used[n] = true
+		}
+	}
+	var out []Problem
+	for _, n := range networks {
+		if used[n.Name] || slices.Contains(defaultNetworks, n.Name) {
+			continue
+		}
+		out = append(out, Problem{
+			Name:   n.Name,
+			Detail: "used by no container",
+			Fix:    fmt.Sprintf("%s network rm %s", cli, n.Name),
+		})
+	}
+	return out
+}
+
+// orphanedUnitProblems returns the units whose container is missing. Units
+// that run a new container (quadlets, podman generate systemd --new) remove
+// it when they stop, so they are only reported when they failed.
+func orphanedUnitProblems(units []systemdUnit, containers []Container) []Problem {
+	names := map[string]bool{}
+	for _, c := range containers {
+		for _, n := range c.Names {
+			names[n] = true
+		}
+	}
+	var out []Problem
+	for _, u := range units {
+		if u.Container == "" || names[u.Container] {
+			continue
+		}
+		switch {
+		case u.Start:
+			out = append(out, Problem{
+				Name:   u.ID,
+				Detail: fmt.Sprintf("starts container %s, which does not exist", u.Container),
+				Fix:    fmt.Sprintf("systemctl --user disable --now %s and remove the unit file, or recreate the container", u.ID),
+			})
+		case u.ActiveState == "failed":
+			out = append(out, Problem{
+				Name:   u.ID,
+				Detail: fmt.Sprintf("failed, container %s is gone", u.Container),
+				Fix:    fmt.Sprintf("journalctl --user -u %s to see why; systemctl --user reset-failed %s if it was removed on purpose", u.ID, u.ID),
+			})
+		}
+	}
+	return out
+}
+
+// podmanUnits returns the loaded user service units that run podman.
+func (s *Server) podmanUnits(ctx context.Context) ([]systemdUnit, error) {
+	out, err := exec.CommandContext(ctx, s.systemctlBin, "--user", "list-units", "--all", "--type=service", "--plain", "--no-legend", "--no-pager").Output()
+	if err != nil {
+		return nil, fmt.Errorf("systemctl list-units: %w", err)
+	}
+	args := []string{"--user", "show", "--property=Id,ActiveState,ExecStart", "--"}
+	for line := range strings.Lines(string(out)) {
+		if f := strings.Fields(line); len(f) > 0 && strings.HasSuffix(f[0], ".service") {
+			args = append(args, f[0])
+		}
+	}
+	if len(args) == 4 {
+		return nil, nil
+	}
+	out, err = exec.CommandContext(ctx, s.systemctlBin, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("systemctl show: %w", err)
+	}
+	return parseUnits(out), nil
+}
+
+// parseUnits parses the output of systemctl show for several units (blocks
+// separated by empty lines), keeping those whose ExecStart runs podman.
+func parseUnits(out []byte) []systemdUnit {
+	var units []systemdUnit
+	var u systemdUnit
+	var podman bool
+	flush := func() {
+		if u.ID != "" && podman {
+			units = append(units, u)
+		}
+		u, podman = systemdUnit{}, false
+	}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		key, value, _ := strings.Cut(sc.Text(), "=")
+		switch key {
+		case "":
+			flush()
+		case "Id":
+			u.ID = value
+		case "ActiveState":
+			u.ActiveState = value
+		case "ExecStart":
+			argv := execArgv(value)
+			if len(argv) < 2 || path.Base(argv[0]) != "podman" {
+				continue
+			}
+			podman = true
+			if name, start := podmanContainerArg(argv[1:]); name != "" {
+				u.Container, u.Start = name, start
+			}
+		}
+	}
+	flush()
+	return units
+}
+
+// execArgv extracts the argv of an ExecStart property value like
+// "{ path=/usr/bin/podman ; argv[]=/usr/bin/podman start web ; ... }".
+func execArgv(value string) []string {
+	_, rest, ok := strings.Cut(value, "argv[]=")
+	if !ok {
+		return nil
+	}
+	argv, _, _ := strings.Cut(rest, " ;")
+	return strings.Fields(argv)
+}
+
+// podmanContainerArg returns the container a podman command line refers to:
+// the --name of podman run, or the argument of podman start.
+func podmanContainerArg(args []string) (name string, start bool) {
+	sub := slices.IndexFunc(args, func(a string) bool { return !strings.HasPrefix(a, "-") })
+	if sub < 0 {
+		return "", false
+	}
+	switch args[sub] {
+	case "start":
+		for _, a := range args[sub+1:] {
+			if !strings.HasPrefix(a, "-") {
+				return a, true
+			}
+		}
+	case "run", "create":
+		for i, a := range args[sub+1:] {
+			if v, ok := strings.CutPrefix(a, "--name="); ok {
+				return v, false
+			}
+			if a == "--name" && sub+2+i < len(args) {
+				return args[sub+2+i], false
+			}
+		}
+	}
+	return "", false
+}
+
+// listNetworks returns the networks of the libpod or compat API.
+func (s *Server) listNetworks(ctx context.Context) ([]NetworkSummary, error) {
+	var list []NetworkSummary
+	p := "/networks/json"
+	if s.backend == backendDocker {
+		p = "/networks"
+	}
+	err := s.podmanGet(ctx, p, &list)
+	return list, err
+}
+
+// loadProblems fetches the data concurrently and runs the checks. Each
+// section fails on its own, like the overview page.
+func (s *Server) loadProblems(ctx context.Context) Problems {
+	ctx, cancel := context.WithTimeout(ctx, problemsTimeout)
+	defer cancel()
+
+	var (
+		wg         sync.WaitGroup
+		containers []Container
+		images     []ImageSummary
+		df         SystemDF
+		networks   []NetworkSummary
+		units      []systemdUnit
+		errs       = map[string]error{}
+		errsMu     sync.Mutex
+	)
+	fetch := func(name string, fn func() error) {
+		wg.Go(func() {
+			if err := fn(); err != nil {
+				errsMu.Lock()
+				errs[name] = err
+				errsMu.Unlock()
+			}
+		})
+	}
+	fetch("containers", func() (err error) {
+		containers, err = s.listContainers(ctx)
+		return err
+	})
+	fetch("images", func() (err error) {
+		images, err = s.listImages(ctx)
+		return err
+	})
+	if s.backend != backendDocker {
+		fetch("volumes", func() error { return s.podmanGet(ctx, "/system/df", &df) })
+		fetch("units", func() (err error) {
+			units, err = s.podmanUnits(ctx)
+			return err
+		})
+	}
+	fetch("networks", func() (err error) {
+		networks, err = s.listNetworks(ctx)
+		return err
+	})
+	wg.Wait()
+
+	p := Problems{Errs: map[string]string{}}
+	for name, err := range errs {
+		log.Printf("[%s] problems %s: %v", reqID(ctx), name, err)
+	}
+	// Every check needs the containers.
+	for _, section := range []string{"images", "volumes", "networks", "units"} {
+		if errs[section] != nil || errs["containers"] != nil {
+			p.Errs[section] = section + " unavailable"
+		}
+	}
+	if s.backend == backendDocker {
+		p.Errs["volumes"] = "not supported with the Docker backend"
+		p.Errs["units"] = "not supported with the Docker backend"
+	}
+
+	cli := backendPodman
+	if s.backend == backendDocker {
+		cli = backendDocker
+	}
+	if p.Errs["images"] == "" {
+		p.MissingImages = missingImageProblems(cli, containers, images)
+	}
+	if p.Errs["volumes"] == "" {
+		p.UnusedVolumes = unusedVolumeProblems(cli, df.Volumes)
+	}
+	if p.Errs["networks"] == "" {
+		p.UnusedNetworks = unusedNetworkProblems(cli, networks, containers)
+	}
+	if p.Errs["units"] == "" {
+		p.OrphanedUnits = orphanedUnitProblems(units, containers)
+	}
+	for _, list := range [][]Problem{p.MissingImages, p.UnusedVolumes, p.UnusedNetworks, p.OrphanedUnits} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	return p
+}
+
+func (s *Server) handleProblems(w http.ResponseWriter, r *http.Request) {
+	s.render(w, r, "problems.html", map[string]any{
+		"Title":    "Problems",
+		"Problems": s.loadProblems(r.Context()),
+	})
+}
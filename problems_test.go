@@ -0,0 +1,122 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMissingImageProblems(t *testing.T) {
+	containers := []Container{
+		{ID: "a", Names: []string{"web"}, Image: "nginx:alpine", ImageID: "1111"},
+		{ID: "b", Names: []string{"old"}, Image: "example.com/old:1", ImageID: "sha256:2222"},
+	}
+	images := []ImageSummary{{ID: "sha256:1111"}}
+	got := missingImageProblems("podman", containers, images)
+	if len(got) != 1 || got[0].Name != "old" || got[0].Link != "/container/b" {
+		t.Fatalf("got %+v", got)
+	}
+	if got[0].Fix != "podman pull example.com/old:1, or podman rm old" {
+		t.Errorf("Fix = %q", got[0].Fix)
+	}
+}
+
+func TestUnusedNetworkProblems(t *testing.T) {
+	networks := []NetworkSummary{{Name: "podman"}, {Name: "bridge"}, {Name: "app_default"}, {Name: "old_net"}}
+	containers := []Container{{Networks: []string{"app_default"}}}
+	got := unusedNetworkProblems("docker", networks, containers)
+	if len(got) != 1 || got[0].Name != "old_net" || got[0].Fix != "docker network rm old_net" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+const testUnits = `Id=container-web.service
+ActiveState=failed
+ExecStart={ path=/usr/bin/podman ; argv[]=/usr/bin/podman start web ; ignore_errors=no ; start_time=[n/a] ; stop_time=[n/a] ; pid=0 ; code=(null) ; status=0/0 }
+
+Id=db.service
+ActiveState=inactive
+ExecStart={ path=/usr/bin/podman ; argv[]=/usr/bin/podman run --name=systemd-db --cidfile=/run/user/1000/db.cid --replace --rm -d postgres ; ignore_errors=no ; start_time=[n/a] ; stop_time=[n/a] ; pid=0 ; code=(null) ; status=0/0 }
+
+Id=cache.service
+ActiveState=failed
+ExecStart={ path=/usr/bin/podman ; argv[]=/usr/bin/podman --log-level=info run --name systemd-cache --rm redis ; ignore_errors=no ; start_time=[n/a] ; stop_time=[n/a] ; pid=0 ; code=(null) ; status=0/0 }
+
+Id=present.service
+ActiveState=active
+ExecStart={ path=/usr/bin/podman ; argv[]=/usr/bin/podman start jellyfin ; ignore_errors=no ; start_time=[n/a] ; stop_time=[n/a] ; pid=0 ; code=(null) ; status=0/0 }
+
+Id=sshd.service
+ActiveState=failed
+ExecStart={ path=/usr/sbin/sshd ; argv[]=/usr/sbin/sshd -D ; ignore_errors=no ; start_time=[n/a] ; stop_time=[n/a] ; pid=0 ; code=(null) ; status=0/0 }
+`
+
+func TestOrphanedUnitProblems(t *testing.T) {
+	units := parseUnits([]byte(testUnits))
+	if len(units) != 4 {
+		t.Fatalf("parsed %d podman units, want 4: %+v", len(units), units)
+	}
+	if u := units[2]; u.Container != "systemd-cache" || u.Start {
+		t.Errorf("cache unit = %+v", u)
+	}
+	got := orphanedUnitProblems(units, []Container{{Names: []string{"jellyfin"}}})
+	var ids []string
+	for _, p := range got {
+		ids = append(ids, p.Name)
+	}
+	// db.service is inactive and runs with --rm, so its container is
+	// expected to be gone.
+	if strings.Join(ids, ",") != "container-web.service,cache.service" {
+		t.Errorf("units = %v", ids)
+	}
+}
+
+func TestProblemsPage(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.backend = backendPodman
+	script := filepath.Join(t.TempDir(), "systemctl")
+	units := filepath.Join(filepath.Dir(script), "units")
+	if err := os.WriteFile(units, []byte(testUnits), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(script, []byte("#!/bin/sh\ncase \"$2\" in\n"+
+		"list-units) printf 'container-web.service loaded failed failed web\\ncache.service loaded failed failed cache\\n' ;;\n"+
+		"show) cat "+units+" ;;\nesac\n"), 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.systemctlBin = script
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/system/problems")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	page := string(body)
+	for _, want := range []string{
+		"All containers have their image.",
+		"podman volume rm orphaned-data",
+		"podman network rm old_net",
+		"systemctl --user disable --now container-web.service",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+	if strings.Contains(page, "network rm podfather_default") {
+		t.Error("used network reported as unused")
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// isDangling reports whether an image has no tags.
+func isDangling(img ImageSummary) bool {
+	for _, t := range img.RepoTags {
+		if t != "<none>:<none>" {
+			return false
+		}
+	}
+	return true
+}
+
+// imageRows flags dangling images and images not used by any container.
+func imageRows(images []ImageSummary, containers []Container) []ImageRow {
+	used := make(map[string]bool, len(containers))
+	for _, c := range containers {
+		used[strings.TrimPrefix(c.ImageID, "sha256:")] = true
+	}
+	rows := make([]ImageRow, len(images))
+	for i, img := range images {
+		rows[i] = ImageRow{
+			ImageSummary: img,
+			Dangling:     isDangling(img),
+			Unused:       !used[strings.TrimPrefix(img.ID, "sha256:")],
+		}
+	}
+	return rows
+}
+
+// pruneCandidates returns the images a prune would delete: dangling unused
+// images, or all unused images if all is set.
+func pruneCandidates(rows []ImageRow, all bool) (out []ImageRow, size int64) {
+	for _, r := range rows {
+		if r.Unused && (all || r.Dangling) {
+			out = append(out, r)
+			size += r.Size
+		}
+	}
+	return out, size
+}
+
+func (s *Server) imageRows(ctx context.Context) ([]ImageRow, error) {
+	var (
+		images     []ImageSummary
+		containers []Container
+	)
+	err := parallel(ctx, 2, func(ctx context.Context, i int) (err error) {
+		if i == 0 {
+			images, err = s.listImages(ctx)
+		} else {
+			containers, err = s.listContainers(ctx)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imageRows(images, containers), nil
+}
+
+// handleImagePruneConfirm shows what a prune would delete.
+func (s *Server) handleImagePruneConfirm(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	rows, err := s.imageRows(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	all := r.URL.Query().Get("all") != ""
+	candidates, size := pruneCandidates(rows, all)
+	s.render(w, r, "prune.html", map[string]any{
+		"Title":  "Prune Images",
+		"All":    all,
+		"Images": candidates,
+		"Size":   size,
+	})
+}
+
+// pruneImages removes dangling images, or all unused ones, and returns how
+// many were deleted and the space reclaimed.
+func (s *Server) pruneImages(ctx context.Context, all bool) (deleted int, reclaimed int64, err error) {
+	path := "/images/prune"
+	if all {
+		path += "?all=true"
+		if s.backend == backendDocker {
+			path = "/images/prune?" + url.Values{"filters": {`{"dangling":["false"]}`}}.Encode()
+		}
+	}
+	resp, err := s.podmanDo(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	s.images.invalidate()
+
+	if s.backend == backendDocker {
+		var report struct {
+			ImagesDeleted []struct {
+				Deleted string `json:"Deleted"`
+			} `json:"ImagesDeleted"`
+			SpaceReclaimed int64 `json:"SpaceReclaimed"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&report)
+		for _, d := range report.ImagesDeleted {
+			if d.Deleted != "" {
+				deleted++
+			}
+		}
+		return deleted, report.SpaceReclaimed, err
+	}
+	var reports []struct {
+		ID   string `json:"Id"`
+		Size int64  `json:"Size"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&reports)
+	for _, p := range reports {
+		reclaimed += p.Size
+	}
+	return len(reports), reclaimed, err
+}
+
+func (s *Server) handleImagePrune(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	all := r.FormValue("all") != ""
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+	deleted, reclaimed, err := s.pruneImages(ctx, all)
+	if err != nil {
+		log.Printf("[%s] image prune: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	name := "image prune"
+	if all {
+		name += " (all unused)"
+	}
+	s.actions.record(r, name, fmt.Sprintf("%d image(s), %s", deleted, humanSize(reclaimed)), nil, "", nil)
+	s.render(w, r, "prune.html", map[string]any{
+		"Title":     "Prune Images",
+		"Done":      true,
+		"Deleted":   deleted,
+		"Reclaimed": reclaimed,
+	})
+}
+
+// isPrunable reports whether a container prune removes a container in
+// state, i.e. whether it is stopped.
+func isPrunable(state string) bool {
+	switch state {
+	case "created", "configured", "exited", "stopped", "dead":
+		return true
+	}
+	return false
+}
+
+// handleContainerPruneConfirm lists the stopped containers a prune would
+// remove, longest exited first.
+func (s *Server) handleContainerPruneConfirm(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	all, err := s.inspectAllContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var candidates []ContainerInspect
+	for _, c := range all {
+		if isPrunable(c.State.Status) {
+			candidates = append(candidates, c)
+		}
+	}
+	slices.SortFunc(candidates, func(a, b ContainerInspect) int {
+		return a.State.FinishedAt.Compare(b.State.FinishedAt)
+	})
+	s.render(w, r, "containers-prune.html", map[string]any{
+		"Title":      "Prune Containers",
+		"Containers": candidates,
+	})
+}
+
+// pruneContainers removes all stopped containers and returns how many were
+// removed and the space reclaimed.
+func (s *Server) pruneContainers(ctx context.Context) (deleted int, reclaimed int64, err error) {
+	resp, err := s.podmanDo(ctx, http.MethodPost, "/containers/prune", nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	if s.backend == backendDocker {
+		var report struct {
+			ContainersDeleted []string `json:"ContainersDeleted"`
+			SpaceReclaimed    int64    `json:"SpaceReclaimed"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&report)
+		return len(report.ContainersDeleted), report.SpaceReclaimed, err
+	}
+	var reports []struct {
+		ID   string `json:"Id"`
+		Size int64  `json:"Size"`
+		Err  string `json:"Err"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&reports)
+	for _, p := range reports {
+		if p.Err != "" {
+			log.Printf("container prune: %s: %s", shortID(p.ID), p.Err)
+			continue
+		}
+		deleted++
+		reclaimed += p.Size
+	}
+	return deleted, reclaimed, err
+}
+
+func (s *Server) handleContainerPrune(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+	deleted, reclaimed, err := s.pruneContainers(ctx)
+	if err != nil {
+		log.Printf("[%s] container prune: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.actions.record(r, "container prune", fmt.Sprintf("%d container(s), %s", deleted, humanSize(reclaimed)), nil, "", nil)
+	s.render(w, r, "containers-prune.html", map[string]any{
+		"Title":     "Prune Containers",
+		"Done":      true,
+		"Deleted":   deleted,
+		"Reclaimed": reclaimed,
+	})
+}
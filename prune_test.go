@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImageRows(t *testing.T) {
+	var images []ImageSummary
+	if err := json.Unmarshal(loadTestFixture(t, "testdata/images.json"), &images); err != nil {
+		t.Fatal(err)
+	}
+	images = append(images, ImageSummary{ID: "0123456789ab", RepoTags: []string{"<none>:<none>"}, Size: 1000})
+	rows := imageRows(images, loadTestContainers(t))
+
+	unused := 0
+	for _, r := range rows {
+		if r.Unused {
+			unused++
+		}
+		if r.Dangling != (r.ID == "0123456789ab") {
+			t.Errorf("image %s dangling = %v", shortID(r.ID), r.Dangling)
+		}
+	}
+	if unused != 3 {
+		t.Errorf("unused = %d, want 3 (cyberchef, mini-qr, dangling)", unused)
+	}
+
+	if got, size := pruneCandidates(rows, false); len(got) != 1 || size != 1000 {
+		t.Errorf("pruneCandidates(dangling) = %d images, %d bytes; want 1, 1000", len(got), size)
+	}
+	if got, _ := pruneCandidates(rows, true); len(got) != 3 {
+		t.Errorf("pruneCandidates(all) = %d images, want 3", len(got))
+	}
+}
+
+func TestImagePrune(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/images/prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("disabled confirm: status = %d, want 404", resp.StatusCode)
+	}
+
+	s.enableActions = true
+	resp, err = http.Get(app.URL + "/images/prune?all=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "cyberchef") || !strings.Contains(string(body), "Delete 2 image(s)") {
+		t.Error("confirmation page does not list the unused images")
+	}
+
+	resp, err = http.PostForm(app.URL+"/images/prune", map[string][]string{"all": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "Deleted 2 image(s)") {
+		t.Errorf("prune: status %d, body does not report deleted images", resp.StatusCode)
+	}
+}
+
+func TestContainerPrune(t *testing.T) {
+	t.Parallel()
+	finished := time.Now().Add(-3 * 24 * time.Hour)
+	inspects := map[string]ContainerInspect{
+		"aaa111": {ID: "aaa111", Name: "web", State: ContainerState{Status: "running", Running: true}},
+		"bbb222": {ID: "bbb222", Name: "migrate", State: ContainerState{Status: "exited", ExitCode: 1, FinishedAt: finished}},
+		"ccc333": {ID: "ccc333", Name: "never", State: ContainerState{Status: "created"}},
+	}
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod")
+		switch {
+		case r.Method == http.MethodPost && p == "/containers/prune":
+			w.Write([]byte(`[{"Id":"bbb222","Size":1048576},{"Id":"ccc333","Size":0},{"Id":"ddd444","Err":"in use"}]`))
+		case p == "/containers/json":
+			json.NewEncoder(w).Encode([]Container{{ID: "aaa111"}, {ID: "bbb222"}, {ID: "ccc333"}})
+		default:
+			id := strings.TrimSuffix(strings.TrimPrefix(p, "/containers/"), "/json")
+			json.NewEncoder(w).Encode(inspects[id])
+		}
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/containers/prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	page := string(body)
+	for _, want := range []string{">migrate<", "3d ago", ">never<", "never started", "Remove 2 container(s)"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("confirmation page lacks %q", want)
+		}
+	}
+	if strings.Contains(page, ">web<") {
+		t.Error("running container listed")
+	}
+	if strings.Index(page, ">never<") > strings.Index(page, ">migrate<") {
+		t.Error("never started container not listed first")
+	}
+
+	resp, err = http.PostForm(app.URL+"/containers/prune", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "Removed 2 container(s) and reclaimed 1.0 MB") {
+		t.Errorf("prune: status %d, body does not report removed containers", resp.StatusCode)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// POST requests from the UI (actions, auto-update, saving views, ...) are
+// rate limited per signed-in user, or per client IP without login, so a
+// misbehaving script cannot restart containers in a loop. Behind a reverse
+// proxy, the client IP is only known with TRUSTED_PROXIES (see clientIP);
+// otherwise all anonymous clients share the proxy's bucket. Requests are
+// limited before csrfProtect parses their body. Webhooks have their own
+// limiter.
+
+// maxLimiterKeys bounds the number of tracked clients. Beyond it, clients
+// whose bucket has refilled are forgotten.
+const maxLimiterKeys = 1024
+
+// keyedLimiter is a token bucket per key.
+type keyedLimiter struct {
+	rate  float64 // tokens per second
+	burst int
+
+	mu      sync.Mutex
+	buckets map[string]*rateLimiter
+}
+
+func newKeyedLimiter(perMinute float64, burst int) *keyedLimiter {
+	return &keyedLimiter{rate: perMinute / 60, burst: burst, buckets: map[string]*rateLimiter{}}
+}
+
+// allow takes a token from the bucket of key.
+func (k *keyedLimiter) allow(key string) bool {
+	k.mu.Lock()
+	b := k.buckets[key]
+	if b == nil {
+		if len(k.buckets) >= maxLimiterKeys {
+			k.prune()
+		}
+		b = newRateLimiter(k.rate, k.burst)
+		k.buckets[key] = b
+	}
+	k.mu.Unlock()
+	return b.allow()
+}
+
+// prune drops the buckets that have refilled. Callers must hold mu.
+func (k *keyedLimiter) prune() {
+	full := time.Duration(float64(k.burst) / k.rate * float64(time.Second))
+	for key, b := range k.buckets {
+		b.mu.Lock()
+		idle := time.Since(b.last) > full
+		b.mu.Unlock()
+		if idle {
+			delete(k.buckets, key)
+		}
+	}
+}
+
+// retryAfter returns the seconds until the next token, rounded up.
+func (k *keyedLimiter) retryAfter() int {
+	return int(math.Ceil(1 / k.rate))
+}
+
+// limitPosts rate limits POST requests except webhooks. It must wrap the
+// handler inside requireLogin, so the user is known, and outside
+// csrfProtect, so limited bodies are not read.
+func (s *Server) limitPosts(next http.Handler) http.Handler {
+	if s.postLimiter == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || strings.HasPrefix(r.URL.Path, s.basePath+hooksPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := currentUser(r.Context())
+		if key == "" {
+			key = clientIP(r)
+		}
+		if !s.postLimiter.allow(key) {
+			log.Printf("[%s] rate limited %s %s", reqID(r.Context()), key, r.URL.Path)
+			w.Header().Set("Retry-After", strconv.Itoa(s.postLimiter.retryAfter()))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
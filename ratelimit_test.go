@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	t.Parallel()
+	l := newKeyedLimiter(1, 2)
+	for i, want := range []bool{true, true, false} {
+		if got := l.allow("10.0.0.1"); got != want {
+			t.Errorf("request %d: allow = %v, want %v", i, got, want)
+		}
+	}
+	if !l.allow("10.0.0.2") {
+		t.Error("other client is limited too")
+	}
+	if got := l.retryAfter(); got != 60 {
+		t.Errorf("retryAfter = %d, want 60", got)
+	}
+}
+
+func TestLimitPosts(t *testing.T) {
+	t.Parallel()
+	s := &Server{postLimiter: newKeyedLimiter(1, 1)}
+	h := s.limitPosts(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	do := func(method, path, user string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if user != "" {
+			r = r.WithContext(context.WithValue(r.Context(), userKey, user))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do("POST", "/container/web/restart", ""); w.Code != http.StatusOK {
+		t.Fatalf("first POST: %d", w.Code)
+	}
+	w := do("POST", "/container/web/restart", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("second POST: %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	// GETs, webhooks and other users are not affected.
+	for _, tt := range []struct{ method, path, user string }{
+		{"GET", "/containers", ""},
+		{"POST", "/hooks/pull", ""},
+		{"POST", "/container/web/restart", "alice"},
+	} {
+		if w := do(tt.method, tt.path, tt.user); w.Code != http.StatusOK {
+			t.Errorf("%s %s as %q: %d", tt.method, tt.path, tt.user, w.Code)
+		}
+	}
+}
+
+func TestLimitPostsBehindProxy(t *testing.T) {
+	t.Parallel()
+	s := &Server{postLimiter: newKeyedLimiter(1, 1)}
+	s.trustedProxies, _ = parseTrustedProxies("10.0.0.1")
+	h := s.limitPosts(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	do := func(xff string) int {
+		r := httptest.NewRequest("POST", "/container/web/restart", nil)
+		r.RemoteAddr = "10.0.0.1:40000"
+		r.Header.Set("X-Forwarded-For", xff)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, s.withClientIP(r))
+		return w.Code
+	}
+
+	if code := do("192.0.2.1"); code != http.StatusOK {
+		t.Fatalf("first client: %d", code)
+	}
+	// Clients behind the proxy have their own bucket; a faked entry in front
+	// of the proxy's does not give a new one.
+	if code := do("192.0.2.2"); code != http.StatusOK {
+		t.Errorf("second client: %d", code)
+	}
+	if code := do("203.0.113.9, 192.0.2.1"); code != http.StatusTooManyRequests {
+		t.Errorf("faked X-Forwarded-For: %d", code)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// /container/{id}/raw and /image/{id}/raw show the full inspect JSON, for
+// the fields the curated pages leave out. Environment variables and the
+// create command are removed before rendering, like everywhere else; the
+// page goes through s.render, so privacy mode masks it as well.
+
+// rawHiddenKeys are removed from the "Config" and "ContainerConfig"
+// objects of inspect JSON.
+var rawHiddenKeys = []string{"Env", "CreateCommand"}
+
+// rawInspect pretty-prints inspect JSON without rawHiddenKeys.
+func rawInspect(data []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // keep sizes and IDs exact
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return "", err
+	}
+	for _, key := range []string{"Config", "ContainerConfig"} {
+		if cfg, ok := m[key].(map[string]any); ok {
+			for _, k := range rawHiddenKeys {
+				delete(cfg, k)
+			}
+		}
+	}
+	out, err := json.MarshalIndent(m, "", "  ")
+	return string(out), err
+}
+
+// handleRaw returns a handler rendering the inspect JSON of the container
+// or image ("container" or "image") in the id path value.
+func (s *Server) handleRaw(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if !validID.MatchString(id) {
+			http.Error(w, "Invalid "+kind+" ID", http.StatusBadRequest)
+			return
+		}
+		var data json.RawMessage
+		err := s.podmanGet(r.Context(), "/"+kind+"s/"+id+"/json", &data)
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		var raw string
+		if err == nil {
+			raw, err = rawInspect(data)
+		}
+		if err != nil {
+			log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		s.render(w, r, "raw.html", map[string]any{
+			"Title": "Inspect: " + shortID(id),
+			"Kind":  kind,
+			"ID":    id,
+			"JSON":  raw,
+		})
+	}
+}
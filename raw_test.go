@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRawInspect(t *testing.T) {
+	t.Parallel()
+	got, err := rawInspect([]byte(`{"Id":"abc","Size":9007199254740993,"Config":{"Env":["TOKEN=secret"],"CreateCommand":["podman","run","-e","TOKEN=secret"],"User":"1000"},"ContainerConfig":{"Env":["A=b"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"Id": "abc"`, `"Size": 9007199254740993`, `"User": "1000"`, `"ContainerConfig": {}`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rawInspect lacks %s:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "secret") || strings.Contains(got, "A=b") {
+		t.Errorf("rawInspect shows environment variables:\n%s", got)
+	}
+	if _, err := rawInspect([]byte(`[]`)); err == nil {
+		t.Error("rawInspect accepted a non-object")
+	}
+}
+
+func TestHandleRaw(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	app := httptest.NewServer(newTestServer(t, mock).newMux("podman"))
+	defer app.Close()
+
+	for _, tt := range []struct {
+		path, want string
+		status     int
+	}{
+		{"/container/jellyfin/raw", `&#34;HealthcheckMaxLogSize&#34;: 500`, http.StatusOK},
+		{"/image/b76de378d572/raw", `&#34;RepoTags&#34;`, http.StatusOK},
+		{"/container/nonexistent/raw", "", http.StatusNotFound},
+		{"/container/-bad/raw", "", http.StatusBadRequest},
+	} {
+		resp, err := http.Get(app.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || !strings.Contains(string(body), tt.want) {
+			t.Errorf("%s: status %d, want %d with %q", tt.path, resp.StatusCode, tt.status, tt.want)
+		}
+		// Image history shows ENV instructions, but not as Config.Env entries.
+		if strings.Contains(string(body), "&#34;NGINX_VERSION=1.29.5&#34;") || strings.Contains(string(body), "--name=jellyfin") {
+			t.Errorf("%s shows environment variables or the create command", tt.path)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// The reachability history records when the URL of an app starts or stops
+// answering the prober, kept for reachDays days in
+// $DATA_DIR/app-reachability.json. App cards show it as a bar of daily
+// availability, like a status page. Slow answers count as up.
+
+const (
+	reachDays     = 30
+	reachCellSize = 6
+	reachCellStep = 8
+	reachHeight   = 16
+)
+
+// Transition is a change of the reachability of an app.
+type Transition struct {
+	At time.Time `json:"at"`
+	Up bool      `json:"up"`
+}
+
+type reachHistory struct {
+	path string
+
+	mu   sync.Mutex
+	apps map[string][]Transition // by app name, oldest first
+}
+
+func newReachHistory(path string) *reachHistory {
+	h := &reachHistory{path: path, apps: map[string][]Transition{}}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &h.apps)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("reachability: loading %s: %v", path, err)
+	}
+	return h
+}
+
+// update records the apps whose state differs from their last transition
+// and drops what is older than reachDays, apart from the transition giving
+// the state at the start of the period. It saves the history if it changed.
+func (h *reachHistory) update(states map[string]bool, now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	changed := false
+	for app, up := range states {
+		ts := h.apps[app]
+		if len(ts) == 0 || ts[len(ts)-1].Up != up {
+			h.apps[app] = append(ts, Transition{At: now, Up: up})
+			changed = true
+		}
+	}
+	cutoff := now.AddDate(0, 0, -reachDays)
+	for app, ts := range h.apps {
+		i := 0
+		for i+1 < len(ts) && !ts[i+1].At.After(cutoff) {
+			i++
+		}
+		if _, probed := states[app]; !probed && ts[len(ts)-1].At.Before(cutoff) {
+			delete(h.apps, app)
+			changed = true
+		} else if i > 0 {
+			h.apps[app] = ts[i:]
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return h.save()
+}
+
+func (h *reachHistory) save() error {
+	data, err := json.Marshal(h.apps)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(h.path, data, 0o600)
+}
+
+// ReachDay is a day of the reachability bar.
+type ReachDay struct {
+	X      int
+	Status string // like UptimeDay.Status
+	Title  string
+}
+
+// ReachBar is the data of the reachability bar SVG of an app.
+type ReachBar struct {
+	Days         []ReachDay
+	Width        int
+	Availability float64 // over the days with data, in percent
+}
+
+// bars returns the reachability bars of all apps with history for the last
+// reachDays days up to now.
+func (h *reachHistory) bars(now time.Time) map[string]*ReachBar {
+	out := map[string]*ReachBar{}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for app, ts := range h.apps {
+		b := reachBar(ts, now)
+		out[app] = &b
+	}
+	return out
+}
+
+// reachBar lays out the daily availability from the transitions.
+func reachBar(ts []Transition, now time.Time) ReachBar {
+	b := ReachBar{Width: reachDays*reachCellStep - (reachCellStep - reachCellSize)}
+	y, m, d := now.Date()
+	first := time.Date(y, m, d-reachDays+1, 0, 0, 0, 0, now.Location())
+	var totalUp, totalKnown time.Duration
+	for i := range reachDays {
+		start := first.AddDate(0, 0, i)
+		end := start.AddDate(0, 0, 1)
+		if now.Before(end) {
+			end = now
+		}
+		var up, known time.Duration
+		for j, t := range ts {
+			segStart, segEnd := t.At, now
+			if j+1 < len(ts) {
+				segEnd = ts[j+1].At
+			}
+			if segStart.Before(start) {
+				segStart = start
+			}
+			if end.Before(segEnd) {
+				segEnd = end
+			}
+			if overlap := segEnd.Sub(segStart); overlap > 0 {
+				known += overlap
+				if t.Up {
+					up += overlap
+				}
+			}
+		}
+		day := ReachDay{X: i * reachCellStep, Status: "none", Title: start.Format(time.DateOnly) + ": no data"}
+		if known > 0 {
+			down := known - up
+			switch {
+			case down == 0:
+				day.Status = "up"
+			case down*2 >= known:
+				day.Status = "down"
+			default:
+				day.Status = "degraded"
+			}
+			day.Title = fmt.Sprintf("%s: %.1f%% reachable", start.Format(time.DateOnly), float64(up)/float64(known)*100)
+		}
+		b.Days = append(b.Days, day)
+		totalUp += up
+		totalKnown += known
+	}
+	if totalKnown > 0 {
+		b.Availability = float64(totalUp) / float64(totalKnown) * 100
+	}
+	return b
+}
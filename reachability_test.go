@@ -0,0 +1,71 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReachBar(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.Local)
+	at := func(days, hours int) time.Time {
+		return time.Date(2026, 10, 15-days, hours, 0, 0, 0, time.Local)
+	}
+	b := reachBar([]Transition{
+		{At: at(40, 0), Up: true},
+		{At: at(2, 18), Up: false}, // 6h down
+		{At: at(1, 0), Up: true},
+		{At: at(0, 3), Up: false}, // 3h down
+		{At: at(0, 6), Up: true},
+	}, now)
+	if len(b.Days) != reachDays || b.Width != reachDays*reachCellStep-2 {
+		t.Fatalf("%d days, width %d", len(b.Days), b.Width)
+	}
+	last := len(b.Days) - 1
+	for i, want := range map[int]string{0: "up", last - 2: "degraded", last - 1: "up", last: "degraded"} {
+		if got := b.Days[i].Status; got != want {
+			t.Errorf("day %d = %s, want %s", i, got, want)
+		}
+	}
+	if got := b.Days[last].Title; got != "2026-10-15: 75.0% reachable" {
+		t.Errorf("today = %q", got)
+	}
+	if got := reachBar([]Transition{{At: at(0, 9), Up: false}}, now); got.Days[last].Status != "down" || got.Days[0].Status != "none" {
+		t.Errorf("down today = %+v", got.Days[last])
+	}
+}
+
+func TestReachHistory(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "app-reachability.json")
+	h := newReachHistory(path)
+	start := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	steps := []map[string]bool{
+		{"Wiki": true, "Old": true},
+		{"Wiki": true},
+		{"Wiki": false},
+		{"Wiki": true},
+	}
+	for i, states := range steps {
+		if err := h.update(states, start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := h.apps["Wiki"]; len(got) != 3 || !got[0].Up || got[1].Up {
+		t.Errorf("Wiki = %+v", got)
+	}
+
+	// 40 days later, only the state at the start of the period is kept, and
+	// apps no longer probed are dropped.
+	if err := h.update(map[string]bool{"Wiki": true}, start.AddDate(0, 0, 40)); err != nil {
+		t.Fatal(err)
+	}
+	h = newReachHistory(path)
+	if got := h.apps["Wiki"]; len(got) != 1 || !got[0].Up || !got[0].At.Equal(start.Add(3*time.Hour)) {
+		t.Errorf("Wiki after 40 days = %+v", got)
+	}
+	if _, ok := h.apps["Old"]; ok {
+		t.Error("Old is kept")
+	}
+}
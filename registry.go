@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// manifestAccept lists the manifest media types we accept, so the registry
+// returns the digest of the manifest list for multi-arch images.
+var manifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// registryClient talks to the HTTP API of registries. Only anonymous access
+// is supported.
+type registryClient struct {
+	client *http.Client
+	// baseURL returns the API base URL for a registry host.
+	baseURL func(host string) string
+}
+
+func newRegistryClient() *registryClient {
+	return &registryClient{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		baseURL: func(host string) string { return "https://" + host },
+	}
+}
+
+// do sends a request for path on the registry host. If the registry asks
+// for a bearer token, an anonymous one is fetched, stored in token for the
+// next requests, and the request is retried. The caller closes the body of
+// the response, which has status 200.
+func (rc *registryClient) do(ctx context.Context, method, host, path, accept string, token *string) (*http.Response, error) {
+	resp, err := rc.send(ctx, method, rc.baseURL(host)+path, accept, *token)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		if *token, err = rc.token(ctx, resp.Header.Get("WWW-Authenticate")); err != nil {
+			return nil, err
+		}
+		if resp, err = rc.send(ctx, method, rc.baseURL(host)+path, accept, *token); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+func (rc *registryClient) send(ctx context.Context, method, url, accept, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return rc.client.Do(req)
+}
+
+// digest returns the manifest digest for r.
+func (rc *registryClient) digest(ctx context.Context, r imageRef) (string, error) {
+	var token string
+	resp, err := rc.do(ctx, http.MethodHead, r.Registry, "/v2/"+r.Repo+"/manifests/"+r.Tag, manifestAccept, &token)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", errors.New("registry: no digest in response")
+	}
+	return digest, nil
+}
+
+// token fetches an anonymous token for a Bearer challenge, e.g.
+// `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`.
+func (rc *registryClient) token(ctx context.Context, challenge string) (string, error) {
+	params, ok := strings.CutPrefix(challenge, "Bearer ")
+	if !ok {
+		return "", errors.New("registry: unsupported authentication")
+	}
+	q := url.Values{}
+	var realm string
+	for _, p := range strings.Split(params, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		v = strings.Trim(v, `"`)
+		if k == "realm" {
+			realm = v
+		} else if k == "service" || k == "scope" {
+			q.Set(k, v)
+		}
+	}
+	if realm == "" {
+		return "", errors.New("registry: no token realm")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := rc.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token: %s", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	return body.Token, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Container pages link the containers related to the shown one: the other
+// members of its pod, the containers it depends on or that depend on it
+// (podman run --requires, pod infra containers) and the ones whose network,
+// IPC, PID or UTS namespace it joins or that join its namespaces
+// (--network container:NAME etc.).
+
+// Relation kinds, in the order they are shown.
+const (
+	relPodMember  = "pod member"
+	relDependsOn  = "depends on"
+	relRequiredBy = "required by"
+	relJoins      = "joins namespace of"
+	relJoinedBy   = "namespace joined by"
+)
+
+var relationKinds = []string{relPodMember, relDependsOn, relRequiredBy, relJoins, relJoinedBy}
+
+// ContainerRelation is a container related to another one.
+type ContainerRelation struct {
+	Kind      string
+	Namespace string // the shared namespace, for relJoins and relJoinedBy
+	ID        string // "" if the container no longer exists
+	Name      string
+	State     string
+	Infra     bool // the pod's infra container
+}
+
+// ContainerRelations are the relations of a container.
+type ContainerRelations struct {
+	PodID     string
+	PodName   string
+	Relations []ContainerRelation
+}
+
+// sharedNamespaces returns the namespaces h joins from other containers,
+// as namespace name to the "container:" reference.
+func sharedNamespaces(h *HostConfig) map[string]string {
+	out := map[string]string{}
+	if h == nil {
+		return out
+	}
+	for ns, mode := range map[string]string{"network": h.NetworkMode, "ipc": h.IpcMode, "pid": h.PidMode, "uts": h.UTSMode} {
+		if ref, ok := strings.CutPrefix(mode, "container:"); ok && ref != "" {
+			out[ns] = ref
+		}
+	}
+	return out
+}
+
+// containerRelations returns the relations of c to the containers in list,
+// using all, the inspected containers, for the reverse relations.
+func containerRelations(c ContainerInspect, list []Container, all []ContainerInspect) ContainerRelations {
+	// find resolves a container reference, an ID, ID prefix or name.
+	find := func(ref string) (Container, bool) {
+		i := slices.IndexFunc(list, func(o Container) bool {
+			return o.ID == ref || slices.Contains(o.Names, ref) || len(ref) >= 12 && strings.HasPrefix(o.ID, ref)
+		})
+		if i < 0 {
+			return Container{}, false
+		}
+		return list[i], true
+	}
+	relation := func(kind, ns, ref string) ContainerRelation {
+		rel := ContainerRelation{Kind: kind, Namespace: ns, Name: shortID(ref)}
+		if o, ok := find(ref); ok {
+			rel.ID, rel.Name, rel.State, rel.Infra = o.ID, cmp.Or(firstName(o.Names), shortID(o.ID)), o.State, o.IsInfra
+		}
+		return rel
+	}
+
+	out := ContainerRelations{PodID: c.Pod}
+	var rels []ContainerRelation
+	for _, o := range list {
+		if c.Pod == "" || o.Pod != c.Pod {
+			continue
+		}
+		out.PodName = cmp.Or(out.PodName, o.PodName)
+		if o.ID != c.ID {
+			rels = append(rels, relation(relPodMember, "", o.ID))
+		}
+	}
+	for _, dep := range c.Dependencies {
+		rels = append(rels, relation(relDependsOn, "", dep))
+	}
+	for ns, ref := range sharedNamespaces(c.HostConfig) {
+		rels = append(rels, relation(relJoins, ns, ref))
+	}
+	self := func(ref string) bool {
+		return ref == c.ID || ref == c.Name || len(ref) >= 12 && strings.HasPrefix(c.ID, ref)
+	}
+	for _, o := range all {
+		if o.ID == c.ID {
+			continue
+		}
+		if slices.ContainsFunc(o.Dependencies, self) {
+			rels = append(rels, relation(relRequiredBy, "", o.ID))
+		}
+		for ns, ref := range sharedNamespaces(o.HostConfig) {
+			if self(ref) {
+				rels = append(rels, relation(relJoinedBy, ns, o.ID))
+			}
+		}
+	}
+	slices.SortFunc(rels, func(a, b ContainerRelation) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(relationKinds, a.Kind), slices.Index(relationKinds, b.Kind)),
+			strings.Compare(a.Name, b.Name),
+			strings.Compare(a.Namespace, b.Namespace),
+		)
+	})
+	out.Relations = rels
+	return out
+}
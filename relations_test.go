@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestContainerRelations(t *testing.T) {
+	t.Parallel()
+	list := []Container{
+		{ID: "aaaaaaaaaaaaaaaa", Names: []string{"web"}, State: "running", Pod: "p1", PodName: "blog"},
+		{ID: "bbbbbbbbbbbbbbbb", Names: []string{"p1-infra"}, State: "running", Pod: "p1", PodName: "blog", IsInfra: true},
+		{ID: "cccccccccccccccc", Names: []string{"db"}, State: "exited"},
+		{ID: "dddddddddddddddd", Names: []string{"sidecar"}, State: "running"},
+		{ID: "eeeeeeeeeeeeeeee", Names: []string{"worker"}, State: "running"},
+	}
+	web := ContainerInspect{
+		ID: "aaaaaaaaaaaaaaaa", Name: "web", Pod: "p1",
+		Dependencies: []string{"bbbbbbbbbbbbbbbb", "cccccccccccccccc", "ffffffffffffffff"},
+		HostConfig:   &HostConfig{NetworkMode: "bridge", IpcMode: "container:db"},
+	}
+	all := []ContainerInspect{
+		web,
+		{ID: "dddddddddddddddd", Name: "sidecar", HostConfig: &HostConfig{NetworkMode: "container:aaaaaaaaaaaa", PidMode: "container:web"}},
+		{ID: "eeeeeeeeeeeeeeee", Name: "worker", Dependencies: []string{"aaaaaaaaaaaaaaaa"}},
+	}
+
+	got := containerRelations(web, list, all)
+	if got.PodID != "p1" || got.PodName != "blog" {
+		t.Errorf("pod = %q %q", got.PodID, got.PodName)
+	}
+	want := []ContainerRelation{
+		{Kind: relPodMember, ID: "bbbbbbbbbbbbbbbb", Name: "p1-infra", State: "running", Infra: true},
+		{Kind: relDependsOn, ID: "cccccccccccccccc", Name: "db", State: "exited"},
+		{Kind: relDependsOn, Name: "ffffffffffff"},
+		{Kind: relDependsOn, ID: "bbbbbbbbbbbbbbbb", Name: "p1-infra", State: "running", Infra: true},
+		{Kind: relRequiredBy, ID: "eeeeeeeeeeeeeeee", Name: "worker", State: "running"},
+		{Kind: relJoins, Namespace: "ipc", ID: "cccccccccccccccc", Name: "db", State: "exited"},
+		{Kind: relJoinedBy, Namespace: "network", ID: "dddddddddddddddd", Name: "sidecar", State: "running"},
+		{Kind: relJoinedBy, Namespace: "pid", ID: "dddddddddddddddd", Name: "sidecar", State: "running"},
+	}
+	if len(got.Relations) != len(want) {
+		t.Fatalf("relations = %+v, want %d", got.Relations, len(want))
+	}
+	for i, w := range want {
+		if got.Relations[i] != w {
+			t.Errorf("relation %d = %+v, want %+v", i, got.Relations[i], w)
+		}
+	}
+
+	if got := containerRelations(ContainerInspect{ID: "cccccccccccccccc"}, list, nil); got.PodID != "" || len(got.Relations) != 0 {
+		t.Errorf("unrelated container: %+v", got)
+	}
+}
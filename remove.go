@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// Removing a container goes through a confirmation page that lists its
+// volumes, so that removing anonymous volumes along with it, or forcing the
+// removal of a running container, is a deliberate choice.
+
+// anonymousVolume matches the generated names of anonymous volumes.
+var anonymousVolume = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// VolumeMount is a volume of a container on the remove confirmation page.
+type VolumeMount struct {
+	Name        string
+	Destination string
+	Anonymous   bool // removed with the container if asked for
+}
+
+// containerVolumes returns the volumes mounted by c.
+func containerVolumes(c ContainerInspect) []VolumeMount {
+	var out []VolumeMount
+	for _, m := range c.Mounts {
+		if m.Type == "volume" {
+			out = append(out, VolumeMount{Name: m.Name, Destination: m.Destination, Anonymous: anonymousVolume.MatchString(m.Name)})
+		}
+	}
+	return out
+}
+
+// removeContainer removes the container id. force stops it first if it is
+// running, volumes also removes its anonymous volumes.
+func (s *Server) removeContainer(ctx context.Context, id string, force, volumes bool) error {
+	q := url.Values{"force": {strconv.FormatBool(force)}, "v": {strconv.FormatBool(volumes)}}
+	resp, err := s.podmanDo(ctx, http.MethodDelete, "/containers/"+id+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil
+}
+
+// inspectForAction inspects the container of the {id} path value, writing
+// an error response and returning false if that fails.
+func (s *Server) inspectForAction(w http.ResponseWriter, r *http.Request) (ContainerInspect, bool) {
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid container ID", http.StatusBadRequest)
+		return ContainerInspect{}, false
+	}
+	c, err := s.inspectContainer(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Container Not Found", http.StatusNotFound)
+			return c, false
+		}
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return c, false
+	}
+	return c, true
+}
+
+func (s *Server) handleContainerRemoveConfirm(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	c, ok := s.inspectForAction(w, r)
+	if !ok {
+		return
+	}
+	s.render(w, r, "container-remove.html", map[string]any{
+		"Title":     "Remove " + c.Name,
+		"Container": c,
+		"Volumes":   containerVolumes(c),
+	})
+}
+
+func (s *Server) handleContainerRemove(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	if r.FormValue("confirm") != "yes" {
+		http.Error(w, "Confirm the removal on the confirmation page", http.StatusBadRequest)
+		return
+	}
+	c, ok := s.inspectForAction(w, r)
+	if !ok {
+		return
+	}
+	force, volumes := r.FormValue("force") == "yes", r.FormValue("volumes") == "yes"
+	if c.State.Running && !force {
+		http.Error(w, "The container is running; check force to stop and remove it", http.StatusConflict)
+		return
+	}
+	err := s.removeContainer(r.Context(), c.ID, force, volumes)
+	target := c.Name
+	if volumes {
+		target += " (with anonymous volumes)"
+	}
+	s.actions.record(r, "remove", target, err, "", nil)
+	if err != nil {
+		log.Printf("[%s] remove %s: %v", reqID(r.Context()), c.Name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, s.basePath+"/containers", http.StatusSeeOther)
+}
+
+// Removing an image likewise goes through a confirmation page listing the
+// containers created from it. Podman's force removes those containers along
+// with the image, so it is only offered once they have been acknowledged.
+
+// removeImage removes the image id. force also removes the containers using
+// it.
+func (s *Server) removeImage(ctx context.Context, id string, force bool) error {
+	q := url.Values{"force": {strconv.FormatBool(force)}}
+	resp, err := s.podmanDo(ctx, http.MethodDelete, "/images/"+id+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	s.images.invalidate(id)
+	return nil
+}
+
+// inspectImageForAction inspects the image of the {id} path value and lists
+// the containers using it, writing an error response and returning false if
+// that fails.
+func (s *Server) inspectImageForAction(w http.ResponseWriter, r *http.Request) (ImageInspect, []Container, bool) {
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid image ID", http.StatusBadRequest)
+		return ImageInspect{}, nil, false
+	}
+	img, err := s.inspectImage(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Image Not Found", http.StatusNotFound)
+			return img, nil, false
+		}
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return img, nil, false
+	}
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return img, nil, false
+	}
+	return img, containersUsingImage(list, img.ID), true
+}
+
+// imageName returns the first tag of img, or its short ID if it has none.
+func imageName(img ImageInspect) string {
+	if len(img.RepoTags) > 0 && img.RepoTags[0] != "" {
+		return img.RepoTags[0]
+	}
+	return shortID(img.ID)
+}
+
+func (s *Server) handleImageRemoveConfirm(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	img, usedBy, ok := s.inspectImageForAction(w, r)
+	if !ok {
+		return
+	}
+	s.render(w, r, "image-remove.html", map[string]any{
+		"Title":  "Remove " + imageName(img),
+		"Name":   imageName(img),
+		"Image":  img,
+		"UsedBy": usedBy,
+	})
+}
+
+func (s *Server) handleImageRemove(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	if r.FormValue("confirm") != "yes" {
+		http.Error(w, "Confirm the removal on the confirmation page", http.StatusBadRequest)
+		return
+	}
+	img, usedBy, ok := s.inspectImageForAction(w, r)
+	if !ok {
+		return
+	}
+	force := r.FormValue("force") == "yes"
+	if len(usedBy) > 0 {
+		if !force {
+			http.Error(w, "The image is used by containers; acknowledge removing them to force the removal", http.StatusConflict)
+			return
+		}
+		// Only remove the containers that were shown.
+		acked := r.Form["container"]
+		for _, c := range usedBy {
+			if !slices.Contains(acked, c.ID) {
+				http.Error(w, "The containers using the image changed; review them again", http.StatusConflict)
+				return
+			}
+		}
+	}
+	err := s.removeImage(r.Context(), img.ID, force)
+	target := imageName(img)
+	if len(usedBy) > 0 {
+		target += fmt.Sprintf(" (and %d container(s))", len(usedBy))
+	}
+	s.actions.record(r, "remove image", target, err, "", nil)
+	if err != nil {
+		log.Printf("[%s] remove image %s: %v", reqID(r.Context()), imageName(img), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, s.basePath+"/images", http.StatusSeeOther)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestContainerVolumes(t *testing.T) {
+	t.Parallel()
+	anon := strings.Repeat("ab", 32)
+	got := containerVolumes(ContainerInspect{Mounts: []Mount{
+		{Type: "volume", Name: "data", Destination: "/data"},
+		{Type: "volume", Name: anon, Destination: "/cache"},
+		{Type: "bind", Source: "/srv", Destination: "/srv"},
+	}})
+	if len(got) != 2 || got[0].Anonymous || !got[1].Anonymous || got[1].Destination != "/cache" {
+		t.Errorf("containerVolumes = %+v", got)
+	}
+}
+
+func TestContainerRemove(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var deletes []string
+	running := true
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodDelete {
+			deletes = append(deletes, strings.TrimPrefix(r.URL.RequestURI(), "/v4.0.0/libpod"))
+			w.Write([]byte(`[]`))
+			return
+		}
+		json.NewEncoder(w).Encode(ContainerInspect{
+			ID:     "abc123",
+			Name:   "worker",
+			State:  ContainerState{Status: "running", Running: running},
+			Mounts: []Mount{{Type: "volume", Name: strings.Repeat("0f", 32), Destination: "/tmp/work"}},
+		})
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/container/abc123/remove")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{"/tmp/work", "(anonymous)", "is stopped first"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("confirmation page lacks %q", want)
+		}
+	}
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	for _, tt := range []struct {
+		form url.Values
+		want int
+	}{
+		{url.Values{"force": {"yes"}}, http.StatusBadRequest}, // not confirmed
+		{url.Values{"confirm": {"yes"}}, http.StatusConflict}, // running, not forced
+		{url.Values{"confirm": {"yes"}, "force": {"yes"}, "volumes": {"yes"}}, http.StatusSeeOther},
+	} {
+		resp, err := noRedirect.PostForm(app.URL+"/container/abc123/remove", tt.form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%v: status = %d, want %d", tt.form, resp.StatusCode, tt.want)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(deletes) != 1 || deletes[0] != "/containers/abc123?force=true&v=true" {
+		t.Errorf("deletes = %v", deletes)
+	}
+	if got := s.actions.list(); len(got) != 1 || got[0].Target != "worker (with anonymous volumes)" {
+		t.Errorf("actions = %+v", got)
+	}
+}
+
+func TestImageRemove(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var deletes []string
+	imageID := strings.Repeat("12", 32)
+	users := []Container{{ID: "abc123", Names: []string{"worker"}, State: "exited", ImageID: imageID}}
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		p := strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod")
+		switch {
+		case r.Method == http.MethodDelete:
+			deletes = append(deletes, strings.TrimPrefix(r.URL.RequestURI(), "/v4.0.0/libpod"))
+			w.Write([]byte(`{"Deleted":["` + imageID + `"]}`))
+		case p == "/containers/json":
+			json.NewEncoder(w).Encode(users)
+		default:
+			json.NewEncoder(w).Encode(ImageInspect{ID: imageID, RepoTags: []string{"tool:1"}})
+		}
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/image/" + imageID + "/remove")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{">worker<", "Also remove the 1 container(s) above", `name="container" value="abc123"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("confirmation page lacks %q", want)
+		}
+	}
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	post := func(form url.Values) int {
+		t.Helper()
+		resp, err := noRedirect.PostForm(app.URL+"/image/"+imageID+"/remove", form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, tt := range []struct {
+		form url.Values
+		want int
+	}{
+		{url.Values{"force": {"yes"}}, http.StatusBadRequest},                   // not confirmed
+		{url.Values{"confirm": {"yes"}}, http.StatusConflict},                   // used, not acknowledged
+		{url.Values{"confirm": {"yes"}, "force": {"yes"}}, http.StatusConflict}, // container not shown
+		{url.Values{"confirm": {"yes"}, "force": {"yes"}, "container": {"abc123"}}, http.StatusSeeOther},
+	} {
+		if got := post(tt.form); got != tt.want {
+			t.Errorf("%v: status = %d, want %d", tt.form, got, tt.want)
+		}
+	}
+
+	mu.Lock()
+	users = nil
+	mu.Unlock()
+	if got := post(url.Values{"confirm": {"yes"}}); got != http.StatusSeeOther {
+		t.Errorf("unused image: status = %d", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"/images/" + imageID + "?force=true", "/images/" + imageID + "?force=false"}
+	if !slices.Equal(deletes, want) {
+		t.Errorf("deletes = %v, want %v", deletes, want)
+	}
+	if got := s.actions.list(); len(got) != 2 || got[1].Target != "tool:1 (and 1 container(s))" {
+		t.Errorf("actions = %+v", got)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scheduleLabel holds the daily windows during which a container should be
+// stopped, e.g. "01:00-07:00" or "01:00-07:00,12:00-13:00", in
+// SCHEDULE_TIMEZONE.
+// A window may span midnight ("22:00-06:00").
+const scheduleLabel = "ch.jo-m.go.podfather.schedule.stop"
+
+// restartScheduleLabel holds a cron expression (see parseCron) on which a
+// running container is restarted, in SCHEDULE_TIMEZONE. Like scheduled
+// jobs, restarts do not happen on SCHEDULE_SKIP_DATES.
+const restartScheduleLabel = "ch.jo-m.go.podfather.restart-schedule"
+
+// stopWindow is a daily time window, in minutes since midnight.
+type stopWindow struct {
+	start, end int
+}
+
+func (w stopWindow) contains(minute int) bool {
+	if w.start <= w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// parseSchedule parses a comma-separated list of HH:MM-HH:MM windows.
+func parseSchedule(s string) ([]stopWindow, error) {
+	var windows []stopWindow
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		from, to, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid window %q, want HH:MM-HH:MM", part)
+		}
+		start, err := parseClock(from)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(to)
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, fmt.Errorf("empty window %q", part)
+		}
+		windows = append(windows, stopWindow{start, end})
+	}
+	return windows, nil
+}
+
+// parseClock parses HH:MM into minutes since midnight.
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	hh, err1 := strconv.Atoi(h)
+	mm, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hh < 0 || hh > 23 || mm < 0 || mm > 59 {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return hh*60 + mm, nil
+}
+
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// ScheduleStatus describes a container's schedule at a point in time.
+type ScheduleStatus struct {
+	Spec     string
+	Err      string
+	Stopped  bool      // inside a stop window
+	Override bool      // inside a stop window, but running
+	Next     time.Time // next window start or end
+}
+
+// scheduleStatus returns the schedule status of c at now, or nil if c has no
+// schedule.
+func scheduleStatus(c Container, now time.Time) *ScheduleStatus {
+	spec, ok := c.Labels[scheduleLabel]
+	if !ok {
+		return nil
+	}
+	st := &ScheduleStatus{Spec: spec}
+	windows, err := parseSchedule(spec)
+	if err != nil {
+		st.Err = err.Error()
+		return st
+	}
+	st.Stopped = inStopWindow(windows, now)
+	st.Override = st.Stopped && c.State == "running"
+	// Scan ahead minute by minute for the next transition; at most a day.
+	t := now.Truncate(time.Minute)
+	for range 24 * 60 {
+		t = t.Add(time.Minute)
+		if inStopWindow(windows, t) != st.Stopped {
+			st.Next = t
+			break
+		}
+	}
+	return st
+}
+
+// NextClock formats Next as HH:MM.
+func (st *ScheduleStatus) NextClock() string {
+	return st.Next.Format("15:04")
+}
+
+// RestartScheduleStatus describes a container's restart schedule.
+type RestartScheduleStatus struct {
+	Spec string
+	Err  string
+	Next time.Time
+
+	spec cronSpec
+}
+
+// restartScheduleStatus returns the restart schedule of c at now, or nil if
+// c has none.
+func (cal *calendar) restartScheduleStatus(c Container, now time.Time) *RestartScheduleStatus {
+	text, ok := c.Labels[restartScheduleLabel]
+	if !ok {
+		return nil
+	}
+	st := &RestartScheduleStatus{Spec: text}
+	spec, err := parseCron(text)
+	if err != nil {
+		st.Err = err.Error()
+		return st
+	}
+	st.spec = spec
+	st.Next = cal.next(spec, now)
+	return st
+}
+
+func inStopWindow(windows []stopWindow, t time.Time) bool {
+	m := minuteOfDay(t)
+	for _, w := range windows {
+		if w.contains(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduler stops and starts containers according to their schedule label.
+// It only acts when a container enters or leaves a stop window, so a
+// container started or stopped by hand stays that way until the next
+// transition. On the first check after startup, containers inside a stop
+// window are stopped, but none are started.
+// It also restarts running containers on their restart schedule.
+type scheduler struct {
+	s *Server
+
+	mu        sync.Mutex
+	last      map[string]bool      // container ID -> inside stop window at last check
+	restarted map[string]time.Time // container ID -> minute of the last scheduled restart
+}
+
+func (sc *scheduler) run(ctx context.Context) {
+	for {
+		sc.check(ctx, sc.s.calendar.now())
+		next := time.Now().Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+func (sc *scheduler) check(ctx context.Context, now time.Time) {
+	list, err := sc.s.listContainers(ctx)
+	if err != nil {
+		log.Printf("scheduler: %v", err)
+		return
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.last == nil {
+		sc.last, sc.restarted = map[string]bool{}, map[string]time.Time{}
+	}
+	sc.restart(ctx, list, now)
+	seen := map[string]bool{}
+	for _, c := range list {
+		st := scheduleStatus(c, now)
+		// Invalid schedules are flagged on the app tiles.
+		if st == nil || st.Err != "" {
+			continue
+		}
+		seen[c.ID] = true
+		prev, known := sc.last[c.ID]
+		sc.last[c.ID] = st.Stopped
+		if known && prev == st.Stopped {
+			continue
+		}
+		switch {
+		case st.Stopped && c.State == "running":
+			sc.act(ctx, c, "stop", "start")
+		case !st.Stopped && known && c.State != "running":
+			sc.act(ctx, c, "start", "stop")
+		}
+	}
+	for id := range sc.last {
+		if !seen[id] {
+			delete(sc.last, id)
+		}
+	}
+}
+
+// restart restarts the running containers in list whose restart schedule
+// matches the minute of now, once per minute.
+func (sc *scheduler) restart(ctx context.Context, list []Container, now time.Time) {
+	minute := now.Truncate(time.Minute)
+	seen := map[string]bool{}
+	for _, c := range list {
+		st := sc.s.calendar.restartScheduleStatus(c, now)
+		// Invalid schedules are flagged on /schedules.
+		if st == nil || st.Err != "" {
+			continue
+		}
+		seen[c.ID] = true
+		if c.State != "running" || !st.spec.matches(now) || sc.s.calendar.skipped(now) || sc.restarted[c.ID].Equal(minute) {
+			continue
+		}
+		sc.restarted[c.ID] = minute
+		err := sc.s.podmanPost(ctx, "/containers/"+c.ID+"/restart")
+		sc.s.actions.add("-", "scheduler", "restart", firstName(c.Names), err, "", nil)
+	}
+	for id := range sc.restarted {
+		if !seen[id] {
+			delete(sc.restarted, id)
+		}
+	}
+}
+
+func (sc *scheduler) act(ctx context.Context, c Container, name, inverse string) {
+	err := sc.s.podmanPost(ctx, "/containers/"+c.ID+"/"+name)
+	undo := func(ctx context.Context) error {
+		return sc.s.podmanPost(ctx, "/containers/"+c.ID+"/"+inverse)
+	}
+	sc.s.actions.add("-", "scheduler", name, firstName(c.Names), err, inverse, undo)
+}
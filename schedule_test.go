@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []stopWindow
+		wantErr bool
+	}{
+		{"01:00-07:00", []stopWindow{{60, 420}}, false},
+		{"22:00-06:30, 12:00-13:00", []stopWindow{{1320, 390}, {720, 780}}, false},
+		{"01:00", nil, true},
+		{"25:00-07:00", nil, true},
+		{"01:00-01:00", nil, true},
+		{"1:x-2:00", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSchedule(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSchedule(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseSchedule(%q) = %v, want %v", tt.spec, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseSchedule(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestScheduleStatus(t *testing.T) {
+	at := func(hm string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", "2026-03-01 "+hm, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	c := Container{State: "running", Labels: map[string]string{scheduleLabel: "22:00-06:00"}}
+
+	st := scheduleStatus(c, at("21:30"))
+	if st.Stopped || st.Override || st.NextClock() != "22:00" {
+		t.Errorf("21:30: %+v, want awake until 22:00", st)
+	}
+	st = scheduleStatus(c, at("23:15"))
+	if !st.Stopped || !st.Override || st.NextClock() != "06:00" {
+		t.Errorf("23:15: %+v, want override until 06:00", st)
+	}
+	c.State = "exited"
+	if st := scheduleStatus(c, at("03:00")); !st.Stopped || st.Override {
+		t.Errorf("03:00: %+v, want stopped", st)
+	}
+
+	if st := scheduleStatus(Container{}, at("03:00")); st != nil {
+		t.Errorf("no label: %+v, want nil", st)
+	}
+	c.Labels[scheduleLabel] = "soon"
+	if st := scheduleStatus(c, at("03:00")); st.Err == "" {
+		t.Error("invalid label: want Err")
+	}
+}
+
+func TestSchedulerCheck(t *testing.T) {
+	containers := []Container{
+		{ID: "game", Names: []string{"game"}, State: "running", Labels: map[string]string{scheduleLabel: "01:00-07:00"}},
+		{ID: "web", Names: []string{"web"}, State: "running"},
+	}
+	var mu sync.Mutex
+	var posts []string
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPost {
+			posts = append(posts, strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod"))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(containers)
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	sc := &scheduler{s: s}
+	at := func(hm string) time.Time {
+		ts, _ := time.ParseInLocation("15:04", hm, time.Local)
+		return ts
+	}
+	check := func(hm, state string, want ...string) {
+		t.Helper()
+		mu.Lock()
+		containers[0].State = state
+		posts = nil
+		mu.Unlock()
+		sc.check(t.Context(), at(hm))
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.Join(posts, " ") != strings.Join(want, " ") {
+			t.Errorf("%s (%s): posts = %v, want %v", hm, state, posts, want)
+		}
+	}
+
+	check("03:00", "running", "/containers/game/stop") // stopped at startup
+	check("03:01", "exited")
+	check("04:00", "running") // manual override stays
+	check("07:00", "running") // window ends, already running
+	check("12:00", "exited")  // stopped by hand, stays stopped
+	check("01:00", "exited")
+	check("07:00", "exited", "/containers/game/start")
+
+	if got := s.actions.list(); len(got) != 2 || got[0].Client != "scheduler" || !got[0].CanUndo() {
+		t.Errorf("actions = %+v, want 2 undoable scheduler actions", got)
+	}
+}
+
+func TestSchedulerRestart(t *testing.T) {
+	containers := []Container{
+		{ID: "db", Names: []string{"db"}, State: "running", Labels: map[string]string{restartScheduleLabel: "30 4 * * *"}},
+		{ID: "cache", Names: []string{"cache"}, State: "exited", Labels: map[string]string{restartScheduleLabel: "30 4 * * *"}},
+		{ID: "bad", Names: []string{"bad"}, State: "running", Labels: map[string]string{restartScheduleLabel: "nightly"}},
+	}
+	var mu sync.Mutex
+	var posts []string
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPost {
+			posts = append(posts, strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod"))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(containers)
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	dates, _ := parseSkipDates("12-25")
+	s.calendar = &calendar{loc: time.UTC, skip: dates}
+	sc := &scheduler{s: s}
+	check := func(at string, want ...string) {
+		t.Helper()
+		mu.Lock()
+		posts = nil
+		mu.Unlock()
+		ts, _ := time.Parse("2006-01-02 15:04:05", at)
+		sc.check(t.Context(), ts)
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.Join(posts, " ") != strings.Join(want, " ") {
+			t.Errorf("%s: posts = %v, want %v", at, posts, want)
+		}
+	}
+
+	check("2026-10-16 04:29:00")
+	check("2026-10-16 04:30:00", "/containers/db/restart")
+	check("2026-10-16 04:30:40") // same minute
+	check("2026-10-16 04:31:00")
+	check("2026-12-25 04:30:00") // skipped date
+
+	if got := s.actions.list(); len(got) != 1 || got[0].Name != "restart" || got[0].Target != "db" {
+		t.Errorf("actions = %+v, want 1 scheduled restart", got)
+	}
+	if st := s.calendar.restartScheduleStatus(containers[2], time.Now()); st.Err == "" {
+		t.Error("invalid label: want Err")
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net"
+	"os"
+)
+
+// sdNotify sends a state such as "READY=1" to the service manager if
+// NOTIFY_SOCKET is set (systemd Type=notify units, podman --sdnotify). It is
+// a no-op otherwise.
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	// A leading @ denotes an abstract socket.
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
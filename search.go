@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// /images/search queries the registries configured for Podman (or Docker
+// Hub) for images, so that adding a service can start with finding and
+// pulling its image in the dashboard.
+
+// maxSearchResults bounds the results of a registry search.
+const maxSearchResults = 50
+
+// SearchResult is an image found by a registry search.
+type SearchResult struct {
+	Name        string
+	Description string
+	Stars       int
+	Official    bool
+	Automated   bool
+}
+
+// searchImages searches the registries for term. The compat API returns
+// Docker Hub's field names.
+func (s *Server) searchImages(ctx context.Context, term string) ([]SearchResult, error) {
+	path := "/images/search?" + url.Values{"term": {term}, "limit": {strconv.Itoa(maxSearchResults)}}.Encode()
+	var out []SearchResult
+	if s.backend == backendDocker {
+		var list []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Stars       int    `json:"star_count"`
+			Official    bool   `json:"is_official"`
+			Automated   bool   `json:"is_automated"`
+		}
+		if err := s.podmanGet(ctx, path, &list); err != nil {
+			return nil, err
+		}
+		for _, r := range list {
+			out = append(out, SearchResult{Name: r.Name, Description: r.Description, Stars: r.Stars, Official: r.Official, Automated: r.Automated})
+		}
+	} else {
+		var list []struct {
+			Name        string `json:"Name"`
+			Description string `json:"Description"`
+			Stars       int    `json:"Stars"`
+			Official    string `json:"Official"` // "[OK]" or ""
+			Automated   string `json:"Automated"`
+		}
+		if err := s.podmanGet(ctx, path, &list); err != nil {
+			return nil, err
+		}
+		for _, r := range list {
+			out = append(out, SearchResult{Name: r.Name, Description: r.Description, Stars: r.Stars, Official: r.Official != "", Automated: r.Automated != ""})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Official != out[j].Official {
+			return out[i].Official
+		}
+		return out[i].Stars > out[j].Stars
+	})
+	return out, nil
+}
+
+func (s *Server) handleImageSearch(w http.ResponseWriter, r *http.Request) {
+	term := strings.TrimSpace(r.URL.Query().Get("term"))
+	if len(term) > 256 {
+		http.Error(w, "Search term too long", http.StatusBadRequest)
+		return
+	}
+	data := map[string]any{
+		"Title": "Search Images",
+		"Term":  term,
+	}
+	if term != "" {
+		results, err := s.searchImages(r.Context(), term)
+		if err != nil {
+			// Usually an unreachable registry, show it with the form.
+			log.Printf("[%s] image search %q: %v", reqID(r.Context()), term, err)
+			data["Failed"] = true
+		}
+		data["Results"] = results
+	}
+	s.render(w, r, "images-search.html", data)
+}
+
+// handleImagePull pulls an image, e.g. a search result, in a job.
+func (s *Server) handleImagePull(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	ref := r.FormValue("ref")
+	if len(ref) > 512 || !validImageRef.MatchString(ref) {
+		http.Error(w, "Invalid image reference", http.StatusBadRequest)
+		return
+	}
+	s.actions.record(r, "pull", ref, nil, "", nil)
+	j := s.jobs.start("Pull " + ref)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+		defer cancel()
+		j.logf("==> Pulling %s", ref)
+		err := s.pullImage(ctx, ref, j)
+		if err == nil {
+			j.logf("==> Pulled %s", ref)
+		}
+		j.finish(err)
+	}()
+	http.Redirect(w, r, s.basePath+"/job/"+j.ID, http.StatusSeeOther)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSearchImages(t *testing.T) {
+	t.Parallel()
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("term") != "nginx" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		if strings.HasPrefix(r.URL.Path, "/v4.0.0/libpod/") {
+			w.Write([]byte(`[{"Name":"docker.io/bitnami/nginx","Stars":10},{"Name":"docker.io/library/nginx","Description":"Official build of Nginx.","Stars":5,"Official":"[OK]"}]`))
+			return
+		}
+		w.Write([]byte(`[{"name":"nginx","star_count":5,"is_official":true},{"name":"bitnami/nginx","star_count":10,"is_automated":true}]`))
+	}))
+	defer mock.Close()
+
+	s := newTestServer(t, mock)
+	got, err := s.searchImages(context.Background(), "nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Official images first.
+	if len(got) != 2 || got[0].Name != "docker.io/library/nginx" || !got[0].Official || got[1].Official {
+		t.Errorf("libpod results = %+v", got)
+	}
+
+	s.backend, s.podmanBaseURL = backendDocker, mock.URL+"/v1.41"
+	got, err = s.searchImages(context.Background(), "nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Name != "nginx" || !got[1].Automated || got[1].Stars != 10 {
+		t.Errorf("compat results = %+v", got)
+	}
+}
+
+func TestImageSearchPull(t *testing.T) {
+	t.Parallel()
+	var pulled string
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod") {
+		case "/images/search":
+			w.Write([]byte(`[{"Name":"docker.io/library/nginx","Description":"Official build of Nginx.","Stars":5,"Official":"[OK]"}]`))
+		case "/images/pull":
+			pulled = r.URL.Query().Get("reference")
+			w.Write([]byte(`{"stream":"Writing manifest to image destination"}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/images/search?term=nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{"Official build of Nginx.", "official", `name="ref" value="docker.io/library/nginx"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("results lack %q", want)
+		}
+	}
+
+	resp, err = http.PostForm(app.URL+"/images/pull", url.Values{"ref": {"docker.io/library/nginx; rm"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid ref: status = %d", resp.StatusCode)
+	}
+	resp, err = http.PostForm(app.URL+"/images/pull", url.Values{"ref": {"docker.io/library/nginx"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	id, ok := strings.CutPrefix(resp.Request.URL.Path, "/job/")
+	if !ok {
+		t.Fatalf("redirected to %s, want a job", resp.Request.URL.Path)
+	}
+	j := s.jobs.get(id)
+	waitJob(t, j)
+	if out, _, errMsg := j.read(0); j.Status() != "done" || !strings.Contains(string(out), "Writing manifest") {
+		t.Errorf("status = %s (err %q), output:\n%s", j.Status(), errMsg, out)
+	}
+	if pulled != "docker.io/library/nginx" {
+		t.Errorf("pulled %q", pulled)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// /security audits the settings of all containers that weaken isolation
+// from the host. Like /system/problems it only reports; rootless Podman
+// maps root in a container to the user running it, so the findings are
+// less severe there, but still worth a look.
+
+// Security checks, in the order they are shown.
+const (
+	checkPrivileged  = "privileged"
+	checkHostNetwork = "host network"
+	checkHostPath    = "sensitive mount"
+	checkRootUser    = "root user"
+	checkWritable    = "writable rootfs"
+)
+
+var securityChecks = []string{checkPrivileged, checkHostNetwork, checkHostPath, checkRootUser, checkWritable}
+
+// sensitiveHostPaths are host directories whose bind mounts give a
+// container control over the host. Mounting "/" itself or anything below
+// the others is flagged.
+var sensitiveHostPaths = []string{"/etc", "/var/run", "/run", "/proc", "/sys", "/dev", "/boot", "/root"}
+
+// SecurityFinding is a check a container fails.
+type SecurityFinding struct {
+	Check  string
+	Detail string
+	Path   string // the host path of a sensitive mount
+}
+
+// SecurityRow is a container with its findings.
+type SecurityRow struct {
+	Container ContainerInspect
+	Findings  []SecurityFinding
+}
+
+// sensitiveHostPath reports whether mounting the host path src is flagged.
+func sensitiveHostPath(src string) bool {
+	if src == "/" {
+		return true
+	}
+	return slices.ContainsFunc(sensitiveHostPaths, func(p string) bool {
+		return src == p || strings.HasPrefix(src, p+"/")
+	})
+}
+
+// rootUser reports whether a container's user, as "user[:group]", is root.
+// The inspect data includes the image's USER, so an empty user is root.
+func rootUser(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "" || name == "root" || name == "0"
+}
+
+// securityFindings returns the checks c fails, in securityChecks order.
+func securityFindings(c ContainerInspect) []SecurityFinding {
+	var out []SecurityFinding
+	hc := c.HostConfig
+	if hc == nil {
+		hc = &HostConfig{}
+	}
+	if hc.Privileged {
+		out = append(out, SecurityFinding{Check: checkPrivileged, Detail: "all capabilities and host devices"})
+	}
+	if hc.NetworkMode == "host" {
+		out = append(out, SecurityFinding{Check: checkHostNetwork, Detail: "shares the host's network stack"})
+	}
+	for _, m := range c.Mounts {
+		if m.Type == "bind" && sensitiveHostPath(m.Source) {
+			f := SecurityFinding{Check: checkHostPath, Detail: "mounted read-only", Path: m.Source}
+			if m.RW {
+				f.Detail = "mounted read-write"
+			}
+			out = append(out, f)
+		}
+	}
+	if rootUser(c.Config.User) {
+		out = append(out, SecurityFinding{Check: checkRootUser, Detail: cmp.Or(c.Config.User, "no user set")})
+	}
+	if !hc.ReadonlyRootfs {
+		out = append(out, SecurityFinding{Check: checkWritable, Detail: "root filesystem is not read-only"})
+	}
+	return out
+}
+
+// securityRows returns the containers with their findings, most findings
+// first, and the number of containers failing each check.
+func securityRows(list []ContainerInspect) ([]SecurityRow, map[string]int) {
+	counts := map[string]int{}
+	rows := make([]SecurityRow, 0, len(list))
+	for _, c := range list {
+		findings := securityFindings(c)
+		seen := map[string]bool{}
+		for _, f := range findings {
+			if !seen[f.Check] {
+				counts[f.Check]++
+				seen[f.Check] = true
+			}
+		}
+		rows = append(rows, SecurityRow{Container: c, Findings: findings})
+	}
+	slices.SortStableFunc(rows, func(a, b SecurityRow) int {
+		return cmp.Or(cmp.Compare(len(b.Findings), len(a.Findings)), strings.Compare(a.Container.Name, b.Container.Name))
+	})
+	return rows, counts
+}
+
+func (s *Server) handleSecurity(w http.ResponseWriter, r *http.Request) {
+	list, err := s.inspectAllContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	rows, counts := securityRows(list)
+	s.render(w, r, "security.html", map[string]any{
+		"Title":  "Security",
+		"Rows":   rows,
+		"Checks": securityChecks,
+		"Counts": counts,
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSecurityFindings(t *testing.T) {
+	t.Parallel()
+	checks := func(c ContainerInspect) []string {
+		var out []string
+		for _, f := range securityFindings(c) {
+			out = append(out, f.Check+":"+f.Path)
+		}
+		return out
+	}
+	hardened := ContainerInspect{
+		Config:     ContainerConfig{User: "1000:1000"},
+		HostConfig: &HostConfig{NetworkMode: "bridge", ReadonlyRootfs: true},
+		Mounts: []Mount{
+			{Type: "bind", Source: "/srv/data", Destination: "/data", RW: true},
+			{Type: "bind", Source: "/etcetera", Destination: "/x"},
+			{Type: "volume", Source: "/var/lib/containers/storage/volumes/db/_data", Destination: "/db"},
+		},
+	}
+	if got := checks(hardened); len(got) != 0 {
+		t.Errorf("hardened container: %q", got)
+	}
+
+	loose := ContainerInspect{
+		HostConfig: &HostConfig{Privileged: true, NetworkMode: "host"},
+		Mounts: []Mount{
+			{Type: "bind", Source: "/", Destination: "/host"},
+			{Type: "bind", Source: "/run/podman/podman.sock", Destination: "/run/docker.sock", RW: true},
+			{Type: "bind", Source: "/etc", Destination: "/host-etc"},
+		},
+	}
+	want := []string{"privileged:", "host network:", "sensitive mount:/", "sensitive mount:/run/podman/podman.sock", "sensitive mount:/etc", "root user:", "writable rootfs:"}
+	if got := checks(loose); !slices.Equal(got, want) {
+		t.Errorf("loose container: %q, want %q", got, want)
+	}
+
+	for user, root := range map[string]bool{"": true, "root": true, "0:0": true, "root:wheel": true, "1000": false, "nobody": false, "app:0": false} {
+		if rootUser(user) != root {
+			t.Errorf("rootUser(%q) = %v", user, !root)
+		}
+	}
+}
+
+func TestHandleSecurity(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	app := httptest.NewServer(newTestServer(t, mock).newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/security")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	for _, want := range []string{"<h1>Security</h1>", ">writable rootfs</span>", "/container/"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("security page lacks %q", want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Stacks are the containers of a Compose project, as labelled by docker
+// compose and podman-compose. They are shown together on /stacks, which
+// helps when migrating from Compose, and can be restarted at once.
+
+// composeProjectLabels name the project of a container, docker compose first.
+var composeProjectLabels = []string{"com.docker.compose.project", "io.podman.compose.project"}
+
+// composeServiceLabel names the service of a container. podman-compose sets
+// it too.
+const composeServiceLabel = "com.docker.compose.service"
+
+// Stack is a Compose project.
+type Stack struct {
+	Name       string
+	Containers []Container // in creation order
+	Running    int
+}
+
+// State is "running" if all containers of the stack run, "down" if none
+// does and "degraded" otherwise.
+func (st Stack) State() string {
+	switch st.Running {
+	case len(st.Containers):
+		return "running"
+	case 0:
+		return "down"
+	}
+	return "degraded"
+}
+
+// composeProject returns the Compose project of c, or "".
+func composeProject(c Container) string {
+	for _, l := range composeProjectLabels {
+		if p := c.Labels[l]; p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// buildStacks groups the containers of Compose projects, sorted by name.
+func buildStacks(list []Container) []Stack {
+	byName := map[string]*Stack{}
+	for _, c := range list {
+		name := composeProject(c)
+		if name == "" {
+			continue
+		}
+		st := byName[name]
+		if st == nil {
+			st = &Stack{Name: name}
+			byName[name] = st
+		}
+		st.Containers = append(st.Containers, c)
+		if c.State == "running" {
+			st.Running++
+		}
+	}
+	stacks := make([]Stack, 0, len(byName))
+	for _, st := range byName {
+		sort.SliceStable(st.Containers, func(i, j int) bool {
+			return st.Containers[i].Created.Before(st.Containers[j].Created)
+		})
+		stacks = append(stacks, *st)
+	}
+	sort.Slice(stacks, func(i, j int) bool { return stacks[i].Name < stacks[j].Name })
+	return stacks
+}
+
+func (s *Server) handleStacks(w http.ResponseWriter, r *http.Request) {
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.render(w, r, "stacks.html", map[string]any{
+		"Title":        "Stacks",
+		"Stacks":       buildStacks(list),
+		"ServiceLabel": composeServiceLabel,
+	})
+}
+
+// handleStackRestart restarts the containers of a stack one by one in
+// creation order, which Compose derives from the dependencies, and stops at
+// the first failure. Stopped containers are started.
+func (s *Server) handleStackRestart(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	name := r.PathValue("name")
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var stack *Stack
+	for _, st := range buildStacks(list) {
+		if st.Name == name {
+			stack = &st
+			break
+		}
+	}
+	if stack == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+	for _, c := range stack.Containers {
+		if err = s.podmanPost(ctx, "/containers/"+c.ID+"/restart"); err != nil {
+			log.Printf("[%s] restart stack %s: %s: %v", reqID(r.Context()), name, firstName(c.Names), err)
+			break
+		}
+	}
+	s.actions.record(r, "restart stack", name, err, "", nil)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, s.basePath+"/stacks", http.StatusSeeOther)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testStackContainers = []Container{
+	{ID: "ccc333", Names: []string{"blog_web_1"}, State: "exited", Created: time.Unix(300, 0),
+		Labels: map[string]string{"io.podman.compose.project": "blog", composeServiceLabel: "web"}},
+	{ID: "bbb222", Names: []string{"blog_db_1"}, State: "running", Created: time.Unix(200, 0),
+		Labels: map[string]string{"io.podman.compose.project": "blog", composeServiceLabel: "db"}},
+	{ID: "aaa111", Names: []string{"wiki-app-1"}, State: "running", Created: time.Unix(100, 0),
+		Labels: map[string]string{"com.docker.compose.project": "wiki", composeServiceLabel: "app"}},
+	{ID: "ddd444", Names: []string{"standalone"}, State: "running"},
+}
+
+func TestBuildStacks(t *testing.T) {
+	t.Parallel()
+	stacks := buildStacks(testStackContainers)
+	if len(stacks) != 2 || stacks[0].Name != "blog" || stacks[1].Name != "wiki" {
+		t.Fatalf("buildStacks = %+v", stacks)
+	}
+	if got := stacks[0].Containers; got[0].ID != "bbb222" || got[1].ID != "ccc333" {
+		t.Errorf("blog containers not in creation order: %+v", got)
+	}
+	if stacks[0].State() != "degraded" || stacks[1].State() != "running" {
+		t.Errorf("states = %s, %s", stacks[0].State(), stacks[1].State())
+	}
+	if st := (Stack{Containers: []Container{{}}}); st.State() != "down" {
+		t.Errorf("state = %s, want down", st.State())
+	}
+}
+
+func TestStacks(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var calls []string
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(testStackContainers)
+			return
+		}
+		mu.Lock()
+		calls = append(calls, strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/stacks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{"blog", "1 of 2 running", "wiki-app-1", "<td>db</td>", "/stack/blog/restart"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("stacks page lacks %q", want)
+		}
+	}
+	if strings.Contains(string(body), "standalone") {
+		t.Error("stacks page shows a container without a project")
+	}
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	for _, tt := range []struct {
+		name string
+		want int
+	}{
+		{"nope", http.StatusNotFound},
+		{"blog", http.StatusSeeOther},
+	} {
+		resp, err := noRedirect.PostForm(app.URL+"/stack/"+tt.name+"/restart", url.Values{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"/containers/bbb222/restart", "/containers/ccc333/restart"}; !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if a := s.actions.list(); len(a) != 1 || a[0].Name != "restart stack" || a[0].Target != "blog" {
+		t.Errorf("actions = %+v", a)
+	}
+}
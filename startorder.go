@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The start order recorder remembers in which order the running containers
+// were started, so that a host not fully managed by systemd can be brought
+// back up "like last time" after a reboot. The replay starts the containers
+// one by one and waits for each to become healthy before the next.
+//
+// The record is only replaced while none of the recorded containers is
+// stopped, so a reboot (or a partial outage) does not overwrite it with the
+// few containers that came up by themselves.
+
+const startOrderInterval = 5 * time.Minute
+
+// StartOrderEntry is a recorded container.
+type StartOrderEntry struct {
+	Name      string    `json:"name"`
+	StartedAt time.Time `json:"started_at"`
+	Requires  []string  `json:"requires,omitempty"` // names of the containers it depends on
+}
+
+// StartOrder is the recorded order, earliest start first.
+type StartOrder struct {
+	Recorded   time.Time         `json:"recorded"`
+	Containers []StartOrderEntry `json:"containers"`
+}
+
+type startOrderRecorder struct {
+	s    *Server
+	path string
+
+	mu    sync.Mutex
+	order StartOrder
+}
+
+func newStartOrderRecorder(s *Server) *startOrderRecorder {
+	r := &startOrderRecorder{s: s, path: filepath.Join(s.dataDir, "start-order.json")}
+	data, err := os.ReadFile(r.path)
+	if err == nil {
+		err = json.Unmarshal(data, &r.order)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("start order: loading %s: %v", r.path, err)
+	}
+	return r
+}
+
+func (r *startOrderRecorder) run(ctx context.Context) {
+	for {
+		if err := r.record(ctx, false); err != nil {
+			log.Printf("start order: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(startOrderInterval):
+		}
+	}
+}
+
+// current returns the recorded order.
+func (r *startOrderRecorder) current() StartOrder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.order
+}
+
+// record replaces the recorded order with the running containers. Unless
+// force is set, it keeps the old record while a recorded container exists
+// but is not running.
+func (r *startOrderRecorder) record(ctx context.Context, force bool) error {
+	list, err := r.s.listContainers(ctx)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !force && hasStoppedEntries(r.order, list) {
+		return nil
+	}
+	ids := map[string]string{}
+	for _, c := range list {
+		ids[c.ID] = firstName(c.Names)
+	}
+	var entries []StartOrderEntry
+	for _, c := range list {
+		if c.State != "running" {
+			continue
+		}
+		ci, err := r.s.inspectContainer(ctx, c.ID)
+		if errors.Is(err, errNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		e := StartOrderEntry{Name: ci.Name, StartedAt: ci.State.StartedAt}
+		for _, dep := range ci.Dependencies {
+			if name, ok := ids[dep]; ok {
+				dep = name
+			}
+			e.Requires = append(e.Requires, dep)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 && !force {
+		return nil
+	}
+	r.order = StartOrder{Recorded: time.Now(), Containers: sortStartOrder(entries)}
+	return r.save()
+}
+
+// save writes the record. Callers must hold mu.
+func (r *startOrderRecorder) save() error {
+	data, err := json.Marshal(r.order)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(r.path, data, 0o600)
+}
+
+// hasStoppedEntries reports whether a recorded container exists but is not
+// running.
+func hasStoppedEntries(order StartOrder, list []Container) bool {
+	for _, c := range list {
+		if c.State != "running" && slices.ContainsFunc(order.Containers, func(e StartOrderEntry) bool {
+			return slices.Contains(c.Names, e.Name)
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortStartOrder sorts entries by start time, moving containers after the
+// ones they require.
+func sortStartOrder(entries []StartOrderEntry) []StartOrderEntry {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].StartedAt.Before(entries[j].StartedAt) })
+	byName := map[string]StartOrderEntry{}
+	for _, e := range entries {
+		byName[e.Name] = e
+	}
+	out := make([]StartOrderEntry, 0, len(entries))
+	placed := map[string]bool{}
+	var place func(e StartOrderEntry)
+	place = func(e StartOrderEntry) {
+		if placed[e.Name] {
+			return
+		}
+		// Mark first, so dependency cycles terminate.
+		placed[e.Name] = true
+		for _, dep := range e.Requires {
+			if d, ok := byName[dep]; ok {
+				place(d)
+			}
+		}
+		out = append(out, e)
+	}
+	for _, e := range entries {
+		place(e)
+	}
+	return out
+}
+
+// replayStartOrder starts the recorded containers that are not running in
+// order, waiting for each to become healthy. It stops at the first
+// container that fails. Containers inside a scheduled stop window are
+// skipped.
+func (s *Server) replayStartOrder(ctx context.Context, j *job, order StartOrder) error {
+	now := s.calendar.now()
+	for i, e := range order.Containers {
+		step := fmt.Sprintf("[%d/%d] %s", i+1, len(order.Containers), e.Name)
+		c, err := s.inspectContainer(ctx, e.Name)
+		switch {
+		case errors.Is(err, errNotFound):
+			j.logf("==> %s: no longer exists, skipping", step)
+			continue
+		case err != nil:
+			return err
+		case c.State.Running:
+			j.logf("==> %s: already running", step)
+			continue
+		}
+		if st := scheduleStatus(Container{Labels: c.Config.Labels}, now); st != nil && st.Stopped {
+			j.logf("==> %s: in a scheduled stop window, skipping", step)
+			continue
+		}
+		j.logf("==> %s: starting", step)
+		if err := s.podmanPost(ctx, "/containers/"+c.ID+"/start"); err != nil {
+			return fmt.Errorf("start %s: %w", e.Name, err)
+		}
+		if err := s.waitHealthy(ctx, c.ID, c.Image); err != nil {
+			return err
+		}
+		j.logf("==> %s: up", step)
+	}
+	j.logf("==> All containers started")
+	return nil
+}
+
+func (s *Server) handleStartOrder(w http.ResponseWriter, r *http.Request) {
+	data := map[string]any{
+		"Title":    "Start Order",
+		"Disabled": s.startOrder == nil,
+	}
+	if s.startOrder != nil {
+		order := s.startOrder.current()
+		states := map[string]string{}
+		if list, err := s.listContainers(r.Context()); err != nil {
+			log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		} else {
+			for _, c := range list {
+				states[firstName(c.Names)] = c.State
+			}
+		}
+		data["Order"] = order
+		data["States"] = states
+	}
+	s.render(w, r, "startorder.html", data)
+}
+
+func (s *Server) handleStartOrderRecord(w http.ResponseWriter, r *http.Request) {
+	if s.startOrder == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	err := s.startOrder.record(r.Context(), true)
+	s.actions.record(r, "record start order", "all", err, "", nil)
+	if err != nil {
+		log.Printf("[%s] start order: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, s.basePath+"/start-order", http.StatusSeeOther)
+}
+
+func (s *Server) handleStartOrderReplay(w http.ResponseWriter, r *http.Request) {
+	if s.startOrder == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	order := s.startOrder.current()
+	if len(order.Containers) == 0 {
+		http.Error(w, "No start order recorded yet", http.StatusBadRequest)
+		return
+	}
+	j := s.jobs.start("Start containers like last time")
+	s.actions.record(r, "replay start order", "all", nil, "", nil)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		j.finish(s.replayStartOrder(ctx, j, order))
+	}()
+	http.Redirect(w, r, s.basePath+"/job/"+j.ID, http.StatusSeeOther)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSortStartOrder(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	entries := []StartOrderEntry{
+		{Name: "web", StartedAt: t0.Add(1 * time.Second), Requires: []string{"db", "cache"}},
+		{Name: "cache", StartedAt: t0.Add(3 * time.Second)},
+		{Name: "db", StartedAt: t0.Add(2 * time.Second)},
+		{Name: "a", StartedAt: t0, Requires: []string{"b"}},
+		{Name: "b", StartedAt: t0.Add(4 * time.Second), Requires: []string{"a"}}, // cycle
+	}
+	var got []string
+	for _, e := range sortStartOrder(entries) {
+		got = append(got, e.Name)
+	}
+	if want := "b,a,db,cache,web"; strings.Join(got, ",") != want {
+		t.Errorf("order = %v, want %s", got, want)
+	}
+}
+
+// newMockStartAPI mocks a host with the given containers (name -> state).
+// Starting a container named "bad*" makes it exit.
+func newMockStartAPI(t *testing.T, states map[string]string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var started []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		p := strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod")
+		name := strings.Split(strings.TrimPrefix(p, "/containers/"), "/")[0]
+		switch {
+		case p == "/containers/json":
+			var list []Container
+			for n, st := range states {
+				list = append(list, Container{ID: n, Names: []string{n}, State: st})
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.Method == http.MethodPost && strings.HasSuffix(p, "/start") && states[name] != "":
+			started = append(started, name)
+			states[name] = "running"
+			if strings.HasPrefix(name, "bad") {
+				states[name] = "exited"
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasSuffix(p, "/json") && states[name] != "":
+			deps := "[]"
+			if name == "web" {
+				deps = `["db"]`
+			}
+			fmt.Fprintf(w, `{"Id":%q,"Name":%q,"Image":"img","State":{"Status":%q,"Running":%t,"StartedAt":"2026-01-01T08:00:0%dZ"},"Dependencies":%s}`,
+				name, name, states[name], states[name] == "running", len(name), deps)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return srv, &started
+}
+
+func TestStartOrderRecord(t *testing.T) {
+	mock, _ := newMockStartAPI(t, map[string]string{"db": "running", "web": "running", "old": "exited"})
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.dataDir = t.TempDir()
+	rec := newStartOrderRecorder(s)
+	if err := rec.record(t.Context(), false); err != nil {
+		t.Fatal(err)
+	}
+	order := rec.current()
+	if len(order.Containers) != 2 || order.Containers[0].Name != "db" || order.Containers[1].Requires[0] != "db" {
+		t.Fatalf("order = %+v", order)
+	}
+
+	// After a "reboot" the record is kept, and loaded from disk.
+	mock2, _ := newMockStartAPI(t, map[string]string{"db": "exited", "web": "running"})
+	defer mock2.Close()
+	s2 := newTestServer(t, mock2)
+	s2.dataDir = s.dataDir
+	rec2 := newStartOrderRecorder(s2)
+	if err := rec2.record(t.Context(), false); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec2.current(); len(got.Containers) != 2 {
+		t.Errorf("record overwritten while db is stopped: %+v", got)
+	}
+	if err := rec2.record(t.Context(), true); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec2.current(); len(got.Containers) != 1 {
+		t.Errorf("forced record: %+v", got)
+	}
+}
+
+func TestReplayStartOrder(t *testing.T) {
+	order := StartOrder{Containers: []StartOrderEntry{{Name: "db"}, {Name: "gone"}, {Name: "web"}, {Name: "bad"}, {Name: "last"}}}
+	mock, started := newMockStartAPI(t, map[string]string{"db": "exited", "web": "running", "bad": "exited", "last": "exited"})
+	defer mock.Close()
+	s := newTestServer(t, mock)
+
+	j := s.jobs.start("test")
+	err := s.replayStartOrder(context.Background(), j, order)
+	if err == nil || !strings.Contains(err.Error(), "bad exited") {
+		t.Errorf("err = %v, want bad exited", err)
+	}
+	if got := strings.Join(*started, ","); got != "db,bad" {
+		t.Errorf("started = %s, want db,bad (stopping at the failure)", got)
+	}
+	out, _, _ := j.read(0)
+	for _, want := range []string{"[2/5] gone: no longer exists", "[3/5] web: already running"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Static assets are embedded and served from /static/ under names with a
+// hash of their content, e.g. style-1a2b3c4d.css, so browsers can cache them
+// for good and still fetch the new version after an upgrade. Only the theme
+// tokens, which depend on the request, stay inline in each page.
+
+// staticFiles are the files in templates/ served as assets, with their
+// content types.
+var staticFiles = map[string]string{
+	"style.css": "text/css; charset=utf-8",
+	"logo.svg":  "image/svg+xml",
+}
+
+type staticAsset struct {
+	data        []byte
+	contentType string
+}
+
+var staticNames, staticAssets = loadStaticAssets()
+
+// loadStaticAssets returns the hashed names of the static files by name,
+// and the assets by hashed name.
+func loadStaticAssets() (map[string]string, map[string]staticAsset) {
+	names := map[string]string{}
+	assets := map[string]staticAsset{}
+	for name, contentType := range staticFiles {
+		data, err := templateFS.ReadFile("templates/" + name)
+		if err != nil {
+			panic(err)
+		}
+		sum := sha256.Sum256(data)
+		ext := path.Ext(name)
+		hashed := strings.TrimSuffix(name, ext) + "-" + hex.EncodeToString(sum[:4]) + ext
+		names[name] = hashed
+		assets[hashed] = staticAsset{data: data, contentType: contentType}
+	}
+	return names, assets
+}
+
+// assetName returns the hashed name of a static file, for use in URLs.
+func assetName(name string) string {
+	return staticNames[name]
+}
+
+// styleCSS returns the stylesheet, for pages that must inline it.
+func styleCSS() template.CSS {
+	return template.CSS(staticAssets[staticNames["style.css"]].data)
+}
+
+func handleStatic(w http.ResponseWriter, r *http.Request) {
+	a, ok := staticAssets[r.PathValue("name")]
+	if !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", a.contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Write(a.data)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStaticAssets(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	css := assetName("style.css")
+	if !strings.HasPrefix(css, "style-") || !strings.HasSuffix(css, ".css") {
+		t.Fatalf("assetName(style.css) = %q", css)
+	}
+	resp, err := http.Get(app.URL + "/containers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{`href="/static/` + css + `"`, `src="/static/` + assetName("logo.svg") + `"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("page lacks %s", want)
+		}
+	}
+	if strings.Contains(string(body), "box-sizing") {
+		t.Error("page inlines the stylesheet")
+	}
+
+	for _, tt := range []struct {
+		name, contentType string
+		want              int
+	}{
+		{css, "text/css; charset=utf-8", http.StatusOK},
+		{assetName("logo.svg"), "image/svg+xml", http.StatusOK},
+		{"style.css", "", http.StatusNotFound},
+		{"style-00000000.css", "", http.StatusNotFound},
+	} {
+		resp, err := http.Get(app.URL + "/static/" + tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+		if tt.want == http.StatusOK && (resp.Header.Get("Content-Type") != tt.contentType || !strings.Contains(resp.Header.Get("Cache-Control"), "immutable")) {
+			t.Errorf("%s: headers = %v", tt.name, resp.Header)
+		}
+	}
+}
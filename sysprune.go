@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// /system/prune is a guarded `podman system prune`: it first shows what
+// would be removed, computed from the container, image, network and disk
+// usage lists in the order Podman prunes (containers first, so their
+// images, networks and volumes count as unused afterwards). The prune only
+// runs if that report has not changed since it was shown. Volumes are only
+// pruned when asked for separately. Podman only; the compat API has no
+// system prune.
+
+// PruneReport is what a system prune would remove.
+type PruneReport struct {
+	All     bool // also unused tagged images, like --all
+	Volumes bool // also unused volumes, like --volumes
+
+	Containers    []Container
+	ContainerSize int64
+	Images        []ImageRow
+	ImageSize     int64
+	Networks      []NetworkSummary
+	// UnusedVolumes are removed only with Volumes set.
+	UnusedVolumes []DFVolume
+	VolumeSize    int64
+}
+
+// Size returns the space the prune would reclaim, at most. Layers shared
+// with remaining images are not freed.
+func (p PruneReport) Size() int64 {
+	size := p.ContainerSize + p.ImageSize
+	if p.Volumes {
+		size += p.VolumeSize
+	}
+	return size
+}
+
+// Empty reports whether the prune would remove nothing.
+func (p PruneReport) Empty() bool {
+	return len(p.Containers) == 0 && len(p.Images) == 0 && len(p.Networks) == 0 &&
+		(!p.Volumes || len(p.UnusedVolumes) == 0)
+}
+
+// Digest identifies the report, so a prune can check that the user saw
+// what it removes.
+func (p PruneReport) Digest() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "all=%v volumes=%v\n", p.All, p.Volumes)
+	for _, c := range p.Containers {
+		fmt.Fprintln(h, "container", c.ID)
+	}
+	for _, img := range p.Images {
+		fmt.Fprintln(h, "image", img.ID)
+	}
+	for _, n := range p.Networks {
+		fmt.Fprintln(h, "network", n.Name)
+	}
+	if p.Volumes {
+		for _, v := range p.UnusedVolumes {
+			fmt.Fprintln(h, "volume", v.VolumeName)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// systemPruneReport returns what a system prune would remove. inspected
+// are the containers with their mounts.
+func systemPruneReport(containers []Container, inspected []ContainerInspect, images []ImageSummary, networks []NetworkSummary, df SystemDF, all, volumes bool) PruneReport {
+	p := PruneReport{All: all, Volumes: volumes}
+	rwSize := map[string]int64{}
+	for _, c := range df.Containers {
+		rwSize[c.ContainerID] = c.RWSize
+	}
+	var kept []Container
+	pruned := map[string]bool{}
+	for _, c := range containers {
+		if isPrunable(c.State) {
+			p.Containers = append(p.Containers, c)
+			p.ContainerSize += rwSize[c.ID]
+			pruned[c.ID] = true
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	p.Images, p.ImageSize = pruneCandidates(imageRows(images, kept), all)
+
+	used := map[string]bool{}
+	for _, c := range kept {
+		for _, n := range c.Networks {
+			used[n] = true
+		}
+	}
+	for _, n := range networks {
+		if !used[n.Name] && !slices.Contains(defaultNetworks, n.Name) {
+			p.Networks = append(p.Networks, n)
+		}
+	}
+
+	mounted := map[string]bool{}
+	for _, c := range inspected {
+		if pruned[c.ID] {
+			continue
+		}
+		for _, m := range c.Mounts {
+			if m.Type == "volume" {
+				mounted[m.Name] = true
+			}
+		}
+	}
+	for _, v := range df.Volumes {
+		if !mounted[v.VolumeName] {
+			p.UnusedVolumes = append(p.UnusedVolumes, v)
+			p.VolumeSize += v.Size
+		}
+	}
+
+	sort.Slice(p.Containers, func(i, j int) bool { return firstName(p.Containers[i].Names) < firstName(p.Containers[j].Names) })
+	sort.Slice(p.Images, func(i, j int) bool { return p.Images[i].Size > p.Images[j].Size })
+	sort.Slice(p.Networks, func(i, j int) bool { return p.Networks[i].Name < p.Networks[j].Name })
+	sort.Slice(p.UnusedVolumes, func(i, j int) bool { return p.UnusedVolumes[i].Size > p.UnusedVolumes[j].Size })
+	return p
+}
+
+// loadPruneReport fetches the lists a system prune report needs.
+func (s *Server) loadPruneReport(ctx context.Context, all, volumes bool) (PruneReport, error) {
+	var (
+		containers []Container
+		inspected  []ContainerInspect
+		images     []ImageSummary
+		networks   []NetworkSummary
+		df         SystemDF
+	)
+	err := parallel(ctx, 5, func(ctx context.Context, i int) (err error) {
+		switch i {
+		case 0:
+			containers, err = s.listContainers(ctx)
+		case 1:
+			inspected, err = s.inspectAllContainers(ctx)
+		case 2:
+			images, err = s.listImages(ctx)
+		case 3:
+			networks, err = s.listNetworks(ctx)
+		default:
+			err = s.podmanGet(ctx, "/system/df", &df)
+		}
+		return err
+	})
+	if err != nil {
+		return PruneReport{}, err
+	}
+	return systemPruneReport(containers, inspected, images, networks, df, all, volumes), nil
+}
+
+func (s *Server) handleSystemPruneConfirm(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	if s.backend == backendDocker {
+		s.render(w, r, "sysprune.html", map[string]any{
+			"Title":       "System Prune",
+			"Unsupported": true,
+		})
+		return
+	}
+	q := r.URL.Query()
+	report, err := s.loadPruneReport(r.Context(), q.Get("all") != "", q.Get("volumes") != "")
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.render(w, r, "sysprune.html", map[string]any{
+		"Title":  "System Prune",
+		"Report": report,
+	})
+}
+
+// systemPruneResult counts what a system prune removed.
+type systemPruneResult struct {
+	Containers, Images, Networks, Volumes int
+	Reclaimed                             int64
+}
+
+// systemPrune runs a system prune and returns what it removed. Failures of
+// single items are logged and not counted.
+func (s *Server) systemPrune(ctx context.Context, all, volumes bool) (systemPruneResult, error) {
+	q := url.Values{"all": {strconv.FormatBool(all)}, "volumes": {strconv.FormatBool(volumes)}}
+	resp, err := s.podmanDo(ctx, http.MethodPost, "/system/prune?"+q.Encode(), nil)
+	if err != nil {
+		return systemPruneResult{}, err
+	}
+	defer resp.Body.Close()
+	s.images.invalidate()
+	type pruneReport struct {
+		ID  string `json:"Id"`
+		Err string `json:"Err"`
+	}
+	var report struct {
+		Containers []pruneReport `json:"ContainerPruneReports"`
+		Images     []pruneReport `json:"ImagePruneReports"`
+		Volumes    []pruneReport `json:"VolumePruneReports"`
+		Networks   []struct {
+			Name  string `json:"Name"`
+			Error string `json:"Error"`
+		} `json:"NetworkPruneReports"`
+		ReclaimedSpace int64 `json:"ReclaimedSpace"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return systemPruneResult{}, err
+	}
+	count := func(kind string, reports []pruneReport) int {
+		n := 0
+		for _, p := range reports {
+			if p.Err != "" {
+				log.Printf("system prune: %s %s: %s", kind, shortID(p.ID), p.Err)
+				continue
+			}
+			n++
+		}
+		return n
+	}
+	res := systemPruneResult{
+		Containers: count("container", report.Containers),
+		Images:     count("image", report.Images),
+		Volumes:    count("volume", report.Volumes),
+		Reclaimed:  report.ReclaimedSpace,
+	}
+	for _, n := range report.Networks {
+		if n.Error != "" {
+			log.Printf("system prune: network %s: %s", n.Name, n.Error)
+			continue
+		}
+		res.Networks++
+	}
+	return res, nil
+}
+
+func (s *Server) handleSystemPrune(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions || s.backend == backendDocker {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	all, volumes := r.FormValue("all") != "", r.FormValue("volumes") != ""
+	report, err := s.loadPruneReport(r.Context(), all, volumes)
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if report.Digest() != r.FormValue("digest") {
+		// Something changed since the report was shown; show the new one.
+		s.render(w, r, "sysprune.html", map[string]any{
+			"Title":   "System Prune",
+			"Report":  report,
+			"Changed": true,
+		})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Minute)
+	defer cancel()
+	res, err := s.systemPrune(ctx, all, volumes)
+	target := fmt.Sprintf("%d container(s), %d image(s), %d network(s), %d volume(s), %s",
+		res.Containers, res.Images, res.Networks, res.Volumes, humanSize(res.Reclaimed))
+	s.actions.record(r, "system prune", target, err, "", nil)
+	if err != nil {
+		log.Printf("[%s] system prune: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.render(w, r, "sysprune.html", map[string]any{
+		"Title":  "System Prune",
+		"Done":   true,
+		"Result": res,
+	})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Fixtures for the system prune: web runs on net1 with volume data, old is
+// stopped and the only user of its image, net2 and volume cache.
+var (
+	pruneContainers = []Container{
+		{ID: "aaa111", Names: []string{"web"}, State: "running", ImageID: "img1", Networks: []string{"net1"}},
+		{ID: "bbb222", Names: []string{"old"}, State: "exited", ImageID: "img2", Networks: []string{"net2"}},
+	}
+	pruneInspects = map[string]ContainerInspect{
+		"aaa111": {ID: "aaa111", Mounts: []Mount{{Type: "volume", Name: "data"}}},
+		"bbb222": {ID: "bbb222", Mounts: []Mount{{Type: "volume", Name: "cache"}, {Type: "bind", Source: "/srv"}}},
+	}
+	pruneImages = []ImageSummary{
+		{ID: "img1", RepoTags: []string{"web:latest"}, Size: 100},
+		{ID: "img2", RepoTags: []string{"<none>:<none>"}, Size: 200},
+		{ID: "img3", RepoTags: []string{"tool:1"}, Size: 400},
+	}
+	pruneNetworks = []NetworkSummary{{Name: "podman"}, {Name: "net1"}, {Name: "net2"}}
+	pruneDF       = SystemDF{
+		Containers: []DFContainer{{ContainerID: "bbb222", RWSize: 10}},
+		Volumes:    []DFVolume{{VolumeName: "data", Links: 1, Size: 1000}, {VolumeName: "cache", Links: 1, Size: 2000}},
+	}
+)
+
+func TestSystemPruneReport(t *testing.T) {
+	t.Parallel()
+	var inspected []ContainerInspect
+	for _, c := range pruneInspects {
+		inspected = append(inspected, c)
+	}
+	p := systemPruneReport(pruneContainers, inspected, pruneImages, pruneNetworks, pruneDF, false, false)
+	if len(p.Containers) != 1 || p.Containers[0].ID != "bbb222" || p.ContainerSize != 10 {
+		t.Errorf("containers = %+v, size %d", p.Containers, p.ContainerSize)
+	}
+	// img2 is only used by the pruned container and dangling.
+	if len(p.Images) != 1 || p.Images[0].ID != "img2" || p.ImageSize != 200 {
+		t.Errorf("images = %+v", p.Images)
+	}
+	if len(p.Networks) != 1 || p.Networks[0].Name != "net2" {
+		t.Errorf("networks = %+v", p.Networks)
+	}
+	if len(p.UnusedVolumes) != 1 || p.UnusedVolumes[0].VolumeName != "cache" {
+		t.Errorf("volumes = %+v", p.UnusedVolumes)
+	}
+	if p.Size() != 210 {
+		t.Errorf("size without volumes = %d, want 210", p.Size())
+	}
+
+	all := systemPruneReport(pruneContainers, inspected, pruneImages, pruneNetworks, pruneDF, true, true)
+	if len(all.Images) != 2 || all.Size() != 2610 {
+		t.Errorf("--all --volumes: %d images, size %d", len(all.Images), all.Size())
+	}
+	if all.Digest() == p.Digest() {
+		t.Error("digest ignores the options")
+	}
+}
+
+func TestSystemPrune(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var pruned []string
+	containers := pruneContainers
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod")
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && p == "/system/prune":
+			pruned = append(pruned, r.URL.RawQuery)
+			w.Write([]byte(`{"ContainerPruneReports":[{"Id":"bbb222","Size":10}],"ImagePruneReports":[{"Id":"img2","Size":200}],"NetworkPruneReports":[{"Name":"net2"}],"VolumePruneReports":[],"ReclaimedSpace":210}`))
+		case p == "/containers/json":
+			json.NewEncoder(w).Encode(containers)
+		case p == "/images/json":
+			json.NewEncoder(w).Encode(pruneImages)
+		case p == "/networks/json":
+			json.NewEncoder(w).Encode(pruneNetworks)
+		case p == "/system/df":
+			json.NewEncoder(w).Encode(pruneDF)
+		default:
+			id := strings.TrimSuffix(strings.TrimPrefix(p, "/containers/"), "/json")
+			json.NewEncoder(w).Encode(pruneInspects[id])
+		}
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/system/prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	page := string(body)
+	for _, want := range []string{">old<", "net2", "cache", "Kept.", "Frees up to 210 B"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+	report := systemPruneReport(pruneContainers, nil, pruneImages, pruneNetworks, pruneDF, false, false)
+
+	// Another container stopped since the report was shown.
+	mu.Lock()
+	containers = []Container{pruneContainers[1], {ID: "ccc333", Names: []string{"new"}, State: "exited"}}
+	mu.Unlock()
+	resp, err = http.PostForm(app.URL+"/system/prune", url.Values{"digest": {report.Digest()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "changed since the report was shown") || len(pruned) != 0 {
+		t.Errorf("changed report: pruned %v", pruned)
+	}
+
+	mu.Lock()
+	containers = pruneContainers
+	mu.Unlock()
+	resp, err = http.PostForm(app.URL+"/system/prune", url.Values{"digest": {report.Digest()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "Removed 1 container(s), 1 image(s), 1 network(s) and 0 volume(s)") {
+		t.Errorf("prune result: %d", resp.StatusCode)
+	}
+	if len(pruned) != 1 || pruned[0] != "all=false&volumes=false" {
+		t.Errorf("prune calls = %v", pruned)
+	}
+}
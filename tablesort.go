@@ -0,0 +1,149 @@
+package main
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// The container and image lists can be sorted with ?sort=<key>&order=asc|desc.
+// Column headers link to the sorted URL (keeping the other query
+// parameters, so filters and saved views keep working); clicking the sorted
+// column again reverses the order.
+
+// sortKeys are the supported ?sort= values, mapped to whether they sort
+// descending by default (newest first for created).
+var sortKeys = map[string]bool{"name": false, "created": true, "state": false, "image": false}
+
+// tableSort is the sort order of a list page.
+type tableSort struct {
+	Key  string
+	Desc bool
+}
+
+// Order returns the ?order= value of ts.
+func (ts tableSort) Order() string {
+	if ts.Desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// SortHeader is the link of a sortable column header.
+type SortHeader struct {
+	Href   string
+	Active bool
+	Desc   bool
+}
+
+// Arrow returns the indicator shown next to the sorted column.
+func (h SortHeader) Arrow() string {
+	switch {
+	case !h.Active:
+		return ""
+	case h.Desc:
+		return " ▼"
+	}
+	return " ▲"
+}
+
+// parseTableSort returns the sort order of the query, or def if it has no
+// valid sort key.
+func parseTableSort(q url.Values, def tableSort) tableSort {
+	key := q.Get("sort")
+	desc, ok := sortKeys[key]
+	if !ok {
+		return def
+	}
+	switch q.Get("order") {
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	}
+	return tableSort{Key: key, Desc: desc}
+}
+
+// headers returns the header links for all sort keys on the page at path.
+func (ts tableSort) headers(path string, q url.Values) map[string]SortHeader {
+	out := make(map[string]SortHeader, len(sortKeys))
+	for key, desc := range sortKeys {
+		h := SortHeader{Active: key == ts.Key, Desc: ts.Desc}
+		if h.Active {
+			desc = !ts.Desc
+		}
+		next := url.Values{}
+		for k, v := range q {
+			next[k] = v
+		}
+		next.Del(csrfFormField)
+		next.Set("sort", key)
+		next.Set("order", tableSort{Desc: desc}.Order())
+		h.Href = path + "?" + next.Encode()
+		out[key] = h
+	}
+	return out
+}
+
+// sortContainers sorts list by ts, ties by name.
+func sortContainers(list []Container, ts tableSort) {
+	slices.SortStableFunc(list, func(a, b Container) int {
+		var c int
+		switch ts.Key {
+		case "created":
+			c = a.Created.Compare(b.Created)
+		case "state":
+			c = strings.Compare(a.State, b.State)
+		case "image":
+			c = strings.Compare(a.Image, b.Image)
+		}
+		if ts.Desc {
+			c = -c
+		}
+		return cmp.Or(c, strings.Compare(firstName(a.Names), firstName(b.Names)))
+	})
+	if ts.Key == "name" && ts.Desc {
+		slices.Reverse(list)
+	}
+}
+
+// imageUsage ranks images for sorting by state: in use, unused, dangling.
+func imageUsage(img ImageRow) int {
+	switch {
+	case img.Dangling:
+		return 2
+	case img.Unused:
+		return 1
+	}
+	return 0
+}
+
+// sortImages sorts list by ts, ties by the first tag. Images are named by
+// their first tag; "image" sorts by ID.
+func sortImages(list []ImageRow, ts tableSort) {
+	name := func(img ImageRow) string {
+		if len(img.RepoTags) > 0 {
+			return img.RepoTags[0]
+		}
+		return ""
+	}
+	slices.SortStableFunc(list, func(a, b ImageRow) int {
+		var c int
+		switch ts.Key {
+		case "created":
+			c = cmp.Compare(a.Created, b.Created)
+		case "state":
+			c = cmp.Compare(imageUsage(a), imageUsage(b))
+		case "image":
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if ts.Desc {
+			c = -c
+		}
+		return cmp.Or(c, strings.Compare(name(a), name(b)))
+	})
+	if ts.Key == "name" && ts.Desc {
+		slices.Reverse(list)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTableSort(t *testing.T) {
+	t.Parallel()
+	def := tableSort{Key: "created", Desc: true}
+	for query, want := range map[string]tableSort{
+		"":                       def,
+		"sort=size":              def,
+		"sort=name":              {Key: "name"},
+		"sort=created":           {Key: "created", Desc: true},
+		"sort=created&order=asc": {Key: "created"},
+		"sort=state&order=desc":  {Key: "state", Desc: true},
+		"sort=image&order=bogus": {Key: "image"},
+	} {
+		q, _ := url.ParseQuery(query)
+		if got := parseTableSort(q, def); got != want {
+			t.Errorf("parseTableSort(%q) = %+v, want %+v", query, got, want)
+		}
+	}
+}
+
+func TestSortHeaders(t *testing.T) {
+	t.Parallel()
+	q, _ := url.ParseQuery("state=running&sort=name&order=asc&_csrf=x")
+	h := parseTableSort(q, tableSort{}).headers("/p/containers", q)
+	if got := h["name"]; !got.Active || got.Href != "/p/containers?order=desc&sort=name&state=running" || got.Arrow() != " ▲" {
+		t.Errorf("name header = %+v", got)
+	}
+	if got := h["created"]; got.Active || got.Href != "/p/containers?order=desc&sort=created&state=running" || got.Arrow() != "" {
+		t.Errorf("created header = %+v", got)
+	}
+	if got := h["image"]; got.Href != "/p/containers?order=asc&sort=image&state=running" {
+		t.Errorf("image header = %+v", got)
+	}
+	if q.Get("sort") != "name" {
+		t.Error("headers changed the query")
+	}
+}
+
+func TestSortContainers(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	list := []Container{
+		{Names: []string{"b"}, Image: "x", State: "running", Created: now},
+		{Names: []string{"a"}, Image: "z", State: "exited", Created: now.Add(-time.Hour)},
+		{Names: []string{"c"}, Image: "y", State: "running", Created: now.Add(time.Hour)},
+	}
+	for _, tc := range []struct {
+		ts   tableSort
+		want string
+	}{
+		{tableSort{Key: "name"}, "abc"},
+		{tableSort{Key: "name", Desc: true}, "cba"},
+		{tableSort{Key: "created", Desc: true}, "cba"},
+		{tableSort{Key: "created"}, "abc"},
+		{tableSort{Key: "state"}, "abc"},
+		{tableSort{Key: "state", Desc: true}, "bca"},
+		{tableSort{Key: "image"}, "bca"},
+	} {
+		sortContainers(list, tc.ts)
+		got := ""
+		for _, c := range list {
+			got += firstName(c.Names)
+		}
+		if got != tc.want {
+			t.Errorf("sortContainers(%+v) = %s, want %s", tc.ts, got, tc.want)
+		}
+	}
+}
+
+func TestSortImages(t *testing.T) {
+	t.Parallel()
+	list := []ImageRow{
+		{ImageSummary: ImageSummary{ID: "2", RepoTags: []string{"b"}, Created: 2}},
+		{ImageSummary: ImageSummary{ID: "3", Created: 3}, Dangling: true, Unused: true},
+		{ImageSummary: ImageSummary{ID: "1", RepoTags: []string{"a"}, Created: 1}, Unused: true},
+	}
+	for _, tc := range []struct {
+		ts   tableSort
+		want string
+	}{
+		{tableSort{Key: "name"}, "312"},
+		{tableSort{Key: "created", Desc: true}, "321"},
+		{tableSort{Key: "state"}, "213"},
+		{tableSort{Key: "image", Desc: true}, "321"},
+	} {
+		sortImages(list, tc.ts)
+		got := ""
+		for _, img := range list {
+			got += img.ID
+		}
+		if got != tc.want {
+			t.Errorf("sortImages(%+v) = %s, want %s", tc.ts, got, tc.want)
+		}
+	}
+}
+
+func TestContainersSorted(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	app := httptest.NewServer(newTestServer(t, mock).newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/containers?sort=name&order=desc&state=running")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	page := string(body)
+	if w, j := strings.Index(page, ">whoami<"), strings.Index(page, ">jellyfin<"); w < 0 || j < 0 || w > j {
+		t.Error("containers not sorted by name descending")
+	}
+	for _, want := range []string{
+		`href="/containers?order=asc&amp;sort=name&amp;state=running"`,
+		`<input type="hidden" name="sort" value="name">`,
+		"Names</a> ▼",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+}
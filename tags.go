@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// /image/{id}/tags lists the tags of the image's repository from the
+// registry, to pick a version to upgrade to. The digest and creation time
+// are fetched for the newest-looking tags only, as each takes up to three
+// registry requests.
+
+const (
+	// maxRegistryTags bounds the tags listed for a repository.
+	maxRegistryTags = 1000
+	// maxTagDetails bounds the tags whose manifest is fetched.
+	maxTagDetails = 20
+)
+
+// RegistryTag is a tag of a repository on the tags page.
+type RegistryTag struct {
+	Name    string
+	Digest  string
+	Created time.Time
+	Current bool // the local image has this digest
+	Err     string
+}
+
+// tagList returns the tags of the repository of r.
+func (rc *registryClient) tagList(ctx context.Context, r imageRef, token *string) ([]string, error) {
+	resp, err := rc.do(ctx, http.MethodGet, r.Registry, "/v2/"+r.Repo+"/tags/list?n=1000", "", token)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var list struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	if len(list.Tags) > maxRegistryTags {
+		list.Tags = list.Tags[:maxRegistryTags]
+	}
+	return list.Tags, nil
+}
+
+// registryManifest is an image manifest or, with Manifests set, an index of
+// per-platform manifests.
+type registryManifest struct {
+	Config struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// getJSON decodes a registry response for path into v and returns the
+// Docker-Content-Digest header.
+func (rc *registryClient) getJSON(ctx context.Context, r imageRef, path, accept string, token *string, v any) (string, error) {
+	resp, err := rc.do(ctx, http.MethodGet, r.Registry, "/v2/"+r.Repo+path, accept, token)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return resp.Header.Get("Docker-Content-Digest"), json.NewDecoder(resp.Body).Decode(v)
+}
+
+// tagDetails returns the manifest digest of the tag of r and when its image
+// was created. For multi-arch images, the creation time is that of the
+// image for os and arch, or the first one.
+func (rc *registryClient) tagDetails(ctx context.Context, r imageRef, os, arch string, token *string) (string, time.Time, error) {
+	var m registryManifest
+	digest, err := rc.getJSON(ctx, r, "/manifests/"+r.Tag, manifestAccept, token, &m)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if len(m.Manifests) > 0 {
+		platform := m.Manifests[0].Digest
+		for _, pm := range m.Manifests {
+			if pm.Platform.OS == os && pm.Platform.Architecture == arch {
+				platform = pm.Digest
+				break
+			}
+		}
+		m = registryManifest{}
+		if _, err := rc.getJSON(ctx, r, "/manifests/"+platform, manifestAccept, token, &m); err != nil {
+			return digest, time.Time{}, err
+		}
+	}
+	if m.Config.Digest == "" {
+		return digest, time.Time{}, errors.New("registry: manifest without config")
+	}
+	var config struct {
+		Created time.Time `json:"created"`
+	}
+	_, err = rc.getJSON(ctx, r, "/blobs/"+m.Config.Digest, "", token, &config)
+	return digest, config.Created, err
+}
+
+// compareTags compares tags like version numbers: runs of digits compare by
+// value, everything else byte by byte.
+func compareTags(a, b string) int {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if c := len(na) - len(nb); c != 0 {
+				return c
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) - len(b)
+}
+
+// digitPrefix returns the leading digits of s.
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
+
+// sortTags drops signature and attestation tags (as pushed by cosign) and
+// sorts the rest newest version first.
+func sortTags(tags []string) []string {
+	tags = slices.DeleteFunc(tags, func(t string) bool {
+		return strings.HasSuffix(t, ".sig") || strings.HasSuffix(t, ".att") || strings.HasSuffix(t, ".sbom")
+	})
+	slices.SortFunc(tags, func(a, b string) int { return compareTags(b, a) })
+	return tags
+}
+
+func (s *Server) handleImageTags(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !validID.MatchString(id) {
+		http.Error(w, "Invalid image ID", http.StatusBadRequest)
+		return
+	}
+	img, err := s.inspectImage(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			http.Error(w, "Image Not Found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	data := map[string]any{
+		"Title": "Tags of " + imageName(img),
+		"Image": img,
+		"Name":  imageName(img),
+	}
+	refs := slices.DeleteFunc(slices.Clone(img.RepoTags), func(t string) bool { return t == "" || t == "<none>:<none>" })
+	if len(refs) == 0 {
+		data["Err"] = "The image has no tag, so its repository is unknown."
+		s.render(w, r, "image-tags.html", data)
+		return
+	}
+	ref := refs[0]
+	if q := r.URL.Query().Get("ref"); slices.Contains(refs, q) {
+		ref = q
+	}
+	data["Ref"], data["Refs"] = ref, refs
+	repo, err := parseImageRef(ref)
+	if err != nil {
+		http.Error(w, "Invalid image reference", http.StatusBadRequest)
+		return
+	}
+	data["Repo"], _ = splitImageRef(ref)
+
+	var token string
+	names, err := s.registry.tagList(r.Context(), repo, &token)
+	if err != nil {
+		log.Printf("[%s] registry tags %s: %v", reqID(r.Context()), ref, err)
+		data["Err"] = "The registry did not list the tags. Only public repositories are supported."
+		s.render(w, r, "image-tags.html", data)
+		return
+	}
+	names = sortTags(names)
+	tags := make([]RegistryTag, min(len(names), maxTagDetails))
+	local := map[string]bool{}
+	for _, d := range img.RepoDigests {
+		_, digest, _ := strings.Cut(d, "@")
+		local[digest] = true
+	}
+	// Errors are shown per tag, so fn never fails.
+	parallel(r.Context(), len(tags), func(ctx context.Context, i int) error {
+		t := &tags[i]
+		t.Name = names[i]
+		tr, token := repo, token
+		tr.Tag = t.Name
+		digest, created, err := s.registry.tagDetails(ctx, tr, img.Os, img.Architecture, &token)
+		t.Digest, t.Created, t.Current = digest, created, local[digest]
+		if err != nil {
+			log.Printf("[%s] registry tag %s:%s: %v", reqID(r.Context()), repo.Repo, t.Name, err)
+			t.Err = "failed"
+		}
+		return nil
+	})
+	data["Tags"] = tags
+	data["More"] = names[len(tags):]
+	s.render(w, r, "image-tags.html", data)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSortTags(t *testing.T) {
+	t.Parallel()
+	got := sortTags([]string{"1.9", "1.25.3", "latest", "1.25", "sha256-abc.sig", "1.25-alpine", "2"})
+	want := []string{"latest", "2", "1.25.3", "1.25-alpine", "1.25", "1.9"}
+	if !slices.Equal(got, want) {
+		t.Errorf("sortTags = %v, want %v", got, want)
+	}
+}
+
+func TestImageTags(t *testing.T) {
+	t.Parallel()
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Write([]byte(`{"token":"t0k"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer t0k" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/token",service="registry",scope="repository:org/web:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/org/web/tags/list":
+			w.Write([]byte(`{"name":"org/web","tags":["1.1","1.2","1.10","sha256-aaa.sig"]}`))
+		case "/v2/org/web/manifests/1.10":
+			// A multi-arch index.
+			w.Header().Set("Docker-Content-Digest", "sha256:index110")
+			w.Write([]byte(`{"manifests":[{"digest":"sha256:arm","platform":{"architecture":"arm64","os":"linux"}},{"digest":"sha256:amd","platform":{"architecture":"amd64","os":"linux"}}]}`))
+		case "/v2/org/web/manifests/sha256:amd":
+			w.Write([]byte(`{"config":{"digest":"sha256:cfg110"}}`))
+		case "/v2/org/web/manifests/1.2", "/v2/org/web/manifests/1.1":
+			tag := strings.TrimPrefix(r.URL.Path, "/v2/org/web/manifests/")
+			w.Header().Set("Docker-Content-Digest", "sha256:digest"+tag)
+			w.Write([]byte(`{"config":{"digest":"sha256:cfg` + tag + `"}}`))
+		case "/v2/org/web/blobs/sha256:cfg110":
+			w.Write([]byte(`{"created":"2026-09-01T00:00:00Z"}`))
+		case "/v2/org/web/blobs/sha256:cfg1.2":
+			w.Write([]byte(`{"created":"2026-06-01T00:00:00Z"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer registry.Close()
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ImageInspect{
+			ID:           strings.Repeat("56", 32),
+			RepoTags:     []string{"registry.example/org/web:1.2"},
+			RepoDigests:  []string{"registry.example/org/web@sha256:digest1.2"},
+			Os:           "linux",
+			Architecture: "amd64",
+		})
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.registry = newRegistryClient()
+	s.registry.baseURL = func(host string) string {
+		if host != "registry.example" {
+			t.Errorf("registry host = %q", host)
+		}
+		return registry.URL
+	}
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/image/" + strings.Repeat("56", 32) + "/tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	page := string(body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d:\n%s", resp.StatusCode, page)
+	}
+	i110, i12, i11 := strings.Index(page, ">1.10"), strings.Index(page, ">1.2 "), strings.Index(page, ">1.1<")
+	if i110 < 0 || i12 < i110 || i11 < i12 {
+		t.Errorf("tags missing or out of order (%d, %d, %d):\n%s", i110, i12, i11, page)
+	}
+	for _, want := range []string{"index110", "2026-09-01", `1.2 <span class="badge badge-running">current</span>`, "failed"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+	if strings.Contains(page, ".sig") {
+		t.Error("page lists a signature tag")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// With ENABLE_DEBUG_PAGE, /debug/templates documents the data every page
+// template gets: the keys of the data map with the Go types of their values,
+// expanded to the fields and methods templates can use, and the template
+// functions. The types are taken from the values of the latest render of
+// each page, so a page shows up once it has been visited.
+
+// maxTypeDepth bounds how deep nested types are expanded.
+const maxTypeDepth = 4
+
+// TemplateField is a row of the data model: a map key, struct field or
+// method, indented by Depth.
+type TemplateField struct {
+	Name  string
+	Type  string
+	Depth int
+}
+
+// TemplateFunc is a template function and its signature.
+type TemplateFunc struct {
+	Name string
+	Type string
+}
+
+// TemplatePage is the data model of one page.
+type TemplatePage struct {
+	Name   string
+	Fields []TemplateField
+}
+
+// templateTypes records the types of the data passed to each page. It is
+// safe to use a nil *templateTypes, which records nothing.
+type templateTypes struct {
+	mu    sync.Mutex
+	pages map[string]map[string]reflect.Type
+}
+
+func newTemplateTypes() *templateTypes {
+	return &templateTypes{pages: map[string]map[string]reflect.Type{}}
+}
+
+// record stores the types of the values in data. Keys that were nil keep
+// the type from an earlier render.
+func (tt *templateTypes) record(page string, data map[string]any) {
+	if tt == nil {
+		return
+	}
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	types := tt.pages[page]
+	if types == nil {
+		types = map[string]reflect.Type{}
+		tt.pages[page] = types
+	}
+	for k, v := range data {
+		if t := reflect.TypeOf(v); t != nil || types[k] == nil {
+			types[k] = t
+		}
+	}
+}
+
+// docs returns the data model of all rendered pages, sorted by name.
+func (tt *templateTypes) docs() []TemplatePage {
+	if tt == nil {
+		return nil
+	}
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	var out []TemplatePage
+	for _, page := range slices.Sorted(maps.Keys(tt.pages)) {
+		p := TemplatePage{Name: page}
+		types := tt.pages[page]
+		for _, k := range slices.Sorted(maps.Keys(types)) {
+			p.Fields = append(p.Fields, describeType("."+k, types[k], 0, nil)...)
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// timeType is shown as a leaf, its fields are unexported.
+var timeType = reflect.TypeFor[time.Time]()
+
+// describeType returns the row for name of type t and, for structs, the
+// rows of their exported fields and of the methods templates can call
+// without arguments. seen stops recursive types.
+func describeType(name string, t reflect.Type, depth int, seen []reflect.Type) []TemplateField {
+	if t == nil {
+		return []TemplateField{{Name: name, Type: "nil", Depth: depth}}
+	}
+	rows := []TemplateField{{Name: name, Type: t.String(), Depth: depth}}
+	if depth >= maxTypeDepth {
+		return rows
+	}
+	// Elements of slices and maps are expanded like the value itself.
+	elem, ptr := t, false
+	for {
+		if k := elem.Kind(); k == reflect.Pointer {
+			elem, ptr = elem.Elem(), true
+		} else if k == reflect.Slice || k == reflect.Array || k == reflect.Map {
+			elem, ptr = elem.Elem(), false
+		} else {
+			break
+		}
+	}
+	if elem.Kind() != reflect.Struct || elem == timeType || slices.Contains(seen, elem) {
+		return rows
+	}
+	seen = append(seen, elem)
+	for i := range elem.NumField() {
+		if f := elem.Field(i); f.IsExported() {
+			rows = append(rows, describeType("."+f.Name, f.Type, depth+1, seen)...)
+		}
+	}
+	// Values in the data map are not addressable, so only pointers have the
+	// pointer methods.
+	methods := elem
+	if ptr {
+		methods = reflect.PointerTo(elem)
+	}
+	for i := range methods.NumMethod() {
+		m := methods.Method(i)
+		if m.Type.NumIn() != 1 || m.Type.NumOut() < 1 || m.Type.NumOut() > 2 {
+			continue
+		}
+		outs := make([]string, m.Type.NumOut())
+		for j := range outs {
+			outs[j] = m.Type.Out(j).String()
+		}
+		rows = append(rows, TemplateField{Name: "." + m.Name, Type: "method returning " + strings.Join(outs, ", "), Depth: depth + 1})
+	}
+	return rows
+}
+
+// templateFuncs returns the functions of funcMap with their signatures.
+func templateFuncs() []TemplateFunc {
+	var out []TemplateFunc
+	for _, name := range slices.Sorted(maps.Keys(funcMap)) {
+		out = append(out, TemplateFunc{Name: name, Type: reflect.TypeOf(funcMap[name]).String()})
+	}
+	return out
+}
+
+func (s *Server) handleDebugTemplates(w http.ResponseWriter, r *http.Request) {
+	if !s.enableDebugPage {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	s.render(w, r, "templatedoc.html", map[string]any{
+		"Title": "Template Data",
+		"Pages": s.templateTypes.docs(),
+		"Funcs": templateFuncs(),
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type docNode struct {
+	Name     string
+	Children []*docNode
+	hidden   int
+}
+
+func (n docNode) Label() string { return n.Name }
+
+func TestDescribeType(t *testing.T) {
+	t.Parallel()
+	rows := describeType(".Root", reflect.TypeFor[*docNode](), 0, nil)
+	var got []string
+	for _, r := range rows {
+		got = append(got, strings.Repeat("  ", r.Depth)+r.Name+" "+r.Type)
+	}
+	want := []string{
+		".Root *main.docNode",
+		"  .Name string",
+		"  .Children []*main.docNode",
+		"  .Label method returning string",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("describeType:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if rows := describeType(".X", nil, 0, nil); len(rows) != 1 || rows[0].Type != "nil" {
+		t.Errorf("describeType(nil) = %+v", rows)
+	}
+}
+
+func TestTemplateTypes(t *testing.T) {
+	t.Parallel()
+	tt := newTemplateTypes()
+	tt.record("a.html", map[string]any{"Title": "A", "Err": nil})
+	tt.record("a.html", map[string]any{"Title": "A", "Err": "boom"})
+	tt.record("a.html", map[string]any{"Title": "A", "Err": nil})
+	docs := tt.docs()
+	if len(docs) != 1 || len(docs[0].Fields) != 2 || docs[0].Fields[0] != (TemplateField{Name: ".Err", Type: "string"}) {
+		t.Errorf("docs = %+v", docs)
+	}
+	var nilTypes *templateTypes
+	nilTypes.record("a.html", map[string]any{"Title": "A"})
+	if nilTypes.docs() != nil {
+		t.Error("nil templateTypes recorded")
+	}
+}
+
+func TestDebugTemplates(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode, string(body)
+	}
+	if code, _ := get("/debug/templates"); code != http.StatusNotFound {
+		t.Errorf("debug page disabled: status %d, want 404", code)
+	}
+	s.enableDebugPage = true
+	s.templateTypes = newTemplateTypes()
+	get("/containers")
+	code, page := get("/debug/templates")
+	if code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	for _, want := range []string{"containers.html", ".Containers", "[]main.ContainerRow", ".Names", "humanSize", "func(int64) string"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+}
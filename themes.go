@@ -0,0 +1,254 @@
+package main
+
+import (
+	"cmp"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Themes are sets of values for the CSS custom properties (design tokens)
+// the stylesheet in base.html is written against. The default theme, "auto",
+// follows the browser's light/dark preference. THEME sets the default for
+// all users; each user can override it on /themes (stored in a cookie).
+// PODFATHER_CUSTOM_CSS is served after the embedded stylesheet, so it can
+// override the tokens (e.g. --accent, --bg) or any rule.
+
+const (
+	themeCookieName = "theme"
+	themeCookieAge  = 365 * 24 * 60 * 60
+	defaultTheme    = "auto"
+)
+
+// themeTokens are the CSS custom properties every theme defines, in output
+// order.
+var themeTokens = []string{
+	"bg", "fg", "surface", "surface-head", "border", "border-row", "row-hover",
+	"nav-bg", "nav-link", "nav-link-hover", "brand",
+	"link", "muted", "desc", "faint", "category", "shadow", "shadow-hover",
+	"accent", "accent-hover", "accent-fg", "danger", "danger-hover",
+	"warn-bg", "warn-fg", "diff-bg", "error", "code-bg", "code-fg",
+	"ok-bg", "ok-fg", "bad-bg", "bad-fg", "pending-bg", "pending-fg",
+	"paused-bg", "paused-fg", "info-bg", "info-fg", "caution-bg", "caution-fg",
+}
+
+// Theme is a named set of token values.
+type Theme struct {
+	Name   string
+	Label  string
+	Dark   bool
+	Tokens map[string]string
+}
+
+var lightTokens = map[string]string{
+	"bg": "#f0f0f5", "fg": "#1a1a2e", "surface": "#fff", "surface-head": "#f8f8fc",
+	"border": "#e2e4ea", "border-row": "#eee", "row-hover": "#f8f9ff",
+	"nav-bg": "#1a1a2e", "nav-link": "#94a3b8", "nav-link-hover": "#fff", "brand": "#e2e8f0",
+	"link": "#2563eb", "muted": "#64748b", "desc": "#475569", "faint": "#94a3b8", "category": "#334155",
+	"shadow": "rgba(0,0,0,.08)", "shadow-hover": "rgba(0,0,0,.12)",
+	"accent": "#2563eb", "accent-hover": "#1d4ed8", "accent-fg": "#fff",
+	"danger": "#ea580c", "danger-hover": "#c2410c",
+	"warn-bg": "#fef3c7", "warn-fg": "#92400e", "diff-bg": "#fef9c3", "error": "#dc2626",
+	"code-bg": "#1e1e2e", "code-fg": "#cdd6f4",
+	"ok-bg": "#dcfce7", "ok-fg": "#166534", "bad-bg": "#fee2e2", "bad-fg": "#991b1b",
+	"pending-bg": "#fef9c3", "pending-fg": "#854d0e", "paused-bg": "#f3e8ff", "paused-fg": "#6b21a8",
+	"info-bg": "#dbeafe", "info-fg": "#1e40af", "caution-bg": "#ffedd5", "caution-fg": "#9a3412",
+}
+
+var darkTokens = map[string]string{
+	"bg": "#0f0f1a", "fg": "#e2e8f0", "surface": "#1e1e30", "surface-head": "#252538",
+	"border": "#3a3a50", "border-row": "#2a2a40", "row-hover": "#28283e",
+	"nav-bg": "#1a1a2e", "nav-link": "#94a3b8", "nav-link-hover": "#fff", "brand": "#e2e8f0",
+	"link": "#60a5fa", "muted": "#94a3b8", "desc": "#94a3b8", "faint": "#64748b", "category": "#cbd5e1",
+	"shadow": "rgba(0,0,0,.3)", "shadow-hover": "rgba(0,0,0,.4)",
+	"accent": "#3b82f6", "accent-hover": "#2563eb", "accent-fg": "#fff",
+	"danger": "#ea580c", "danger-hover": "#c2410c",
+	"warn-bg": "#451a03", "warn-fg": "#fcd34d", "diff-bg": "#3f3a12", "error": "#f87171",
+	"code-bg": "#1e1e2e", "code-fg": "#cdd6f4",
+	"ok-bg": "#14532d", "ok-fg": "#86efac", "bad-bg": "#7f1d1d", "bad-fg": "#fca5a5",
+	"pending-bg": "#713f12", "pending-fg": "#fde68a", "paused-bg": "#581c87", "paused-fg": "#d8b4fe",
+	"info-bg": "#1e3a8a", "info-fg": "#93c5fd", "caution-bg": "#7c2d12", "caution-fg": "#fdba74",
+}
+
+// themes are the bundled themes. "auto" has no tokens of its own, it uses
+// light and dark.
+var themes = []Theme{
+	{Name: "auto", Label: "Auto (light/dark)"},
+	{Name: "light", Label: "Light", Tokens: lightTokens},
+	{Name: "dark", Label: "Dark", Dark: true, Tokens: darkTokens},
+	{Name: "nord", Label: "Nord", Dark: true, Tokens: map[string]string{
+		"bg": "#2e3440", "fg": "#eceff4", "surface": "#3b4252", "surface-head": "#434c5e",
+		"border": "#4c566a", "border-row": "#434c5e", "row-hover": "#434c5e",
+		"nav-bg": "#242933", "nav-link": "#d8dee9", "nav-link-hover": "#eceff4", "brand": "#eceff4",
+		"link": "#88c0d0", "muted": "#d8dee9", "desc": "#d8dee9", "faint": "#7b88a1", "category": "#e5e9f0",
+		"shadow": "rgba(0,0,0,.3)", "shadow-hover": "rgba(0,0,0,.4)",
+		"accent": "#5e81ac", "accent-hover": "#81a1c1", "accent-fg": "#eceff4",
+		"danger": "#d08770", "danger-hover": "#bf616a",
+		"warn-bg": "#4a4335", "warn-fg": "#ebcb8b", "diff-bg": "#4a4335", "error": "#bf616a",
+		"code-bg": "#242933", "code-fg": "#d8dee9",
+		"ok-bg": "#a3be8c", "ok-fg": "#2e3440", "bad-bg": "#bf616a", "bad-fg": "#2e3440",
+		"pending-bg": "#ebcb8b", "pending-fg": "#2e3440", "paused-bg": "#b48ead", "paused-fg": "#2e3440",
+		"info-bg": "#81a1c1", "info-fg": "#2e3440", "caution-bg": "#d08770", "caution-fg": "#2e3440",
+	}},
+	{Name: "catppuccin", Label: "Catppuccin Mocha", Dark: true, Tokens: map[string]string{
+		"bg": "#181825", "fg": "#cdd6f4", "surface": "#1e1e2e", "surface-head": "#313244",
+		"border": "#45475a", "border-row": "#313244", "row-hover": "#313244",
+		"nav-bg": "#11111b", "nav-link": "#a6adc8", "nav-link-hover": "#cdd6f4", "brand": "#cdd6f4",
+		"link": "#89b4fa", "muted": "#bac2de", "desc": "#a6adc8", "faint": "#6c7086", "category": "#bac2de",
+		"shadow": "rgba(0,0,0,.3)", "shadow-hover": "rgba(0,0,0,.4)",
+		"accent": "#89b4fa", "accent-hover": "#b4befe", "accent-fg": "#11111b",
+		"danger": "#fab387", "danger-hover": "#eba0ac",
+		"warn-bg": "#45475a", "warn-fg": "#f9e2af", "diff-bg": "#45475a", "error": "#f38ba8",
+		"code-bg": "#11111b", "code-fg": "#cdd6f4",
+		"ok-bg": "#a6e3a1", "ok-fg": "#11111b", "bad-bg": "#f38ba8", "bad-fg": "#11111b",
+		"pending-bg": "#f9e2af", "pending-fg": "#11111b", "paused-bg": "#cba6f7", "paused-fg": "#11111b",
+		"info-bg": "#89b4fa", "info-fg": "#11111b", "caution-bg": "#fab387", "caution-fg": "#11111b",
+	}},
+	{Name: "solarized", Label: "Solarized Light", Tokens: map[string]string{
+		"bg": "#eee8d5", "fg": "#586e75", "surface": "#fdf6e3", "surface-head": "#eee8d5",
+		"border": "#93a1a1", "border-row": "#eee8d5", "row-hover": "#f5efdc",
+		"nav-bg": "#002b36", "nav-link": "#93a1a1", "nav-link-hover": "#fdf6e3", "brand": "#eee8d5",
+		"link": "#268bd2", "muted": "#657b83", "desc": "#657b83", "faint": "#93a1a1", "category": "#073642",
+		"shadow": "rgba(0,0,0,.06)", "shadow-hover": "rgba(0,0,0,.1)",
+		"accent": "#268bd2", "accent-hover": "#1f6fa8", "accent-fg": "#fdf6e3",
+		"danger": "#cb4b16", "danger-hover": "#a83c10",
+		"warn-bg": "#f6e7b4", "warn-fg": "#7a5c00", "diff-bg": "#f3e5ab", "error": "#dc322f",
+		"code-bg": "#002b36", "code-fg": "#93a1a1",
+		"ok-bg": "#859900", "ok-fg": "#fdf6e3", "bad-bg": "#dc322f", "bad-fg": "#fdf6e3",
+		"pending-bg": "#b58900", "pending-fg": "#fdf6e3", "paused-bg": "#6c71c4", "paused-fg": "#fdf6e3",
+		"info-bg": "#268bd2", "info-fg": "#fdf6e3", "caution-bg": "#cb4b16", "caution-fg": "#fdf6e3",
+	}},
+	{Name: "high-contrast", Label: "High contrast", Dark: true, Tokens: map[string]string{
+		"bg": "#000", "fg": "#fff", "surface": "#000", "surface-head": "#1a1a1a",
+		"border": "#fff", "border-row": "#888", "row-hover": "#1a1a1a",
+		"nav-bg": "#000", "nav-link": "#ffff00", "nav-link-hover": "#fff", "brand": "#fff",
+		"link": "#ffff00", "muted": "#fff", "desc": "#fff", "faint": "#ccc", "category": "#fff",
+		"shadow": "transparent", "shadow-hover": "transparent",
+		"accent": "#ffff00", "accent-hover": "#fff", "accent-fg": "#000",
+		"danger": "#ff8c00", "danger-hover": "#fff",
+		"warn-bg": "#000", "warn-fg": "#ffff00", "diff-bg": "#333", "error": "#ff6b6b",
+		"code-bg": "#000", "code-fg": "#fff",
+		"ok-bg": "#00ff00", "ok-fg": "#000", "bad-bg": "#ff4d4d", "bad-fg": "#000",
+		"pending-bg": "#ffff00", "pending-fg": "#000", "paused-bg": "#ff80ff", "paused-fg": "#000",
+		"info-bg": "#80c0ff", "info-fg": "#000", "caution-bg": "#ffa500", "caution-fg": "#000",
+	}},
+}
+
+// validTheme reports whether name is a bundled theme.
+func validTheme(name string) bool {
+	return slices.ContainsFunc(themes, func(t Theme) bool { return t.Name == name })
+}
+
+// themeNames returns the names of the bundled themes, for messages.
+func themeNames() string {
+	names := make([]string, len(themes))
+	for i, t := range themes {
+		names[i] = t.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// declarations returns the tokens as CSS custom property declarations.
+func (t Theme) declarations() string {
+	var b strings.Builder
+	for _, name := range themeTokens {
+		b.WriteString("--" + name + ":" + t.Tokens[name] + ";")
+	}
+	if t.Dark {
+		b.WriteString("color-scheme:dark;")
+	} else {
+		b.WriteString("color-scheme:light;")
+	}
+	return b.String()
+}
+
+// Style returns the declarations for an inline style attribute, used by the
+// previews ("auto" is previewed as light). The values are constants, so this
+// is safe CSS.
+func (t Theme) Style() template.CSS {
+	if t.Tokens == nil {
+		t = Theme{Tokens: lightTokens}
+	}
+	return template.CSS(t.declarations())
+}
+
+// themeCSS returns the :root rules of the named theme for the stylesheet.
+func themeCSS(name string) template.CSS {
+	i := slices.IndexFunc(themes, func(t Theme) bool { return t.Name == name })
+	if i < 0 || themes[i].Tokens == nil {
+		light := Theme{Tokens: lightTokens}
+		dark := Theme{Dark: true, Tokens: darkTokens}
+		return template.CSS(":root{" + light.declarations() + "}\n@media (prefers-color-scheme: dark){:root{" + dark.declarations() + "}}")
+	}
+	return template.CSS(":root{" + themes[i].declarations() + "}")
+}
+
+// theme returns the theme for the request: the user's choice from the
+// cookie, or the configured default.
+func (s *Server) theme(r *http.Request) string {
+	if c, err := r.Cookie(themeCookieName); err == nil && validTheme(c.Value) {
+		return c.Value
+	}
+	return cmp.Or(s.defaultTheme, defaultTheme)
+}
+
+func (s *Server) handleThemes(w http.ResponseWriter, r *http.Request) {
+	s.render(w, r, "themes.html", map[string]any{
+		"Title":   "Themes",
+		"Themes":  themes,
+		"Default": cmp.Or(s.defaultTheme, defaultTheme),
+	})
+}
+
+// handleSetTheme stores the user's theme in a cookie. An empty theme resets
+// it to the default.
+func (s *Server) handleSetTheme(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("theme")
+	if name != "" && !validTheme(name) {
+		http.Error(w, "Unknown theme", http.StatusBadRequest)
+		return
+	}
+	cookie := &http.Cookie{
+		Name:     themeCookieName,
+		Value:    name,
+		Path:     s.basePath + "/",
+		MaxAge:   themeCookieAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if name == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+	http.Redirect(w, r, s.basePath+"/themes", http.StatusSeeOther)
+}
+
+// handleCustomCSS serves PODFATHER_CUSTOM_CSS. The file is read on every
+// request, so edits show up on reload; browsers revalidate by modification
+// time.
+func (s *Server) handleCustomCSS(w http.ResponseWriter, r *http.Request) {
+	if s.customCSS == "" {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(s.customCSS)
+	if err != nil {
+		log.Printf("[%s] custom CSS: %v", reqID(r.Context()), err)
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		log.Printf("[%s] custom CSS: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "custom.css", st.ModTime(), f)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestThemeTokens(t *testing.T) {
+	for _, th := range themes {
+		if th.Tokens == nil {
+			continue
+		}
+		for _, name := range themeTokens {
+			if th.Tokens[name] == "" {
+				t.Errorf("theme %s: missing token %s", th.Name, name)
+			}
+		}
+		if len(th.Tokens) != len(themeTokens) {
+			t.Errorf("theme %s: %d tokens, want %d", th.Name, len(th.Tokens), len(themeTokens))
+		}
+	}
+	if css := string(themeCSS("auto")); !strings.Contains(css, "prefers-color-scheme: dark") || !strings.Contains(css, "--bg:#0f0f1a;") {
+		t.Errorf("auto theme CSS = %s", css)
+	}
+	if css := string(themeCSS("nord")); !strings.HasPrefix(css, ":root{--bg:#2e3440;") || strings.Contains(css, "@media") {
+		t.Errorf("nord theme CSS = %s", css)
+	}
+}
+
+func TestThemeSelection(t *testing.T) {
+	s := &Server{defaultTheme: "solarized"}
+	setTheme := func(name string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/themes", strings.NewReader(url.Values{"theme": {name}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.handleSetTheme(w, r)
+		return w
+	}
+	themeOf := func(cookies []*http.Cookie) string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		return s.theme(r)
+	}
+
+	if got := themeOf(nil); got != "solarized" {
+		t.Errorf("default theme = %s, want solarized", got)
+	}
+	w := setTheme("nord")
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if got := themeOf(w.Result().Cookies()); got != "nord" {
+		t.Errorf("theme = %s, want nord", got)
+	}
+	if w := setTheme("<script>"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown theme: status = %d", w.Code)
+	}
+	if c := setTheme("").Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 {
+		t.Errorf("reset did not delete the cookie: %+v", c)
+	}
+	if got := themeOf([]*http.Cookie{{Name: themeCookieName, Value: "bogus"}}); got != "solarized" {
+		t.Errorf("bogus cookie: theme = %s, want solarized", got)
+	}
+}
+
+func TestThemesPage(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, app.URL+"/themes", nil)
+	req.AddCookie(&http.Cookie{Name: themeCookieName, Value: "catppuccin"})
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	page := string(body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if !strings.Contains(page, ":root{--bg:#181825;") {
+		t.Error("page is not styled with the chosen theme")
+	}
+	if !strings.Contains(page, `style="--bg:#2e3440;`) {
+		t.Error("nord preview missing")
+	}
+}
+
+func TestCustomCSS(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+	if resp, _ := get("/custom.css"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("no custom CSS: status %d, want 404", resp.StatusCode)
+	}
+	if _, page := get("/themes"); strings.Contains(page, "custom.css") {
+		t.Error("page links a custom stylesheet that is not configured")
+	}
+
+	s.customCSS = filepath.Join(t.TempDir(), "custom.css")
+	if err := os.WriteFile(s.customCSS, []byte(":root{--accent:#ff0066}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	resp, css := get("/custom.css")
+	if resp.StatusCode != http.StatusOK || css != ":root{--accent:#ff0066}" || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/css") {
+		t.Errorf("custom CSS: status %d, %q, Content-Type %q", resp.StatusCode, css, resp.Header.Get("Content-Type"))
+	}
+	_, page := get("/themes")
+	if i, j := strings.Index(page, "</style>"), strings.Index(page, `<link rel="stylesheet" href="/custom.css">`); j < 0 || j < i {
+		t.Error("custom stylesheet not linked after the embedded one")
+	}
+}
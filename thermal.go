@@ -0,0 +1,111 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Host temperatures from the kernel's thermal zones, shown next to the CPU
+// information. A zone at or above its throttling point likely explains slow
+// containers, which is common on passively cooled boards like the
+// Raspberry Pi.
+
+// defaultThrottleTemp is used for zones without a passive trip point. The
+// Raspberry Pi firmware starts throttling at 80 °C.
+const defaultThrottleTemp = 80.0
+
+// ThermalZone is a temperature sensor in °C.
+type ThermalZone struct {
+	Name     string  // zone type, e.g. cpu-thermal or x86_pkg_temp
+	Temp     float64 // current temperature
+	Throttle float64 // temperature at which the CPU is throttled
+}
+
+// Hot reports whether the zone is at or above its throttling point.
+func (z ThermalZone) Hot() bool { return z.Temp >= z.Throttle }
+
+// hotZones returns the zones at or above their throttling point.
+func hotZones(zones []ThermalZone) []ThermalZone {
+	var out []ThermalZone
+	for _, z := range zones {
+		if z.Hot() {
+			out = append(out, z)
+		}
+	}
+	return out
+}
+
+// readThermalZones reads the thermal zones below dir (normally
+// /sys/class/thermal). It returns nil if there are none, e.g. in VMs.
+func readThermalZones(dir string) ([]ThermalZone, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "thermal_zone*"))
+	if err != nil {
+		return nil, err
+	}
+	var zones []ThermalZone
+	var errs []error
+	for _, p := range paths {
+		temp, err := readMilliCelsius(filepath.Join(p, "temp"))
+		if err != nil {
+			// Disabled zones fail to read their temperature.
+			errs = append(errs, err)
+			continue
+		}
+		name, _ := os.ReadFile(filepath.Join(p, "type"))
+		zones = append(zones, ThermalZone{
+			Name:     cmp.Or(strings.TrimSpace(string(name)), filepath.Base(p)),
+			Temp:     temp,
+			Throttle: passiveTripPoint(p),
+		})
+	}
+	if len(zones) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	slices.SortFunc(zones, func(a, b ThermalZone) int { return strings.Compare(a.Name, b.Name) })
+	return zones, nil
+}
+
+// passiveTripPoint returns the lowest passive trip point of the zone, or
+// defaultThrottleTemp.
+func passiveTripPoint(zone string) float64 {
+	types, _ := filepath.Glob(filepath.Join(zone, "trip_point_*_type"))
+	low := 0.0
+	for _, p := range types {
+		typ, err := os.ReadFile(p)
+		if err != nil || strings.TrimSpace(string(typ)) != "passive" {
+			continue
+		}
+		temp, err := readMilliCelsius(strings.TrimSuffix(p, "_type") + "_temp")
+		if err != nil || temp <= 0 {
+			continue
+		}
+		if low == 0 || temp < low {
+			low = temp
+		}
+	}
+	if low == 0 {
+		return defaultThrottleTemp
+	}
+	return low
+}
+
+func readMilliCelsius(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return float64(v) / 1000, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZone creates a thermal zone below dir with the given sysfs files.
+func writeZone(t *testing.T, dir, zone string, files map[string]string) {
+	t.Helper()
+	p := filepath.Join(dir, zone)
+	if err := os.MkdirAll(p, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(p, name), []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadThermalZones(t *testing.T) {
+	dir := t.TempDir()
+	writeZone(t, dir, "thermal_zone0", map[string]string{
+		"type":              "x86_pkg_temp",
+		"temp":              "54000",
+		"trip_point_0_type": "critical",
+		"trip_point_0_temp": "105000",
+		"trip_point_1_type": "passive",
+		"trip_point_1_temp": "95000",
+	})
+	writeZone(t, dir, "thermal_zone1", map[string]string{
+		"type": "cpu-thermal",
+		"temp": "81234",
+	})
+	// Disabled zones cannot be read and are skipped.
+	writeZone(t, dir, "thermal_zone2", map[string]string{"type": "acpitz"})
+
+	zones, err := readThermalZones(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ThermalZone{
+		{Name: "cpu-thermal", Temp: 81.234, Throttle: defaultThrottleTemp},
+		{Name: "x86_pkg_temp", Temp: 54, Throttle: 95},
+	}
+	if len(zones) != len(want) {
+		t.Fatalf("got %+v, want %+v", zones, want)
+	}
+	for i := range want {
+		if zones[i] != want[i] {
+			t.Errorf("zone %d = %+v, want %+v", i, zones[i], want[i])
+		}
+	}
+	if hot := hotZones(zones); len(hot) != 1 || hot[0].Name != "cpu-thermal" {
+		t.Errorf("hotZones = %+v", hot)
+	}
+
+	if zones, err := readThermalZones(filepath.Join(dir, "missing")); zones != nil || err != nil {
+		t.Errorf("missing dir: %v, %v", zones, err)
+	}
+}
+
+func TestThermalWarning(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.thermalDir = t.TempDir()
+	writeZone(t, s.thermalDir, "thermal_zone0", map[string]string{"type": "cpu-thermal", "temp": "83000"})
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	for _, path := range []string{"/overview", "/system/cpus"} {
+		resp, err := http.Get(app.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		page := string(body)
+		if !strings.Contains(page, "cpu-thermal is at 83.0 &deg;C") || !strings.Contains(page, "thermally throttled") {
+			t.Errorf("%s does not warn about throttling", path)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// With PODFATHER_TLS_CERT and PODFATHER_TLS_KEY set, podfather serves HTTPS
+// itself. The files are re-read when the certificate file changes, so
+// renewals (e.g. by certbot) are picked up without a restart.
+
+// certReloader loads a certificate and key pair, reloading it when the
+// modification time of the certificate file changes.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// newCertReloader loads the certificate once, so configuration errors are
+// reported at startup.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := cr.getCertificate(nil); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// getCertificate implements tls.Config.GetCertificate. If reloading fails,
+// the previous certificate is kept.
+func (cr *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	fi, err := os.Stat(cr.certFile)
+	if err == nil && cr.cert != nil && fi.ModTime().Equal(cr.modTime) {
+		return cr.cert, nil
+	}
+	if err == nil {
+		var cert tls.Certificate
+		cert, err = tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+		if err == nil {
+			cr.cert, cr.modTime = &cert, fi.ModTime()
+			return cr.cert, nil
+		}
+	}
+	if cr.cert == nil {
+		return nil, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	log.Printf("tls: reloading certificate: %v, keeping the previous one", err)
+	cr.modTime = fi.ModTime()
+	return cr.cert, nil
+}
+
+// tlsConfig returns a TLS configuration with modern defaults: TLS 1.2 or
+// newer, and only forward-secret AEAD cipher suites for TLS 1.2 (TLS 1.3
+// suites are not configurable and all fine).
+func tlsConfig(cr *certReloader) *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: cr.getCertificate,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		CurvePreferences: []tls.CurveID{tls.X25519MLKEM768, tls.X25519, tls.CurveP256},
+	}
+}
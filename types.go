@@ -2,6 +2,8 @@ package main
 
 import (
 	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -57,6 +59,10 @@ type Container struct {
 	Ports        []Port              `json:"Ports"`
 	ExposedPorts map[string][]string `json:"ExposedPorts"`
 	Labels       map[string]string   `json:"Labels"`
+	Networks     []string            `json:"Networks"`
+	Pod          string              `json:"Pod"`     // libpod only, ID of the pod
+	PodName      string              `json:"PodName"` // libpod only
+	IsInfra      bool                `json:"IsInfra"` // libpod only, the pod's infra container
 }
 
 type Port struct {
@@ -66,6 +72,32 @@ type Port struct {
 	Protocol      string `json:"protocol"`
 }
 
+// dockerContainer is a container list entry from the Docker Engine compat
+// API, which differs from the libpod shape in Created, Command and Ports.
+type dockerContainer struct {
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	ImageID string            `json:"ImageID"`
+	Command string            `json:"Command"`
+	Created int64             `json:"Created"`
+	State   string            `json:"State"`
+	Status  string            `json:"Status"`
+	Ports   []dockerPort      `json:"Ports"`
+	Labels  map[string]string `json:"Labels"`
+
+	NetworkSettings struct {
+		Networks map[string]json.RawMessage `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+type dockerPort struct {
+	IP          string `json:"IP"`
+	PrivatePort uint16 `json:"PrivatePort"`
+	PublicPort  uint16 `json:"PublicPort"`
+	Type        string `json:"Type"`
+}
+
 type ContainerInspect struct {
 	ID              string           `json:"Id"`
 	Name            string           `json:"Name"`
@@ -81,6 +113,8 @@ type ContainerInspect struct {
 	RestartCount    int32            `json:"RestartCount"`
 	HostConfig      *HostConfig      `json:"HostConfig"`
 	OCIRuntime      string           `json:"OCIRuntime"`
+	Dependencies    []string         `json:"Dependencies"` // libpod only
+	Pod             string           `json:"Pod"`          // libpod only, ID of the pod
 	Driver          string           `json:"Driver"`
 }
 
@@ -97,11 +131,44 @@ type ContainerState struct {
 }
 
 type Health struct {
-	Status string `json:"Status"`
+	Status        string      `json:"Status"`
+	FailingStreak int         `json:"FailingStreak"`
+	Log           []HealthLog `json:"Log"`
+}
+
+// healthLogMax is the number of healthcheck results shown. Podman and
+// Docker keep the last five by default, but it is configurable.
+const healthLogMax = 10
+
+// Recent returns the last healthLogMax healthcheck results, newest first.
+func (h *Health) Recent() []HealthLog {
+	n := min(len(h.Log), healthLogMax)
+	out := make([]HealthLog, n)
+	for i := range out {
+		out[i] = h.Log[len(h.Log)-1-i]
+	}
+	return out
+}
+
+// HealthLog is the result of a single healthcheck run.
+type HealthLog struct {
+	Start    time.Time `json:"Start"`
+	End      time.Time `json:"End"`
+	ExitCode int       `json:"ExitCode"`
+	Output   string    `json:"Output"`
+}
+
+// Duration returns how long the healthcheck took.
+func (l HealthLog) Duration() time.Duration {
+	if l.End.Before(l.Start) {
+		return 0
+	}
+	return l.End.Sub(l.Start).Round(time.Millisecond)
 }
 
 type ContainerConfig struct {
 	Hostname     string              `json:"Hostname"`
+	Tty          bool                `json:"Tty"`
 	Image        string              `json:"Image"`
 	User         string              `json:"User"`
 	Cmd          []string            `json:"Cmd"`
@@ -111,17 +178,157 @@ type ContainerConfig struct {
 	Labels       map[string]string   `json:"Labels"`
 	Annotations  map[string]string   `json:"Annotations"`
 	ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	Secrets      []SecretRef         `json:"Secrets"`
 	// CreateCommand is intentionally omitted — may contain secrets in args.
 	// Env is intentionally omitted — never show environment variables.
 }
 
+// RunsAsRoot reports whether the container's processes run as root inside
+// the container.
+func (c ContainerConfig) RunsAsRoot() bool {
+	return rootUser(c.User)
+}
+
 type HostConfig struct {
 	RestartPolicy  RestartPolicy `json:"RestartPolicy"`
 	NetworkMode    string        `json:"NetworkMode"`
+	IpcMode        string        `json:"IpcMode"` // "container:ID" if joining another container's namespace
+	PidMode        string        `json:"PidMode"`
+	UTSMode        string        `json:"UTSMode"`
 	Privileged     bool          `json:"Privileged"`
 	ReadonlyRootfs bool          `json:"ReadonlyRootfs"`
 	AutoRemove     bool          `json:"AutoRemove"`
 	LogConfig      LogConfig     `json:"LogConfig"`
+	CpusetCpus     string        `json:"CpusetCpus"`
+	CpusetMems     string        `json:"CpusetMems"`
+
+	// Resource limits, 0 for none.
+	Memory            int64    `json:"Memory"`
+	MemoryReservation int64    `json:"MemoryReservation"`
+	MemorySwap        int64    `json:"MemorySwap"` // memory plus swap, -1 for unlimited swap
+	NanoCpus          int64    `json:"NanoCpus"`   // Docker --cpus
+	CpuQuota          int64    `json:"CpuQuota"`   // Podman --cpus, per CpuPeriod
+	CpuPeriod         int64    `json:"CpuPeriod"`
+	CpuShares         int64    `json:"CpuShares"`
+	PidsLimit         *int64   `json:"PidsLimit"` // 0 or -1 for none
+	Ulimits           []Ulimit `json:"Ulimits"`
+	CapAdd            []string `json:"CapAdd"`
+	CapDrop           []string `json:"CapDrop"`
+	SecurityOpt       []string `json:"SecurityOpt"`
+
+	UsernsMode string      `json:"UsernsMode"` // "" for the engine default
+	IDMappings *IDMappings `json:"IDMappings"` // libpod only, with --userns or --uidmap
+}
+
+// IDMappings are the UID and GID mappings of a container's user namespace,
+// as "container:host:size" ranges. Host IDs are those of the namespace
+// Podman runs in, i.e. subordinate IDs for rootless Podman.
+type IDMappings struct {
+	UIDMap []string `json:"UidMap"`
+	GIDMap []string `json:"GidMap"`
+}
+
+// IDMapping is a range of IDs mapped into a user namespace.
+type IDMapping struct {
+	Container int64
+	Host      int64
+	Size      int64
+}
+
+// parseIDMap parses "container:host:size" ranges, skipping malformed ones.
+func parseIDMap(ranges []string) []IDMapping {
+	var out []IDMapping
+	for _, r := range ranges {
+		parts := strings.Split(r, ":")
+		if len(parts) != 3 {
+			continue
+		}
+		var ids [3]int64
+		valid := true
+		for i, p := range parts {
+			n, err := strconv.ParseInt(p, 10, 64)
+			if err != nil || n < 0 {
+				valid = false
+				break
+			}
+			ids[i] = n
+		}
+		if valid {
+			out = append(out, IDMapping{Container: ids[0], Host: ids[1], Size: ids[2]})
+		}
+	}
+	return out
+}
+
+// UIDs returns the parsed UID mappings.
+func (m IDMappings) UIDs() []IDMapping { return parseIDMap(m.UIDMap) }
+
+// GIDs returns the parsed GID mappings.
+func (m IDMappings) GIDs() []IDMapping { return parseIDMap(m.GIDMap) }
+
+// RootUID returns the host UID that root in the container maps to, or ""
+// if it is not mapped.
+func (m IDMappings) RootUID() string {
+	for _, r := range m.UIDs() {
+		if r.Container == 0 && r.Size > 0 {
+			return strconv.FormatInt(r.Host, 10)
+		}
+	}
+	return ""
+}
+
+// Ulimit is a resource limit of the container's processes.
+type Ulimit struct {
+	Name string `json:"Name"`
+	Soft int64  `json:"Soft"`
+	Hard int64  `json:"Hard"`
+}
+
+// CPULimit returns the CPU limit as a number of CPUs, or "" without one.
+func (h HostConfig) CPULimit() string {
+	var cpus float64
+	switch {
+	case h.NanoCpus > 0:
+		cpus = float64(h.NanoCpus) / 1e9
+	case h.CpuQuota > 0 && h.CpuPeriod > 0:
+		cpus = float64(h.CpuQuota) / float64(h.CpuPeriod)
+	default:
+		return ""
+	}
+	return strconv.FormatFloat(cpus, 'f', -1, 64)
+}
+
+// SwapLimit returns the swap the container may use in addition to its
+// memory: "unlimited", a size, or "" for the engine default.
+func (h HostConfig) SwapLimit() string {
+	switch {
+	case h.MemorySwap < 0:
+		return "unlimited"
+	case h.MemorySwap > 0 && h.Memory > 0:
+		return humanSize(h.MemorySwap - h.Memory)
+	}
+	return ""
+}
+
+// Pids returns the PIDs limit, or 0 without one.
+func (h HostConfig) Pids() int64 {
+	if h.PidsLimit == nil || *h.PidsLimit < 0 {
+		return 0
+	}
+	return *h.PidsLimit
+}
+
+// SecurityOpts returns the security options. Docker inlines custom seccomp
+// profiles as JSON; they are shortened.
+func (h HostConfig) SecurityOpts() []string {
+	out := make([]string, len(h.SecurityOpt))
+	for i, opt := range h.SecurityOpt {
+		if key, value, _ := strings.Cut(opt, "="); strings.HasPrefix(value, "{") {
+			opt = key + "=(custom profile)"
+		}
+		out[i] = opt
+	}
+	return out
 }
 
 type RestartPolicy struct {
@@ -135,13 +342,29 @@ type LogConfig struct {
 
 type Mount struct {
 	Type        string `json:"Type"`
+	Name        string `json:"Name"` // of a volume
 	Source      string `json:"Source"`
 	Destination string `json:"Destination"`
 	RW          bool   `json:"RW"`
 }
 
 type NetworkSettings struct {
-	Ports map[string][]HostPort `json:"Ports"`
+	Ports     map[string][]HostPort      `json:"Ports"`
+	IPAddress string                     `json:"IPAddress"`
+	Networks  map[string]NetworkEndpoint `json:"Networks"`
+}
+
+// NetworkEndpoint is a container's attachment to a network.
+type NetworkEndpoint struct {
+	NetworkID           string   `json:"NetworkID"`
+	IPAddress           string   `json:"IPAddress"`
+	IPPrefixLen         int      `json:"IPPrefixLen"`
+	Gateway             string   `json:"Gateway"`
+	GlobalIPv6Address   string   `json:"GlobalIPv6Address"`
+	GlobalIPv6PrefixLen int      `json:"GlobalIPv6PrefixLen"`
+	IPv6Gateway         string   `json:"IPv6Gateway"`
+	MacAddress          string   `json:"MacAddress"`
+	Aliases             []string `json:"Aliases"` // DNS names of the container in the network
 }
 
 type HostPort struct {
@@ -150,10 +373,19 @@ type HostPort struct {
 }
 
 type ImageSummary struct {
-	ID       string   `json:"Id"`
-	RepoTags []string `json:"RepoTags"`
-	Created  int64    `json:"Created"`
-	Size     int64    `json:"Size"`
+	ID       string            `json:"Id"`
+	RepoTags []string          `json:"RepoTags"`
+	Created  int64             `json:"Created"`
+	Size     int64             `json:"Size"`
+	Labels   map[string]string `json:"Labels"`
+}
+
+// ImageRow is an image list entry with usage flags.
+type ImageRow struct {
+	ImageSummary
+	Dangling bool     // no tags
+	Unused   bool     // not used by any container
+	Missing  []string // labels required by the image policy but missing
 }
 
 type ImageInspect struct {
@@ -197,6 +429,140 @@ type ImageHistory struct {
 	Empty     bool      `json:"empty_layer"`
 }
 
+// ImageHistoryEntry is a build step from the image history endpoint, which
+// lists steps newest first with the size of the layer they created.
+type ImageHistoryEntry struct {
+	CreatedBy string `json:"CreatedBy"`
+	Size      int64  `json:"Size"`
+}
+
+// Secret is a Podman secret. Only metadata is parsed; secret values are
+// never requested from the API.
+type Secret struct {
+	ID        string     `json:"ID"`
+	Spec      SecretSpec `json:"Spec"`
+	CreatedAt time.Time  `json:"CreatedAt"`
+	UpdatedAt time.Time  `json:"UpdatedAt"`
+}
+
+type SecretSpec struct {
+	Name   string       `json:"Name"`
+	Driver SecretDriver `json:"Driver"`
+}
+
+// SecretDriver.Options is intentionally omitted — driver options may contain
+// credentials (e.g. for the shell driver).
+type SecretDriver struct {
+	Name string `json:"Name"`
+}
+
+// SecretRef is a container's reference to a secret, from inspect data.
+type SecretRef struct {
+	Name string `json:"Name"`
+	ID   string `json:"ID"`
+}
+
+// SecretUsage is a secret together with the containers referencing it.
+type SecretUsage struct {
+	Secret     Secret
+	Containers []ContainerInspect
+}
+
+// MissingSecret is a secret reference of a container that matches no
+// existing secret, e.g. because the secret was removed or recreated.
+type MissingSecret struct {
+	Container ContainerInspect
+	Ref       SecretRef
+}
+
+// LogLine is a parsed container log line.
+type LogLine struct {
+	Stream  string // "stdout" or "stderr"
+	Time    time.Time
+	Raw     string
+	Format  string // "json", "logfmt" or "" for plain text
+	Level   string // one of logLevels, or "" if unknown
+	Message string
+	Fields  []LogField
+	Source  string // container name, in merged app logs
+	Color   int    // index into the log tag colors, in merged app logs
+	Match   bool   // matched ?grep=
+	Gap     bool   // lines were left out before this one by ?grep=
+}
+
+type LogField struct {
+	Key   string
+	Value string
+}
+
+// SystemDF is the libpod disk usage report (/system/df).
+type SystemDF struct {
+	Images     []DFImage     `json:"Images"`
+	Containers []DFContainer `json:"Containers"`
+	Volumes    []DFVolume    `json:"Volumes"`
+}
+
+type DFImage struct {
+	Repository string    `json:"Repository"`
+	Tag        string    `json:"Tag"`
+	ImageID    string    `json:"ImageID"`
+	Created    time.Time `json:"Created"`
+	Size       int64     `json:"Size"`
+	SharedSize int64     `json:"SharedSize"`
+	UniqueSize int64     `json:"UniqueSize"`
+	Containers int       `json:"Containers"`
+}
+
+type DFContainer struct {
+	ContainerID string    `json:"ContainerID"`
+	Image       string    `json:"Image"`
+	Size        int64     `json:"Size"`
+	RWSize      int64     `json:"RWSize"`
+	Created     time.Time `json:"Created"`
+	Status      string    `json:"Status"`
+	Names       string    `json:"Names"`
+}
+
+type DFVolume struct {
+	VolumeName      string `json:"VolumeName"`
+	Links           int    `json:"Links"`
+	Size            int64  `json:"Size"`
+	ReclaimableSize int64  `json:"ReclaimableSize"`
+}
+
+// DFSummary is one row of the disk usage totals, like `podman system df`.
+type DFSummary struct {
+	Type        string
+	Total       int
+	Active      int
+	Size        int64
+	Reclaimable int64
+}
+
+// CPUCore is a host CPU and the containers pinned to it.
+type CPUCore struct {
+	ID         int
+	Containers []ContainerInspect
+}
+
+// Contended reports whether more than one running container is pinned to
+// the CPU.
+func (c CPUCore) Contended() bool {
+	n := 0
+	for _, ci := range c.Containers {
+		if ci.State.Running {
+			n++
+		}
+	}
+	return n > 1
+}
+
+// CPUOverlap is another container pinned to some of the same CPUs.
+type CPUOverlap struct {
+	Container ContainerInspect
+	CPUs      string
+}
+
 // App label prefix for container metadata.
 const appLabelPrefix = "ch.jo-m.go.podfather.app."
 
@@ -210,10 +576,16 @@ type App struct {
 	Description string
 	URL         string
 	Containers  []Container
+	// Condition is "degraded", "down" or "invalid" if the app's
+	// degraded-when/down-when labels say so, see conditions.go.
+	Condition       string
+	ConditionReason string
 }
 
 // AppCategory groups apps under a category heading.
 type AppCategory struct {
-	Name string
-	Apps []App
+	Name        string
+	Icon        string // from PODFATHER_CATEGORY_*, see categories.go
+	Description string
+	Apps        []App
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Containers managed by systemd (Quadlet or `podman generate systemd --new`)
+// carry the name of their unit in the PODMAN_SYSTEMD_UNIT label. Their page
+// shows the state of the unit from `systemctl --user show` and, with
+// ENABLE_ACTIONS, starts, stops and restarts the unit instead of the
+// container, so that systemd does not fight the action.
+
+// unitActions are the systemctl verbs offered for a container's unit.
+var unitActions = []string{"start", "stop", "restart"}
+
+// validUnit matches the service unit names systemctl is called with.
+var validUnit = regexp.MustCompile(`^[a-zA-Z0-9:_.@\\-]+\.service$`)
+
+// UnitStatus is the state of a container's systemd unit.
+type UnitStatus struct {
+	ID            string
+	ActiveState   string // e.g. "active", "failed"
+	SubState      string // e.g. "running", "exited"
+	UnitFileState string // e.g. "enabled", or "generated" for Quadlet units
+	Result        string // of the last run, e.g. "success", "exit-code"
+	NRestarts     int    // automatic restarts since the unit was started
+}
+
+// Badge returns the badge class for the active state.
+func (u UnitStatus) Badge() string {
+	switch u.ActiveState {
+	case "active":
+		return "running"
+	case "failed":
+		return "failed"
+	case "activating", "deactivating", "reloading":
+		return "created"
+	}
+	return "stopped"
+}
+
+// parseUnitStatus parses the output of systemctl show for one unit.
+func parseUnitStatus(out []byte) UnitStatus {
+	var u UnitStatus
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		key, value, _ := strings.Cut(sc.Text(), "=")
+		switch key {
+		case "Id":
+			u.ID = value
+		case "ActiveState":
+			u.ActiveState = value
+		case "SubState":
+			u.SubState = value
+		case "UnitFileState":
+			u.UnitFileState = value
+		case "Result":
+			u.Result = value
+		case "NRestarts":
+			u.NRestarts, _ = strconv.Atoi(value)
+		}
+	}
+	return u
+}
+
+// unitStatus returns the state of the user unit.
+func (s *Server) unitStatus(ctx context.Context, unit string) (UnitStatus, error) {
+	out, err := exec.CommandContext(ctx, s.systemctlBin, "--user", "show", "--property=Id,ActiveState,SubState,UnitFileState,Result,NRestarts", "--", unit).Output()
+	if err != nil {
+		return UnitStatus{}, fmt.Errorf("systemctl show: %w", err)
+	}
+	return parseUnitStatus(out), nil
+}
+
+// containerUnit returns the systemd unit of c, or "" if it has none or it
+// cannot be managed from here.
+func (s *Server) containerUnit(c ContainerInspect) string {
+	unit := c.Config.Labels[systemdUnitLabel]
+	if s.backend != backendPodman || !validUnit.MatchString(unit) {
+		return ""
+	}
+	return unit
+}
+
+// handleContainerUnit runs an action from unitActions on the unit of a
+// systemd-managed container.
+func (s *Server) handleContainerUnit(w http.ResponseWriter, r *http.Request) {
+	if !s.enableActions {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	action := r.PathValue("action")
+	if !slices.Contains(unitActions, action) {
+		http.Error(w, "Invalid action", http.StatusBadRequest)
+		return
+	}
+	c, ok := s.inspectForAction(w, r)
+	if !ok {
+		return
+	}
+	unit := s.containerUnit(c)
+	if unit == "" {
+		http.Error(w, "The container is not managed by systemd", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+	var out bytes.Buffer
+	err := runCommand(ctx, &out, s.systemctlBin, "--user", action, "--", unit)
+	s.actions.record(r, action+" unit", unit, err, "", nil)
+	if err != nil {
+		log.Printf("[%s] %s %s: %v: %s", reqID(r.Context()), action, unit, err, strings.TrimSpace(out.String()))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, s.basePath+"/container/"+c.ID, http.StatusSeeOther)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testUnitShow = `Id=web.service
+ActiveState=active
+SubState=running
+UnitFileState=generated
+Result=exit-code
+NRestarts=3
+`
+
+func TestParseUnitStatus(t *testing.T) {
+	t.Parallel()
+	want := UnitStatus{ID: "web.service", ActiveState: "active", SubState: "running", UnitFileState: "generated", Result: "exit-code", NRestarts: 3}
+	if got := parseUnitStatus([]byte(testUnitShow)); got != want {
+		t.Errorf("parseUnitStatus = %+v, want %+v", got, want)
+	}
+}
+
+func TestContainerUnit(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	bin, calls := filepath.Join(dir, "systemctl"), filepath.Join(dir, "calls")
+	err := os.WriteFile(bin, []byte("#!/bin/sh\ncase \"$2\" in\n"+
+		"show) printf '"+strings.ReplaceAll(testUnitShow, "\n", `\n`)+"' ;;\n*) echo \"$@\" >>"+calls+" ;;\nesac\n"), 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ContainerInspect{
+			ID:     "abc123",
+			Name:   "web",
+			Config: ContainerConfig{Labels: map[string]string{systemdUnitLabel: "web.service"}},
+			State:  ContainerState{Status: "running", Running: true},
+		})
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.backend = backendPodman
+	s.systemctlBin = bin
+	s.enableActions = true
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+
+	resp, err := http.Get(app.URL + "/container/abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{"Systemd Unit", `<span class="badge badge-running">active</span> (running)`, `<span class="badge badge-failed">exit-code</span>`, "/unit/restart"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("container page lacks %q", want)
+		}
+	}
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	for _, tt := range []struct {
+		action string
+		want   int
+	}{
+		{"reload", http.StatusBadRequest},
+		{"restart", http.StatusSeeOther},
+	} {
+		resp, err := noRedirect.PostForm(app.URL+"/container/abc123/unit/"+tt.action, url.Values{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.action, resp.StatusCode, tt.want)
+		}
+	}
+	got, _ := os.ReadFile(calls)
+	if string(got) != "--user restart -- web.service\n" {
+		t.Errorf("systemctl calls = %q", got)
+	}
+	if a := s.actions.list(); len(a) != 1 || a[0].Name != "restart unit" || a[0].Target != "web.service" {
+		t.Errorf("actions = %+v", a)
+	}
+}
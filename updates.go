@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The update checker periodically asks the registry for the current digest
+// of each running container's image reference (a HEAD request on the
+// manifest, like podman auto-update) and compares it with the local image's
+// repo digests. Only anonymous registry access is supported, so private
+// images are reported as check failures.
+
+// imageRef is a parsed image reference.
+type imageRef struct {
+	Registry string // e.g. "registry-1.docker.io"
+	Repo     string // e.g. "library/nginx"
+	Tag      string
+}
+
+// parseImageRef parses a tagged image reference, applying the Docker Hub
+// defaults. References pinned by digest are rejected, as they cannot change.
+func parseImageRef(ref string) (imageRef, error) {
+	if strings.Contains(ref, "@") {
+		return imageRef{}, errors.New("reference is pinned by digest")
+	}
+	var r imageRef
+	name := ref
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		name, r.Tag = ref[:i], ref[i+1:]
+	}
+	if r.Tag == "" {
+		r.Tag = "latest"
+	}
+	first, rest, ok := strings.Cut(name, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		r.Registry, r.Repo = first, rest
+	} else {
+		r.Registry, r.Repo = "docker.io", name
+	}
+	if r.Registry == "docker.io" {
+		r.Registry = "registry-1.docker.io"
+		if !strings.Contains(r.Repo, "/") {
+			r.Repo = "library/" + r.Repo
+		}
+	}
+	if r.Repo == "" || !validID.MatchString(r.Tag) {
+		return imageRef{}, fmt.Errorf("invalid image reference %q", ref)
+	}
+	return r, nil
+}
+
+// UpdateStatus is the result of the last check for an image reference.
+type UpdateStatus struct {
+	Available bool
+	Checked   time.Time
+	Err       string
+}
+
+type updateChecker struct {
+	*registryClient
+	s        *Server
+	interval time.Duration
+
+	mu      sync.Mutex
+	results map[string]UpdateStatus // by image reference
+	next    time.Time               // of the next check
+}
+
+func newUpdateChecker(s *Server, interval time.Duration) *updateChecker {
+	return &updateChecker{
+		registryClient: newRegistryClient(),
+		s:              s,
+		interval:       interval,
+	}
+}
+
+// status returns the last result for an image reference.
+func (u *updateChecker) status(ref string) UpdateStatus {
+	if u == nil {
+		return UpdateStatus{}
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.results[ref]
+}
+
+// available returns the image references with an update available.
+func (u *updateChecker) available() map[string]bool {
+	out := map[string]bool{}
+	if u == nil {
+		return out
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for ref, st := range u.results {
+		if st.Available {
+			out[ref] = true
+		}
+	}
+	return out
+}
+
+// nextCheck returns the time of the next check.
+func (u *updateChecker) nextCheck() time.Time {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.next
+}
+
+func (u *updateChecker) run(ctx context.Context) {
+	for {
+		u.check(ctx)
+		wait := u.interval + u.s.calendar.jitterDelay()
+		u.mu.Lock()
+		u.next = time.Now().Add(wait)
+		u.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// check checks the images of all running containers.
+func (u *updateChecker) check(ctx context.Context) {
+	list, err := u.s.listContainers(ctx)
+	if err != nil {
+		log.Printf("update check: %v", err)
+		return
+	}
+	images := map[string]string{} // reference -> image ID
+	for _, c := range list {
+		if c.State == "running" {
+			images[c.Image] = c.ImageID
+		}
+	}
+	results := map[string]UpdateStatus{}
+	for ref, id := range images {
+		st := UpdateStatus{Checked: time.Now()}
+		available, err := u.checkImage(ctx, ref, id)
+		if err != nil {
+			log.Printf("update check %s: %v", ref, err)
+			st.Err = err.Error()
+		}
+		st.Available = available
+		results[ref] = st
+	}
+	u.mu.Lock()
+	u.results = results
+	u.mu.Unlock()
+}
+
+func (u *updateChecker) checkImage(ctx context.Context, ref, imageID string) (bool, error) {
+	r, err := parseImageRef(ref)
+	if err != nil {
+		return false, err
+	}
+	img, err := u.s.inspectImage(ctx, imageID)
+	if err != nil {
+		return false, err
+	}
+	remote, err := u.digest(ctx, r)
+	if err != nil {
+		return false, err
+	}
+	for _, d := range img.RepoDigests {
+		if _, digest, _ := strings.Cut(d, "@"); digest == remote {
+			return false, nil
+		}
+	}
+	return true, nil
+}
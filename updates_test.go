@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    imageRef
+		wantErr bool
+	}{
+		{"nginx", imageRef{"registry-1.docker.io", "library/nginx", "latest"}, false},
+		{"docker.io/library/nginx:alpine", imageRef{"registry-1.docker.io", "library/nginx", "alpine"}, false},
+		{"jo-m/podfather:v1", imageRef{"registry-1.docker.io", "jo-m/podfather", "v1"}, false},
+		{"ghcr.io/org/app:1.2", imageRef{"ghcr.io", "org/app", "1.2"}, false},
+		{"localhost:5000/app", imageRef{"localhost:5000", "app", "latest"}, false},
+		{"nginx@sha256:abcd", imageRef{}, true},
+		{"ghcr.io/", imageRef{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseImageRef(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseImageRef(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseImageRef(%q) = %+v, want %+v", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateCheck(t *testing.T) {
+	t.Parallel()
+	var registry *httptest.Server
+	registry = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"token":"t0ken"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+registry.URL+`/token",service="test",scope="repository:x:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/library/nginx/manifests/alpine":
+			w.Header().Set("Docker-Content-Digest", "sha256:1d13701a5f9f3fb01aaa88cef2344d65b6b5bf6b7d9fa4cf0dca557a8d7702ba")
+		case "/v2/library/httpd/manifests/alpine":
+			w.Header().Set("Docker-Content-Digest", "sha256:ffff")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer registry.Close()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+
+	s := newTestServer(t, mock)
+	s.updates = newUpdateChecker(s, time.Hour)
+	s.updates.baseURL = func(string) string { return registry.URL }
+	s.updates.check(t.Context())
+
+	if st := s.updates.status("docker.io/library/nginx:alpine"); st.Available || st.Err != "" {
+		t.Errorf("nginx: %+v, want up to date", st)
+	}
+	if st := s.updates.status("docker.io/library/httpd:alpine"); !st.Available {
+		t.Errorf("httpd: %+v, want update available", st)
+	}
+	if st := s.updates.status("docker.io/library/busybox:latest"); st.Available || !strings.Contains(st.Err, "404") {
+		t.Errorf("busybox: %+v, want 404 error", st)
+	}
+	if got := s.updates.available(); len(got) != 1 || !got["docker.io/library/httpd:alpine"] {
+		t.Errorf("available() = %v", got)
+	}
+
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+	resp, err := http.Get(app.URL + "/apps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	buf := new(strings.Builder)
+	if _, err := io.Copy(buf, resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "badge-update") {
+		t.Error("apps page has no update badge")
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The uptime tracker samples the state of every app with containers and
+// counts the samples per day as up, degraded or down. The app page shows
+// the counts as a heatmap of the last uptimeDays days, one cell per day.
+
+const (
+	uptimeSampleInterval = 5 * time.Minute
+	uptimeDays           = 90
+	heatCellSize         = 12
+	heatCellStep         = 14
+)
+
+// UptimeDay counts the samples of one app on one day.
+type UptimeDay struct {
+	Up       int `json:"up"`
+	Degraded int `json:"degraded"`
+	Down     int `json:"down"`
+}
+
+func (d UptimeDay) total() int { return d.Up + d.Degraded + d.Down }
+
+// Status is "none" without samples, "down" if the app was down for at
+// least half of them, "degraded" if it was not always up, or "up".
+func (d UptimeDay) Status() string {
+	switch {
+	case d.total() == 0:
+		return "none"
+	case d.Down*2 >= d.total():
+		return "down"
+	case d.Down > 0 || d.Degraded > 0:
+		return "degraded"
+	}
+	return "up"
+}
+
+// Availability is the share of samples the app was up or degraded, in
+// percent.
+func (d UptimeDay) Availability() float64 {
+	if d.total() == 0 {
+		return 0
+	}
+	return float64(d.Up+d.Degraded) / float64(d.total()) * 100
+}
+
+type uptimeTracker struct {
+	s    *Server
+	path string
+
+	mu sync.Mutex
+	// days maps a local date (2006-01-02) to the samples per app name.
+	days map[string]map[string]UptimeDay
+}
+
+func newUptimeTracker(s *Server) *uptimeTracker {
+	t := &uptimeTracker{
+		s:    s,
+		path: filepath.Join(s.dataDir, "uptime.json"),
+		days: map[string]map[string]UptimeDay{},
+	}
+	data, err := os.ReadFile(t.path)
+	if err == nil {
+		err = json.Unmarshal(data, &t.days)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("uptime: loading %s: %v", t.path, err)
+	}
+	return t
+}
+
+func (t *uptimeTracker) run(ctx context.Context) {
+	for {
+		if err := t.sample(ctx, time.Now()); err != nil {
+			log.Printf("uptime: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(uptimeSampleInterval):
+		}
+	}
+}
+
+// appStatus returns "down" if no container of the app runs or its
+// down-when condition matches, "degraded" if its health is degraded or its
+// degraded-when condition matches, and "up" otherwise.
+func appStatus(app App) string {
+	switch health := appHealth(app.Containers); {
+	case health == "down" || app.Condition == "down":
+		return "down"
+	case health == "degraded" || app.Condition == "degraded":
+		return "degraded"
+	}
+	return "up"
+}
+
+// sample records the current status of every app with containers.
+func (t *uptimeTracker) sample(ctx context.Context, now time.Time) error {
+	list, err := t.s.listContainers(ctx)
+	if err != nil {
+		return err
+	}
+	categories := t.s.buildAppCategories(list)
+	if err := t.s.applyAppConditions(ctx, categories); err != nil {
+		log.Printf("uptime: app conditions: %v", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	today := now.Format(time.DateOnly)
+	for _, cat := range categories {
+		for _, app := range cat.Apps {
+			if len(app.Containers) == 0 {
+				continue
+			}
+			if t.days[today] == nil {
+				t.days[today] = map[string]UptimeDay{}
+			}
+			d := t.days[today][app.Name]
+			switch appStatus(app) {
+			case "up":
+				d.Up++
+			case "degraded":
+				d.Degraded++
+			default:
+				d.Down++
+			}
+			t.days[today][app.Name] = d
+		}
+	}
+
+	oldest := now.AddDate(0, 0, -uptimeDays+1).Format(time.DateOnly)
+	for day := range t.days {
+		if day < oldest {
+			delete(t.days, day)
+		}
+	}
+	return t.save()
+}
+
+func (t *uptimeTracker) save() error {
+	data, err := json.Marshal(t.days)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(t.path, data, 0o600)
+}
+
+// history returns the samples of app per day. It is safe to call on a nil
+// tracker.
+func (t *uptimeTracker) history(app string) map[string]UptimeDay {
+	out := map[string]UptimeDay{}
+	if t == nil {
+		return out
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for day, apps := range t.days {
+		if d, ok := apps[app]; ok {
+			out[day] = d
+		}
+	}
+	return out
+}
+
+// HeatCell is a day of the heatmap.
+type HeatCell struct {
+	X, Y   int
+	Status string
+	Title  string
+}
+
+// Heatmap is the data of the uptime heatmap SVG: weeks as columns, Monday
+// to Sunday as rows, like GitHub's contribution graph.
+type Heatmap struct {
+	Cells         []HeatCell
+	Width, Height int
+	Size          int
+	Total         UptimeDay // over all days
+}
+
+// uptimeHeatmap lays out the last uptimeDays days up to now.
+func uptimeHeatmap(days map[string]UptimeDay, now time.Time) Heatmap {
+	h := Heatmap{Size: heatCellSize, Height: 7 * heatCellStep}
+	first := now.AddDate(0, 0, -uptimeDays+1)
+	// Start the first column on the Monday of the first day's week.
+	offset := (int(first.Weekday()) + 6) % 7
+	for i := range uptimeDays {
+		day := first.AddDate(0, 0, i)
+		date := day.Format(time.DateOnly)
+		d := days[date]
+		pos := offset + i
+		title := date + ": no data"
+		if d.total() > 0 {
+			title = fmt.Sprintf("%s: %.1f%% available (%d up, %d degraded, %d down samples)", date, d.Availability(), d.Up, d.Degraded, d.Down)
+		}
+		h.Cells = append(h.Cells, HeatCell{
+			X:      pos / 7 * heatCellStep,
+			Y:      pos % 7 * heatCellStep,
+			Status: d.Status(),
+			Title:  title,
+		})
+		h.Total.Up += d.Up
+		h.Total.Degraded += d.Degraded
+		h.Total.Down += d.Down
+		h.Width = pos/7*heatCellStep + heatCellStep
+	}
+	return h
+}
+
+func (s *Server) handleApp(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		log.Printf("[%s] podman API error: %v", reqID(r.Context()), err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	categories := s.buildAppCategories(list)
+	if err := s.applyAppConditions(r.Context(), categories); err != nil {
+		log.Printf("[%s] app conditions: %v", reqID(r.Context()), err)
+	}
+	for _, cat := range categories {
+		for _, app := range cat.Apps {
+			if app.Name != name {
+				continue
+			}
+			s.render(w, r, "app.html", map[string]any{
+				"Title":    app.Name,
+				"App":      app,
+				"Tracked":  s.uptime != nil,
+				"Heatmap":  uptimeHeatmap(s.uptime.history(app.Name), time.Now()),
+				"Days":     uptimeDays,
+				"Interval": uptimeSampleInterval,
+			})
+			return
+		}
+	}
+	http.Error(w, "App Not Found", http.StatusNotFound)
+}
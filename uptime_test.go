@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUptimeDayStatus(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		day  UptimeDay
+		want string
+	}{
+		{UptimeDay{}, "none"},
+		{UptimeDay{Up: 288}, "up"},
+		{UptimeDay{Up: 287, Degraded: 1}, "degraded"},
+		{UptimeDay{Up: 200, Down: 88}, "degraded"},
+		{UptimeDay{Up: 144, Down: 144}, "down"},
+	}
+	for _, tt := range tests {
+		if got := tt.day.Status(); got != tt.want {
+			t.Errorf("%+v.Status() = %q, want %q", tt.day, got, tt.want)
+		}
+	}
+	if got := (UptimeDay{Up: 3, Degraded: 1, Down: 4}).Availability(); got != 50 {
+		t.Errorf("Availability = %v, want 50", got)
+	}
+}
+
+func TestAppStatus(t *testing.T) {
+	t.Parallel()
+	running, exited := Container{State: "running"}, Container{State: "exited"}
+	tests := []struct {
+		app  App
+		want string
+	}{
+		{App{Containers: []Container{running, running}}, "up"},
+		{App{Containers: []Container{running, exited}}, "degraded"},
+		{App{Containers: []Container{exited}}, "down"},
+		{App{Containers: []Container{running, {State: "running", Status: "unhealthy"}}}, "degraded"},
+		{App{Containers: []Container{running}, Condition: "degraded"}, "degraded"},
+		{App{Containers: []Container{running}, Condition: "down"}, "down"},
+	}
+	for _, tt := range tests {
+		if got := appStatus(tt.app); got != tt.want {
+			t.Errorf("appStatus(%+v) = %q, want %q", tt.app, got, tt.want)
+		}
+	}
+}
+
+func TestUptimeHeatmap(t *testing.T) {
+	t.Parallel()
+	// A Saturday: the first day, 89 days earlier, is a Monday.
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+	h := uptimeHeatmap(map[string]UptimeDay{
+		"2026-07-20": {Up: 10},
+		"2026-10-17": {Down: 10},
+	}, now)
+	if len(h.Cells) != uptimeDays {
+		t.Fatalf("%d cells, want %d", len(h.Cells), uptimeDays)
+	}
+	first, last := h.Cells[0], h.Cells[len(h.Cells)-1]
+	if first.X != 0 || first.Y != 0 || first.Status != "up" {
+		t.Errorf("first cell = %+v", first)
+	}
+	// 89 days after a Monday: week 12, Saturday.
+	if last.X != 12*heatCellStep || last.Y != 5*heatCellStep || last.Status != "down" {
+		t.Errorf("last cell = %+v", last)
+	}
+	if !strings.HasPrefix(last.Title, "2026-10-17: 0.0% available") {
+		t.Errorf("title = %q", last.Title)
+	}
+	if h.Width != 13*heatCellStep || h.Total != (UptimeDay{Up: 10, Down: 10}) {
+		t.Errorf("width %d, total %+v", h.Width, h.Total)
+	}
+}
+
+func TestUptimeTracker(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.dataDir = t.TempDir()
+	s.uptime = newUptimeTracker(s)
+	now := time.Now()
+	for range 3 {
+		if err := s.uptime.sample(t.Context(), now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	today := s.uptime.history("Jellyfin")[now.Format(time.DateOnly)]
+	if today.total() != 3 {
+		t.Errorf("Jellyfin today = %+v, want 3 samples", today)
+	}
+
+	// The samples survive a restart.
+	if got := newUptimeTracker(s).history("Jellyfin"); got[now.Format(time.DateOnly)] != today {
+		t.Errorf("reloaded history = %+v", got)
+	}
+
+	app := httptest.NewServer(s.newMux("podman"))
+	defer app.Close()
+	resp, err := http.Get(app.URL + "/app/Jellyfin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `<svg class="heatmap"`) {
+		t.Errorf("app page: %d, no heatmap", resp.StatusCode)
+	}
+	resp, err = http.Get(app.URL + "/app/Nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown app: %d, want 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Saved views are named query-string presets for list pages, e.g.
+// "/containers?state=exited". They are stored in a cookie, so they are per
+// browser and need no server-side state.
+
+const viewsCookieName = "views"
+
+const (
+	maxViews       = 10
+	maxViewName    = 40
+	maxViewQuery   = 512
+	viewsCookieAge = 365 * 24 * 60 * 60
+)
+
+// viewPages are the pages views can be saved for.
+var viewPages = map[string]bool{"containers": true, "images": true}
+
+// SavedView is a named query string for one of viewPages.
+type SavedView struct {
+	Name  string `json:"n"`
+	Page  string `json:"p"`
+	Query string `json:"q"`
+}
+
+// Href returns the view URL relative to the base path.
+func (v SavedView) Href() string {
+	if v.Query == "" {
+		return "/" + v.Page
+	}
+	return "/" + v.Page + "?" + v.Query
+}
+
+// readViews returns the saved views from the request cookie. Invalid
+// entries are dropped, so a tampered cookie cannot inject arbitrary pages.
+func readViews(r *http.Request) []SavedView {
+	c, err := r.Cookie(viewsCookieName)
+	if err != nil {
+		return nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil {
+		return nil
+	}
+	var views []SavedView
+	if err := json.Unmarshal(data, &views); err != nil {
+		return nil
+	}
+	out := views[:0]
+	for _, v := range views {
+		if nv, ok := normalizeView(v.Name, v.Page, v.Query); ok {
+			out = append(out, nv)
+		}
+	}
+	return out[:min(len(out), maxViews)]
+}
+
+func (s *Server) writeViews(w http.ResponseWriter, r *http.Request, views []SavedView) {
+	data, _ := json.Marshal(views)
+	http.SetCookie(w, &http.Cookie{
+		Name:     viewsCookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(data),
+		Path:     s.basePath + "/",
+		MaxAge:   viewsCookieAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// normalizeView validates a view and re-encodes its query string.
+func normalizeView(name, page, query string) (SavedView, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxViewName || !viewPages[page] || len(query) > maxViewQuery {
+		return SavedView{}, false
+	}
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return SavedView{}, false
+	}
+	q.Del(csrfFormField)
+	return SavedView{Name: name, Page: page, Query: q.Encode()}, true
+}
+
+func (s *Server) handleSaveView(w http.ResponseWriter, r *http.Request) {
+	v, ok := normalizeView(r.FormValue("name"), r.FormValue("page"), r.FormValue("query"))
+	if !ok {
+		http.Error(w, "Invalid view", http.StatusBadRequest)
+		return
+	}
+	views := readViews(r)
+	replaced := false
+	for i := range views {
+		if views[i].Name == v.Name {
+			views[i], replaced = v, true
+		}
+	}
+	if !replaced {
+		if len(views) >= maxViews {
+			http.Error(w, "Too many saved views", http.StatusBadRequest)
+			return
+		}
+		views = append(views, v)
+	}
+	s.writeViews(w, r, views)
+	http.Redirect(w, r, s.basePath+v.Href(), http.StatusSeeOther)
+}
+
+func (s *Server) handleDeleteView(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	views := readViews(r)
+	out := views[:0]
+	for _, v := range views {
+		if v.Name != name {
+			out = append(out, v)
+		}
+	}
+	s.writeViews(w, r, out)
+	http.Redirect(w, r, s.basePath+"/views", http.StatusSeeOther)
+}
+
+func (s *Server) handleViews(w http.ResponseWriter, r *http.Request) {
+	s.render(w, r, "views.html", map[string]any{
+		"Title": "Saved Views",
+	})
+}
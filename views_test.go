@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeView(t *testing.T) {
+	tests := []struct {
+		name, page, query string
+		wantOK            bool
+		wantQuery         string
+	}{
+		{"Exited", "containers", "state=exited", true, "state=exited"},
+		{" Big ", "images", "sort=size&_csrf=abc&order=desc", true, "order=desc&sort=size"},
+		{"", "containers", "q=x", false, ""},
+		{"Secrets", "secrets", "", false, ""},
+		{"Bad", "images", "%zz", false, ""},
+		{strings.Repeat("x", 41), "images", "", false, ""},
+	}
+	for _, tt := range tests {
+		v, ok := normalizeView(tt.name, tt.page, tt.query)
+		if ok != tt.wantOK {
+			t.Errorf("normalizeView(%q, %q, %q) ok = %v, want %v", tt.name, tt.page, tt.query, ok, tt.wantOK)
+			continue
+		}
+		if ok && v.Query != tt.wantQuery {
+			t.Errorf("normalizeView(%q) query = %q, want %q", tt.query, v.Query, tt.wantQuery)
+		}
+	}
+}
+
+func TestSavedViews(t *testing.T) {
+	s := &Server{}
+	post := func(path string, form url.Values, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		if strings.HasSuffix(path, "/delete") {
+			s.handleDeleteView(w, r)
+		} else {
+			s.handleSaveView(w, r)
+		}
+		return w
+	}
+	views := func(cookies []*http.Cookie) []SavedView {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		return readViews(r)
+	}
+
+	w := post("/views", url.Values{"name": {"Exited"}, "page": {"containers"}, "query": {"state=exited"}}, nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/containers?state=exited" {
+		t.Fatalf("save: status %d location %q", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	w = post("/views", url.Values{"name": {"Big"}, "page": {"images"}, "query": {"sort=size"}}, cookies)
+	cookies = w.Result().Cookies()
+	if got := views(cookies); len(got) != 2 || got[1].Href() != "/images?sort=size" {
+		t.Fatalf("views = %+v, want 2", got)
+	}
+
+	// Saving under an existing name replaces the view.
+	w = post("/views", url.Values{"name": {"Big"}, "page": {"images"}, "query": {"sort=created"}}, cookies)
+	cookies = w.Result().Cookies()
+	if got := views(cookies); len(got) != 2 || got[1].Query != "sort=created" {
+		t.Fatalf("views after replace = %+v", got)
+	}
+
+	w = post("/views/delete", url.Values{"name": {"Exited"}}, cookies)
+	cookies = w.Result().Cookies()
+	if got := views(cookies); len(got) != 1 || got[0].Name != "Big" {
+		t.Errorf("views after delete = %+v, want [Big]", got)
+	}
+
+	// A tampered cookie with an unknown page is ignored.
+	bad := []*http.Cookie{{Name: viewsCookieName, Value: "W3sibiI6IngiLCJwIjoiaGFjayIsInEiOiIifV0"}}
+	if got := views(bad); len(got) != 0 {
+		t.Errorf("tampered views = %+v, want none", got)
+	}
+}
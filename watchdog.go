@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// The watchdog restarts containers that crash or whose healthcheck turns
+// unhealthy. It needs ENABLE_ACTIONS and containers opt in with the label
+//
+//	ch.jo-m.go.podfather.watchdog=true
+//
+// Restarts are delayed with exponential backoff, so a container that keeps
+// failing is not restarted in a tight loop, and are recorded in the action
+// log. Containers that were stopped or killed are not restarted, even if
+// they exited with SIGKILL (see stopLog).
+
+const (
+	watchdogLabel = "ch.jo-m.go.podfather.watchdog"
+	// watchdogMaxDelay caps the backoff.
+	watchdogMaxDelay = 10 * time.Minute
+	// watchdogReset resets the backoff of a container that has not been
+	// restarted for this long.
+	watchdogReset = time.Hour
+)
+
+// watchdogReason returns why ev calls for a restart, or false if it does not
+// or the container has not opted in.
+func watchdogReason(ev containerEvent) (string, bool) {
+	attrs := ev.Actor.Attributes
+	if attrs[watchdogLabel] != "true" {
+		return "", false
+	}
+	switch ev.Action {
+	case "die", "died":
+		if code, crashed := crashExitCode(ev); crashed {
+			return "exited with code " + code, true
+		}
+	case "health_status", "health_status: unhealthy":
+		// Podman reports the status as an attribute, Docker in the action.
+		if ev.Action != "health_status" || attrs["health_status"] == "unhealthy" {
+			return "unhealthy", true
+		}
+	}
+	return "", false
+}
+
+// WatchdogStatus is what the watchdog did to a container.
+type WatchdogStatus struct {
+	Restarts int       // in total, since podfather started
+	Last     time.Time // last restart
+	Reason   string    // of the last restart
+	Next     time.Time // pending restart, zero if none
+}
+
+type watchdogState struct {
+	WatchdogStatus
+	backoff int // restarts without watchdogReset in between
+}
+
+type watchdog struct {
+	s        *Server
+	minDelay time.Duration // delay of the first restart, doubled for each further one
+
+	mu         sync.Mutex
+	containers map[string]*watchdogState // by container ID
+}
+
+func newWatchdog(s *Server) *watchdog {
+	return &watchdog{s: s, minDelay: 10 * time.Second, containers: map[string]*watchdogState{}}
+}
+
+// delay returns the backoff before the next restart after n restarts.
+func (wd *watchdog) delay(n int) time.Duration {
+	return min(wd.minDelay<<min(n, 16), watchdogMaxDelay)
+}
+
+// handle schedules a restart if ev calls for one and none is pending. It is
+// safe to call on a nil watchdog.
+func (wd *watchdog) handle(ctx context.Context, ev containerEvent, now time.Time) {
+	if wd == nil {
+		return
+	}
+	reason, ok := watchdogReason(ev)
+	if !ok || wd.s.stops.stopped(ev.Actor.ID, now) {
+		return
+	}
+	id := ev.Actor.ID
+	wd.mu.Lock()
+	st := wd.containers[id]
+	if st == nil {
+		st = &watchdogState{}
+		wd.containers[id] = st
+	}
+	if !st.Next.IsZero() {
+		wd.mu.Unlock()
+		return
+	}
+	if now.Sub(st.Last) > watchdogReset {
+		st.backoff = 0
+	}
+	delay := wd.delay(st.backoff)
+	st.Next = now.Add(delay)
+	wd.mu.Unlock()
+	go wd.restart(ctx, id, ev.Action, reason, now, delay)
+}
+
+// restart restarts the container after delay, unless it was stopped around
+// the event at t or recovered in the meantime, e.g. through its restart
+// policy.
+func (wd *watchdog) restart(ctx context.Context, id, action, reason string, t time.Time, delay time.Duration) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(delay):
+	}
+	var gone, attempted, restarted bool
+	defer func() {
+		wd.mu.Lock()
+		defer wd.mu.Unlock()
+		if gone {
+			delete(wd.containers, id)
+			return
+		}
+		st := wd.containers[id]
+		st.Next = time.Time{}
+		if attempted {
+			st.backoff++
+		}
+		if restarted {
+			st.Restarts++
+			st.Last, st.Reason = time.Now(), reason
+		}
+	}()
+
+	if wd.s.stops.stopped(id, t) {
+		return
+	}
+	c, err := wd.s.inspectContainer(ctx, id)
+	if err != nil {
+		gone = errors.Is(err, errNotFound)
+		log.Printf("watchdog: %v", err)
+		return
+	}
+	if action == "die" || action == "died" {
+		if c.State.Running {
+			return
+		}
+	} else if c.State.Health == nil || c.State.Health.Status != "unhealthy" {
+		return
+	}
+	err = wd.s.podmanPost(ctx, "/containers/"+c.ID+"/restart")
+	attempted = true
+	wd.s.actions.add("-", "watchdog", "restart ("+reason+")", c.Name, err, "", nil)
+	restarted = err == nil
+}
+
+// status returns what the watchdog did to the container id, or false if
+// nothing. It is safe to call on a nil watchdog.
+func (wd *watchdog) status(id string) (WatchdogStatus, bool) {
+	if wd == nil {
+		return WatchdogStatus{}, false
+	}
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	st := wd.containers[id]
+	if st == nil {
+		return WatchdogStatus{}, false
+	}
+	return st.WatchdogStatus, true
+}
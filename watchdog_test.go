@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchdogReason(t *testing.T) {
+	t.Parallel()
+	on := func(attrs map[string]string) map[string]string {
+		attrs[watchdogLabel] = "true"
+		return attrs
+	}
+	tests := []struct {
+		ev   containerEvent
+		want string // "" if no restart
+	}{
+		{testEvent("died", on(map[string]string{"containerExitCode": "1"})), "exited with code 1"},
+		{testEvent("died", on(map[string]string{"containerExitCode": "0"})), ""},
+		{testEvent("die", on(map[string]string{"exitCode": "143"})), ""},
+		{testEvent("health_status", on(map[string]string{"health_status": "unhealthy"})), "unhealthy"},
+		{testEvent("health_status", on(map[string]string{"health_status": "healthy"})), ""},
+		{testEvent("health_status: unhealthy", on(map[string]string{})), "unhealthy"},
+		{testEvent("oom", on(map[string]string{})), ""},
+		{testEvent("died", map[string]string{"containerExitCode": "1"}), ""},
+	}
+	for _, tt := range tests {
+		got, ok := watchdogReason(tt.ev)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("watchdogReason(%s %v) = %q, %v; want %q", tt.ev.Action, tt.ev.Actor.Attributes, got, ok, tt.want)
+		}
+	}
+}
+
+func TestWatchdogDelay(t *testing.T) {
+	t.Parallel()
+	wd := &watchdog{minDelay: 10 * time.Second}
+	for n, want := range map[int]time.Duration{0: 10 * time.Second, 1: 20 * time.Second, 3: 80 * time.Second, 100: watchdogMaxDelay} {
+		if got := wd.delay(n); got != want {
+			t.Errorf("delay(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestWatchdogRestart(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var posts []string
+	state := ContainerState{Status: "exited"}
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPost {
+			posts = append(posts, strings.TrimPrefix(r.URL.Path, "/v4.0.0/libpod"))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(ContainerInspect{ID: "4b9d1f0c2e3a", Name: "jellyfin", State: state})
+	}))
+	defer mock.Close()
+	s := newTestServer(t, mock)
+	s.stops = newStopLog()
+	wd := newWatchdog(s)
+	wd.minDelay = time.Millisecond
+
+	crash := func(running bool, want ...string) {
+		t.Helper()
+		mu.Lock()
+		state.Running, posts = running, nil
+		mu.Unlock()
+		wd.handle(t.Context(), testEvent("died", map[string]string{"name": "jellyfin", "containerExitCode": "1", watchdogLabel: "true"}), time.Now())
+		for {
+			if st, _ := wd.status("4b9d1f0c2e3a"); st.Next.IsZero() {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.Join(posts, " ") != strings.Join(want, " ") {
+			t.Errorf("running=%v: posts = %v, want %v", running, posts, want)
+		}
+	}
+	crash(false, "/containers/4b9d1f0c2e3a/restart")
+	crash(true) // restarted by its restart policy meanwhile
+	crash(false, "/containers/4b9d1f0c2e3a/restart")
+
+	st, ok := wd.status("4b9d1f0c2e3a")
+	if !ok || st.Restarts != 2 || st.Reason != "exited with code 1" {
+		t.Errorf("status = %+v, %v; want 2 restarts", st, ok)
+	}
+	if got := s.actions.list(); len(got) != 2 || got[0].Client != "watchdog" || got[0].Target != "jellyfin" {
+		t.Errorf("actions = %+v, want 2 watchdog restarts", got)
+	}
+
+	// Stopped or killed containers exiting with SIGKILL are left alone,
+	// whether the stop event comes first (Podman) or last (Docker).
+	for n, actions := range [][2]string{{"stop", "died"}, {"died", "stop"}, {"kill", "died"}} {
+		wd := newWatchdog(s)
+		wd.minDelay = 20 * time.Millisecond
+		mu.Lock()
+		state.Running, posts = false, nil
+		mu.Unlock()
+		now := time.Now().Add(time.Duration(n) * time.Hour)
+		for i, action := range actions {
+			attrs := map[string]string{"name": "jellyfin", watchdogLabel: "true"}
+			if action == "died" {
+				attrs["containerExitCode"] = "137"
+			}
+			// Like eventWatcher.handle.
+			ev, at := testEvent(action, attrs), now.Add(time.Duration(i)*10*time.Second)
+			s.stops.record(ev, at)
+			wd.handle(t.Context(), ev, at)
+		}
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		if len(posts) != 0 {
+			t.Errorf("%s then %s: posts = %v, want none", actions[0], actions[1], posts)
+		}
+		mu.Unlock()
+	}
+
+	var nilWatchdog *watchdog
+	nilWatchdog.handle(t.Context(), testEvent("died", map[string]string{"containerExitCode": "1", watchdogLabel: "true"}), time.Now())
+	if _, ok := nilWatchdog.status("4b9d1f0c2e3a"); ok {
+		t.Error("nil watchdog has a status")
+	}
+}
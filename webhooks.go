@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Inbound webhooks let CI pipelines trigger actions after pushing a new
+// image. They are disabled unless WEBHOOK_TOKEN is set, authenticate with
+// "Authorization: Bearer <token>" and are exempt from CSRF protection.
+
+const hooksPrefix = "/hooks/"
+
+// validImageRef matches image references like "ghcr.io/org/app:tag" or
+// "app@sha256:...". It is deliberately stricter than the registry grammar.
+var validImageRef = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/:@-]*$`)
+
+// rateLimiter is a token bucket: it holds up to burst tokens and refills at
+// rate tokens per second.
+type rateLimiter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{tokens: float64(burst), rate: rate, burst: float64(burst)}
+}
+
+func (l *rateLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// hook wraps a webhook handler with token authentication, rate limiting and
+// audit logging. Every call is logged with its outcome.
+func (s *Server) hook(action string, next func(w http.ResponseWriter, r *http.Request) (string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := reqID(r.Context())
+		if s.webhookToken == "" {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.webhookToken)) != 1 {
+			log.Printf("[%s] audit: webhook %s from %s: unauthorized", id, action, clientIP(r))
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !s.webhookLimiter.allow() {
+			log.Printf("[%s] audit: webhook %s from %s: rate limited", id, action, clientIP(r))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		// No webhook takes a body; refuse anything sizeable.
+		r.Body = http.MaxBytesReader(w, r.Body, 4096)
+		if _, err := io.Copy(io.Discard, r.Body); err != nil {
+			http.Error(w, "Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		msg, err := next(w, r)
+		var he hookError
+		switch {
+		case errors.As(err, &he):
+			log.Printf("[%s] audit: webhook %s from %s: rejected: %s", id, action, clientIP(r), he.msg)
+			http.Error(w, he.msg, he.status)
+		case err != nil:
+			log.Printf("[%s] audit: webhook %s from %s: failed: %v", id, action, clientIP(r), err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		default:
+			log.Printf("[%s] audit: webhook %s from %s: %s", id, action, clientIP(r), msg)
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprintln(w, msg)
+		}
+	}
+}
+
+// hookError is a client-visible webhook failure.
+type hookError struct {
+	status int
+	msg    string
+}
+
+func (e hookError) Error() string { return e.msg }
+
+func (s *Server) hookAutoUpdate(podmanBin string) func(http.ResponseWriter, *http.Request) (string, error) {
+	return func(w http.ResponseWriter, r *http.Request) (string, error) {
+		if s.backend != backendPodman {
+			return "", hookError{http.StatusNotFound, "Not Found"}
+		}
+		if !s.startAutoUpdate(podmanBin) {
+			return "", hookError{http.StatusConflict, "auto-update already running"}
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return "auto-update started", nil
+	}
+}
+
+func (s *Server) hookAppRestart(w http.ResponseWriter, r *http.Request) (string, error) {
+	name := r.PathValue("name")
+	if name == "" || len(name) > 256 {
+		return "", hookError{http.StatusBadRequest, "invalid app name"}
+	}
+	list, err := s.listContainers(r.Context())
+	if err != nil {
+		return "", err
+	}
+	var ids []string
+	for _, c := range list {
+		if c.Labels[appLabelPrefix+"name"] == name {
+			ids = append(ids, c.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return "", hookError{http.StatusNotFound, "app not found"}
+	}
+	for _, id := range ids {
+		if err := s.podmanPost(r.Context(), "/containers/"+id+"/restart"); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("restarted %d container(s) of app %q", len(ids), name), nil
+}
+
+func (s *Server) hookPull(w http.ResponseWriter, r *http.Request) (string, error) {
+	ref := r.URL.Query().Get("image")
+	if len(ref) > 512 || !validImageRef.MatchString(ref) {
+		return "", hookError{http.StatusBadRequest, "invalid image reference"}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Minute)
+	defer cancel()
+	if err := s.pullImage(ctx, ref, io.Discard); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pulled %s", ref), nil
+}
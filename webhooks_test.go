@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newHookTestApp(t *testing.T, s *Server) *httptest.Server {
+	t.Helper()
+	s.webhookToken = "s3cret"
+	s.webhookLimiter = newRateLimiter(0, 100)
+	return httptest.NewServer(s.csrfProtect(s.newMux("true")))
+}
+
+func postHook(t *testing.T, app *httptest.Server, path, token string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest("POST", app.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+
+	s := newTestServer(t, mock)
+	s.backend = backendPodman
+	app := newHookTestApp(t, s)
+	defer app.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"missing token", "/hooks/pull?image=nginx:latest", "", http.StatusUnauthorized, ""},
+		{"wrong token", "/hooks/pull?image=nginx:latest", "wrong", http.StatusUnauthorized, ""},
+		{"pull", "/hooks/pull?image=example.com/app:latest", "s3cret", http.StatusOK, "pulled example.com/app:latest"},
+		{"pull invalid ref", "/hooks/pull?image=-rf%20/", "s3cret", http.StatusBadRequest, "invalid image reference"},
+		{"pull missing ref", "/hooks/pull", "s3cret", http.StatusBadRequest, "invalid image reference"},
+		{"pull registry error", "/hooks/pull?image=example.com/missing:latest", "s3cret", http.StatusInternalServerError, ""},
+		{"restart app", "/hooks/app/Gitea/restart", "s3cret", http.StatusOK, "restarted 2 container(s)"},
+		{"restart unknown app", "/hooks/app/Nope/restart", "s3cret", http.StatusNotFound, "app not found"},
+		{"auto-update", "/hooks/auto-update", "s3cret", http.StatusAccepted, "auto-update started"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := postHook(t, app, tt.path, tt.token)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %q)", status, tt.wantStatus, body)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("body = %q, want substring %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWebhooksDisabledWithoutToken(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+
+	s := newTestServer(t, mock)
+	app := httptest.NewServer(s.csrfProtect(s.newMux("true")))
+	defer app.Close()
+
+	if status, _ := postHook(t, app, "/hooks/auto-update", ""); status != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestWebhooksRateLimit(t *testing.T) {
+	t.Parallel()
+	mock := newMockPodmanAPI(t)
+	defer mock.Close()
+
+	s := newTestServer(t, mock)
+	app := newHookTestApp(t, s)
+	defer app.Close()
+	s.webhookLimiter = newRateLimiter(0, 2)
+
+	for i := range 2 {
+		if status, _ := postHook(t, app, "/hooks/pull?image=example.com/app:latest", "s3cret"); status != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i, status, http.StatusOK)
+		}
+	}
+	if status, _ := postHook(t, app, "/hooks/pull?image=example.com/app:latest", "s3cret"); status != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+}